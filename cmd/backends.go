@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/dr8co/doppel/internal/s3"
+	"github.com/dr8co/doppel/internal/sftpfs"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// newBackends returns a registry of the backends serving URL scan targets.
+// Backends read their configuration when a URL of theirs is first opened.
+func newBackends() *vfs.Registry {
+	backends := vfs.NewRegistry()
+	backends.Register(s3.Scheme, s3.OpenerFromEnv())
+	backends.Register(sftpfs.Scheme, sftpfs.Opener(sftpfs.ConfigFromEnv()))
+	return backends
+}
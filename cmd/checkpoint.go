@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/checkpoint"
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// scanCheckpoint records the progress of a scan to a checkpoint file and, when resuming a scan,
+// holds the progress recorded before it was interrupted. A nil scanCheckpoint records nothing.
+type scanCheckpoint struct {
+	writer  *checkpoint.Writer
+	resumed *checkpoint.Checkpoint
+	closed  bool
+}
+
+// resumeFlags are the flags that may be given with --resume. They change how fast the scan runs,
+// what it reports, and how it exits, but not which files are scanned or how they are compared,
+// so that the files recorded before the interrupt and those found after it are selected alike.
+var resumeFlags = map[string]bool{
+	"resume":              true,
+	"workers":             true,
+	"verbose":             true,
+	"output-format":       true,
+	"output-file":         true,
+	"metrics-listen":      true,
+	"metrics-textfile":    true,
+	"progress-fd":         true,
+	"no-progress":         true,
+	"fail-on-error":       true,
+	"fail-if-wasted-over": true,
+	"fail-if-groups-over": true,
+	"io-per-device":       true,
+	"max-read-rate":       true,
+	"max-open-files":      true,
+	"idle-io":             true,
+	"nice":                true,
+}
+
+// checkResumeFlags returns an error naming the flags set that cannot be given with --resume.
+func checkResumeFlags(c *cli.Command) error {
+	var rejected []string
+	for _, f := range c.Flags {
+		name := f.Names()[0]
+		if f.IsSet() && !resumeFlags[name] {
+			rejected = append(rejected, "--"+name)
+		}
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("%s cannot be given with --resume: the scan resumes with the options it was started with",
+			strings.Join(rejected, ", "))
+	}
+	return nil
+}
+
+// loadCheckpoint reads the checkpoint to resume a scan from, which must be resumed from the directory
+// it was started in, since the paths of its configuration may be relative.
+func loadCheckpoint(path string) (*checkpoint.Checkpoint, error) {
+	resumed, err := checkpoint.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if resumed.Header.Dir != wd {
+		return nil, fmt.Errorf("the scan recorded in %s must be resumed from %s, the directory it was started in",
+			path, resumed.Header.Dir)
+	}
+	return resumed, nil
+}
+
+// openCheckpoint starts recording the progress of a scan of roots to cfg.Checkpoint, if set, or goes on
+// recording to the checkpoint resumed, if not nil. paths are the paths the scan was given.
+func openCheckpoint(cfg *config.FindConfig, paths []string, roots []scanner.Root, resumed *checkpoint.Checkpoint,
+) (*scanCheckpoint, error) {
+	if cfg.ShowFilters || (cfg.Checkpoint == "" && resumed == nil) {
+		return nil, nil
+	}
+	if cfg.FilesFrom == "-" {
+		return nil, errors.New("a scan reading its file list from standard input cannot be resumed")
+	}
+
+	rootPaths := make([]string, len(roots))
+	for i, root := range roots {
+		rootPaths[i] = root.Path
+	}
+
+	status := findStatusOutput(cfg)
+	if resumed != nil {
+		// Snapshots locate files by the index of their root.
+		if !slices.Equal(rootPaths, resumed.Header.Roots) {
+			return nil, fmt.Errorf("the paths to scan changed since the scan was interrupted: %v, now %v",
+				resumed.Header.Roots, rootPaths)
+		}
+		writer, err := resumed.Append(checkpoint.DefaultInterval)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Verbose {
+			resume := resumed.Resume()
+			_, _ = fmt.Fprintf(status, "⏯️ Resuming the scan recorded in %s: %d file%s found, %d hashed\n",
+				cfg.Checkpoint, resume.State.Stats.TotalFiles, pluralize(resume.State.Stats.TotalFiles),
+				resumed.Hashed())
+		}
+		return &scanCheckpoint{writer: writer, resumed: resumed}, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	header := checkpoint.Header{Dir: wd, Paths: paths, Roots: rootPaths, Config: *cfg}
+	writer, err := checkpoint.Create(cfg.Checkpoint, header, checkpoint.DefaultInterval)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Verbose {
+		_, _ = fmt.Fprintf(status, "💾 Recording the progress of the scan to %s\n", cfg.Checkpoint)
+	}
+	return &scanCheckpoint{writer: writer}, nil
+}
+
+// scanOptions returns the scanner options recording the walk, and resuming it.
+func (cp *scanCheckpoint) scanOptions() []scanner.Option {
+	if cp == nil {
+		return nil
+	}
+	opts := []scanner.Option{scanner.WithCheckpoint(cp.writer)}
+	if cp.resumed != nil {
+		opts = append(opts, scanner.WithResume(cp.resumed.Resume()))
+	}
+	return opts
+}
+
+// hashOptions returns the finder options recording the hashes computed.
+func (cp *scanCheckpoint) hashOptions() []finder.Option {
+	if cp == nil {
+		return nil
+	}
+	return []finder.Option{finder.WithRecorder(cp.writer)}
+}
+
+// err returns the error met reading back the files found before the scan was interrupted, if any.
+func (cp *scanCheckpoint) err() error {
+	if cp == nil || cp.resumed == nil {
+		return nil
+	}
+	return cp.resumed.Err()
+}
+
+// close syncs the checkpoint to disk and closes it. Closing it again does nothing.
+func (cp *scanCheckpoint) close() error {
+	if cp == nil || cp.closed {
+		return nil
+	}
+	cp.closed = true
+	return cp.writer.Close()
+}
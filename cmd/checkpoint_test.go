@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/config"
+)
+
+func TestCheckResumeFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"resume only", []string{"--resume", "scan.ckpt"}, false},
+		{"workers and output", []string{"--resume", "scan.ckpt", "--workers", "2", "--output-format", "json"}, false},
+		{"progress and thresholds", []string{"--resume", "scan.ckpt", "--no-progress", "--fail-if-groups-over", "3"}, false},
+		{"exclude filter", []string{"--resume", "scan.ckpt", "--exclude-dirs", "tmp"}, true},
+		{"size filter", []string{"--resume", "scan.ckpt", "--min-size", "1K"}, true},
+		{"owner filter", []string{"--resume", "scan.ckpt", "--owner", "root"}, true},
+		{"follow symlinks", []string{"--resume", "scan.ckpt", "--follow-symlinks"}, true},
+		{"samples", []string{"--resume", "scan.ckpt", "--samples", "3"}, true},
+		{"max memory", []string{"--resume", "scan.ckpt", "--max-memory", "1G"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			c := FindCommand(&config.FindConfig{})
+			c.Action = func(_ context.Context, c *cli.Command) error {
+				err = checkResumeFlags(c)
+				return nil
+			}
+			if runErr := c.Run(context.Background(), append([]string{"find"}, tt.args...)); runErr != nil {
+				t.Fatalf("Run() error = %v", runErr)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkResumeFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/output"
+)
+
+// Exit statuses of the commands, as returned by [ExitCode].
+const (
+	// ExitOK is the exit status of a command that succeeded. For the scanning commands,
+	// it means that no duplicates were found, or none over the thresholds set.
+	ExitOK = 0
+
+	// ExitDuplicates is the exit status of a scan that found duplicates, or duplication over the thresholds set.
+	ExitDuplicates = 1
+
+	// ExitError is the exit status of a command that failed, including interrupted scans,
+	// and scans with --fail-on-error that could not read some files.
+	ExitError = 2
+)
+
+var (
+	// ErrDuplicatesFound is returned by the scanning commands once the report is written, if duplicates were found,
+	// or duplication over the thresholds set.
+	ErrDuplicatesFound = errors.New("duplicates found")
+
+	// ErrInterrupted is returned by the scanning commands once the partial report of an interrupted scan is written.
+	ErrInterrupted = errors.New("the scan was interrupted, the report is partial")
+
+	// ErrFileErrors is returned by the scanning commands with --fail-on-error once the report is written,
+	// if any file could not be read.
+	ErrFileErrors = errors.New("some files could not be read")
+)
+
+// ExitCode returns the exit status of a command that returned err.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrDuplicatesFound):
+		return ExitDuplicates
+	default:
+		return ExitError
+	}
+}
+
+// thresholds are the limits of duplication over which a scan counts as having found duplicates.
+// Zero limits are not checked, and with no limits set, any duplicate counts.
+type thresholds struct {
+	wasted int64
+	groups int
+}
+
+// parseThresholds returns the thresholds set in cfg.
+func parseThresholds(cfg *config.FindConfig) (thresholds, error) {
+	wasted, err := filter.ParseFileSize(cfg.FailIfWastedOver)
+	if err != nil {
+		return thresholds{}, fmt.Errorf("invalid fail-if-wasted-over: %w", err)
+	}
+	if cfg.FailIfGroupsOver < 0 {
+		return thresholds{}, fmt.Errorf("invalid fail-if-groups-over: %d", cfg.FailIfGroupsOver)
+	}
+	return thresholds{wasted: wasted, groups: cfg.FailIfGroupsOver}, nil
+}
+
+// checkReport returns the error deciding the exit status of a scan, once its report is written.
+// Duplication over the thresholds is logged, while any duplicate at all is shown by the report itself.
+func checkReport(ctx context.Context, cfg *config.FindConfig, report *model.DuplicateReport, limits thresholds,
+) error {
+	if !report.Complete {
+		return ErrInterrupted
+	}
+	if n := report.Stats.ErrorCount; cfg.FailOnError && n > 0 {
+		return fmt.Errorf("%w: %d error%s", ErrFileErrors, n, pluralize(n))
+	}
+
+	if limits.wasted == 0 && limits.groups == 0 {
+		if len(report.Groups) > 0 {
+			return ErrDuplicatesFound
+		}
+		return nil
+	}
+
+	var attrs []slog.Attr
+	//nolint:gosec
+	if limits.wasted > 0 && report.TotalWastedSpace > uint64(limits.wasted) {
+		//nolint:gosec
+		attrs = append(attrs, slog.String("wasted", output.FormatBytes(int64(report.TotalWastedSpace))),
+			slog.String("fail_if_wasted_over", output.FormatBytes(limits.wasted)))
+	}
+	if n := len(report.Groups); limits.groups > 0 && n > limits.groups {
+		attrs = append(attrs, slog.Int("groups", n), slog.Int("fail_if_groups_over", limits.groups))
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	logger.WarnAttrs(ctx, "duplication over the thresholds", attrs...)
+	return ErrDuplicatesFound
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, ExitOK},
+		{"duplicates", ErrDuplicatesFound, ExitDuplicates},
+		{"wrapped duplicates", fmt.Errorf("scan: %w", ErrDuplicatesFound), ExitDuplicates},
+		{"interrupted", ErrInterrupted, ExitError},
+		{"file errors", fmt.Errorf("%w: 2 errors", ErrFileErrors), ExitError},
+		{"other error", errors.New("invalid configuration"), ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		name    string
+		wasted  string
+		groups  int
+		want    thresholds
+		wantErr bool
+	}{
+		{"none", "", 0, thresholds{}, false},
+		{"wasted in bytes", "100", 0, thresholds{wasted: 100}, false},
+		{"wasted with unit", "1KiB", 0, thresholds{wasted: 1024}, false},
+		{"groups", "", 3, thresholds{groups: 3}, false},
+		{"both", "2048", 5, thresholds{wasted: 2048, groups: 5}, false},
+		{"malformed wasted", "lots", 0, thresholds{}, true},
+		{"unknown unit", "10XB", 0, thresholds{}, true},
+		{"blank wasted", " ", 0, thresholds{}, true},
+		{"negative groups", "", -1, thresholds{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseThresholds(&config.FindConfig{FailIfWastedOver: tt.wasted, FailIfGroupsOver: tt.groups})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseThresholds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseThresholds() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// reportOf returns a complete report of n duplicate groups wasting wasted bytes,
+// with unreadable files that could not be read.
+func reportOf(n int, wasted, unreadable uint64) *model.DuplicateReport {
+	report := &model.DuplicateReport{Complete: true, TotalWastedSpace: wasted,
+		Stats: &model.Stats{ErrorCount: unreadable}}
+	for range n {
+		report.Groups = append(report.Groups, model.DuplicateGroup{})
+	}
+	return report
+}
+
+func TestCheckReport(t *testing.T) {
+	interrupted := reportOf(1, 10, 0)
+	interrupted.Complete = false
+
+	tests := []struct {
+		name        string
+		failOnError bool
+		limits      thresholds
+		report      *model.DuplicateReport
+		wantErr     error
+		wantCode    int
+	}{
+		{"no duplicates", false, thresholds{}, reportOf(0, 0, 0), nil, ExitOK},
+		{"duplicates", false, thresholds{}, reportOf(2, 10, 0), ErrDuplicatesFound, ExitDuplicates},
+		{"interrupted", false, thresholds{}, interrupted, ErrInterrupted, ExitError},
+		{"interrupted without duplicates", false, thresholds{}, &model.DuplicateReport{Stats: &model.Stats{}},
+			ErrInterrupted, ExitError},
+
+		{"wasted at the limit", false, thresholds{wasted: 100}, reportOf(3, 100, 0), nil, ExitOK},
+		{"wasted over the limit", false, thresholds{wasted: 100}, reportOf(3, 101, 0), ErrDuplicatesFound, ExitDuplicates},
+		{"groups at the limit", false, thresholds{groups: 3}, reportOf(3, 1000, 0), nil, ExitOK},
+		{"groups over the limit", false, thresholds{groups: 3}, reportOf(4, 1000, 0), ErrDuplicatesFound, ExitDuplicates},
+		{"both at the limits", false, thresholds{wasted: 100, groups: 3}, reportOf(3, 100, 0), nil, ExitOK},
+		{"wasted over, groups at the limit", false, thresholds{wasted: 100, groups: 3}, reportOf(3, 101, 0),
+			ErrDuplicatesFound, ExitDuplicates},
+		{"groups over, wasted at the limit", false, thresholds{wasted: 100, groups: 3}, reportOf(4, 100, 0),
+			ErrDuplicatesFound, ExitDuplicates},
+
+		{"unreadable files", false, thresholds{}, reportOf(0, 0, 2), nil, ExitOK},
+		{"fail on error without unreadable files", true, thresholds{}, reportOf(0, 0, 0), nil, ExitOK},
+		{"fail on error with unreadable files", true, thresholds{}, reportOf(0, 0, 2), ErrFileErrors, ExitError},
+		{"fail on error over duplicates", true, thresholds{}, reportOf(2, 10, 1), ErrFileErrors, ExitError},
+		{"fail on error interrupted", true, thresholds{}, interrupted, ErrInterrupted, ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.FindConfig{FailOnError: tt.failOnError}
+			err := checkReport(context.Background(), cfg, tt.report, tt.limits)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("checkReport() error = %v, want %v", err, tt.wantErr)
+			}
+			if code := ExitCode(err); code != tt.wantCode {
+				t.Errorf("ExitCode() = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -3,6 +3,10 @@
 // This package implements the CLI commands using the urfave/cli framework, including
 //   - find: The main command for finding duplicate files with extensive filtering options
 //   - preset: Command for using predefined filter configurations for common scenarios
+//   - manifest: Command for recording file hashes to match against on other machines
+//   - hash: Command for writing checksum files compatible with sha256sum and b3sum
+//   - watch: Command for tracking duplicate files as directories change
+//   - serve: Command for running scans requested over an HTTP API
 //
 // Each command supports various flags for controlling worker threads, output formats,
 // filtering criteria, and other operational parameters.
@@ -10,35 +14,52 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/urfave/cli/v3"
 
+	"github.com/dr8co/doppel/internal/checkpoint"
+	"github.com/dr8co/doppel/internal/checksum"
 	"github.com/dr8co/doppel/internal/config"
 	"github.com/dr8co/doppel/internal/filter"
 	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/manifest"
 	"github.com/dr8co/doppel/internal/model"
 	"github.com/dr8co/doppel/internal/output"
 	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
 )
 
+// maxListedPaths is the maximum number of paths listed individually in verbose output.
+const maxListedPaths = 10
+
 // FindCommand returns the find command configuration.
 func FindCommand(cfg *config.FindConfig) *cli.Command {
 	return &cli.Command{
 		Name:    "find",
 		Aliases: []string{"search", "f"},
 		Usage:   "Find duplicate files in specified directories",
-		Description: `Scan directories for duplicate files. If no directories are specified, 
-only the current working directory is scanned.
-Files are compared by their hashes after filtration.`,
-		ArgsUsage:             "[directories...]",
+		Description: `Scan directories and files for duplicate files. If no paths are specified
+(and no file list is given), only the current working directory is scanned.
+Paths may also be URLs such as s3://bucket/prefix or sftp://user@host/path.
+Files are compared by their hashes after filtration.
+With --checkpoint, the progress of the scan is recorded, so that an interrupted scan
+can be resumed with --resume.
+
+Exit status: 0 if no duplicates were found, 1 if duplicates were found (or more than
+--fail-if-wasted-over or --fail-if-groups-over allow), and 2 if an error occurred.`,
+		ArgsUsage:             "[directories or files...]",
 		EnableShellCompletion: true,
 		Suggest:               true,
 
@@ -88,6 +109,70 @@ Files are compared by their hashes after filtration.`,
 				Usage: "Maximum file size (e.g., 100MB, 2GB, 1TiB) (0 = no limit)",
 				Value: "",
 			},
+			&cli.StringFlag{
+				Name:  "owner",
+				Usage: "Only include files owned by this user (name or ID)",
+				Value: "",
+			},
+			&cli.StringFlag{
+				Name:  "group",
+				Usage: "Only include files owned by this group (name or ID)",
+				Value: "",
+			},
+			&cli.StringFlag{
+				Name:  "uid",
+				Usage: "Only include files owned by this numeric user ID",
+				Value: "",
+			},
+			&cli.StringFlag{
+				Name:  "gid",
+				Usage: "Only include files owned by this numeric group ID",
+				Value: "",
+			},
+			&cli.BoolFlag{
+				Name:  "writable-only",
+				Usage: "Skip files the current user cannot remove (parent directory not writable)",
+			},
+			&cli.BoolFlag{
+				Name:    "follow-symlinks",
+				Aliases: []string{"L"},
+				Usage:   "Follow symlinks to files and directories (loops are detected and skipped)",
+			},
+			&cli.BoolFlag{
+				Name:  "report-symlinks",
+				Usage: "Flag symlinks whose targets duplicate other files",
+			},
+			&cli.BoolFlag{
+				Name:    "one-file-system",
+				Aliases: []string{"x"},
+				Usage:   "Stay on the filesystem of each scanned directory, skipping other mount points",
+			},
+			&cli.StringFlag{
+				Name:  "exclude-fstype",
+				Usage: "Comma-separated list of filesystem types to skip (e.g., nfs,proc,tmpfs) (Linux only)",
+				Value: "",
+			},
+			&cli.StringFlag{
+				Name:  "files-from",
+				Usage: "Read the paths to scan from a file (- for stdin), separated by NUL bytes or newlines",
+				Value: "",
+			},
+			&cli.BoolFlag{
+				Name:  "scan-archives",
+				Usage: "Scan the members of zip and tar (.tar, .tar.gz, .tar.bz2, .tar.xz) archives as read-only files",
+			},
+			&cli.StringSliceFlag{
+				Name:  "reference",
+				Usage: "Directory with canonical copies that are never suggested for removal (repeatable)",
+			},
+			&cli.StringSliceFlag{
+				Name:  "against",
+				Usage: "Match files against a hash manifest from `doppel manifest create` (repeatable)",
+			},
+			&cli.StringSliceFlag{
+				Name:  "known-hashes",
+				Usage: "Match files against a checksum file like SHA256SUMS or B3SUMS (repeatable, e.g. sha256:SUMS)",
+			},
 			&cli.BoolFlag{
 				Name:  "show-filters",
 				Usage: "Show active filters and exit without scanning",
@@ -102,6 +187,78 @@ Files are compared by their hashes after filtration.`,
 				Usage: "Write output to file (default: stdout)",
 				Value: "",
 			},
+			&cli.BoolFlag{
+				Name:  "fail-on-error",
+				Usage: "Fail once the report is written if any file could not be read",
+			},
+			&cli.StringFlag{
+				Name:  "fail-if-wasted-over",
+				Usage: "Only exit with status 1 if duplicates waste more than this much space (e.g. '100MB')",
+			},
+			&cli.IntFlag{
+				Name:  "fail-if-groups-over",
+				Usage: "Only exit with status 1 if there are more than this many duplicate groups",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-listen",
+				Usage: "Serve Prometheus metrics on this address while scanning (e.g. :9101)",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-textfile",
+				Usage: "Write Prometheus metrics to this file when done, for the node_exporter textfile collector",
+			},
+			&cli.IntFlag{
+				Name:  "progress-fd",
+				Usage: "Write JSON progress events to this file descriptor (e.g. 3), for scripts and front ends",
+			},
+			&cli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "Do not show the progress bar on terminals",
+			},
+			&cli.StringFlag{
+				Name:  "io-per-device",
+				Usage: "Read each device with workers of its own, as many as set per kind of device (e.g. 'hdd=1,ssd=8')",
+			},
+			&cli.StringFlag{
+				Name:  "max-read-rate",
+				Usage: "Read files at most this fast, across all workers (e.g. '50MB/s')",
+			},
+			&cli.IntFlag{
+				Name:  "max-open-files",
+				Usage: "Read at most this many files at the same time (0 for no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "idle-io",
+				Usage: "Only read the disks when no other process needs them (Linux only)",
+			},
+			&cli.IntFlag{
+				Name:  "nice",
+				Usage: "Run with this nice level, from -20 to 19 (Linux only)",
+			},
+			&cli.StringFlag{
+				Name:  "samples",
+				Usage: "Hash samples of large files at these points before reading them in full (e.g. 'middle,random=4')",
+			},
+			&cli.StringFlag{
+				Name:  "chunked-compare",
+				Usage: "Compare files of at least this size block by block, dropping them as soon as they differ (e.g. '64MB')",
+			},
+			&cli.StringFlag{
+				Name:  "max-memory",
+				Usage: "Keep memory under this ceiling by spilling the files found to disk (e.g. '2GB'); symlink options still use memory per file",
+			},
+			&cli.StringFlag{
+				Name:  "spill-dir",
+				Usage: "Directory to spill the files found to with --max-memory (default is the temporary directory)",
+			},
+			&cli.StringFlag{
+				Name:  "checkpoint",
+				Usage: "Record the progress of the scan to this file, to resume the scan with --resume if interrupted",
+			},
+			&cli.StringFlag{
+				Name:  "resume",
+				Usage: "Resume the scan recorded in this checkpoint file, with the paths and options it was started with",
+			},
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
 			return findDuplicatesCmd(ctx, c, cfg)
@@ -111,6 +268,30 @@ Files are compared by their hashes after filtration.`,
 
 // findDuplicatesCmd is the action function for the find command.
 func findDuplicatesCmd(ctx context.Context, c *cli.Command, cfg *config.FindConfig) error {
+	// A resumed scan runs with the configuration it was started with; only the flags that do not change
+	// which files are scanned, or how they are compared, may be set again.
+	var resumed *checkpoint.Checkpoint
+	paths := c.Args().Slice()
+	if c.IsSet("resume") {
+		if c.Args().Present() {
+			return errors.New("paths cannot be given with --resume: the scan resumes with the paths it was started with")
+		}
+		if c.IsSet("checkpoint") {
+			return errors.New("--checkpoint cannot be given with --resume, which records to the checkpoint resumed")
+		}
+		if err := checkResumeFlags(c); err != nil {
+			return err
+		}
+		var err error
+		resumed, err = loadCheckpoint(c.String("resume"))
+		if err != nil {
+			return err
+		}
+		*cfg = resumed.Header.Config
+		cfg.Checkpoint = c.String("resume")
+		paths = resumed.Header.Paths
+	}
+
 	// Override with CLI flags
 	if c.IsSet("workers") {
 		cfg.Workers = c.Int("workers")
@@ -136,6 +317,48 @@ func findDuplicatesCmd(ctx context.Context, c *cli.Command, cfg *config.FindConf
 	if c.IsSet("max-size") {
 		cfg.MaxSize = c.String("max-size")
 	}
+	if c.IsSet("owner") {
+		cfg.Owner = c.String("owner")
+	}
+	if c.IsSet("group") {
+		cfg.Group = c.String("group")
+	}
+	if c.IsSet("uid") {
+		cfg.UID = c.String("uid")
+	}
+	if c.IsSet("gid") {
+		cfg.GID = c.String("gid")
+	}
+	if c.IsSet("writable-only") {
+		cfg.WritableOnly = c.Bool("writable-only")
+	}
+	if c.IsSet("follow-symlinks") {
+		cfg.FollowSymlinks = c.Bool("follow-symlinks")
+	}
+	if c.IsSet("report-symlinks") {
+		cfg.ReportSymlinks = c.Bool("report-symlinks")
+	}
+	if c.IsSet("one-file-system") {
+		cfg.OneFileSystem = c.Bool("one-file-system")
+	}
+	if c.IsSet("exclude-fstype") {
+		cfg.ExcludeFSType = c.String("exclude-fstype")
+	}
+	if c.IsSet("files-from") {
+		cfg.FilesFrom = c.String("files-from")
+	}
+	if c.IsSet("scan-archives") {
+		cfg.ScanArchives = c.Bool("scan-archives")
+	}
+	if c.IsSet("reference") {
+		cfg.Reference = c.StringSlice("reference")
+	}
+	if c.IsSet("against") {
+		cfg.Against = c.StringSlice("against")
+	}
+	if c.IsSet("known-hashes") {
+		cfg.KnownHashes = c.StringSlice("known-hashes")
+	}
 	if c.IsSet("show-filters") {
 		cfg.ShowFilters = c.Bool("show-filters")
 	}
@@ -145,25 +368,100 @@ func findDuplicatesCmd(ctx context.Context, c *cli.Command, cfg *config.FindConf
 	if c.IsSet("output-format") {
 		cfg.OutputFormat = c.String("output-format")
 	}
+	if c.IsSet("fail-on-error") {
+		cfg.FailOnError = c.Bool("fail-on-error")
+	}
+	if c.IsSet("fail-if-wasted-over") {
+		cfg.FailIfWastedOver = c.String("fail-if-wasted-over")
+	}
+	if c.IsSet("fail-if-groups-over") {
+		cfg.FailIfGroupsOver = c.Int("fail-if-groups-over")
+	}
+	if c.IsSet("metrics-listen") {
+		cfg.MetricsListen = c.String("metrics-listen")
+	}
+	if c.IsSet("metrics-textfile") {
+		cfg.MetricsTextfile = c.String("metrics-textfile")
+	}
+	if c.IsSet("progress-fd") {
+		cfg.ProgressFD = c.Int("progress-fd")
+	}
+	if c.IsSet("no-progress") {
+		cfg.NoProgress = c.Bool("no-progress")
+	}
+	if c.IsSet("io-per-device") {
+		cfg.IOPerDevice = c.String("io-per-device")
+	}
+	if c.IsSet("max-read-rate") {
+		cfg.MaxReadRate = c.String("max-read-rate")
+	}
+	if c.IsSet("max-open-files") {
+		cfg.MaxOpenFiles = c.Int("max-open-files")
+	}
+	if c.IsSet("idle-io") {
+		cfg.IdleIO = c.Bool("idle-io")
+	}
+	if c.IsSet("nice") {
+		cfg.Nice = c.Int("nice")
+	}
+	if c.IsSet("samples") {
+		cfg.Samples = c.String("samples")
+	}
+	if c.IsSet("chunked-compare") {
+		cfg.ChunkedCompare = c.String("chunked-compare")
+	}
+	if c.IsSet("max-memory") {
+		cfg.MaxMemory = c.String("max-memory")
+	}
+	if c.IsSet("spill-dir") {
+		cfg.SpillDir = c.String("spill-dir")
+	}
+	if c.IsSet("checkpoint") {
+		cfg.Checkpoint = c.String("checkpoint")
+	}
 
-	directories, err := scanner.GetDirectoriesFromArgs(c)
+	backends := newBackends()
+	defer func(backends *vfs.Registry) {
+		_ = backends.Close()
+	}(backends)
+
+	roots, err := scanner.GetRoots(ctx, paths, cfg.FilesFrom, cfg.Reference, backends)
 	if err != nil {
 		return err
 	}
 
+	filterConfig, err := buildFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	cp, err := openCheckpoint(cfg, paths, roots, resumed)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = cp.close()
+	}()
+
+	return findDuplicates(ctx, cfg, roots, filterConfig, cp)
+}
+
+// buildFilter builds the filter configuration of the find command.
+func buildFilter(cfg *config.FindConfig) (*filter.Config, error) {
 	// Parse size strings to int64 bytes
 	var minSize, maxSize int64
+	var err error
 	if cfg.MinSize != "" {
 		minSize, err = filter.ParseFileSize(cfg.MinSize)
 		if err != nil {
-			return fmt.Errorf("invalid min-size: %w", err)
+			return nil, fmt.Errorf("invalid min-size: %w", err)
 		}
 	}
 
 	if cfg.MaxSize != "" {
 		maxSize, err = filter.ParseFileSize(cfg.MaxSize)
 		if err != nil {
-			return fmt.Errorf("invalid max-size: %w", err)
+			return nil, fmt.Errorf("invalid max-size: %w", err)
 		}
 	}
 
@@ -177,115 +475,465 @@ func findDuplicatesCmd(ctx context.Context, c *cli.Command, cfg *config.FindConf
 		maxSize,
 	)
 	if err != nil {
-		return fmt.Errorf("error building filter configuration: %w", err)
+		return nil, fmt.Errorf("error building filter configuration: %w", err)
 	}
 
-	return findDuplicates(ctx, cfg, directories, filterConfig)
+	err = filterConfig.ApplyOwnership(cfg.Owner, cfg.Group, cfg.UID, cfg.GID, cfg.WritableOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error building filter configuration: %w", err)
+	}
+
+	return filterConfig, nil
 }
 
-// findDuplicates performs the main logic of finding duplicate files.
-func findDuplicates(ctx context.Context, cfg *config.FindConfig, directories []string, filterConfig *filter.Config) error {
+// findDuplicates performs the main logic of finding duplicate files, recording its progress to cp if not nil.
+func findDuplicates(ctx context.Context, cfg *config.FindConfig, roots []scanner.Root, filterConfig *filter.Config,
+	cp *scanCheckpoint,
+) error {
 	if cfg.ShowFilters {
-		filter.DisplayActiveFilters(filterConfig)
+		printFilters(os.Stdout, filterConfig)
 		return nil
 	}
 
-	sp := spinner.New(spinner.CharSets[35], 100*time.Millisecond, spinner.WithSuffix(" scanning...\n"))
-	_ = sp.Color("fgHiRed", "bold")
-
+	status := findStatusOutput(cfg)
 	if cfg.Verbose {
-		fmt.Printf("🔍 Scanning directories: %v\n", directories)
-		filter.DisplayActiveFilters(filterConfig)
-		sp.UpdateCharSet(spinner.CharSets[7])
+		var directories, references []string
+		for _, root := range roots {
+			if root.Role == scanner.RoleReference {
+				references = append(references, root.Path)
+			} else {
+				directories = append(directories, root.Path)
+			}
+		}
+		if len(directories) > maxListedPaths {
+			_, _ = fmt.Fprintf(status, "🔍 Scanning %d paths\n", len(directories))
+		} else {
+			_, _ = fmt.Fprintf(status, "🔍 Scanning directories: %v\n", directories)
+		}
+		if len(references) > 0 {
+			_, _ = fmt.Fprintf(status, "📌 Reference directories: %v\n", references)
+		}
+		printFilters(status, filterConfig)
 	}
 
-	sp.Start()
-	s := &model.Stats{StartTime: time.Now()}
+	if err := iosched.LowerPriority(cfg.IdleIO, cfg.Nice); err != nil {
+		logger.WarnAttrs(ctx, "could not lower the priority of the scan", slog.String("err", err.Error()))
+	}
 
-	// Phase 1: Group files by size
-	sizeGroups, err := scanner.GroupFilesBySize(ctx, directories, filterConfig, s, cfg.Verbose)
-	sp.Stop()
+	maxMemory, err := filter.ParseFileSize(cfg.MaxMemory)
+	if err != nil {
+		return fmt.Errorf("invalid max-memory: %w", err)
+	}
+	limits, err := parseThresholds(cfg)
 	if err != nil {
-		return fmt.Errorf("error scanning files: %w", err)
+		return err
+	}
+	if maxMemory > 0 {
+		// The garbage collector runs more often as the heap gets close to the ceiling.
+		debug.SetMemoryLimit(maxMemory)
+	}
+
+	s := &model.Stats{StartTime: time.Now(), Errors: &model.ErrorLog{}}
+	newPresenter(ctx, status, s, cfg.Workers, cfg.Verbose)
+	m, stopMetrics, err := startMetrics(ctx, cfg, roots, s)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+
+	stopProgress, err := startProgress(cfg, s)
+	if err != nil {
+		return err
+	}
+	defer stopProgress()
+
+	// Phases 1 and 2: Group files by size, then hash files that have potential duplicates
+	var report *model.DuplicateReport
+	if maxMemory > 0 {
+		report, err = findInBatches(ctx, cfg, maxMemory, roots, filterConfig, s, cp)
+	} else {
+		report, err = findInMemory(ctx, cfg, roots, filterConfig, s, cp)
+	}
+	if err != nil {
+		return err
+	}
+	if err := cp.close(); err != nil {
+		return err
+	}
+	stopProgress()
+	if err := finishMetrics(cfg, m, report); err != nil {
+		return err
+	}
+
+	// Phase 3: Output the results
+	reg, err := output.InitFormatters()
+	if err != nil {
+		return fmt.Errorf("error initializing formatters: %w", err)
+	}
+
+	out, outputFile, closeOutput, err := createOutput(cfg.OutputFile, "doppel-report.txt")
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	err = reg.Format(cfg.OutputFormat, report, out)
+	if err != nil {
+		return fmt.Errorf("error formatting report: %w", err)
+	}
+
+	if outputFile != "" {
+		_, _ = fmt.Fprintf(status, "\n✅ Results written to \"%s\"\n", outputFile)
+	} else if out == status {
+		_, _ = fmt.Fprintln(status)
+	}
+
+	return checkReport(ctx, cfg, report, limits)
+}
+
+// findStatusOutput returns the destination of the status messages of the find command,
+// which only share standard output with pretty reports.
+func findStatusOutput(cfg *config.FindConfig) io.Writer {
+	return statusOutput(cfg.OutputFile, !strings.EqualFold(cfg.OutputFormat, "pretty"))
+}
+
+// findInMemory scans the roots, grouping the files found by size in memory, then hashes the files
+// that have potential duplicates and returns the report. The progress of the scan is recorded to cp if not nil.
+func findInMemory(ctx context.Context, cfg *config.FindConfig, roots []scanner.Root, filterConfig *filter.Config,
+	s *model.Stats, cp *scanCheckpoint,
+) (*model.DuplicateReport, error) {
+	sizeGroups, err := scanner.GroupRootsBySize(ctx, roots, filterConfig, s,
+		append(scanOptions(cfg), cp.scanOptions()...)...)
+	if err != nil && ctx.Err() != nil {
+		return interruptedReport(s), nil
+	}
+	if err == nil {
+		err = cp.err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning files: %w", err)
 	}
 
 	if cfg.Verbose {
+		status := findStatusOutput(cfg)
 		if s.TotalFiles > 0 {
 			n := len(sizeGroups)
-			fmt.Printf("📊 Found %d file%s, %d size group%s.\n", s.TotalFiles, pluralize(s.TotalFiles), n, pluralize(n))
+			_, _ = fmt.Fprintf(status, "📊 Found %d file%s, %d size group%s.\n",
+				s.TotalFiles, pluralize(s.TotalFiles), n, pluralize(n))
 		} else {
-			fmt.Println(" Did not find any regular files.")
+			_, _ = fmt.Fprintln(status, " Did not find any regular files.")
 		}
 	}
 
-	// Phase 2: Hash files that have potential duplicates
-	report, err := finder.FindDuplicatesByHash(ctx, sizeGroups, cfg.Workers, s, cfg.Verbose)
-	s.Duration = time.Since(s.StartTime)
+	opts, err := hashOptions(cfg)
 	if err != nil {
-		return fmt.Errorf("error finding duplicates: %w", err)
+		return nil, err
 	}
+	return matchAndHash(ctx, cfg, sizeGroups, s, append(opts, cp.hashOptions()...))
+}
 
-	// Phase 3: Output the results
-	reg, err := output.InitFormatters()
-	if err != nil {
-		return fmt.Errorf("error initializing formatters: %w", err)
+// findInBatches is the low-memory mode of [findInMemory], keeping memory use under maxMemory bytes.
+// The files found are spilled to sorted run files on disk whenever they take a quarter of maxMemory,
+// then read back in batches of sizes taking another quarter, hashed one batch at a time.
+// The rest is left to the hashing itself, and to the manifests and checksum files matched against,
+// which are read again for each batch.
+func findInBatches(ctx context.Context, cfg *config.FindConfig, maxMemory int64, roots []scanner.Root,
+	filterConfig *filter.Config, s *model.Stats, cp *scanCheckpoint,
+) (*model.DuplicateReport, error) {
+	status := findStatusOutput(cfg)
+	budget := max(maxMemory/4, 1)
+	if cfg.Verbose {
+		_, _ = fmt.Fprintf(status, "💾 Keeping memory under %s, spilling the files found to disk\n",
+			output.FormatBytes(maxMemory))
 	}
 
-	outputFile := cfg.OutputFile
-	var out io.Writer = os.Stdout
+	index, err := scanner.IndexRootsBySize(ctx, roots, filterConfig, s,
+		slices.Concat(scanOptions(cfg), cp.scanOptions(), []scanner.Option{scanner.WithSpill(cfg.SpillDir, budget)})...)
+	if err != nil && ctx.Err() != nil {
+		return interruptedReport(s), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning files: %w", err)
+	}
+	defer func() {
+		_ = index.Close()
+	}()
+	if err := cp.err(); err != nil {
+		return nil, fmt.Errorf("error scanning files: %w", err)
+	}
 
-	if outputFile != "" && strings.ToLower(outputFile) != "stdout" {
-		if strings.ToLower(outputFile) == "stderr" {
-			out = os.Stderr
+	if cfg.Verbose {
+		if s.TotalFiles > 0 {
+			runs := index.Runs()
+			_, _ = fmt.Fprintf(status, "📊 Found %d file%s, spilled to %d run%s.\n",
+				s.TotalFiles, pluralize(s.TotalFiles), runs, pluralize(runs))
 		} else {
-			outputFile = filepath.Clean(outputFile)
-			if outputFile == "." {
-				outputFile = "doppel-report.txt"
-			}
+			_, _ = fmt.Fprintln(status, " Did not find any regular files.")
+		}
+	}
+
+	opts, err := hashOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, cp.hashOptions()...)
+
+	// Files without others of the same size can only match files recorded in manifests and checksum files.
+	minFiles := 2
+	if len(cfg.Against) > 0 || len(cfg.KnownHashes) > 0 {
+		minFiles = 1
+	}
+
+	report := &model.DuplicateReport{Stats: s}
+	for batch := range index.Batches(budget, minFiles) {
+		part, err := matchAndHash(ctx, cfg, batch, s,
+			append(slices.Clip(opts), finder.WithFirstGroupID(len(report.Groups)+1)))
+		if err != nil {
+			return nil, err
+		}
+		report.Groups = append(report.Groups, part.Groups...)
+		report.TotalWastedSpace += part.TotalWastedSpace
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if err := index.Err(); err != nil {
+		return nil, err
+	}
 
-			outputFile, err = filepath.Abs(outputFile)
-			if err != nil {
-				return fmt.Errorf("error getting absolute path for output file: %w", err)
+	report.ScanDate = time.Now()
+	report.Complete = ctx.Err() == nil
+	report.Errors = s.Errors.List()
+	s.Duration = time.Since(s.StartTime)
+	return report, nil
+}
+
+// interruptedReport returns the report of a scan interrupted before it was done walking, which holds no groups.
+func interruptedReport(s *model.Stats) *model.DuplicateReport {
+	s.Duration = time.Since(s.StartTime)
+	return &model.DuplicateReport{ScanDate: time.Now(), Stats: s, Errors: s.Errors.List()}
+}
+
+// scanOptions returns the scanner options set by the configuration.
+func scanOptions(cfg *config.FindConfig) []scanner.Option {
+	return []scanner.Option{
+		scanner.WithFollowSymlinks(cfg.FollowSymlinks),
+		scanner.WithReportSymlinks(cfg.ReportSymlinks),
+		scanner.WithOneFileSystem(cfg.OneFileSystem),
+		scanner.WithExcludeFSTypes(splitCommaSeparated(cfg.ExcludeFSType)),
+		scanner.WithScanArchives(cfg.ScanArchives),
+	}
+}
+
+// matchAndHash matches the size groups against manifests and checksum files, then hashes the files
+// that have potential duplicates with the given finder options, and returns the report.
+func matchAndHash(ctx context.Context, cfg *config.FindConfig, sizeGroups map[int64][]scanner.FileInfo,
+	s *model.Stats, opts []finder.Option,
+) (*model.DuplicateReport, error) {
+	// Files recorded in manifests join the size groups as references, with their hashes.
+	for _, path := range cfg.Against {
+		files, err := manifest.Load(path, func(size int64) bool { return len(sizeGroups[size]) > 0 })
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			sizeGroups[file.Size] = append(sizeGroups[file.Size], file)
+		}
+		if cfg.Verbose {
+			_, _ = fmt.Fprintf(findStatusOutput(cfg), "📒 Matching against %d file%s from %s\n",
+				len(files), pluralize(len(files)), path)
+		}
+	}
+
+	// Files listed in checksum files join the size groups of the local files with the same digests.
+	for _, spec := range cfg.KnownHashes {
+		list, err := checksum.Load(spec)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Verbose {
+			status := findStatusOutput(cfg)
+			_, _ = fmt.Fprintf(status, "🧾 Matching against %d %s checksum%s from %s\n",
+				len(list.Entries), list.Algorithm.Name, pluralize(len(list.Entries)), list.Path)
+			if list.Skipped > 0 {
+				_, _ = fmt.Fprintf(status, "⚠️ Skipped %d line%s that could not be parsed.\n",
+					list.Skipped, pluralize(list.Skipped))
 			}
+		}
+		matchKnownHashes(ctx, list, sizeGroups, cfg.Workers, s, opts...)
+	}
+
+	opts = append(opts,
+		finder.WithReferenceMode(len(cfg.Reference) > 0 || len(cfg.Against) > 0 || len(cfg.KnownHashes) > 0))
+	report, err := finder.FindDuplicatesByHash(ctx, sizeGroups, cfg.Workers, s, opts...)
+	s.Duration = time.Since(s.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("error finding duplicates: %w", err)
+	}
+	return report, nil
+}
+
+// hashOptions returns the finder options setting the optional hashing stages, and scheduling and throttling
+// the reads of the files to hash. In verbose mode, the options set are written to the status output.
+func hashOptions(cfg *config.FindConfig) ([]finder.Option, error) {
+	var opts []finder.Option
+	status := findStatusOutput(cfg)
+	if cfg.IOPerDevice != "" {
+		perDevice, err := iosched.ParseLimits(cfg.IOPerDevice)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, finder.WithIOPerDevice(perDevice))
+		if cfg.Verbose {
+			_, _ = fmt.Fprintf(status, "💽 Reading each device separately, with %s workers per device\n", perDevice)
+		}
+	}
 
-			if err := os.MkdirAll(filepath.Dir(outputFile), 0o750); err != nil {
-				return fmt.Errorf("error creating output directory: %w", err)
+	rate, err := iosched.ParseRate(cfg.MaxReadRate)
+	if err != nil {
+		return nil, err
+	}
+	if throttle := iosched.NewThrottle(rate, cfg.MaxOpenFiles); throttle != nil {
+		opts = append(opts, finder.WithThrottle(throttle))
+		if cfg.Verbose {
+			if rate > 0 {
+				_, _ = fmt.Fprintf(status, "🐢 Reading at most %s/s\n", output.FormatBytes(rate))
+			}
+			if cfg.MaxOpenFiles > 0 {
+				_, _ = fmt.Fprintf(status, "🐢 Reading at most %d file%s at a time\n",
+					cfg.MaxOpenFiles, pluralize(cfg.MaxOpenFiles))
 			}
+		}
+	}
 
-			file, err := os.Create(outputFile)
-			if err != nil {
-				return fmt.Errorf("error opening output file: %w", err)
+	samples, err := scanner.ParseSamples(cfg.Samples)
+	if err != nil {
+		return nil, err
+	}
+	if samples.Enabled() {
+		opts = append(opts, finder.WithSamples(samples))
+		if cfg.Verbose {
+			_, _ = fmt.Fprintf(status, "🎯 Sampling large files at: %s\n", samples)
+		}
+	}
+
+	compareSize, err := filter.ParseFileSize(cfg.ChunkedCompare)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunked-compare: %w", err)
+	}
+	if compareSize > 0 {
+		opts = append(opts, finder.WithChunkedCompare(compareSize))
+		if cfg.Verbose {
+			_, _ = fmt.Fprintf(status, "🧩 Comparing files of %s or more block by block\n", output.FormatBytes(compareSize))
+		}
+	}
+	return opts, nil
+}
+
+// matchKnownHashes flags the files in sizeGroups whose content is listed in a checksum file,
+// without reading the listed files: only the local files are hashed with the algorithm of the list.
+//
+// Listed files that were scanned become references themselves. The others join the size group of
+// a matching local file as references, sharing its hashes.
+// When every listed file is found next to the checksum file, only local files of the same sizes are hashed.
+func matchKnownHashes(ctx context.Context, list *checksum.List, sizeGroups map[int64][]scanner.FileInfo,
+	workers int, s *model.Stats, opts ...finder.Option,
+) {
+	index := list.Index()
+
+	// Sizes of the listed files, if they can all be found.
+	sizes := make(map[int64]bool, len(list.Entries))
+	for _, paths := range index {
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				sizes = nil
+				break
 			}
+			sizes[info.Size()] = true
+		}
+		if sizes == nil {
+			break
+		}
+	}
 
-			defer func(file *os.File) {
-				_ = file.Close()
-			}(file)
+	var candidates []scanner.FileInfo
+	for size, files := range sizeGroups {
+		if sizes != nil && !sizes[size] {
+			continue
+		}
+		for _, file := range files {
+			if file.Role == scanner.RoleScan && !file.QuickHashed {
+				candidates = append(candidates, file)
+			}
+		}
+	}
 
-			out = file
+	scanned := make(map[string]bool)
+	for _, files := range sizeGroups {
+		for _, file := range files {
+			scanned[file.Path] = true
 		}
 	}
 
-	var sp2 *spinner.Spinner
-	isFsFile := out != os.Stdout && out != os.Stderr
-	if isFsFile {
-		sp2 = spinner.New(spinner.CharSets[70], 100*time.Millisecond, spinner.WithSuffix("  writing the results...\n"))
-		_ = sp2.Color("fgHiMagenta", "bold")
-		sp2.Start()
-		defer sp2.Stop()
+	listed := make(map[string]bool)
+	var known []scanner.FileInfo
+	references := make(map[string][]string)
+	for _, digest := range finder.ChecksumFiles(ctx, candidates, workers, s, list.Algorithm.New, opts...) {
+		key := string(digest.Sum)
+		paths, ok := index[key]
+		if !ok {
+			continue
+		}
+		if slices.Contains(paths, digest.File.Path) {
+			listed[digest.File.Path] = true
+		}
+		// The listed files that were not scanned are matched once, through the first local copy.
+		if unscanned := slices.DeleteFunc(slices.Clone(paths), func(path string) bool { return scanned[path] }); len(unscanned) > 0 {
+			known = append(known, digest.File)
+			references[digest.File.Path] = unscanned
+			delete(index, key)
+		}
 	}
 
-	err = reg.Format(cfg.OutputFormat, report, out)
-	if err != nil {
-		return fmt.Errorf("error formatting report: %w", err)
+	for _, files := range sizeGroups {
+		for i := range files {
+			if listed[files[i].Path] {
+				files[i].Role = scanner.RoleReference
+			}
+		}
 	}
 
-	if isFsFile {
-		sp2.Stop()
-		fmt.Printf("\n✅ Results written to \"%s\"", outputFile)
+	// The listed files that were not scanned share the hashes of their local copies.
+	for _, file := range finder.HashFiles(ctx, known, workers, s, opts...) {
+		group := sizeGroups[file.Size]
+		for i := range group {
+			if group[i].Path == file.Path {
+				group[i].QuickHash, group[i].QuickHashed, group[i].Hash = file.QuickHash, true, file.Hash
+			}
+		}
+		for _, path := range references[file.Path] {
+			sizeGroups[file.Size] = append(sizeGroups[file.Size], scanner.FileInfo{
+				Path:        path,
+				Size:        file.Size,
+				Hash:        file.Hash,
+				QuickHash:   file.QuickHash,
+				QuickHashed: true,
+				Role:        scanner.RoleReference,
+			})
+		}
 	}
-	fmt.Println()
+}
 
-	return nil
+// splitCommaSeparated splits a comma-separated list, trimming whitespace and dropping empty items.
+func splitCommaSeparated(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 type integral interface {
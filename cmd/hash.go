@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/checksum"
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// HashCommand returns the hash command configuration.
+func HashCommand(cfg *config.HashConfig) *cli.Command {
+	return &cli.Command{
+		Name:      "hash",
+		Usage:     "Write checksum lines for every file, like sha256sum or b3sum",
+		ArgsUsage: "[directories or files...]",
+		Description: `Hash every file in the given directories and write one checksum line per file,
+in the format of the coreutils tools (sha256sum, md5sum, ...) and b3sum.
+The output can be verified with 'sha256sum --check' (or the tool of the chosen algorithm),
+and matched against with 'doppel find --known-hashes'.
+
+Paths are written relative to the directory of the output file,
+or to the current directory when writing to stdout.`,
+		EnableShellCompletion: true,
+		Suggest:               true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "algorithm",
+				Aliases: []string{"a"},
+				Value:   "sha256",
+				Usage:   "Hash algorithm: " + strings.Join(checksum.Names(), ", "),
+			},
+			&cli.IntFlag{
+				Name:    "workers",
+				Aliases: []string{"w"},
+				Value:   runtime.NumCPU(),
+				Usage:   "Number of worker goroutines for parallel hashing",
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Enable verbose output with detailed progress information",
+			},
+			&cli.StringFlag{
+				Name:  "exclude-dirs",
+				Usage: "Comma-separated list of directory patterns to exclude",
+			},
+			&cli.StringFlag{
+				Name:  "exclude-files",
+				Usage: "Comma-separated list of file patterns to exclude",
+			},
+			&cli.StringFlag{
+				Name:  "min-size",
+				Usage: "Minimum file size to hash (e.g. 1MB, 512KiB)",
+			},
+			&cli.StringFlag{
+				Name:  "max-size",
+				Usage: "Maximum file size to hash (e.g. 1GB, 0 = no limit)",
+			},
+			&cli.StringFlag{
+				Name:    "output-file",
+				Aliases: []string{"o"},
+				Usage:   "Write the checksums to file (default: stdout)",
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return hashFilesCmd(ctx, c, cfg)
+		},
+	}
+}
+
+// hashFilesCmd is the action function for the hash command.
+func hashFilesCmd(ctx context.Context, c *cli.Command, cfg *config.HashConfig) error {
+	// Override with CLI flags
+	if c.IsSet("algorithm") {
+		cfg.Algorithm = c.String("algorithm")
+	}
+	if c.IsSet("workers") {
+		cfg.Workers = c.Int("workers")
+	}
+	if c.IsSet("verbose") {
+		cfg.Verbose = c.Bool("verbose")
+	}
+	if c.IsSet("exclude-dirs") {
+		cfg.ExcludeDirs = c.String("exclude-dirs")
+	}
+	if c.IsSet("exclude-files") {
+		cfg.ExcludeFiles = c.String("exclude-files")
+	}
+	if c.IsSet("min-size") {
+		cfg.MinSize = c.String("min-size")
+	}
+	if c.IsSet("max-size") {
+		cfg.MaxSize = c.String("max-size")
+	}
+	if c.IsSet("output-file") {
+		cfg.OutputFile = c.String("output-file")
+	}
+
+	alg, err := checksum.Lookup(cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	backends := newBackends()
+	defer func(backends *vfs.Registry) {
+		_ = backends.Close()
+	}(backends)
+
+	roots, err := scanner.GetRootsFromArgs(ctx, c, "", nil, backends)
+	if err != nil {
+		return err
+	}
+
+	var minSize, maxSize int64
+	if cfg.MinSize != "" {
+		if minSize, err = filter.ParseFileSize(cfg.MinSize); err != nil {
+			return fmt.Errorf("invalid min-size: %w", err)
+		}
+	}
+	if cfg.MaxSize != "" {
+		if maxSize, err = filter.ParseFileSize(cfg.MaxSize); err != nil {
+			return fmt.Errorf("invalid max-size: %w", err)
+		}
+	}
+
+	filterConfig, err := filter.BuildConfig(cfg.ExcludeDirs, cfg.ExcludeFiles, "", "", minSize, maxSize)
+	if err != nil {
+		return fmt.Errorf("error building filter configuration: %w", err)
+	}
+
+	status := statusOutput(cfg.OutputFile, true)
+	s := &model.Stats{StartTime: time.Now()}
+	newPresenter(ctx, status, s, cfg.Workers, cfg.Verbose)
+	sizeGroups, err := scanner.GroupRootsBySize(ctx, roots, filterConfig, s)
+	if err != nil {
+		return fmt.Errorf("error scanning files: %w", err)
+	}
+
+	var files []scanner.FileInfo
+	for _, group := range sizeGroups {
+		files = append(files, group...)
+	}
+
+	if cfg.Verbose {
+		_, _ = fmt.Fprintf(status, "🔐 Hashing %d file%s with %s and %d workers.\n", len(files), pluralize(len(files)), alg.Name, cfg.Workers)
+	}
+	digests := finder.ChecksumFiles(ctx, files, cfg.Workers, s, alg.New)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	out, outputFile, closeOutput, err := createOutput(cfg.OutputFile, alg.FileName)
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	base := filepath.Dir(outputFile)
+	if outputFile == "" {
+		if base, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+
+	w := checksum.NewWriter(out)
+	written := 0
+	for _, digest := range digests {
+		// The checksum file does not list itself.
+		if digest.File.Path == outputFile {
+			continue
+		}
+		if err := w.Write(relativeTo(base, digest.File.Path), digest.Sum); err != nil {
+			return fmt.Errorf("error writing checksums: %w", err)
+		}
+		written++
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing checksums: %w", err)
+	}
+
+	if outputFile != "" {
+		_, _ = fmt.Fprintf(status, "✅ Checksums of %d file%s written to \"%s\"\n", written, pluralize(written), outputFile)
+	}
+	if s.ErrorCount > 0 {
+		// Keep warnings out of checksums written to stdout.
+		_, _ = fmt.Fprintf(os.Stderr, "⚠️ %d file%s could not be read and left out of the checksums.\n",
+			s.ErrorCount, pluralize(s.ErrorCount))
+	}
+
+	return nil
+}
+
+// relativeTo returns path relative to base if it is under base, and path unchanged otherwise.
+func relativeTo(base, path string) string {
+	if vfs.IsURL(path) {
+		return path
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/manifest"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// ManifestCommand returns the manifest command configuration.
+func ManifestCommand(cfg *config.ManifestConfig) *cli.Command {
+	return &cli.Command{
+		Name:  "manifest",
+		Usage: "Record file hashes for matching across machines",
+		Description: `Hash manifests record the path, size, and hashes of every file in a tree.
+Create a manifest on one machine, copy it to another, and match the files there
+against it with 'doppel find --against manifest.jsonl'. The first machine
+never has to be reachable, and its files are not hashed again.`,
+		EnableShellCompletion: true,
+		Suggest:               true,
+
+		Commands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Hash every file in the given directories and write a manifest",
+				ArgsUsage: "[directories or files...]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:    "workers",
+						Aliases: []string{"w"},
+						Value:   runtime.NumCPU(),
+						Usage:   "Number of worker goroutines for parallel hashing",
+					},
+					&cli.BoolFlag{
+						Name:    "verbose",
+						Aliases: []string{"v"},
+						Usage:   "Enable verbose output with detailed progress information",
+					},
+					&cli.StringFlag{
+						Name:  "exclude-dirs",
+						Usage: "Comma-separated list of directory patterns to exclude",
+					},
+					&cli.StringFlag{
+						Name:  "exclude-files",
+						Usage: "Comma-separated list of file patterns to exclude",
+					},
+					&cli.StringFlag{
+						Name:  "min-size",
+						Usage: "Minimum file size to record (e.g. 1MB, 512KiB)",
+					},
+					&cli.StringFlag{
+						Name:  "max-size",
+						Usage: "Maximum file size to record (e.g. 1GB, 0 = no limit)",
+					},
+					&cli.StringFlag{
+						Name:    "output-file",
+						Aliases: []string{"o"},
+						Usage:   "Write the manifest to file (default: stdout)",
+					},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return createManifest(ctx, c, cfg)
+				},
+				Suggest:               true,
+				EnableShellCompletion: true,
+			},
+		},
+	}
+}
+
+// createManifest is the action function for the manifest create command.
+func createManifest(ctx context.Context, c *cli.Command, cfg *config.ManifestConfig) error {
+	// Override with CLI flags
+	if c.IsSet("workers") {
+		cfg.Workers = c.Int("workers")
+	}
+	if c.IsSet("verbose") {
+		cfg.Verbose = c.Bool("verbose")
+	}
+	if c.IsSet("exclude-dirs") {
+		cfg.ExcludeDirs = c.String("exclude-dirs")
+	}
+	if c.IsSet("exclude-files") {
+		cfg.ExcludeFiles = c.String("exclude-files")
+	}
+	if c.IsSet("min-size") {
+		cfg.MinSize = c.String("min-size")
+	}
+	if c.IsSet("max-size") {
+		cfg.MaxSize = c.String("max-size")
+	}
+	if c.IsSet("output-file") {
+		cfg.OutputFile = c.String("output-file")
+	}
+
+	backends := newBackends()
+	defer func(backends *vfs.Registry) {
+		_ = backends.Close()
+	}(backends)
+
+	roots, err := scanner.GetRootsFromArgs(ctx, c, "", nil, backends)
+	if err != nil {
+		return err
+	}
+
+	var minSize, maxSize int64
+	if cfg.MinSize != "" {
+		if minSize, err = filter.ParseFileSize(cfg.MinSize); err != nil {
+			return fmt.Errorf("invalid min-size: %w", err)
+		}
+	}
+	if cfg.MaxSize != "" {
+		if maxSize, err = filter.ParseFileSize(cfg.MaxSize); err != nil {
+			return fmt.Errorf("invalid max-size: %w", err)
+		}
+	}
+
+	filterConfig, err := filter.BuildConfig(cfg.ExcludeDirs, cfg.ExcludeFiles, "", "", minSize, maxSize)
+	if err != nil {
+		return fmt.Errorf("error building filter configuration: %w", err)
+	}
+
+	status := statusOutput(cfg.OutputFile, true)
+	s := &model.Stats{StartTime: time.Now()}
+	newPresenter(ctx, status, s, cfg.Workers, cfg.Verbose)
+	sizeGroups, err := scanner.GroupRootsBySize(ctx, roots, filterConfig, s)
+	if err != nil {
+		return fmt.Errorf("error scanning files: %w", err)
+	}
+
+	var files []scanner.FileInfo
+	for _, group := range sizeGroups {
+		files = append(files, group...)
+	}
+
+	if cfg.Verbose {
+		_, _ = fmt.Fprintf(status, "🔐 Hashing %d file%s with %d workers.\n", len(files), pluralize(len(files)), cfg.Workers)
+	}
+	hashed := finder.HashFiles(ctx, files, cfg.Workers, s)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	out, outputFile, closeOutput, err := createOutput(cfg.OutputFile, "manifest.jsonl")
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	rootPaths := make([]string, len(roots))
+	for i, root := range roots {
+		rootPaths[i] = root.Path
+	}
+
+	w, err := manifest.NewWriter(out, rootPaths)
+	if err != nil {
+		return err
+	}
+	for _, file := range hashed {
+		if err := w.Write(file); err != nil {
+			return fmt.Errorf("error writing manifest: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+
+	if outputFile != "" {
+		_, _ = fmt.Fprintf(status, "✅ Manifest of %d file%s written to \"%s\"\n", len(hashed), pluralize(len(hashed)), outputFile)
+	}
+	if s.ErrorCount > 0 {
+		// Keep warnings out of manifests written to stdout.
+		_, _ = fmt.Fprintf(os.Stderr, "⚠️ %d file%s could not be read and left out of the manifest.\n",
+			s.ErrorCount, pluralize(s.ErrorCount))
+	}
+
+	return nil
+}
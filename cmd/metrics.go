@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/metrics"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// startMetrics collects the metrics of a scan updating s, if the configuration asks for them,
+// and serves them on cfg.MetricsListen until the returned stop function is called.
+// It returns nil metrics when neither a listen address nor a textfile is set.
+func startMetrics(ctx context.Context, cfg *config.FindConfig, roots []scanner.Root, s *model.Stats) (
+	*metrics.Metrics, func(), error,
+) {
+	if cfg.MetricsListen == "" && cfg.MetricsTextfile == "" {
+		return nil, func() {}, nil
+	}
+
+	paths := make([]string, 0, len(roots))
+	for _, root := range roots {
+		paths = append(paths, root.Path)
+	}
+	m := metrics.New(s, metrics.Label{Name: "roots", Value: strings.Join(paths, ",")})
+
+	if cfg.MetricsListen == "" {
+		return m, func() {}, nil
+	}
+
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", cfg.MetricsListen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error serving metrics: %w", err)
+	}
+	if cfg.Verbose {
+		_, _ = fmt.Fprintf(findStatusOutput(cfg), "📈 Serving metrics on http://%s/metrics\n", ln.Addr())
+	}
+
+	serveCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := m.Serve(serveCtx, ln); err != nil {
+			logger.ErrorAttrs(ctx, "failed to serve metrics", slog.String("err", err.Error()))
+		}
+	}()
+
+	return m, func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// finishMetrics records the report of a scan in m, and writes the metrics to cfg.MetricsTextfile if set.
+func finishMetrics(cfg *config.FindConfig, m *metrics.Metrics, report *model.DuplicateReport) error {
+	if m == nil {
+		return nil
+	}
+	m.Finish(report)
+	if cfg.MetricsTextfile == "" {
+		return nil
+	}
+	return m.WriteFile(cfg.MetricsTextfile)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// createOutput opens the destination of a command's output: standard output if outputFile is empty or "stdout",
+// standard error if it is "stderr", and otherwise the named file (defaultName if it names the current directory),
+// creating its parent directories.
+// It returns the writer, the absolute path of the file (if any), and a function closing the file.
+func createOutput(outputFile, defaultName string) (io.Writer, string, func(), error) {
+	switch strings.ToLower(outputFile) {
+	case "", "stdout":
+		return os.Stdout, "", func() {}, nil
+	case "stderr":
+		return os.Stderr, "", func() {}, nil
+	}
+
+	outputFile = filepath.Clean(outputFile)
+	if outputFile == "." {
+		outputFile = defaultName
+	}
+
+	outputFile, err := filepath.Abs(outputFile)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error getting absolute path for output file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0o750); err != nil {
+		return nil, "", nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error opening output file: %w", err)
+	}
+
+	return file, outputFile, func() {
+		_ = file.Close()
+	}, nil
+}
+
+// statusOutput returns the destination of the status messages of a command writing its output to outputFile:
+// standard output, unless the output is machine-readable and goes to standard output as well,
+// in which case the messages go to standard error so that they do not corrupt it.
+func statusOutput(outputFile string, machineReadable bool) io.Writer {
+	switch strings.ToLower(outputFile) {
+	case "", "stdout":
+		if machineReadable {
+			return os.Stderr
+		}
+	}
+	return os.Stdout
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/output"
+)
+
+// presenter renders the events of a scan for users.
+// In verbose mode, it writes a summary of each stage and logs the entries skipped.
+// Errors are always logged, except those met while walking, which are only logged in verbose mode.
+type presenter struct {
+	ctx     context.Context
+	w       io.Writer
+	stats   *model.Stats
+	workers int
+	verbose bool
+
+	mu      sync.Mutex
+	started map[string]time.Time
+}
+
+// newPresenter returns a presenter writing to w, and registers it as the event observer of s.
+// Status messages only go to standard error when standard output carries machine-readable output,
+// so logs sent to standard output are then moved to standard error as well.
+func newPresenter(ctx context.Context, w io.Writer, s *model.Stats, workers int, verbose bool) *presenter {
+	if w == os.Stderr {
+		logger.RedirectStdout(os.Stderr)
+	}
+	p := &presenter{ctx: ctx, w: w, stats: s, workers: workers, verbose: verbose, started: make(map[string]time.Time)}
+	s.Events = p
+	return p
+}
+
+// printf writes a status message.
+func (p *presenter) printf(format string, a ...any) {
+	_, _ = fmt.Fprintf(p.w, format, a...)
+}
+
+// StageStarted announces the hashing stages.
+func (p *presenter) StageStarted(stage string, files, _ int64) {
+	p.mu.Lock()
+	p.started[stage] = time.Now()
+	p.mu.Unlock()
+
+	if !p.verbose {
+		return
+	}
+	switch stage {
+	case model.StageQuickHash:
+		p.printf("\n🔐 Multi-stage hashing %d candidate files with %d workers.\n\n", files, p.workers)
+		p.printf("Stage 1: Quick hashing...\n")
+	case model.StageSample:
+		p.printf("Sampling %d files with the same quick hash...\n", files)
+	case model.StageCompare:
+		p.printf("Comparing %d large files block by block...\n", files)
+	case model.StageFullHash:
+		p.printf("Stage 2: Full hashing %d files with potential duplicates...\n", files)
+	}
+}
+
+// StageFinished summarizes the walk, and reports the time taken by the hashing stages.
+func (p *presenter) StageFinished(stage string) {
+	p.mu.Lock()
+	elapsed := time.Since(p.started[stage]).Round(time.Millisecond)
+	p.mu.Unlock()
+
+	if !p.verbose {
+		return
+	}
+	switch stage {
+	case model.StageWalk:
+		p.printSkipped()
+	case model.StageQuickHash:
+		p.printf("Quick hashing took %s.\n\n", elapsed)
+	case model.StageSample:
+		p.printf("Sampling took %s.\n\n", elapsed)
+	case model.StageCompare:
+		p.printf("Comparing took %s.\n\n", elapsed)
+	case model.StageFullHash:
+		p.printf("Full hashing took %s.\n", elapsed)
+	}
+}
+
+// printSkipped summarizes the entries skipped by the walk.
+func (p *presenter) printSkipped() {
+	s := p.stats
+	if s.SkippedDirs > 0 || s.SkippedFiles > 0 {
+		var parts []string
+		if s.SkippedDirs > 0 {
+			suffix := "y"
+			if s.SkippedDirs > 1 {
+				suffix = "ies"
+			}
+			parts = append(parts, fmt.Sprintf("%d director%s", s.SkippedDirs, suffix))
+		}
+		if s.SkippedFiles > 0 {
+			parts = append(parts, fmt.Sprintf("%d file%s", s.SkippedFiles, pluralize(s.SkippedFiles)))
+		}
+		p.printf("\n⏭️ Skipped %s due to filters.\n", strings.Join(parts, " and "))
+	}
+	if s.SkippedMounts > 0 {
+		p.printf("⏭️ Skipped %d mount point%s: %s\n", s.SkippedMounts, pluralize(s.SkippedMounts),
+			strings.Join(s.SkippedMountPoints, ", "))
+	}
+	if s.DanglingSymlinks > 0 {
+		p.printf("🔗 Found %d dangling symlink%s.\n", s.DanglingSymlinks, pluralize(s.DanglingSymlinks))
+	}
+}
+
+// FileSkipped logs an entry skipped, in verbose mode.
+func (p *presenter) FileSkipped(kind, path, reason string) {
+	if p.verbose {
+		logger.InfoAttrs(p.ctx, "skipping "+kind, slog.String("path", path), slog.String("exclusion reason", reason))
+	}
+}
+
+// Error logs an error that left a file out of a stage.
+func (p *presenter) Error(stage, path string, err error) {
+	if stage == model.StageWalk {
+		if p.verbose {
+			logFileError(p.ctx, "failed to access a file", path, err)
+		}
+		return
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		logger.ErrorAttrs(p.ctx, "file (likely) removed after the scan but before hashing",
+			slog.String("path", path), slog.String("err", err.Error()))
+		return
+	}
+	logFileError(p.ctx, "failed to "+stage+" a file", path, err)
+}
+
+// GroupFound logs a group of duplicates found, at the debug level.
+func (p *presenter) GroupFound(group *model.DuplicateGroup) {
+	logger.DebugAttrs(p.ctx, "found duplicate group", slog.Int("id", group.ID), slog.Int("count", group.Count),
+		slog.Int64("size", group.Size))
+}
+
+// logFileError logs an error about a file, with the operation that failed if known.
+func logFileError(ctx context.Context, msg, path string, err error) {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		logger.ErrorAttrs(ctx, msg, slog.String("path", pathErr.Path), slog.String("op", pathErr.Op),
+			slog.String("err", pathErr.Err.Error()))
+		return
+	}
+	logger.ErrorAttrs(ctx, msg, slog.String("path", path), slog.String("err", err.Error()))
+}
+
+// printFilters writes the active filters of fc to w.
+func printFilters(w io.Writer, fc *filter.Config) {
+	_, _ = fmt.Fprintln(w, "🔧 Active filters:")
+	if len(fc.ExcludeDirs) > 0 {
+		_, _ = fmt.Fprintf(w, "  📁 Exclude directories: %s\n", strings.Join(fc.ExcludeDirs, ", "))
+	}
+	if len(fc.ExcludeFiles) > 0 {
+		_, _ = fmt.Fprintf(w, "  📄 Exclude files: %s\n", strings.Join(fc.ExcludeFiles, ", "))
+	}
+	if len(fc.ExcludeDirRegexRaw) > 0 {
+		_, _ = fmt.Fprintf(w, "  📁 Exclude directory regex: %q\n", fc.ExcludeDirRegexRaw)
+	}
+	if len(fc.ExcludeFileRegexRaw) > 0 {
+		_, _ = fmt.Fprintf(w, "  📄 Exclude file regex: %q\n", fc.ExcludeFileRegexRaw)
+	}
+	if fc.MinSize > 0 {
+		_, _ = fmt.Fprintf(w, "  📏 Minimum file size: %s\n", output.FormatBytes(fc.MinSize))
+	}
+	if fc.MaxSize > 0 {
+		_, _ = fmt.Fprintf(w, "  📏 Maximum file size: %s\n", output.FormatBytes(fc.MaxSize))
+	}
+	if fc.Owner != "" {
+		_, _ = fmt.Fprintf(w, "  👤 Owner: %s\n", fc.Owner)
+	}
+	if fc.Group != "" {
+		_, _ = fmt.Fprintf(w, "  👥 Group: %s\n", fc.Group)
+	}
+	if fc.WritableOnly {
+		_, _ = fmt.Fprintln(w, "  ✏️ Only files removable by the current user")
+	}
+	if !fc.Active() {
+		_, _ = fmt.Fprintln(w, "  ✅ No filters active")
+	}
+	_, _ = fmt.Fprintln(w)
+}
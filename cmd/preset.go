@@ -9,6 +9,7 @@ import (
 	"github.com/dr8co/doppel/internal/config"
 	"github.com/dr8co/doppel/internal/filter"
 	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
 )
 
 // PresetCommand returns the preset command configuration.
@@ -21,8 +22,11 @@ func PresetCommand(cfg *config.PresetConfig) *cli.Command {
   - dev: Skip development directories and files
   - media: Focus on media files, skip small files
   - docs: Focus on document files
-  - clean: Skip temporary and cache files`,
-		ArgsUsage:             "[directories...]",
+  - clean: Skip temporary and cache files
+
+Exit status: 0 if no duplicates were found, 1 if duplicates were found (or more than
+--fail-if-wasted-over or --fail-if-groups-over allow), and 2 if an error occurred.`,
+		ArgsUsage:             "[directories or files...]",
 		EnableShellCompletion: true,
 		Suggest:               true,
 
@@ -52,6 +56,70 @@ func PresetCommand(cfg *config.PresetConfig) *cli.Command {
 				Usage: "Write output to file (default: stdout)",
 				Value: "",
 			},
+			&cli.BoolFlag{
+				Name:  "fail-on-error",
+				Usage: "Fail once the report is written if any file could not be read",
+			},
+			&cli.StringFlag{
+				Name:  "fail-if-wasted-over",
+				Usage: "Only exit with status 1 if duplicates waste more than this much space (e.g. '100MB')",
+			},
+			&cli.IntFlag{
+				Name:  "fail-if-groups-over",
+				Usage: "Only exit with status 1 if there are more than this many duplicate groups",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-listen",
+				Usage: "Serve Prometheus metrics on this address while scanning (e.g. :9101)",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-textfile",
+				Usage: "Write Prometheus metrics to this file when done, for the node_exporter textfile collector",
+			},
+			&cli.IntFlag{
+				Name:  "progress-fd",
+				Usage: "Write JSON progress events to this file descriptor (e.g. 3), for scripts and front ends",
+			},
+			&cli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "Do not show the progress bar on terminals",
+			},
+			&cli.StringFlag{
+				Name:  "io-per-device",
+				Usage: "Read each device with workers of its own, as many as set per kind of device (e.g. 'hdd=1,ssd=8')",
+			},
+			&cli.StringFlag{
+				Name:  "max-read-rate",
+				Usage: "Read files at most this fast, across all workers (e.g. '50MB/s')",
+			},
+			&cli.IntFlag{
+				Name:  "max-open-files",
+				Usage: "Read at most this many files at the same time (0 for no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "idle-io",
+				Usage: "Only read the disks when no other process needs them (Linux only)",
+			},
+			&cli.IntFlag{
+				Name:  "nice",
+				Usage: "Run with this nice level, from -20 to 19 (Linux only)",
+			},
+			&cli.StringFlag{
+				Name:  "samples",
+				Usage: "Hash samples of large files at these points before reading them in full (e.g. 'middle,random=4')",
+			},
+			&cli.StringFlag{
+				Name:  "chunked-compare",
+				Usage: "Compare files of at least this size block by block, dropping them as soon as they differ (e.g. '64MB')",
+			},
+			&cli.StringFlag{
+				Name:  "max-memory",
+				Usage: "Keep memory under this ceiling by spilling the files found to disk (e.g. '2GB'); symlink options still use memory per file",
+			},
+			&cli.StringFlag{
+				Name:  "spill-dir",
+				Usage: "Directory to spill the files found to with --max-memory (default is the temporary directory)",
+			},
 		},
 		Commands: []*cli.Command{
 			{
@@ -96,7 +164,12 @@ func PresetCommand(cfg *config.PresetConfig) *cli.Command {
 
 // findDuplicatesWithPreset finds duplicates using a specific preset configuration.
 func findDuplicatesWithPreset(ctx context.Context, c *cli.Command, cfg *config.PresetConfig, preset string) error {
-	directories, err := scanner.GetDirectoriesFromArgs(c)
+	backends := newBackends()
+	defer func(backends *vfs.Registry) {
+		_ = backends.Close()
+	}(backends)
+
+	roots, err := scanner.GetRootsFromArgs(ctx, c, "", nil, backends)
 	if err != nil {
 		return err
 	}
@@ -118,14 +191,78 @@ func findDuplicatesWithPreset(ctx context.Context, c *cli.Command, cfg *config.P
 	if c.IsSet("output-format") {
 		cfg.OutputFormat = c.String("output-format")
 	}
+	if c.IsSet("fail-on-error") {
+		cfg.FailOnError = c.Bool("fail-on-error")
+	}
+	if c.IsSet("fail-if-wasted-over") {
+		cfg.FailIfWastedOver = c.String("fail-if-wasted-over")
+	}
+	if c.IsSet("fail-if-groups-over") {
+		cfg.FailIfGroupsOver = c.Int("fail-if-groups-over")
+	}
+	if c.IsSet("metrics-listen") {
+		cfg.MetricsListen = c.String("metrics-listen")
+	}
+	if c.IsSet("metrics-textfile") {
+		cfg.MetricsTextfile = c.String("metrics-textfile")
+	}
+	if c.IsSet("progress-fd") {
+		cfg.ProgressFD = c.Int("progress-fd")
+	}
+	if c.IsSet("no-progress") {
+		cfg.NoProgress = c.Bool("no-progress")
+	}
+	if c.IsSet("io-per-device") {
+		cfg.IOPerDevice = c.String("io-per-device")
+	}
+	if c.IsSet("max-read-rate") {
+		cfg.MaxReadRate = c.String("max-read-rate")
+	}
+	if c.IsSet("max-open-files") {
+		cfg.MaxOpenFiles = c.Int("max-open-files")
+	}
+	if c.IsSet("idle-io") {
+		cfg.IdleIO = c.Bool("idle-io")
+	}
+	if c.IsSet("nice") {
+		cfg.Nice = c.Int("nice")
+	}
+	if c.IsSet("samples") {
+		cfg.Samples = c.String("samples")
+	}
+	if c.IsSet("chunked-compare") {
+		cfg.ChunkedCompare = c.String("chunked-compare")
+	}
+	if c.IsSet("max-memory") {
+		cfg.MaxMemory = c.String("max-memory")
+	}
+	if c.IsSet("spill-dir") {
+		cfg.SpillDir = c.String("spill-dir")
+	}
 
 	cfg2 := config.FindConfig{
-		Workers:      cfg.Workers,
-		Verbose:      cfg.Verbose,
-		ShowFilters:  cfg.ShowFilters,
-		OutputFile:   cfg.OutputFile,
-		OutputFormat: cfg.OutputFormat,
+		Workers:          cfg.Workers,
+		Verbose:          cfg.Verbose,
+		ShowFilters:      cfg.ShowFilters,
+		OutputFile:       cfg.OutputFile,
+		OutputFormat:     cfg.OutputFormat,
+		FailOnError:      cfg.FailOnError,
+		FailIfWastedOver: cfg.FailIfWastedOver,
+		FailIfGroupsOver: cfg.FailIfGroupsOver,
+		MetricsListen:    cfg.MetricsListen,
+		MetricsTextfile:  cfg.MetricsTextfile,
+		ProgressFD:       cfg.ProgressFD,
+		NoProgress:       cfg.NoProgress,
+		IOPerDevice:      cfg.IOPerDevice,
+		MaxReadRate:      cfg.MaxReadRate,
+		MaxOpenFiles:     cfg.MaxOpenFiles,
+		IdleIO:           cfg.IdleIO,
+		Nice:             cfg.Nice,
+		Samples:          cfg.Samples,
+		ChunkedCompare:   cfg.ChunkedCompare,
+		MaxMemory:        cfg.MaxMemory,
+		SpillDir:         cfg.SpillDir,
 	}
 
-	return findDuplicates(ctx, &cfg2, directories, filterConfig)
+	return findDuplicates(ctx, &cfg2, roots, filterConfig, nil)
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/progress"
+)
+
+// startProgress reports the progress of a scan updating s: with a bar on standard error if it is a terminal
+// (unless turned off), and with JSON events on the file descriptor cfg.ProgressFD if set.
+// The returned function writes the final progress and stops reporting; later calls do nothing.
+func startProgress(cfg *config.FindConfig, s *model.Stats) (func(), error) {
+	var reporters []progress.Reporter
+
+	stderr := int(os.Stderr.Fd()) //nolint:gosec
+	if !cfg.NoProgress && term.IsTerminal(stderr) {
+		width, _, _ := term.GetSize(stderr)
+		reporters = append(reporters, progress.NewBar(os.Stderr, width))
+	}
+
+	var events *os.File
+	if cfg.ProgressFD > 0 {
+		events = os.NewFile(uintptr(cfg.ProgressFD), "progress")
+		if _, err := events.Stat(); err != nil {
+			return nil, fmt.Errorf("invalid progress file descriptor %d: %w", cfg.ProgressFD, err)
+		}
+		reporters = append(reporters, progress.NewJSON(events, time.Second))
+	}
+
+	if len(reporters) == 0 {
+		return func() {}, nil
+	}
+
+	t := progress.NewTracker()
+	s.Progress = t
+	d := progress.Start(t, 100*time.Millisecond, reporters...)
+
+	return sync.OnceFunc(func() {
+		d.Stop()
+		// Closing the descriptor tells the reader that no more events follow,
+		// but standard output and standard error are left alone.
+		if events != nil && cfg.ProgressFD > 2 {
+			_ = events.Close()
+		}
+	}), nil
+}
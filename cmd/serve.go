@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/output"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/server"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// ServeCommand returns the serve command configuration.
+// Scans started through the API take the options they leave out from findCfg.
+func ServeCommand(cfg *config.ServeConfig, findCfg *config.FindConfig) *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Run scans requested over an HTTP API",
+		Description: `Serve a REST API to start scans, poll their progress, cancel them,
+and fetch their reports in any output format.
+
+Scans wait in a bounded queue, and the jobs and their reports are kept in a data directory,
+so that the history survives restarts. The API has no authentication:
+expose it only to trusted clients.`,
+		EnableShellCompletion: true,
+		Suggest:               true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "listen",
+				Aliases: []string{"l"},
+				Value:   "localhost:8080",
+				Usage:   "Address to listen on (e.g. :8080 for every interface)",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   1,
+				Usage:   "Number of scans that run at the same time",
+			},
+			&cli.IntFlag{
+				Name:  "queue-size",
+				Value: 16,
+				Usage: "Number of scans that can wait for a free worker",
+			},
+			&cli.StringFlag{
+				Name:  "data-dir",
+				Usage: "Directory to keep jobs and reports in (default: doppel/jobs in the user cache directory)",
+			},
+			&cli.IntFlag{
+				Name:  "max-history",
+				Value: 100,
+				Usage: "Number of finished jobs to keep (0 = keep every job)",
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return serveCmd(ctx, c, cfg, findCfg)
+		},
+	}
+}
+
+// serveCmd is the action function for the serve command.
+func serveCmd(ctx context.Context, c *cli.Command, cfg *config.ServeConfig, findCfg *config.FindConfig) error {
+	// Override with CLI flags
+	if c.IsSet("listen") {
+		cfg.Listen = c.String("listen")
+	}
+	if c.IsSet("jobs") {
+		cfg.Jobs = c.Int("jobs")
+	}
+	if c.IsSet("queue-size") {
+		cfg.QueueSize = c.Int("queue-size")
+	}
+	if c.IsSet("data-dir") {
+		cfg.DataDir = c.String("data-dir")
+	}
+	if c.IsSet("max-history") {
+		cfg.MaxHistory = c.Int("max-history")
+	}
+
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("error finding the data directory: %w", err)
+		}
+		dataDir = filepath.Join(cacheDir, "doppel", "jobs")
+	}
+
+	reg, err := output.InitFormatters()
+	if err != nil {
+		return fmt.Errorf("error initializing formatters: %w", err)
+	}
+
+	srv, err := server.New(runScan, reg,
+		server.WithConcurrency(cfg.Jobs),
+		server.WithQueueSize(cfg.QueueSize),
+		server.WithDataDir(dataDir),
+		server.WithMaxHistory(cfg.MaxHistory),
+		server.WithDefaults(*findCfg),
+	)
+	if err != nil {
+		return err
+	}
+
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", cfg.Listen)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🌐 Serving the API on http://%s (jobs in \"%s\")\n", ln.Addr(), dataDir)
+
+	return srv.Serve(ctx, ln)
+}
+
+// runScan runs the scan of a job started through the API.
+func runScan(ctx context.Context, req server.Request, s *model.Stats) (*model.DuplicateReport, error) {
+	cfg := req.FindConfig
+
+	backends := newBackends()
+	defer func(backends *vfs.Registry) {
+		_ = backends.Close()
+	}(backends)
+
+	roots, err := scanner.GetRoots(ctx, req.Paths, cfg.FilesFrom, cfg.Reference, backends)
+	if err != nil {
+		return nil, err
+	}
+
+	filterConfig, err := buildFilter(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jobs print nothing, but the errors met are logged.
+	newPresenter(ctx, io.Discard, s, cfg.Workers, false)
+
+	maxMemory, err := filter.ParseFileSize(cfg.MaxMemory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max_memory: %w", err)
+	}
+	if maxMemory > 0 {
+		return findInBatches(ctx, &cfg, maxMemory, roots, filterConfig, s, nil)
+	}
+	return findInMemory(ctx, &cfg, roots, filterConfig, s, nil)
+}
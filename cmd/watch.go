@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/output"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/watch"
+)
+
+// WatchCommand returns the watch command configuration.
+func WatchCommand(cfg *config.WatchConfig) *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "Keep track of duplicate files as directories change",
+		ArgsUsage: "[directories...]",
+		Description: `Scan the given directories once, then watch them for changes and report
+files that become duplicates, and duplicate groups that are resolved, as it happens.
+
+Events are written as log records, or as JSON lines with --events ndjson.
+Watching is only supported on Linux.`,
+		EnableShellCompletion: true,
+		Suggest:               true,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "workers",
+				Aliases: []string{"w"},
+				Value:   runtime.NumCPU(),
+				Usage:   "Number of worker goroutines for hashing during the initial scan",
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Enable verbose output with detailed progress information",
+			},
+			&cli.StringFlag{
+				Name:  "exclude-dirs",
+				Usage: "Comma-separated list of directory patterns to exclude",
+			},
+			&cli.StringFlag{
+				Name:  "exclude-files",
+				Usage: "Comma-separated list of file patterns to exclude",
+			},
+			&cli.StringFlag{
+				Name:  "min-size",
+				Usage: "Minimum file size to consider (e.g. 1MB, 512KiB)",
+			},
+			&cli.StringFlag{
+				Name:  "max-size",
+				Usage: "Maximum file size to consider (e.g. 1GB, 0 = no limit)",
+			},
+			&cli.StringFlag{
+				Name:  "owner",
+				Usage: "Only watch files owned by this user (name or ID)",
+			},
+			&cli.StringFlag{
+				Name:  "group",
+				Usage: "Only watch files owned by this group (name or ID)",
+			},
+			&cli.StringFlag{
+				Name:  "uid",
+				Usage: "Only watch files owned by this numeric user ID",
+			},
+			&cli.StringFlag{
+				Name:  "gid",
+				Usage: "Only watch files owned by this numeric group ID",
+			},
+			&cli.BoolFlag{
+				Name:  "writable-only",
+				Usage: "Skip files the current user cannot remove (parent directory not writable)",
+			},
+			&cli.StringFlag{
+				Name:  "events",
+				Value: "log",
+				Usage: "How to emit events: log, ndjson",
+			},
+			&cli.StringFlag{
+				Name:    "output-file",
+				Aliases: []string{"o"},
+				Usage:   "Write NDJSON events to file (default: stdout)",
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return watchCmd(ctx, c, cfg)
+		},
+	}
+}
+
+// watchCmd is the action function for the watch command.
+func watchCmd(ctx context.Context, c *cli.Command, cfg *config.WatchConfig) error {
+	// Override with CLI flags
+	if c.IsSet("workers") {
+		cfg.Workers = c.Int("workers")
+	}
+	if c.IsSet("verbose") {
+		cfg.Verbose = c.Bool("verbose")
+	}
+	if c.IsSet("exclude-dirs") {
+		cfg.ExcludeDirs = c.String("exclude-dirs")
+	}
+	if c.IsSet("exclude-files") {
+		cfg.ExcludeFiles = c.String("exclude-files")
+	}
+	if c.IsSet("min-size") {
+		cfg.MinSize = c.String("min-size")
+	}
+	if c.IsSet("max-size") {
+		cfg.MaxSize = c.String("max-size")
+	}
+	if c.IsSet("owner") {
+		cfg.Owner = c.String("owner")
+	}
+	if c.IsSet("group") {
+		cfg.Group = c.String("group")
+	}
+	if c.IsSet("uid") {
+		cfg.UID = c.String("uid")
+	}
+	if c.IsSet("gid") {
+		cfg.GID = c.String("gid")
+	}
+	if c.IsSet("writable-only") {
+		cfg.WritableOnly = c.Bool("writable-only")
+	}
+	if c.IsSet("events") {
+		cfg.Events = c.String("events")
+	}
+	if c.IsSet("output-file") {
+		cfg.OutputFile = c.String("output-file")
+	}
+
+	var emit func(ctx context.Context, e watch.Event) error
+	switch strings.ToLower(cfg.Events) {
+	case "log", "":
+		emit = logEvent
+	case "ndjson":
+	default:
+		return fmt.Errorf("invalid events format: %s, must be one of [log ndjson]", cfg.Events)
+	}
+
+	// Only local directories can be watched, so no remote backends are registered.
+	roots, err := scanner.GetRootsFromArgs(ctx, c, "", nil, nil)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if root.FS != nil {
+			return fmt.Errorf("cannot watch remote path: %s", root.Path)
+		}
+		if info, err := os.Stat(root.Path); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("not a directory: %s", root.Path)
+		}
+		paths = append(paths, root.Path)
+	}
+
+	var minSize, maxSize int64
+	if cfg.MinSize != "" {
+		if minSize, err = filter.ParseFileSize(cfg.MinSize); err != nil {
+			return fmt.Errorf("invalid min-size: %w", err)
+		}
+	}
+	if cfg.MaxSize != "" {
+		if maxSize, err = filter.ParseFileSize(cfg.MaxSize); err != nil {
+			return fmt.Errorf("invalid max-size: %w", err)
+		}
+	}
+
+	filterConfig, err := filter.BuildConfig(cfg.ExcludeDirs, cfg.ExcludeFiles, "", "", minSize, maxSize)
+	if err != nil {
+		return fmt.Errorf("error building filter configuration: %w", err)
+	}
+	err = filterConfig.ApplyOwnership(cfg.Owner, cfg.Group, cfg.UID, cfg.GID, cfg.WritableOnly)
+	if err != nil {
+		return fmt.Errorf("error building filter configuration: %w", err)
+	}
+
+	if emit == nil {
+		out, outputFile, closeOutput, err := createOutput(cfg.OutputFile, "doppel-events.ndjson")
+		if err != nil {
+			return err
+		}
+		defer closeOutput()
+		if outputFile != "" && cfg.Verbose {
+			_, _ = fmt.Fprintf(os.Stderr, "📝 Writing events to \"%s\"\n", outputFile)
+		}
+		emit = ndjsonEmitter(out)
+	}
+
+	ready := func(files int) {
+		if cfg.Verbose {
+			// Status messages go to stderr, so that events written to stdout can be piped.
+			_, _ = fmt.Fprintf(os.Stderr, "👀 Watching %d file%s for changes. Press Ctrl+C to stop.\n",
+				files, pluralize(files))
+		}
+	}
+
+	// Status messages go to stderr, so that events written to stdout can be piped.
+	s := &model.Stats{StartTime: time.Now()}
+	newPresenter(ctx, os.Stderr, s, cfg.Workers, cfg.Verbose)
+	w := watch.New(paths, filterConfig, s,
+		watch.WithWorkers(cfg.Workers),
+		watch.WithReady(ready),
+	)
+
+	var emitErr error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	err = w.Run(ctx, func(e watch.Event) {
+		if emitErr != nil {
+			return
+		}
+		if emitErr = emit(ctx, e); emitErr != nil {
+			cancel()
+		}
+	})
+	if emitErr != nil {
+		return fmt.Errorf("error writing events: %w", emitErr)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// ndjsonEmitter returns a function writing each event to out as a line of JSON.
+func ndjsonEmitter(out io.Writer) func(context.Context, watch.Event) error {
+	enc := json.NewEncoder(out)
+	return func(_ context.Context, e watch.Event) error {
+		return enc.Encode(e)
+	}
+}
+
+// logEvent writes an event as a log record.
+func logEvent(ctx context.Context, e watch.Event) error {
+	msg := map[watch.Kind]string{
+		watch.KindGroup:     "duplicate group",
+		watch.KindDuplicate: "new duplicate",
+		watch.KindRemoved:   "duplicate removed",
+		watch.KindResolved:  "duplicate group resolved",
+		watch.KindMoved:     "duplicate moved",
+	}[e.Kind]
+
+	attrs := []slog.Attr{slog.String("path", e.Path)}
+	if e.From != "" {
+		attrs = append(attrs, slog.String("from", e.From))
+	}
+	attrs = append(attrs,
+		slog.String("size", output.FormatBytes(e.Size)),
+		slog.Any("files", e.Files),
+	)
+	logger.InfoAttrs(ctx, msg, attrs...)
+	return nil
+}
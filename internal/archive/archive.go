@@ -0,0 +1,235 @@
+// Package archive reads the members of zip and tar archives so their contents can be scanned
+// for duplicates like regular files.
+//
+// Supported formats, detected by file extension:
+//   - zip (.zip)
+//   - tar (.tar), optionally compressed with gzip (.tar.gz, .tgz), bzip2 (.tar.bz2, .tbz2, .tbz),
+//     or xz (.tar.xz, .txz)
+//
+// Archives are read through a [vfs.FS], so they can live on any backend.
+// Members are identified by virtual paths of the form "archive.zip!/inner/file",
+// and [FS] exposes them as a read-only [vfs.FS].
+// Archives nested inside archives are not expanded.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Separator separates the archive path from the member name in a virtual path.
+const Separator = "!/"
+
+// ErrUnsupported is returned for files that are not supported archives.
+var ErrUnsupported = errors.New("unsupported archive format")
+
+// format identifies an archive format.
+type format uint8
+
+const (
+	formatNone format = iota
+	formatZip
+	formatTar
+	formatTarGz
+	formatTarBz2
+	formatTarXz
+)
+
+// extensions maps file name suffixes to archive formats.
+// Longer suffixes come first so that ".tar.gz" wins over ".gz".
+var extensions = []struct {
+	suffix string
+	format format
+}{
+	{".tar.gz", formatTarGz},
+	{".tar.bz2", formatTarBz2},
+	{".tar.xz", formatTarXz},
+	{".tgz", formatTarGz},
+	{".tbz2", formatTarBz2},
+	{".tbz", formatTarBz2},
+	{".txz", formatTarXz},
+	{".tar", formatTar},
+	{".zip", formatZip},
+}
+
+// Member describes a regular file stored in an archive.
+type Member struct {
+	// Name is the slash-separated path of the member inside the archive.
+	Name string
+
+	// Size is the uncompressed size of the member.
+	Size int64
+}
+
+// detect returns the format of an archive based on its name.
+func detect(name string) format {
+	lower := strings.ToLower(name)
+	for _, ext := range extensions {
+		if strings.HasSuffix(lower, ext.suffix) {
+			return ext.format
+		}
+	}
+	return formatNone
+}
+
+// IsArchive reports whether name has the extension of a supported archive format.
+func IsArchive(name string) bool {
+	return detect(name) != formatNone
+}
+
+// Streamed reports whether the members of the archive can only be read in order, by decompressing
+// the archive from the start. Reading such members through [FS] is expensive,
+// so [Walk] hands their contents to its callback instead.
+func Streamed(name string) bool {
+	switch detect(name) {
+	case formatTarGz, formatTarBz2, formatTarXz:
+		return true
+	default:
+		return false
+	}
+}
+
+// JoinPath returns the virtual path of a member inside an archive.
+func JoinPath(archivePath, member string) string {
+	return archivePath + Separator + member
+}
+
+// SplitPath splits a virtual path into the archive path and the member name.
+// It reports false if the path does not name an archive member.
+func SplitPath(virtualPath string) (archivePath, member string, ok bool) {
+	// Archives are not nested, so the first separator following an archive name is the split point.
+	rest := virtualPath
+	offset := 0
+	for {
+		i := strings.Index(rest, Separator)
+		if i < 0 {
+			return "", "", false
+		}
+		if IsArchive(virtualPath[:offset+i]) {
+			return virtualPath[:offset+i], virtualPath[offset+i+len(Separator):], true
+		}
+		offset += i + len(Separator)
+		rest = rest[i+len(Separator):]
+	}
+}
+
+// Walk calls fn for every regular file in the archive at archivePath on fsys, in archive order.
+//
+// For [Streamed] archives, r reads the contents of the member and is only valid during the call.
+// For other archives, r is nil and the member can be opened later through [FS].
+// Walk stops at the first error returned by fn.
+func Walk(fsys vfs.FS, archivePath string, fn func(m Member, r io.Reader) error) error {
+	f := detect(archivePath)
+	if f == formatNone {
+		return fmt.Errorf("%s: %w", archivePath, ErrUnsupported)
+	}
+
+	file, err := fsys.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func(file vfs.File) {
+		_ = file.Close()
+	}(file)
+
+	if f == formatZip {
+		zr, err := openZip(file)
+		if err != nil {
+			return err
+		}
+		for _, zf := range zr.File {
+			if !zf.Mode().IsRegular() {
+				continue
+			}
+			//nolint:gosec
+			if err := fn(Member{Name: cleanName(zf.Name), Size: int64(zf.UncompressedSize64)}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tr, _, err := openTar(file, f)
+	if err != nil {
+		return err
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		var r io.Reader
+		if f != formatTar {
+			r = tr
+		}
+		if err := fn(Member{Name: cleanName(hdr.Name), Size: hdr.Size}, r); err != nil {
+			return err
+		}
+	}
+}
+
+// openZip reads the central directory of an opened zip archive.
+func openZip(file vfs.File) (*zip.Reader, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(file, info.Size())
+}
+
+// openTar reads an opened tar archive, decompressing it if needed.
+// For plain tar archives, it also returns the seekable section the tar reader reads from.
+func openTar(file vfs.File, f format) (*tar.Reader, io.Seeker, error) {
+	var r io.Reader
+	switch f {
+	case formatTarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = gz
+	case formatTarBz2:
+		r = bzip2.NewReader(file)
+	case formatTarXz:
+		xr, err := xz.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = xr
+	default:
+		// Plain tar files are read through a seekable section, which lets the tar reader
+		// skip over member contents and report where each member starts.
+		info, err := file.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		section := io.NewSectionReader(file, 0, info.Size())
+		return tar.NewReader(section), section, nil
+	}
+
+	return tar.NewReader(r), nil, nil
+}
+
+// cleanName normalizes a member name to a clean, relative, slash-separated path.
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
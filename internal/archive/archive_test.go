@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// testMembers are the regular files written to every test archive.
+var testMembers = map[string]string{
+	"a.txt":       "first member",
+	"dir/b.txt":   "second member",
+	"dir/c/d.txt": "",
+}
+
+// writeZip writes a zip archive holding testMembers and a directory entry.
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("dir/"); err != nil {
+		t.Fatalf("Failed to add directory: %v", err)
+	}
+	for name, content := range testMembers {
+		// Store one member uncompressed, so both compression methods are covered
+		method := zip.Deflate
+		if name == "a.txt" {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+}
+
+// writeTar writes a tar archive holding testMembers and a directory entry, compressed by wrap if not nil.
+func writeTar(t *testing.T, path string, wrap func(io.Writer) (io.WriteCloser, error)) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+
+	var w io.Writer = f
+	var cw io.WriteCloser
+	if wrap != nil {
+		cw, err = wrap(f)
+		if err != nil {
+			t.Fatalf("Failed to create compressor: %v", err)
+		}
+		w = cw
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatalf("Failed to add directory: %v", err)
+	}
+	for name, content := range testMembers {
+		hdr := &tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			t.Fatalf("Failed to close compressor: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+}
+
+// testArchives lists an archive of every supported format, with a mixed-case extension among them.
+var testArchives = []struct {
+	name     string
+	write    func(t *testing.T, path string)
+	streamed bool
+}{
+	{name: "data.zip", write: writeZip},
+	{name: "data.tar", write: func(t *testing.T, path string) { writeTar(t, path, nil) }},
+	{name: "data.tar.gz", streamed: true, write: func(t *testing.T, path string) {
+		writeTar(t, path, func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil })
+	}},
+	{name: "data.TXZ", streamed: true, write: func(t *testing.T, path string) {
+		writeTar(t, path, func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) })
+	}},
+}
+
+// TestWalk verifies that every supported format lists its regular members, and that members can be read.
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, tt := range testArchives {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			tt.write(t, path)
+
+			if got := Streamed(path); got != tt.streamed {
+				t.Errorf("Streamed() = %v, want %v", got, tt.streamed)
+			}
+
+			got := make(map[string]string)
+			fsys := NewFS(vfs.OS{}, path)
+			err := Walk(vfs.OS{}, path, func(m Member, r io.Reader) error {
+				if r == nil {
+					rc, err := fsys.Open(JoinPath(path, m.Name))
+					if err != nil {
+						return err
+					}
+					defer func(rc io.ReadCloser) {
+						_ = rc.Close()
+					}(rc)
+					r = rc
+				}
+				content, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				if int64(len(content)) != m.Size {
+					t.Errorf("Member %s has size %d, read %d bytes", m.Name, m.Size, len(content))
+				}
+				got[m.Name] = string(content)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+
+			if len(got) != len(testMembers) {
+				t.Errorf("Walk() listed %d members, want %d", len(got), len(testMembers))
+			}
+			for name, content := range testMembers {
+				if got[name] != content {
+					t.Errorf("Member %s = %q, want %q", name, got[name], content)
+				}
+			}
+		})
+	}
+
+}
+
+// TestSplitPath verifies that virtual paths are split at the archive name.
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantArchive string
+		wantMember  string
+		wantOK      bool
+	}{
+		{path: "/data/a.zip!/dir/file.txt", wantArchive: "/data/a.zip", wantMember: "dir/file.txt", wantOK: true},
+		{path: "/odd!/b.tar.gz!/file", wantArchive: "/odd!/b.tar.gz", wantMember: "file", wantOK: true},
+		{path: "/data/a.zip", wantOK: false},
+		{path: "/data/not-an-archive!/file", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			archivePath, member, ok := SplitPath(tt.path)
+			if archivePath != tt.wantArchive || member != tt.wantMember || ok != tt.wantOK {
+				t.Errorf("SplitPath() = (%q, %q, %v), want (%q, %q, %v)",
+					archivePath, member, ok, tt.wantArchive, tt.wantMember, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// errIsDir is returned when opening a directory of an archive.
+var errIsDir = errors.New("is a directory")
+
+// FS is a read-only [vfs.FS] over the members of an archive stored on a parent [vfs.FS].
+// Names are virtual paths such as "archive.zip!/inner/file", and the archive root is "archive.zip!/".
+//
+// The archive is indexed on first use. Members of zip archives stored without compression
+// and of plain tar archives are read in place with random access.
+// Other members are decompressed sequentially, restarting when read backwards.
+type FS struct {
+	parent vfs.FS
+	path   string
+	format format
+
+	once  sync.Once
+	index map[string]*entry
+	err   error
+}
+
+// entry is a member or directory of an archive. It doubles as its own [fs.FileInfo].
+type entry struct {
+	name     string
+	dir      bool
+	size     int64
+	modTime  time.Time
+	children []*entry
+
+	// offset is the position of the member data in the archive, or -1 if it must be read sequentially.
+	offset int64
+
+	// compressedSize and method describe the stored data of zip members.
+	compressedSize int64
+	method         uint16
+}
+
+// NewFS returns a filesystem over the members of the archive at archivePath on parent.
+func NewFS(parent vfs.FS, archivePath string) *FS {
+	return &FS{parent: parent, path: archivePath, format: detect(archivePath)}
+}
+
+// Root returns the name of the root directory of the archive.
+func (a *FS) Root() string {
+	return a.path + Separator
+}
+
+// Open opens the named member for reading.
+func (a *FS) Open(name string) (vfs.File, error) {
+	e, member, err := a.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.dir {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	}
+
+	switch {
+	case e.offset >= 0 && (a.format == formatTar || e.method == zip.Store):
+		file, err := a.parent.Open(a.path)
+		if err != nil {
+			return nil, err
+		}
+		return &sectionFile{SectionReader: io.NewSectionReader(file, e.offset, e.size), file: file, info: e}, nil
+
+	case a.format == formatZip && e.method == zip.Deflate:
+		return &seqFile{info: e, open: func() (io.ReadCloser, error) {
+			file, err := a.parent.Open(a.path)
+			if err != nil {
+				return nil, err
+			}
+			rc := flate.NewReader(io.NewSectionReader(file, e.offset, e.compressedSize))
+			return &readCloser{Reader: rc, closers: []io.Closer{rc, file}}, nil
+		}}, nil
+
+	case a.format == formatZip:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: zip.ErrAlgorithm}
+
+	default:
+		return &seqFile{info: e, open: func() (io.ReadCloser, error) {
+			return a.openTarMember(name, member)
+		}}, nil
+	}
+}
+
+// Stat returns information about the named member or directory.
+func (a *FS) Stat(name string) (fs.FileInfo, error) {
+	e, _, err := a.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Lstat returns information about the named member or directory.
+// Symlinks in archives are not followed, so it is the same as [FS.Stat].
+func (a *FS) Lstat(name string) (fs.FileInfo, error) {
+	e, _, err := a.lookup("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ReadDir reads the named directory of the archive, returning its entries sorted by name.
+func (a *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, _, err := a.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries := make([]fs.DirEntry, len(e.children))
+	for i, child := range e.children {
+		entries[i] = fs.FileInfoToDirEntry(child)
+	}
+	return entries, nil
+}
+
+// EvalSymlinks returns the canonical virtual path of an existing member or directory.
+func (a *FS) EvalSymlinks(name string) (string, error) {
+	_, member, err := a.lookup("lstat", name)
+	if err != nil {
+		return "", err
+	}
+	return JoinPath(a.path, member), nil
+}
+
+// Join joins name elements with slashes. The first element may be the archive root.
+func (a *FS) Join(elem ...string) string {
+	if len(elem) == 0 {
+		return ""
+	}
+	rest := path.Join(elem[1:]...)
+	if rest == "" {
+		return elem[0]
+	}
+	return strings.TrimSuffix(elem[0], "/") + "/" + rest
+}
+
+// lookup returns the entry and the member name for a virtual path.
+func (a *FS) lookup(op, name string) (*entry, string, error) {
+	a.once.Do(func() {
+		a.index, a.err = a.buildIndex()
+	})
+	if a.err != nil {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: a.err}
+	}
+
+	rest, ok := strings.CutPrefix(name, a.path+"!")
+	if ok && (rest == "" || rest[0] == '/') {
+		member := cleanName(rest)
+		if e, found := a.index[member]; found {
+			return e, member, nil
+		}
+	}
+	return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// buildIndex lists the archive, recording where each member is stored.
+func (a *FS) buildIndex() (map[string]*entry, error) {
+	if a.format == formatNone {
+		return nil, ErrUnsupported
+	}
+
+	file, err := a.parent.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file vfs.File) {
+		_ = file.Close()
+	}(file)
+
+	index := map[string]*entry{"": {name: path.Base(a.path) + "!", dir: true, offset: -1}}
+
+	if a.format == formatZip {
+		zr, err := openZip(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if !zf.Mode().IsRegular() {
+				continue
+			}
+			offset, err := zf.DataOffset()
+			if err != nil {
+				return nil, fmt.Errorf("error locating %s: %w", zf.Name, err)
+			}
+			//nolint:gosec
+			addEntry(index, cleanName(zf.Name), &entry{
+				size:           int64(zf.UncompressedSize64),
+				modTime:        zf.Modified,
+				offset:         offset,
+				compressedSize: int64(zf.CompressedSize64),
+				method:         zf.Method,
+			})
+		}
+		return index, nil
+	}
+
+	tr, section, err := openTar(file, a.format)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return index, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		e := &entry{size: hdr.Size, modTime: hdr.ModTime, offset: -1}
+		if section != nil && !isSparse(hdr) {
+			// The tar reader has consumed exactly the headers, so the section is positioned at the data.
+			if offset, err := section.Seek(0, io.SeekCurrent); err == nil {
+				e.offset = offset
+			}
+		}
+		addEntry(index, cleanName(hdr.Name), e)
+	}
+}
+
+// addEntry adds a member to the index, creating its parent directories.
+// A later member with the same name replaces an earlier one, as when extracting the archive.
+func addEntry(index map[string]*entry, member string, e *entry) {
+	if old, ok := index[member]; ok {
+		if !old.dir {
+			e.name = old.name
+			*old = *e
+		}
+		return
+	}
+
+	e.name = path.Base(member)
+	index[member] = e
+
+	for child, dir := e, path.Dir(member); ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		parent, ok := index[dir]
+		if ok {
+			if parent.dir {
+				i, _ := slices.BinarySearchFunc(parent.children, child.name, func(c *entry, name string) int {
+					return strings.Compare(c.name, name)
+				})
+				parent.children = slices.Insert(parent.children, i, child)
+			}
+			return
+		}
+
+		parent = &entry{name: path.Base(dir), dir: true, offset: -1, children: []*entry{child}}
+		index[dir] = parent
+		child = parent
+	}
+}
+
+// isSparse reports whether a tar member is a sparse file, whose data is not stored contiguously.
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// openTarMember opens a member of a tar archive by reading the archive up to it.
+func (a *FS) openTarMember(name, member string) (io.ReadCloser, error) {
+	file, err := a.parent.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+
+	tr, _, err := openTar(file, a.format)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			_ = file.Close()
+			if errors.Is(err, io.EOF) {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+			}
+			return nil, err
+		}
+		if hdr.FileInfo().Mode().IsRegular() && cleanName(hdr.Name) == member {
+			return &readCloser{Reader: tr, closers: []io.Closer{file}}, nil
+		}
+	}
+}
+
+func (e *entry) Name() string       { return e.name }
+func (e *entry) Size() int64        { return e.size }
+func (e *entry) ModTime() time.Time { return e.modTime }
+func (e *entry) IsDir() bool        { return e.dir }
+func (e *entry) Sys() any           { return nil }
+
+// Mode returns read-only permissions, since archive members cannot be modified in place.
+func (e *entry) Mode() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// sectionFile is a member stored contiguously in the archive.
+type sectionFile struct {
+	*io.SectionReader
+	file vfs.File
+	info *entry
+}
+
+// Stat returns information about the member.
+func (f *sectionFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close closes the archive.
+func (f *sectionFile) Close() error {
+	return f.file.Close()
+}
+
+// seqFile is a member that can only be decompressed in order.
+// Random access is emulated by skipping forward, and by reopening the member to go backwards.
+type seqFile struct {
+	open func() (io.ReadCloser, error)
+	info *entry
+
+	rc  io.ReadCloser
+	pos int64
+
+	// readOff is the offset of the next Read, which is independent of ReadAt.
+	readOff int64
+}
+
+// Read reads the member sequentially.
+func (f *seqFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.readOff)
+	f.readOff += int64(n)
+	if n > 0 && errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes of the member starting at off.
+func (f *seqFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	if off >= f.info.size {
+		return 0, io.EOF
+	}
+
+	if f.rc == nil || off < f.pos {
+		if f.rc != nil {
+			_ = f.rc.Close()
+			f.rc = nil
+		}
+		rc, err := f.open()
+		if err != nil {
+			return 0, err
+		}
+		f.rc, f.pos = rc, 0
+	}
+
+	if off > f.pos {
+		skipped, err := io.CopyN(io.Discard, f.rc, off-f.pos)
+		f.pos += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := io.ReadFull(f.rc, p)
+	f.pos += int64(n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Stat returns information about the member.
+func (f *seqFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close releases the decompressor and the archive.
+func (f *seqFile) Close() error {
+	if f.rc == nil {
+		return nil
+	}
+	err := f.rc.Close()
+	f.rc = nil
+	return err
+}
+
+// readCloser reads an opened member and releases its resources when closed.
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the member and the archive it belongs to.
+func (r *readCloser) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
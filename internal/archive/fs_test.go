@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// TestFS verifies that archive members can be walked, stated, and read with random access,
+// with the archive itself stored on an in-memory filesystem.
+func TestFS(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, tt := range testArchives {
+		t.Run(tt.name, func(t *testing.T) {
+			diskPath := filepath.Join(dir, tt.name)
+			tt.write(t, diskPath)
+			data, err := os.ReadFile(diskPath)
+			if err != nil {
+				t.Fatalf("Failed to read archive: %v", err)
+			}
+
+			mem := vfs.NewMemFS()
+			archivePath := "/archives/" + tt.name
+			if err := mem.WriteFile(archivePath, data); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			fsys := NewFS(mem, archivePath)
+			got := make(map[string]string)
+			err = vfs.WalkDir(fsys, fsys.Root(), func(name string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+
+				info, err := fsys.Stat(name)
+				if err != nil {
+					return err
+				}
+				if info.Mode().Perm()&0o222 != 0 {
+					t.Errorf("Member %s has mode %v, want read-only", name, info.Mode())
+				}
+
+				f, err := fsys.Open(name)
+				if err != nil {
+					return err
+				}
+				defer func(f vfs.File) {
+					_ = f.Close()
+				}(f)
+
+				// Read the tail first, then the whole member, to exercise going backwards.
+				if info.Size() > 3 {
+					tail := make([]byte, info.Size()-3)
+					if _, err := f.ReadAt(tail, 3); err != nil {
+						return err
+					}
+					if want := testMembers[name[len(fsys.Root()):]][3:]; string(tail) != want {
+						t.Errorf("ReadAt() of %s = %q, want %q", name, tail, want)
+					}
+				}
+				content, err := io.ReadAll(f)
+				if err != nil {
+					return err
+				}
+				got[name] = string(content)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkDir() error = %v", err)
+			}
+
+			if len(got) != len(testMembers) {
+				t.Errorf("WalkDir() found %d members, want %d", len(got), len(testMembers))
+			}
+			for member, content := range testMembers {
+				if name := JoinPath(archivePath, member); got[name] != content {
+					t.Errorf("Member %s = %q, want %q", name, got[name], content)
+				}
+			}
+
+			if _, err := fsys.Open(JoinPath(archivePath, "missing.txt")); !os.IsNotExist(err) {
+				t.Errorf("Open() of a missing member error = %v, want not exist", err)
+			}
+			if _, err := fsys.Open(JoinPath(archivePath, "dir")); err == nil {
+				t.Error("Open() of a directory should return an error")
+			}
+		})
+	}
+}
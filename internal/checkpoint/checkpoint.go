@@ -0,0 +1,509 @@
+// Package checkpoint records the progress of a scan to a file, so that a scan interrupted by a signal
+// or a reboot can be resumed instead of started over.
+//
+// A checkpoint is a JSON Lines file. The first line is a [Header], recording the configuration and the paths
+// of the scan; each following line records one of the files found, the directories walked, a snapshot
+// of the walk, the end of the walk, or a hash computed. The lines are written as the scan goes,
+// and synced to disk at every snapshot, and at least every sync interval while hashing.
+//
+// A checkpoint is only valid up to its last snapshot, or up to its last complete line once the walk is done:
+// whatever follows is dropped when resuming, and found or hashed again.
+package checkpoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+const (
+	// Version is the checkpoint format version written by [Create].
+	Version = 1
+
+	// DefaultInterval is the default time between two snapshots of a walk, and between two syncs while hashing.
+	DefaultInterval = 30 * time.Second
+)
+
+// Header describes the scan a checkpoint records.
+type Header struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+
+	// Dir is the working directory of the scan, which relative paths are resolved against.
+	Dir string `json:"dir"`
+
+	// Paths are the paths given to the scan, and Roots the roots walked, in order.
+	Paths []string `json:"paths,omitempty"`
+	Roots []string `json:"roots"`
+
+	// Config is the configuration of the scan.
+	Config config.FindConfig `json:"config"`
+}
+
+// line is a line of a checkpoint after the header, holding a single record.
+type line struct {
+	File   *fileLine          `json:"file,omitempty"`
+	Dir    *scanner.DirID     `json:"dir,omitempty"`
+	Link   *linkLine          `json:"link,omitempty"`
+	State  *scanner.WalkState `json:"state,omitempty"`
+	Walked *scanner.WalkStats `json:"walked,omitempty"`
+	Quick  *hashLine          `json:"quick,omitempty"`
+	Hash   *hashLine          `json:"hash,omitempty"`
+}
+
+// fileLine records a file found. Hashes known in advance, such as those of archive members, are hex-encoded.
+type fileLine struct {
+	Path      string       `json:"path"`
+	Size      int64        `json:"size"`
+	Target    string       `json:"target,omitempty"`
+	Symlinks  []string     `json:"symlinks,omitempty"`
+	Role      scanner.Role `json:"role,omitempty"`
+	ReadOnly  bool         `json:"read_only,omitempty"`
+	QuickHash string       `json:"quick_hash,omitempty"`
+	Hash      string       `json:"hash,omitempty"`
+	Checksum  string       `json:"checksum,omitempty"`
+	Dev       uint64       `json:"dev,omitempty"`
+	Ino       uint64       `json:"ino,omitempty"`
+	Root      int          `json:"root"`
+	Archive   string       `json:"archive,omitempty"`
+	ModTime   time.Time    `json:"mod_time"`
+}
+
+// linkLine records a symlink to a file found.
+type linkLine struct {
+	Path string `json:"path"`
+	Link string `json:"link"`
+}
+
+// hashLine records a hash computed, hex-encoded.
+type hashLine struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// Writer records the progress of a scan to a checkpoint. It records the walk as a [scanner.Checkpointer],
+// and the hashes computed as a finder.Recorder.
+//
+// The methods recording a single file or hash do not return errors: the first error met is returned
+// by the next snapshot, by the end of the walk, and by [Writer.Close].
+type Writer struct {
+	mu       sync.Mutex
+	f        *os.File
+	w        *bufio.Writer
+	enc      *json.Encoder
+	interval time.Duration
+	synced   time.Time
+	err      error
+}
+
+// Create creates the checkpoint at path, replacing any file there, and writes its header.
+// Snapshots are due, and hashes synced to disk, every interval.
+func Create(path string, header Header, interval time.Duration) (*Writer, error) {
+	//nolint:gosec
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating checkpoint: %w", err)
+	}
+
+	header.Version = Version
+	if header.Created.IsZero() {
+		header.Created = time.Now().UTC()
+	}
+	w := newWriter(f, interval)
+	if err := w.write(header); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := w.sync(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// newWriter returns a writer appending lines to f.
+func newWriter(f *os.File, interval time.Duration) *Writer {
+	bw := bufio.NewWriter(f)
+	w := &Writer{f: f, w: bw, enc: json.NewEncoder(bw), interval: interval, synced: time.Now()}
+	w.enc.SetEscapeHTML(false)
+	return w
+}
+
+// AddFile records a file found.
+func (w *Writer) AddFile(rec scanner.FileRecord) {
+	file := rec.File
+	l := &fileLine{
+		Path:     file.Path,
+		Size:     file.Size,
+		Target:   file.Target,
+		Symlinks: file.Symlinks,
+		Role:     file.Role,
+		ReadOnly: file.ReadOnly,
+		Hash:     hex.EncodeToString([]byte(file.Hash)),
+		Checksum: file.Checksum,
+		Dev:      file.Dev,
+		Ino:      file.Ino,
+		Root:     rec.Root,
+		Archive:  rec.Archive,
+		ModTime:  rec.ModTime,
+	}
+	if file.QuickHashed {
+		l.QuickHash = fmt.Sprintf("%016x", file.QuickHash)
+	}
+	w.record(line{File: l})
+}
+
+// AddDir records a directory walked.
+func (w *Writer) AddDir(dir scanner.DirID) {
+	w.record(line{Dir: &dir})
+}
+
+// AddSymlink records a symlink to a file found.
+func (w *Writer) AddSymlink(path, link string) {
+	w.record(line{Link: &linkLine{Path: path, Link: link}})
+}
+
+// Due reports whether a snapshot of the walk is due.
+func (w *Writer) Due() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Since(w.synced) >= w.interval
+}
+
+// Snapshot records the state of the walk, and syncs the checkpoint to disk.
+func (w *Writer) Snapshot(state scanner.WalkState) error {
+	return w.commit(line{State: &state})
+}
+
+// Walked records the end of the walk, and syncs the checkpoint to disk.
+func (w *Writer) Walked(stats scanner.WalkStats) error {
+	return w.commit(line{Walked: &stats})
+}
+
+// QuickHashed records the quick hash of a file.
+func (w *Writer) QuickHashed(file scanner.FileInfo, hash uint64) {
+	w.recordHash(line{Quick: &hashLine{Path: file.Path, Hash: fmt.Sprintf("%016x", hash)}})
+}
+
+// Hashed records the full hash of a file.
+func (w *Writer) Hashed(file scanner.FileInfo, hash string) {
+	w.recordHash(line{Hash: &hashLine{Path: file.Path, Hash: hex.EncodeToString([]byte(hash))}})
+}
+
+// Close syncs the checkpoint to disk and closes it.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.sync()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// record writes a line, keeping the first error met.
+func (w *Writer) record(l line) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.write(l)
+}
+
+// recordHash writes a line, and syncs the checkpoint to disk if due.
+func (w *Writer) recordHash(l line) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.write(l) == nil && time.Since(w.synced) >= w.interval {
+		_ = w.sync()
+	}
+}
+
+// commit writes a line and syncs the checkpoint to disk, returning the first error met.
+func (w *Writer) commit(l line) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.write(l); err != nil {
+		return err
+	}
+	return w.sync()
+}
+
+// write encodes v as a line. w.mu must be held.
+func (w *Writer) write(v any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.enc.Encode(v); err != nil {
+		w.err = fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return w.err
+}
+
+// sync flushes the buffered lines and syncs the checkpoint to disk. w.mu must be held.
+func (w *Writer) sync() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.err = fmt.Errorf("error writing checkpoint: %w", err)
+		return w.err
+	}
+	if err := w.f.Sync(); err != nil {
+		w.err = fmt.Errorf("error syncing checkpoint: %w", err)
+		return w.err
+	}
+	w.synced = time.Now()
+	return nil
+}
+
+// Checkpoint is a checkpoint read back to resume the scan it records.
+type Checkpoint struct {
+	Header Header
+
+	path   string
+	end    int64
+	walked bool
+	state  scanner.WalkState
+	dirs   []scanner.DirID
+	links  map[string][]string
+	quick  map[string]uint64
+	hashes map[string]string
+	err    error
+}
+
+// Load reads the checkpoint at path. The files found are left on disk, and read again by [Checkpoint.Resume],
+// while the hashes computed are held in memory.
+func Load(path string) (*Checkpoint, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening checkpoint: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	c := &Checkpoint{path: path, links: make(map[string][]string), quick: make(map[string]uint64),
+		hashes: make(map[string]string)}
+	if err := c.read(f); err != nil {
+		return nil, fmt.Errorf("error reading checkpoint %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// read reads the records of a checkpoint, up to its last snapshot or, once the walk is done, to its last complete line.
+func (c *Checkpoint) read(r io.Reader) error {
+	var (
+		offset int64
+		dirs   []scanner.DirID
+	)
+	for n, l := range lines(r) {
+		if l.err != nil {
+			return fmt.Errorf("line %d: %w", n, l.err)
+		}
+		offset += int64(len(l.data))
+
+		if n == 1 {
+			if err := json.Unmarshal(l.data, &c.Header); err != nil {
+				return fmt.Errorf("line 1: invalid header: %w", err)
+			}
+			if c.Header.Version != Version {
+				return fmt.Errorf("unsupported checkpoint version %d", c.Header.Version)
+			}
+			c.end = offset
+			continue
+		}
+
+		var rec line
+		if err := json.Unmarshal(l.data, &rec); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		switch {
+		case rec.Dir != nil:
+			dirs = append(dirs, *rec.Dir)
+		case rec.Link != nil:
+			c.links[rec.Link.Path] = append(c.links[rec.Link.Path], rec.Link.Link)
+		case rec.State != nil:
+			c.state, c.dirs, c.end = *rec.State, dirs, offset
+		case rec.Walked != nil:
+			c.walked, c.dirs = true, dirs
+			c.state = scanner.WalkState{Stats: *rec.Walked}
+		case rec.Quick != nil:
+			hash, err := strconv.ParseUint(rec.Quick.Hash, 16, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid quick hash of %s: %w", n, rec.Quick.Path, err)
+			}
+			c.quick[rec.Quick.Path] = hash
+		case rec.Hash != nil:
+			hash, err := hex.DecodeString(rec.Hash.Hash)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid hash of %s: %w", n, rec.Hash.Path, err)
+			}
+			c.hashes[rec.Hash.Path] = string(hash)
+		}
+		if c.walked {
+			c.end = offset
+		}
+	}
+	if c.Header.Version == 0 {
+		return errors.New("empty checkpoint")
+	}
+	if !c.walked {
+		// The symlinks to files are recorded once the walk is done.
+		clear(c.links)
+	}
+	return nil
+}
+
+// lineData is a complete line of a checkpoint, with its newline, or the error met reading it.
+type lineData struct {
+	data []byte
+	err  error
+}
+
+// lines returns an iterator over the complete lines of r, numbered from 1, skipping the last line
+// if it was cut short.
+func lines(r io.Reader) iter.Seq2[int, lineData] {
+	return func(yield func(int, lineData) bool) {
+		br := bufio.NewReaderSize(r, 64*1024)
+		for n := 1; ; n++ {
+			data, err := br.ReadBytes('\n')
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(n, lineData{err: err})
+				return
+			}
+			if len(bytes.TrimSpace(data)) == 0 {
+				continue
+			}
+			if !yield(n, lineData{data: data}) {
+				return
+			}
+		}
+	}
+}
+
+// Resume returns the progress of the walk recorded, to resume it with [scanner.WithResume].
+// The files are read again from the checkpoint as the walk is resumed, with the hashes recorded for them;
+// the errors met reading them are returned by [Checkpoint.Err].
+func (c *Checkpoint) Resume() *scanner.Resume {
+	return &scanner.Resume{Walked: c.walked, State: c.state, Files: c.files, Dirs: c.dirs}
+}
+
+// Hashed returns the number of files with hashes recorded.
+func (c *Checkpoint) Hashed() int {
+	return len(c.quick)
+}
+
+// Err returns the error met reading the files recorded, if any.
+func (c *Checkpoint) Err() error {
+	return c.err
+}
+
+// files yields the files recorded, with the symlinks and the hashes recorded for them.
+func (c *Checkpoint) files(yield func(scanner.FileRecord) bool) {
+	//nolint:gosec
+	f, err := os.Open(c.path)
+	if err != nil {
+		c.err = fmt.Errorf("error opening checkpoint: %w", err)
+		return
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	for n, l := range lines(io.NewSectionReader(f, 0, c.end)) {
+		if l.err != nil {
+			c.err = fmt.Errorf("error reading checkpoint %s: line %d: %w", c.path, n, l.err)
+			return
+		}
+		if n == 1 {
+			continue
+		}
+
+		var rec line
+		if err := json.Unmarshal(l.data, &rec); err != nil {
+			c.err = fmt.Errorf("error reading checkpoint %s: line %d: %w", c.path, n, err)
+			return
+		}
+		if rec.File == nil {
+			continue
+		}
+		file, err := c.fileInfo(rec.File)
+		if err != nil {
+			c.err = fmt.Errorf("error reading checkpoint %s: line %d: %w", c.path, n, err)
+			return
+		}
+		if !yield(scanner.FileRecord{File: file, Root: rec.File.Root, Archive: rec.File.Archive,
+			ModTime: rec.File.ModTime}) {
+			return
+		}
+	}
+}
+
+// fileInfo converts a file record into a file, with the symlinks and the hashes recorded for it.
+func (c *Checkpoint) fileInfo(l *fileLine) (scanner.FileInfo, error) {
+	file := scanner.FileInfo{
+		Path:     l.Path,
+		Size:     l.Size,
+		Target:   l.Target,
+		Symlinks: append(l.Symlinks, c.links[l.Path]...),
+		Role:     l.Role,
+		ReadOnly: l.ReadOnly,
+		Checksum: l.Checksum,
+		Dev:      l.Dev,
+		Ino:      l.Ino,
+	}
+
+	if l.QuickHash != "" {
+		quickHash, err := strconv.ParseUint(l.QuickHash, 16, 64)
+		if err != nil {
+			return scanner.FileInfo{}, fmt.Errorf("invalid quick hash of %s: %w", l.Path, err)
+		}
+		file.QuickHash, file.QuickHashed = quickHash, true
+	} else if quickHash, ok := c.quick[l.Path]; ok {
+		file.QuickHash, file.QuickHashed = quickHash, true
+	}
+
+	hash, err := hex.DecodeString(l.Hash)
+	if err != nil {
+		return scanner.FileInfo{}, fmt.Errorf("invalid hash of %s: %w", l.Path, err)
+	}
+	file.Hash = string(hash)
+	if file.Hash == "" {
+		file.Hash = c.hashes[l.Path]
+	}
+	return file, nil
+}
+
+// Append reopens the checkpoint to record the rest of the scan, dropping whatever follows its last snapshot,
+// or its last complete line once the walk is done.
+func (c *Checkpoint) Append(interval time.Duration) (*Writer, error) {
+	//nolint:gosec
+	f, err := os.OpenFile(c.path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error opening checkpoint: %w", err)
+	}
+	if err := f.Truncate(c.end); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("error truncating checkpoint: %w", err)
+	}
+	if _, err := f.Seek(c.end, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("error truncating checkpoint: %w", err)
+	}
+	return newWriter(f, interval), nil
+}
@@ -0,0 +1,236 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// errInterrupted stops a walk, as a signal would.
+var errInterrupted = errors.New("interrupted")
+
+// interrupter stops the walk it records after a number of snapshots.
+type interrupter struct {
+	*Writer
+	snapshots int
+}
+
+func (i *interrupter) Snapshot(state scanner.WalkState) error {
+	if err := i.Writer.Snapshot(state); err != nil {
+		return err
+	}
+	if i.snapshots--; i.snapshots == 0 {
+		return errInterrupted
+	}
+	return nil
+}
+
+// writeTree creates files of the given contents under a new directory and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return dir
+}
+
+// scan walks the roots and hashes the files found, recording the progress to w if not nil.
+func scan(t *testing.T, roots []scanner.Root, w *Writer, resume *scanner.Resume) (*model.DuplicateReport, *model.Stats) {
+	t.Helper()
+	var (
+		scanOpts []scanner.Option
+		hashOpts []finder.Option
+	)
+	if w != nil {
+		scanOpts = append(scanOpts, scanner.WithCheckpoint(w))
+		hashOpts = append(hashOpts, finder.WithRecorder(w))
+	}
+	if resume != nil {
+		scanOpts = append(scanOpts, scanner.WithResume(resume))
+	}
+
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupRootsBySize(context.Background(), roots, &filter.Config{}, s, scanOpts...)
+	if err != nil {
+		t.Fatalf("GroupRootsBySize() error = %v", err)
+	}
+	report, err := finder.FindDuplicatesByHash(context.Background(), sizeGroups, 2, s, hashOpts...)
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	return report, s
+}
+
+// groupsOf returns the files of each group of a report, sorted.
+func groupsOf(report *model.DuplicateReport) [][]string {
+	var groups [][]string
+	for _, group := range report.Groups {
+		groups = append(groups, slices.Sorted(slices.Values(group.Files)))
+	}
+	slices.SortFunc(groups, func(a, b []string) int { return slices.Compare(a, b) })
+	return groups
+}
+
+// TestResume verifies that a scan interrupted during its walk, with a line cut short, resumes
+// to the same report as an uninterrupted scan, and that a finished scan resumes without reading any file.
+func TestResume(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 12 {
+		files[fmt.Sprintf("dir%d/file%02d", i%3, i)] = fmt.Sprintf("content %d", i%4)
+	}
+	root := writeTree(t, files)
+	roots := []scanner.Root{{Path: root}}
+	want, wantStats := scan(t, roots, nil, nil)
+
+	path := filepath.Join(t.TempDir(), "scan.ckpt")
+	header := Header{Dir: root, Roots: []string{root}, Config: config.FindConfig{Workers: 2, OutputFormat: "json"}}
+	w, err := Create(path, header, 0)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, err = scanner.GroupRootsBySize(context.Background(), roots, &filter.Config{}, &model.Stats{},
+		scanner.WithCheckpoint(&interrupter{Writer: w, snapshots: 5}))
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("GroupRootsBySize() error = %v, want the walk interrupted", err)
+	}
+	w.AddFile(scanner.FileRecord{File: scanner.FileInfo{Path: filepath.Join(root, "after"), Size: 1}})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	_, _ = f.WriteString(`{"file":{"pa`)
+	_ = f.Close()
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(c.Header.Roots, header.Roots) || !reflect.DeepEqual(c.Header.Config, header.Config) {
+		t.Errorf("Header = %+v, want %+v", c.Header, header)
+	}
+	resume := c.Resume()
+	var found []string
+	for rec := range resume.Files {
+		found = append(found, rec.File.Path)
+	}
+	if resume.Walked || len(found) != 5 || resume.State.Cursor != found[4] {
+		t.Errorf("Resume() = walked %v, %d files to %q, want 5 files to the last snapshot",
+			resume.Walked, len(found), resume.State.Cursor)
+	}
+
+	w, err = c.Append(0)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	got, gotStats := scan(t, roots, w, c.Resume())
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if !reflect.DeepEqual(groupsOf(got), groupsOf(want)) || gotStats.TotalFiles != wantStats.TotalFiles {
+		t.Errorf("Resumed scan = %v of %d files, want %v of %d files",
+			groupsOf(got), gotStats.TotalFiles, groupsOf(want), wantStats.TotalFiles)
+	}
+
+	// The scan is done: resuming it reads the hashes recorded instead of the files.
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Hashed() != 12 {
+		t.Errorf("Hashed() = %d, want every file", c.Hashed())
+	}
+	for name := range files {
+		if err := os.Remove(filepath.Join(root, name)); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+	}
+	again, againStats := scan(t, roots, nil, c.Resume())
+	if !reflect.DeepEqual(groupsOf(again), groupsOf(want)) || againStats.ErrorCount != 0 {
+		t.Errorf("Resumed scan = %v, %d errors, want %v without reading the files",
+			groupsOf(again), againStats.ErrorCount, groupsOf(want))
+	}
+}
+
+// TestResume_Modified verifies that the hashes recorded for a file rewritten at the same size
+// are dropped when resuming, so that it is hashed again.
+func TestResume_Modified(t *testing.T) {
+	root := writeTree(t, map[string]string{"a": "same content", "b": "same content", "c": "other things"})
+	roots := []scanner.Root{{Path: root}}
+
+	path := filepath.Join(t.TempDir(), "scan.ckpt")
+	w, err := Create(path, Header{Dir: root, Roots: []string{root}}, 0)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if report, _ := scan(t, roots, w, nil); len(report.Groups) != 1 {
+		t.Fatalf("Scan found %d duplicate groups, want 1", len(report.Groups))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// b is rewritten at the same size, as c.
+	b := filepath.Join(root, "b")
+	if err := os.WriteFile(b, []byte("other things"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(b, later, later); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got, _ := scan(t, roots, nil, c.Resume())
+	want := [][]string{{b, filepath.Join(root, "c")}}
+	if !reflect.DeepEqual(groupsOf(got), want) {
+		t.Errorf("Resumed scan = %v, want %v", groupsOf(got), want)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":   "",
+		"version": `{"version":9,"roots":[]}` + "\n",
+		"record":  `{"version":1,"roots":[]}` + "\nnot json\n" + `{"walked":{}}` + "\n",
+		"hash":    `{"version":1,"roots":[]}` + "\n" + `{"walked":{}}` + "\n" + `{"hash":{"path":"/a","hash":"zz"}}` + "\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scan.ckpt")
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("Load() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+// Package checksum reads and writes checksum files in the formats of the GNU coreutils tools
+// (sha256sum and friends) and b3sum, such as the SHA256SUMS and B3SUMS files shipped with archives.
+//
+// Each line of a checksum file holds the hex digest of a file, followed by two spaces
+// (or a space and an asterisk for files hashed in binary mode) and the file name:
+//
+//	e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty.txt
+//
+// Lines in the BSD format written by 'sha256sum --tag' are also understood:
+//
+//	SHA256 (empty.txt) = e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+//
+// Names containing backslashes or newlines are escaped, and their lines start with a backslash.
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"lukechampine.com/blake3"
+)
+
+// Algorithm is a hash algorithm used in checksum files.
+type Algorithm struct {
+	// Name is the name of the algorithm, as accepted by [Lookup].
+	Name string
+
+	// Tag is the algorithm name used in BSD-style lines.
+	Tag string
+
+	// Size is the length of a digest in bytes.
+	Size int
+
+	// FileName is the conventional name of checksum files, such as "SHA256SUMS".
+	FileName string
+
+	// New returns a new hasher for the algorithm.
+	New func() hash.Hash
+}
+
+// algorithms lists the supported algorithms.
+var algorithms = []Algorithm{
+	{Name: "md5", Tag: "MD5", Size: md5.Size, FileName: "MD5SUMS", New: md5.New},
+	{Name: "sha1", Tag: "SHA1", Size: sha1.Size, FileName: "SHA1SUMS", New: sha1.New},
+	{Name: "sha224", Tag: "SHA224", Size: sha256.Size224, FileName: "SHA224SUMS", New: sha256.New224},
+	{Name: "sha256", Tag: "SHA256", Size: sha256.Size, FileName: "SHA256SUMS", New: sha256.New},
+	{Name: "sha384", Tag: "SHA384", Size: sha512.Size384, FileName: "SHA384SUMS", New: sha512.New384},
+	{Name: "sha512", Tag: "SHA512", Size: sha512.Size, FileName: "SHA512SUMS", New: sha512.New},
+	{Name: "blake3", Tag: "BLAKE3", Size: 32, FileName: "B3SUMS", New: func() hash.Hash { return blake3.New(32, nil) }},
+}
+
+// Names returns the names of the supported algorithms.
+func Names() []string {
+	names := make([]string, len(algorithms))
+	for i, alg := range algorithms {
+		names[i] = alg.Name
+	}
+	return names
+}
+
+// Lookup returns the algorithm with the given name or BSD tag, ignoring case.
+// "b3" is accepted for blake3, as in the name of b3sum.
+func Lookup(name string) (Algorithm, error) {
+	name = strings.ToLower(name)
+	if name == "b3" {
+		name = "blake3"
+	}
+	for _, alg := range algorithms {
+		if alg.Name == name {
+			return alg, nil
+		}
+	}
+	return Algorithm{}, fmt.Errorf("unknown checksum algorithm '%s' (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Detect guesses the algorithm of a checksum file from its name,
+// such as "SHA256SUMS", "B3SUMS", "image.iso.sha256", or "files.md5sum".
+func Detect(path string) (Algorithm, bool) {
+	base := strings.ToLower(filepath.Base(path))
+	if ext := filepath.Ext(base); ext != "" {
+		base = strings.TrimSuffix(ext[1:], "sum")
+	} else {
+		base = strings.TrimSuffix(strings.TrimSuffix(base, "sums"), "sum")
+	}
+	if alg, err := Lookup(base); err == nil {
+		return alg, true
+	}
+	return Algorithm{}, false
+}
+
+// Entry is a line of a checksum file.
+type Entry struct {
+	// Name is the file name, as written in the checksum file.
+	Name string
+
+	// Sum is the digest of the file.
+	Sum []byte
+}
+
+// List is the content of a checksum file.
+type List struct {
+	// Path is the path of the checksum file, if it was read with [Load].
+	Path string
+
+	// Algorithm is the hash algorithm of the digests.
+	Algorithm Algorithm
+
+	// Entries are the lines of the file, in order.
+	Entries []Entry
+
+	// Skipped counts the lines that could not be parsed, such as the lines of a PGP signature.
+	Skipped int
+}
+
+// Load reads a checksum file.
+// The algorithm is taken from a name prefix like "sha256:" in spec if there is one, and otherwise
+// from the BSD-style lines of the file or the file name.
+// Digest lengths tell the remaining algorithms apart, except sha256 and blake3.
+func Load(spec string) (*List, error) {
+	path := spec
+	var alg *Algorithm
+	if name, rest, ok := strings.Cut(spec, ":"); ok {
+		if a, err := Lookup(name); err == nil {
+			path, alg = rest, &a
+		}
+	}
+	if alg == nil {
+		if a, ok := Detect(path); ok {
+			alg = &a
+		}
+	}
+
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening checksum file: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	list, err := Parse(f, alg)
+	if err != nil {
+		return nil, fmt.Errorf("error reading checksum file %s: %w", path, err)
+	}
+	if list.Path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Parse reads checksum lines with the digests of alg. If alg is nil, the algorithm is taken from
+// the BSD-style lines, or from the length of the digests when it is unambiguous.
+// Blank lines, comments, and lines that cannot be parsed are skipped; an error is returned if no line
+// can be parsed, or if lines use different algorithms.
+func Parse(r io.Reader, alg *Algorithm) (*List, error) {
+	list := &List{}
+	var untagged []Entry
+	tagged := alg != nil
+	if tagged {
+		list.Algorithm = *alg
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tag, entry, ok := parseLine(line)
+		if !ok {
+			list.Skipped++
+			continue
+		}
+
+		switch {
+		case tag != "":
+			a, err := Lookup(tag)
+			if err != nil {
+				list.Skipped++
+				continue
+			}
+			if !tagged {
+				list.Algorithm, tagged = a, true
+			} else if a.Name != list.Algorithm.Name {
+				return nil, fmt.Errorf("mixed checksum algorithms %s and %s", list.Algorithm.Name, a.Name)
+			}
+			list.Entries = append(list.Entries, entry)
+		case tagged:
+			list.Entries = append(list.Entries, entry)
+		default:
+			untagged = append(untagged, entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(untagged) > 0 {
+		if !tagged {
+			a, err := bySize(len(untagged[0].Sum))
+			if err != nil {
+				return nil, err
+			}
+			list.Algorithm = a
+		}
+		list.Entries = append(list.Entries, untagged...)
+	}
+
+	if len(list.Entries) == 0 {
+		return nil, errors.New("no checksum lines found")
+	}
+
+	for _, entry := range list.Entries {
+		if len(entry.Sum) != list.Algorithm.Size {
+			return nil, fmt.Errorf("invalid %s digest for %s", list.Algorithm.Name, entry.Name)
+		}
+	}
+
+	return list, nil
+}
+
+// bySize returns the only algorithm with digests of size bytes.
+func bySize(size int) (Algorithm, error) {
+	var matches []Algorithm
+	for _, alg := range algorithms {
+		if alg.Size == size {
+			matches = append(matches, alg)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Algorithm{}, fmt.Errorf("no checksum algorithm has %d-byte digests", size)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, alg := range matches {
+			names[i] = alg.Name
+		}
+		return Algorithm{}, fmt.Errorf("digests could be %s; prefix the file name with the algorithm, as in %s:SUMS",
+			strings.Join(names, " or "), names[0])
+	}
+}
+
+// parseLine parses a GNU or BSD-style checksum line. The tag is empty for GNU-style lines.
+func parseLine(line string) (tag string, entry Entry, ok bool) {
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+
+	var name, digest string
+	if open := strings.Index(line, " ("); open > 0 && !strings.Contains(line[:open], " ") && !isHex(line[:open]) {
+		// BSD style: TAG (name) = digest
+		closing := strings.LastIndex(line, ") = ")
+		if closing < open {
+			return "", Entry{}, false
+		}
+		tag, name, digest = line[:open], line[open+2:closing], line[closing+4:]
+	} else {
+		// GNU style: digest, then two spaces or " *", then the name
+		var found bool
+		digest, name, found = strings.Cut(line, " ")
+		if !found || name == "" || (name[0] != ' ' && name[0] != '*') {
+			return "", Entry{}, false
+		}
+		name = name[1:]
+	}
+
+	if escaped {
+		var err error
+		if name, err = unescape(name); err != nil {
+			return "", Entry{}, false
+		}
+	}
+
+	sum, err := hex.DecodeString(digest)
+	if err != nil || len(sum) == 0 || name == "" {
+		return "", Entry{}, false
+	}
+
+	return tag, Entry{Name: name, Sum: sum}, true
+}
+
+// isHex reports whether s is a non-empty string of hex digits.
+func isHex(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789abcdefABCDEF") == ""
+}
+
+// unescape decodes the escapes of a file name on an escaped line.
+func unescape(name string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' {
+			b.WriteByte(name[i])
+			continue
+		}
+		i++
+		if i == len(name) {
+			return "", errors.New("trailing backslash")
+		}
+		switch name[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", fmt.Errorf("unknown escape \\%c", name[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// nameEscaper escapes the characters that cannot appear verbatim in a file name on a checksum line.
+var nameEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// Writer writes checksum lines in the GNU format.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a writer of checksum lines to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes the checksum line of a file.
+func (w *Writer) Write(name string, sum []byte) error {
+	var line bytes.Buffer
+	if escaped := nameEscaper.Replace(name); escaped != name {
+		line.WriteByte('\\')
+		name = escaped
+	}
+	line.WriteString(hex.EncodeToString(sum))
+	line.WriteString("  ")
+	line.WriteString(name)
+	line.WriteByte('\n')
+	_, err := w.w.Write(line.Bytes())
+	return err
+}
+
+// Flush writes any buffered lines to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Resolve returns the path of a listed file.
+// Relative names are relative to the directory of the checksum file, as for 'sha256sum --check'.
+func (l *List) Resolve(name string) string {
+	if filepath.IsAbs(name) || l.Path == "" {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(filepath.Dir(l.Path), name)
+}
+
+// Index maps the digests of a list to the resolved paths of the files with them.
+func (l *List) Index() map[string][]string {
+	index := make(map[string][]string, len(l.Entries))
+	for _, entry := range l.Entries {
+		key, path := string(entry.Sum), l.Resolve(entry.Name)
+		if !slices.Contains(index[key], path) {
+			index[key] = append(index[key], path)
+		}
+	}
+	return index
+}
@@ -0,0 +1,225 @@
+package checksum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	emptyBLAKE3 = "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"
+	emptyMD5    = "d41d8cd98f00b204e9800998ecf8427e"
+)
+
+// TestAlgorithms verifies the digests of the supported algorithms against known values.
+func TestAlgorithms(t *testing.T) {
+	for name, want := range map[string]string{"sha256": emptySHA256, "b3": emptyBLAKE3, "MD5": emptyMD5} {
+		alg, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%q) error = %v", name, err)
+		}
+		if got := hex.EncodeToString(alg.New().Sum(nil)); got != want || len(got) != 2*alg.Size {
+			t.Errorf("%s digest of nothing = %s, want %s", alg.Name, got, want)
+		}
+	}
+	if _, err := Lookup("crc32"); err == nil {
+		t.Error("Lookup(\"crc32\") should return an error")
+	}
+}
+
+// TestDetect verifies that algorithms are detected from the names of checksum files.
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"SHA256SUMS":               "sha256",
+		"/srv/iso/SHA512SUMS":      "sha512",
+		"B3SUMS":                   "blake3",
+		"MD5SUM":                   "md5",
+		"image.iso.sha256":         "sha256",
+		"files.md5sum":             "md5",
+		"CHECKSUM":                 "",
+		"sums.txt":                 "",
+		"release-1.0.tar.gz.sha1":  "sha1",
+		"release-1.0.tar.gz.b3sum": "blake3",
+	}
+	for path, want := range tests {
+		alg, ok := Detect(path)
+		if ok != (want != "") || alg.Name != want {
+			t.Errorf("Detect(%q) = %q, %v, want %q", path, alg.Name, ok, want)
+		}
+	}
+}
+
+// TestParse verifies the parsing of GNU and BSD-style lines.
+func TestParse(t *testing.T) {
+	sha256Alg, _ := Lookup("sha256")
+	tests := []struct {
+		name    string
+		input   string
+		alg     *Algorithm
+		want    string
+		names   []string
+		skipped int
+		wantErr bool
+	}{
+		{
+			name:  "text and binary mode",
+			input: emptySHA256 + "  a.txt\n" + strings.ToUpper(emptySHA256) + " *dir/b c.bin\r\n",
+			alg:   &sha256Alg,
+			want:  "sha256",
+			names: []string{"a.txt", "dir/b c.bin"},
+		},
+		{
+			name:  "escaped names",
+			input: `\` + emptySHA256 + `  back\\slash` + "\n" + `\` + emptySHA256 + `  new\nline` + "\n",
+			alg:   &sha256Alg,
+			want:  "sha256",
+			names: []string{`back\slash`, "new\nline"},
+		},
+		{
+			name:  "name starting with a parenthesis",
+			input: emptySHA256 + "  (1) = x\n",
+			alg:   &sha256Alg,
+			want:  "sha256",
+			names: []string{"(1) = x"},
+		},
+		{
+			name:  "BSD style",
+			input: "SHA256 (a.txt) = " + emptySHA256 + "\nSHA256 (b (1).txt) = " + emptySHA256 + "\n",
+			want:  "sha256",
+			names: []string{"a.txt", "b (1).txt"},
+		},
+		{
+			name:    "signed file with comments",
+			input:   "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA256\n\n# Fedora\nSHA256 (a.iso) = " + emptySHA256 + "\n-----BEGIN PGP SIGNATURE-----\n",
+			want:    "sha256",
+			names:   []string{"a.iso"},
+			skipped: 3,
+		},
+		{
+			name:  "unambiguous digest length",
+			input: emptyMD5 + "  a.txt\n",
+			want:  "md5",
+			names: []string{"a.txt"},
+		},
+		{
+			name:    "ambiguous digest length",
+			input:   emptySHA256 + "  a.txt\n",
+			wantErr: true,
+		},
+		{
+			name:    "digest of the wrong length",
+			input:   emptyMD5 + "  a.txt\n",
+			alg:     &sha256Alg,
+			wantErr: true,
+		},
+		{
+			name:    "mixed algorithms",
+			input:   "SHA256 (a) = " + emptySHA256 + "\nMD5 (b) = " + emptyMD5 + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "no checksum lines",
+			input:   "# nothing here\n\n",
+			alg:     &sha256Alg,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Parse(strings.NewReader(tt.input), tt.alg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse() should return an error, got %+v", list)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if list.Algorithm.Name != tt.want {
+				t.Errorf("Parse() algorithm = %s, want %s", list.Algorithm.Name, tt.want)
+			}
+			var names []string
+			for _, entry := range list.Entries {
+				names = append(names, entry.Name)
+			}
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("Parse() names = %q, want %q", names, tt.names)
+			}
+			if list.Skipped != tt.skipped {
+				t.Errorf("Parse() skipped %d lines, want %d", list.Skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+// TestWriter verifies that written lines are read back unchanged, in the format of sha256sum.
+func TestWriter(t *testing.T) {
+	sum, _ := hex.DecodeString(emptySHA256)
+	names := []string{"a.txt", "dir/with space", `back\slash`, "new\nline"}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, name := range names {
+		if err := w.Write(name, sum); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := emptySHA256 + "  a.txt\n" +
+		emptySHA256 + "  dir/with space\n" +
+		`\` + emptySHA256 + `  back\\slash` + "\n" +
+		`\` + emptySHA256 + `  new\nline` + "\n"
+	if buf.String() != want {
+		t.Errorf("Writer output = %q, want %q", buf.String(), want)
+	}
+
+	alg, _ := Lookup("sha256")
+	list, err := Parse(&buf, &alg)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	for i, entry := range list.Entries {
+		if entry.Name != names[i] || !bytes.Equal(entry.Sum, sum) {
+			t.Errorf("Parse() entry %d = %q %x, want %q %x", i, entry.Name, entry.Sum, names[i], sum)
+		}
+	}
+}
+
+// TestLoad verifies the algorithm prefix and that listed names resolve relative to the checksum file.
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "CHECKSUMS")
+	abs := filepath.Join(dir, "other", "b.txt")
+	data := emptySHA256 + "  sub/a.txt\n" + emptySHA256 + "  " + abs + "\n" + emptySHA256 + "  sub/a.txt\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write checksum file: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Load() of a sha256 or blake3 file without an algorithm should return an error")
+	}
+
+	list, err := Load("blake3:" + path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if list.Algorithm.Name != "blake3" || list.Path != path {
+		t.Errorf("Load() = %s list at %s, want blake3 list at %s", list.Algorithm.Name, list.Path, path)
+	}
+
+	sum, _ := hex.DecodeString(emptySHA256)
+	want := []string{filepath.Join(dir, "sub", "a.txt"), abs}
+	if got := list.Index()[string(sum)]; !slices.Equal(got, want) {
+		t.Errorf("Index() = %v, want %v", got, want)
+	}
+}
@@ -42,6 +42,18 @@ type Config struct {
 
 	// Preset holds the 'preset' command configuration.
 	Preset PresetConfig `toml:"preset" yaml:"preset" json:"preset"`
+
+	// Manifest holds the 'manifest' command configuration.
+	Manifest ManifestConfig `toml:"manifest" yaml:"manifest" json:"manifest"`
+
+	// Hash holds the 'hash' command configuration.
+	Hash HashConfig `toml:"hash" yaml:"hash" json:"hash"`
+
+	// Watch holds the 'watch' command configuration.
+	Watch WatchConfig `toml:"watch" yaml:"watch" json:"watch"`
+
+	// Serve holds the 'serve' command configuration.
+	Serve ServeConfig `toml:"serve" yaml:"serve" json:"serve"`
 }
 
 // LogConfig holds the logging configuration.
@@ -74,10 +86,81 @@ type FindConfig struct {
 	MinSize string `toml:"min_size" yaml:"min_size" json:"min_size"`
 	// MaxSize sets the maximum file size to consider (e.g., "100MB", "1GB").
 	MaxSize string `toml:"max_size" yaml:"max_size" json:"max_size"`
+	// Owner restricts the search to files owned by this user name or ID.
+	Owner string `toml:"owner" yaml:"owner" json:"owner"`
+	// Group restricts the search to files owned by this group name or ID.
+	Group string `toml:"group" yaml:"group" json:"group"`
+	// UID restricts the search to files owned by this numeric user ID.
+	UID string `toml:"uid" yaml:"uid" json:"uid"`
+	// GID restricts the search to files owned by this numeric group ID.
+	GID string `toml:"gid" yaml:"gid" json:"gid"`
+	// WritableOnly excludes files the current user cannot remove.
+	WritableOnly bool `toml:"writable_only" yaml:"writable_only" json:"writable_only"`
+	// FollowSymlinks enables following symlinks to files and directories.
+	FollowSymlinks bool `toml:"follow_symlinks" yaml:"follow_symlinks" json:"follow_symlinks"`
+	// ReportSymlinks enables flagging symlinks whose targets duplicate other files.
+	ReportSymlinks bool `toml:"report_symlinks" yaml:"report_symlinks" json:"report_symlinks"`
+	// OneFileSystem restricts the search to the filesystem of each directory being scanned.
+	OneFileSystem bool `toml:"one_file_system" yaml:"one_file_system" json:"one_file_system"`
+	// FilesFrom names a file listing the paths to scan ("-" for standard input).
+	// Entries are separated by NUL bytes if the list contains any, and by newlines otherwise.
+	FilesFrom string `toml:"files_from" yaml:"files_from" json:"files_from"`
+	// ExcludeFSType holds the filesystem types to skip (e.g., "nfs,proc,tmpfs").
+	// This is a comma-separated list.
+	ExcludeFSType string `toml:"exclude_fstype" yaml:"exclude_fstype" json:"exclude_fstype"`
+	// ScanArchives enables scanning the members of zip and tar archives as read-only files.
+	ScanArchives bool `toml:"scan_archives" yaml:"scan_archives" json:"scan_archives"`
+	// Reference lists directories holding canonical copies, which are never suggested for removal.
+	// When set, only groups with both reference and non-reference files are reported.
+	Reference []string `toml:"reference" yaml:"reference" json:"reference"`
+	// Against lists hash manifests of files on other machines, which are matched as references.
+	Against []string `toml:"against" yaml:"against" json:"against"`
+	// KnownHashes lists checksum files (like SHA256SUMS) whose files are matched as references.
+	// An algorithm prefix like "sha256:" may precede each path.
+	KnownHashes []string `toml:"known_hashes" yaml:"known_hashes" json:"known_hashes"`
 	// OutputFormat sets the output format (e.g., "pretty", "json", "yaml").
 	OutputFormat string `toml:"output_format" yaml:"output_format" json:"output_format"`
 	// OutputFile sets the file to write output to (default is stdout).
 	OutputFile string `toml:"output_file" yaml:"output_file" json:"output_file"`
+	// FailOnError makes the command fail once the report is written if any file could not be read.
+	FailOnError bool `toml:"fail_on_error" yaml:"fail_on_error" json:"fail_on_error"`
+	// FailIfWastedOver sets the space wasted by duplicates (e.g., "100MB") over which the scan counts as having
+	// found duplicates. Empty leaves it unchecked.
+	FailIfWastedOver string `toml:"fail_if_wasted_over" yaml:"fail_if_wasted_over" json:"fail_if_wasted_over"`
+	// FailIfGroupsOver sets the number of duplicate groups over which the scan counts as having found duplicates
+	// (0 to leave it unchecked).
+	FailIfGroupsOver int `toml:"fail_if_groups_over" yaml:"fail_if_groups_over" json:"fail_if_groups_over"`
+	// MetricsListen sets the address to serve Prometheus metrics on while scanning.
+	MetricsListen string `toml:"metrics_listen" yaml:"metrics_listen" json:"metrics_listen"`
+	// MetricsTextfile sets the file to write Prometheus metrics to once the scan is done.
+	MetricsTextfile string `toml:"metrics_textfile" yaml:"metrics_textfile" json:"metrics_textfile"`
+	// ProgressFD sets a file descriptor to write JSON progress events to (0 to disable).
+	ProgressFD int `toml:"progress_fd" yaml:"progress_fd" json:"progress_fd"`
+	// NoProgress disables the progress bar shown on terminals.
+	NoProgress bool `toml:"no_progress" yaml:"no_progress" json:"no_progress"`
+	// IOPerDevice sets the number of files read at the same time from each kind of device
+	// (e.g., "hdd=1,ssd=8"). Files are read from all devices at once when empty.
+	IOPerDevice string `toml:"io_per_device" yaml:"io_per_device" json:"io_per_device"`
+	// MaxReadRate limits the rate at which files are read for hashing (e.g., "50MB/s"). Empty means no limit.
+	MaxReadRate string `toml:"max_read_rate" yaml:"max_read_rate" json:"max_read_rate"`
+	// MaxOpenFiles limits the number of files open at the same time for hashing (0 for no limit).
+	MaxOpenFiles int `toml:"max_open_files" yaml:"max_open_files" json:"max_open_files"`
+	// IdleIO sets the idle I/O scheduling class, so that the disks are only read when no one else needs them (Linux only).
+	IdleIO bool `toml:"idle_io" yaml:"idle_io" json:"idle_io"`
+	// Nice sets the nice level of the process (Linux only, 0 to leave it as is).
+	Nice int `toml:"nice" yaml:"nice" json:"nice"`
+	// Samples sets the extra sample points hashed before reading whole files (e.g., "middle,random=4").
+	Samples string `toml:"samples" yaml:"samples" json:"samples"`
+	// ChunkedCompare sets the size from which files are compared block by block instead of hashed in full
+	// (e.g., "64MB"). Empty turns it off.
+	ChunkedCompare string `toml:"chunked_compare" yaml:"chunked_compare" json:"chunked_compare"`
+	// MaxMemory sets the memory ceiling of low-memory mode (e.g., "2GB"), in which the files found are spilled
+	// to disk and hashed a batch of sizes at a time. Empty keeps every file in memory.
+	MaxMemory string `toml:"max_memory" yaml:"max_memory" json:"max_memory"`
+	// SpillDir sets the directory of the files spilled in low-memory mode (default is the temporary directory).
+	SpillDir string `toml:"spill_dir" yaml:"spill_dir" json:"spill_dir"`
+	// Checkpoint sets the file the progress of the scan is recorded to, so that an interrupted scan can be resumed.
+	Checkpoint string `toml:"checkpoint" yaml:"checkpoint" json:"checkpoint"`
 	// Workers sets the number of concurrent workers for file processing.
 	// Default is the number of CPU cores.
 	Workers int `toml:"workers" yaml:"workers" json:"workers"`
@@ -104,6 +187,180 @@ type PresetConfig struct {
 
 	// OutputFile sets the file to write output to (default is stdout).
 	OutputFile string `toml:"output_file" yaml:"output_file" json:"output_file"`
+
+	// FailOnError makes the command fail once the report is written if any file could not be read.
+	FailOnError bool `toml:"fail_on_error" yaml:"fail_on_error" json:"fail_on_error"`
+
+	// FailIfWastedOver sets the space wasted by duplicates (e.g., "100MB") over which the scan counts as having
+	// found duplicates. Empty leaves it unchecked.
+	FailIfWastedOver string `toml:"fail_if_wasted_over" yaml:"fail_if_wasted_over" json:"fail_if_wasted_over"`
+
+	// FailIfGroupsOver sets the number of duplicate groups over which the scan counts as having found duplicates
+	// (0 to leave it unchecked).
+	FailIfGroupsOver int `toml:"fail_if_groups_over" yaml:"fail_if_groups_over" json:"fail_if_groups_over"`
+
+	// MetricsListen sets the address to serve Prometheus metrics on while scanning.
+	MetricsListen string `toml:"metrics_listen" yaml:"metrics_listen" json:"metrics_listen"`
+
+	// MetricsTextfile sets the file to write Prometheus metrics to once the scan is done.
+	MetricsTextfile string `toml:"metrics_textfile" yaml:"metrics_textfile" json:"metrics_textfile"`
+
+	// ProgressFD sets a file descriptor to write JSON progress events to (0 to disable).
+	ProgressFD int `toml:"progress_fd" yaml:"progress_fd" json:"progress_fd"`
+
+	// NoProgress disables the progress bar shown on terminals.
+	NoProgress bool `toml:"no_progress" yaml:"no_progress" json:"no_progress"`
+
+	// IOPerDevice sets the number of files read at the same time from each kind of device
+	// (e.g., "hdd=1,ssd=8"). Files are read from all devices at once when empty.
+	IOPerDevice string `toml:"io_per_device" yaml:"io_per_device" json:"io_per_device"`
+
+	// MaxReadRate limits the rate at which files are read for hashing (e.g., "50MB/s"). Empty means no limit.
+	MaxReadRate string `toml:"max_read_rate" yaml:"max_read_rate" json:"max_read_rate"`
+
+	// MaxOpenFiles limits the number of files open at the same time for hashing (0 for no limit).
+	MaxOpenFiles int `toml:"max_open_files" yaml:"max_open_files" json:"max_open_files"`
+
+	// IdleIO sets the idle I/O scheduling class, so that the disks are only read when no one else needs them (Linux only).
+	IdleIO bool `toml:"idle_io" yaml:"idle_io" json:"idle_io"`
+
+	// Nice sets the nice level of the process (Linux only, 0 to leave it as is).
+	Nice int `toml:"nice" yaml:"nice" json:"nice"`
+
+	// Samples sets the extra sample points hashed before reading whole files (e.g., "middle,random=4").
+	Samples string `toml:"samples" yaml:"samples" json:"samples"`
+
+	// ChunkedCompare sets the size from which files are compared block by block instead of hashed in full
+	// (e.g., "64MB"). Empty turns it off.
+	ChunkedCompare string `toml:"chunked_compare" yaml:"chunked_compare" json:"chunked_compare"`
+
+	// MaxMemory sets the memory ceiling of low-memory mode (e.g., "2GB"), in which the files found are spilled
+	// to disk and hashed a batch of sizes at a time. Empty keeps every file in memory.
+	MaxMemory string `toml:"max_memory" yaml:"max_memory" json:"max_memory"`
+
+	// SpillDir sets the directory of the files spilled in low-memory mode (default is the temporary directory).
+	SpillDir string `toml:"spill_dir" yaml:"spill_dir" json:"spill_dir"`
+}
+
+// ManifestConfig holds configuration for the 'manifest' command.
+type ManifestConfig struct {
+	// ExcludeDirs holds the glob patterns to exclude directories from the manifest.
+	// This is a comma-separated list of patterns, which should be escaped as needed.
+	ExcludeDirs string `toml:"exclude_dirs" yaml:"exclude_dirs" json:"exclude_dirs"`
+
+	// ExcludeFiles holds the glob patterns to exclude files from the manifest.
+	// This is a comma-separated list of patterns, which should be escaped as needed.
+	ExcludeFiles string `toml:"exclude_files" yaml:"exclude_files" json:"exclude_files"`
+
+	// MinSize sets the minimum file size to record (e.g., "10KB", "5MB").
+	MinSize string `toml:"min_size" yaml:"min_size" json:"min_size"`
+
+	// MaxSize sets the maximum file size to record (e.g., "100MB", "1GB").
+	MaxSize string `toml:"max_size" yaml:"max_size" json:"max_size"`
+
+	// Workers sets the number of concurrent workers for file processing.
+	// Default is the number of CPU cores.
+	Workers int `toml:"workers" yaml:"workers" json:"workers"`
+
+	// Verbose enables verbose output.
+	Verbose bool `toml:"verbose" yaml:"verbose" json:"verbose"`
+
+	// OutputFile sets the file to write the manifest to (default is stdout).
+	OutputFile string `toml:"output_file" yaml:"output_file" json:"output_file"`
+}
+
+// HashConfig holds configuration for the 'hash' command.
+type HashConfig struct {
+	// ExcludeDirs holds the glob patterns to exclude directories from hashing.
+	// This is a comma-separated list of patterns, which should be escaped as needed.
+	ExcludeDirs string `toml:"exclude_dirs" yaml:"exclude_dirs" json:"exclude_dirs"`
+
+	// ExcludeFiles holds the glob patterns to exclude files from hashing.
+	// This is a comma-separated list of patterns, which should be escaped as needed.
+	ExcludeFiles string `toml:"exclude_files" yaml:"exclude_files" json:"exclude_files"`
+
+	// MinSize sets the minimum file size to hash (e.g., "10KB", "5MB").
+	MinSize string `toml:"min_size" yaml:"min_size" json:"min_size"`
+
+	// MaxSize sets the maximum file size to hash (e.g., "100MB", "1GB").
+	MaxSize string `toml:"max_size" yaml:"max_size" json:"max_size"`
+
+	// Algorithm sets the hash algorithm (e.g., "sha256", "blake3", "md5").
+	Algorithm string `toml:"algorithm" yaml:"algorithm" json:"algorithm"`
+
+	// Workers sets the number of concurrent workers for file processing.
+	// Default is the number of CPU cores.
+	Workers int `toml:"workers" yaml:"workers" json:"workers"`
+
+	// Verbose enables verbose output.
+	Verbose bool `toml:"verbose" yaml:"verbose" json:"verbose"`
+
+	// OutputFile sets the file to write the checksums to (default is stdout).
+	OutputFile string `toml:"output_file" yaml:"output_file" json:"output_file"`
+}
+
+// WatchConfig holds configuration for the 'watch' command.
+type WatchConfig struct {
+	// ExcludeDirs holds the glob patterns to exclude directories from watching.
+	// This is a comma-separated list of patterns, which should be escaped as needed.
+	ExcludeDirs string `toml:"exclude_dirs" yaml:"exclude_dirs" json:"exclude_dirs"`
+
+	// ExcludeFiles holds the glob patterns to exclude files from watching.
+	// This is a comma-separated list of patterns, which should be escaped as needed.
+	ExcludeFiles string `toml:"exclude_files" yaml:"exclude_files" json:"exclude_files"`
+
+	// MinSize sets the minimum file size to consider (e.g., "10KB", "5MB").
+	MinSize string `toml:"min_size" yaml:"min_size" json:"min_size"`
+
+	// MaxSize sets the maximum file size to consider (e.g., "100MB", "1GB").
+	MaxSize string `toml:"max_size" yaml:"max_size" json:"max_size"`
+
+	// Owner restricts watching to files owned by this user name or ID.
+	Owner string `toml:"owner" yaml:"owner" json:"owner"`
+
+	// Group restricts watching to files owned by this group name or ID.
+	Group string `toml:"group" yaml:"group" json:"group"`
+
+	// UID restricts watching to files owned by this numeric user ID.
+	UID string `toml:"uid" yaml:"uid" json:"uid"`
+
+	// GID restricts watching to files owned by this numeric group ID.
+	GID string `toml:"gid" yaml:"gid" json:"gid"`
+
+	// WritableOnly excludes files the current user cannot remove.
+	WritableOnly bool `toml:"writable_only" yaml:"writable_only" json:"writable_only"`
+
+	// Workers sets the number of concurrent workers for the initial scan.
+	// Default is the number of CPU cores.
+	Workers int `toml:"workers" yaml:"workers" json:"workers"`
+
+	// Verbose enables verbose output.
+	Verbose bool `toml:"verbose" yaml:"verbose" json:"verbose"`
+
+	// Events sets how events are emitted: "log" for log records, or "ndjson" for JSON lines.
+	Events string `toml:"events" yaml:"events" json:"events"`
+
+	// OutputFile sets the file to write NDJSON events to (default is stdout).
+	OutputFile string `toml:"output_file" yaml:"output_file" json:"output_file"`
+}
+
+// ServeConfig holds configuration for the 'serve' command.
+type ServeConfig struct {
+	// Listen sets the address the API listens on (e.g., "localhost:8080", ":8080").
+	Listen string `toml:"listen" yaml:"listen" json:"listen"`
+
+	// Jobs sets the number of scans that run at the same time.
+	Jobs int `toml:"jobs" yaml:"jobs" json:"jobs"`
+
+	// QueueSize sets the number of scans that can wait for a free worker.
+	QueueSize int `toml:"queue_size" yaml:"queue_size" json:"queue_size"`
+
+	// DataDir sets the directory where jobs and reports are persisted.
+	// Default is a "doppel/jobs" directory in the user cache directory.
+	DataDir string `toml:"data_dir" yaml:"data_dir" json:"data_dir"`
+
+	// MaxHistory sets the number of finished jobs to keep (0 keeps every job).
+	MaxHistory int `toml:"max_history" yaml:"max_history" json:"max_history"`
 }
 
 // Provider defines the interface for configuration providers.
@@ -144,6 +401,39 @@ func defaultPresetConfig() PresetConfig {
 	}
 }
 
+// defaultManifestConfig returns a ManifestConfig instance with default settings.
+func defaultManifestConfig() ManifestConfig {
+	return ManifestConfig{
+		Workers: runtime.NumCPU(),
+	}
+}
+
+// defaultHashConfig returns a HashConfig instance with default settings.
+func defaultHashConfig() HashConfig {
+	return HashConfig{
+		Algorithm: "sha256",
+		Workers:   runtime.NumCPU(),
+	}
+}
+
+// defaultWatchConfig returns a WatchConfig instance with default settings.
+func defaultWatchConfig() WatchConfig {
+	return WatchConfig{
+		Workers: runtime.NumCPU(),
+		Events:  "log",
+	}
+}
+
+// defaultServeConfig returns a ServeConfig instance with default settings.
+func defaultServeConfig() ServeConfig {
+	return ServeConfig{
+		Listen:     "localhost:8080",
+		Jobs:       1,
+		QueueSize:  16,
+		MaxHistory: 100,
+	}
+}
+
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
@@ -152,8 +442,12 @@ func DefaultConfig() *Config {
 			Format: pretty,
 			Output: "stdout",
 		},
-		Find:   defaultFindConfig(),
-		Preset: defaultPresetConfig(),
+		Find:     defaultFindConfig(),
+		Preset:   defaultPresetConfig(),
+		Manifest: defaultManifestConfig(),
+		Hash:     defaultHashConfig(),
+		Watch:    defaultWatchConfig(),
+		Serve:    defaultServeConfig(),
 	}
 }
 
@@ -90,6 +90,23 @@ func TestDefaultConfig(t *testing.T) {
 					Workers:      runtime.NumCPU(),
 					OutputFormat: "pretty",
 				},
+				Manifest: ManifestConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Hash: HashConfig{
+					Algorithm: "sha256",
+					Workers:   runtime.NumCPU(),
+				},
+				Watch: WatchConfig{
+					Workers: runtime.NumCPU(),
+					Events:  "log",
+				},
+				Serve: ServeConfig{
+					Listen:     "localhost:8080",
+					Jobs:       1,
+					QueueSize:  16,
+					MaxHistory: 100,
+				},
 			},
 		},
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -41,9 +42,13 @@ func (p *EnvProvider) Load(ctx context.Context) (*Config, error) {
 	}
 
 	config := &Config{
-		Log:    LogConfig{},
-		Find:   FindConfig{},
-		Preset: PresetConfig{},
+		Log:      LogConfig{},
+		Find:     FindConfig{},
+		Preset:   PresetConfig{},
+		Manifest: ManifestConfig{},
+		Hash:     HashConfig{},
+		Watch:    WatchConfig{},
+		Serve:    ServeConfig{},
 	}
 
 	// Load log configuration
@@ -60,9 +65,40 @@ func (p *EnvProvider) Load(ctx context.Context) (*Config, error) {
 	p.loadStringFromEnv("FIND_EXCLUDE_FILE_REGEX", &config.Find.ExcludeFileRegex)
 	p.loadStringFromEnv("FIND_MIN_SIZE", &config.Find.MinSize)
 	p.loadStringFromEnv("FIND_MAX_SIZE", &config.Find.MaxSize)
+	p.loadStringFromEnv("FIND_OWNER", &config.Find.Owner)
+	p.loadStringFromEnv("FIND_GROUP", &config.Find.Group)
+	p.loadStringFromEnv("FIND_UID", &config.Find.UID)
+	p.loadStringFromEnv("FIND_GID", &config.Find.GID)
+	p.loadBoolFromEnv("FIND_WRITABLE_ONLY", &config.Find.WritableOnly)
+	p.loadBoolFromEnv("FIND_FOLLOW_SYMLINKS", &config.Find.FollowSymlinks)
+	p.loadBoolFromEnv("FIND_REPORT_SYMLINKS", &config.Find.ReportSymlinks)
+	p.loadBoolFromEnv("FIND_ONE_FILE_SYSTEM", &config.Find.OneFileSystem)
+	p.loadStringFromEnv("FIND_EXCLUDE_FSTYPE", &config.Find.ExcludeFSType)
+	p.loadStringFromEnv("FIND_FILES_FROM", &config.Find.FilesFrom)
+	p.loadBoolFromEnv("FIND_SCAN_ARCHIVES", &config.Find.ScanArchives)
+	p.loadPathListFromEnv("FIND_REFERENCE", &config.Find.Reference)
+	p.loadPathListFromEnv("FIND_AGAINST", &config.Find.Against)
+	p.loadPathListFromEnv("FIND_KNOWN_HASHES", &config.Find.KnownHashes)
 	p.loadBoolFromEnv("FIND_SHOW_FILTERS", &config.Find.ShowFilters)
 	p.loadStringFromEnv("FIND_OUTPUT_FORMAT", &config.Find.OutputFormat)
 	p.loadStringFromEnv("FIND_OUTPUT_FILE", &config.Find.OutputFile)
+	p.loadBoolFromEnv("FIND_FAIL_ON_ERROR", &config.Find.FailOnError)
+	p.loadStringFromEnv("FIND_FAIL_IF_WASTED_OVER", &config.Find.FailIfWastedOver)
+	p.loadIntFromEnv("FIND_FAIL_IF_GROUPS_OVER", &config.Find.FailIfGroupsOver)
+	p.loadStringFromEnv("FIND_METRICS_LISTEN", &config.Find.MetricsListen)
+	p.loadStringFromEnv("FIND_METRICS_TEXTFILE", &config.Find.MetricsTextfile)
+	p.loadIntFromEnv("FIND_PROGRESS_FD", &config.Find.ProgressFD)
+	p.loadBoolFromEnv("FIND_NO_PROGRESS", &config.Find.NoProgress)
+	p.loadStringFromEnv("FIND_IO_PER_DEVICE", &config.Find.IOPerDevice)
+	p.loadStringFromEnv("FIND_MAX_READ_RATE", &config.Find.MaxReadRate)
+	p.loadIntFromEnv("FIND_MAX_OPEN_FILES", &config.Find.MaxOpenFiles)
+	p.loadBoolFromEnv("FIND_IDLE_IO", &config.Find.IdleIO)
+	p.loadIntFromEnv("FIND_NICE", &config.Find.Nice)
+	p.loadStringFromEnv("FIND_SAMPLES", &config.Find.Samples)
+	p.loadStringFromEnv("FIND_CHUNKED_COMPARE", &config.Find.ChunkedCompare)
+	p.loadStringFromEnv("FIND_MAX_MEMORY", &config.Find.MaxMemory)
+	p.loadStringFromEnv("FIND_SPILL_DIR", &config.Find.SpillDir)
+	p.loadStringFromEnv("FIND_CHECKPOINT", &config.Find.Checkpoint)
 
 	// Load preset configuration
 	p.loadIntFromEnv("PRESET_WORKERS", &config.Preset.Workers)
@@ -70,6 +106,63 @@ func (p *EnvProvider) Load(ctx context.Context) (*Config, error) {
 	p.loadBoolFromEnv("PRESET_SHOW_FILTERS", &config.Preset.ShowFilters)
 	p.loadStringFromEnv("PRESET_OUTPUT_FORMAT", &config.Preset.OutputFormat)
 	p.loadStringFromEnv("PRESET_OUTPUT_FILE", &config.Preset.OutputFile)
+	p.loadBoolFromEnv("PRESET_FAIL_ON_ERROR", &config.Preset.FailOnError)
+	p.loadStringFromEnv("PRESET_FAIL_IF_WASTED_OVER", &config.Preset.FailIfWastedOver)
+	p.loadIntFromEnv("PRESET_FAIL_IF_GROUPS_OVER", &config.Preset.FailIfGroupsOver)
+	p.loadStringFromEnv("PRESET_METRICS_LISTEN", &config.Preset.MetricsListen)
+	p.loadStringFromEnv("PRESET_METRICS_TEXTFILE", &config.Preset.MetricsTextfile)
+	p.loadIntFromEnv("PRESET_PROGRESS_FD", &config.Preset.ProgressFD)
+	p.loadBoolFromEnv("PRESET_NO_PROGRESS", &config.Preset.NoProgress)
+	p.loadStringFromEnv("PRESET_IO_PER_DEVICE", &config.Preset.IOPerDevice)
+	p.loadStringFromEnv("PRESET_MAX_READ_RATE", &config.Preset.MaxReadRate)
+	p.loadIntFromEnv("PRESET_MAX_OPEN_FILES", &config.Preset.MaxOpenFiles)
+	p.loadBoolFromEnv("PRESET_IDLE_IO", &config.Preset.IdleIO)
+	p.loadIntFromEnv("PRESET_NICE", &config.Preset.Nice)
+	p.loadStringFromEnv("PRESET_SAMPLES", &config.Preset.Samples)
+	p.loadStringFromEnv("PRESET_CHUNKED_COMPARE", &config.Preset.ChunkedCompare)
+	p.loadStringFromEnv("PRESET_MAX_MEMORY", &config.Preset.MaxMemory)
+	p.loadStringFromEnv("PRESET_SPILL_DIR", &config.Preset.SpillDir)
+
+	// Load manifest configuration
+	p.loadStringFromEnv("MANIFEST_EXCLUDE_DIRS", &config.Manifest.ExcludeDirs)
+	p.loadStringFromEnv("MANIFEST_EXCLUDE_FILES", &config.Manifest.ExcludeFiles)
+	p.loadStringFromEnv("MANIFEST_MIN_SIZE", &config.Manifest.MinSize)
+	p.loadStringFromEnv("MANIFEST_MAX_SIZE", &config.Manifest.MaxSize)
+	p.loadIntFromEnv("MANIFEST_WORKERS", &config.Manifest.Workers)
+	p.loadBoolFromEnv("MANIFEST_VERBOSE", &config.Manifest.Verbose)
+	p.loadStringFromEnv("MANIFEST_OUTPUT_FILE", &config.Manifest.OutputFile)
+
+	// Load hash configuration
+	p.loadStringFromEnv("HASH_EXCLUDE_DIRS", &config.Hash.ExcludeDirs)
+	p.loadStringFromEnv("HASH_EXCLUDE_FILES", &config.Hash.ExcludeFiles)
+	p.loadStringFromEnv("HASH_MIN_SIZE", &config.Hash.MinSize)
+	p.loadStringFromEnv("HASH_MAX_SIZE", &config.Hash.MaxSize)
+	p.loadStringFromEnv("HASH_ALGORITHM", &config.Hash.Algorithm)
+	p.loadIntFromEnv("HASH_WORKERS", &config.Hash.Workers)
+	p.loadBoolFromEnv("HASH_VERBOSE", &config.Hash.Verbose)
+	p.loadStringFromEnv("HASH_OUTPUT_FILE", &config.Hash.OutputFile)
+
+	// Load watch configuration
+	p.loadStringFromEnv("WATCH_EXCLUDE_DIRS", &config.Watch.ExcludeDirs)
+	p.loadStringFromEnv("WATCH_EXCLUDE_FILES", &config.Watch.ExcludeFiles)
+	p.loadStringFromEnv("WATCH_MIN_SIZE", &config.Watch.MinSize)
+	p.loadStringFromEnv("WATCH_MAX_SIZE", &config.Watch.MaxSize)
+	p.loadStringFromEnv("WATCH_OWNER", &config.Watch.Owner)
+	p.loadStringFromEnv("WATCH_GROUP", &config.Watch.Group)
+	p.loadStringFromEnv("WATCH_UID", &config.Watch.UID)
+	p.loadStringFromEnv("WATCH_GID", &config.Watch.GID)
+	p.loadBoolFromEnv("WATCH_WRITABLE_ONLY", &config.Watch.WritableOnly)
+	p.loadIntFromEnv("WATCH_WORKERS", &config.Watch.Workers)
+	p.loadBoolFromEnv("WATCH_VERBOSE", &config.Watch.Verbose)
+	p.loadStringFromEnv("WATCH_EVENTS", &config.Watch.Events)
+	p.loadStringFromEnv("WATCH_OUTPUT_FILE", &config.Watch.OutputFile)
+
+	// Load serve configuration
+	p.loadStringFromEnv("SERVE_LISTEN", &config.Serve.Listen)
+	p.loadIntFromEnv("SERVE_JOBS", &config.Serve.Jobs)
+	p.loadIntFromEnv("SERVE_QUEUE_SIZE", &config.Serve.QueueSize)
+	p.loadStringFromEnv("SERVE_DATA_DIR", &config.Serve.DataDir)
+	p.loadIntFromEnv("SERVE_MAX_HISTORY", &config.Serve.MaxHistory)
 
 	return config, nil
 }
@@ -81,6 +174,14 @@ func (p *EnvProvider) loadStringFromEnv(key string, target *string) {
 	}
 }
 
+// loadPathListFromEnv loads a list of paths from the environment,
+// separated by the OS path list separator (like PATH).
+func (p *EnvProvider) loadPathListFromEnv(key string, target *[]string) {
+	if value := os.Getenv(p.prefix + key); value != "" {
+		*target = filepath.SplitList(value)
+	}
+}
+
 // loadIntFromEnv loads an int from the environment.
 func (p *EnvProvider) loadIntFromEnv(key string, target *int) {
 	if value := os.Getenv(p.prefix + key); value != "" {
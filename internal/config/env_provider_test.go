@@ -44,17 +44,35 @@ func TestEnvProvider(t *testing.T) {
 		{
 			name: "find configuration",
 			env: map[string]string{
-				"TEST_FIND_WORKERS":            "4",
-				"TEST_FIND_VERBOSE":            "true",
-				"TEST_FIND_EXCLUDE_DIRS":       "node_modules,vendor",
-				"TEST_FIND_EXCLUDE_FILES":      "*.log",
-				"TEST_FIND_EXCLUDE_DIR_REGEX":  "^\\.",
-				"TEST_FIND_EXCLUDE_FILE_REGEX": "^\\.",
-				"TEST_FIND_MIN_SIZE":           "1MB",
-				"TEST_FIND_MAX_SIZE":           "100MB",
-				"TEST_FIND_SHOW_FILTERS":       "true",
-				"TEST_FIND_OUTPUT_FORMAT":      "json",
-				"TEST_FIND_OUTPUT_FILE":        "out.json",
+				"TEST_FIND_WORKERS":             "4",
+				"TEST_FIND_VERBOSE":             "true",
+				"TEST_FIND_EXCLUDE_DIRS":        "node_modules,vendor",
+				"TEST_FIND_EXCLUDE_FILES":       "*.log",
+				"TEST_FIND_EXCLUDE_DIR_REGEX":   "^\\.",
+				"TEST_FIND_EXCLUDE_FILE_REGEX":  "^\\.",
+				"TEST_FIND_MIN_SIZE":            "1MB",
+				"TEST_FIND_MAX_SIZE":            "100MB",
+				"TEST_FIND_OWNER":               "alice",
+				"TEST_FIND_GID":                 "100",
+				"TEST_FIND_WRITABLE_ONLY":       "true",
+				"TEST_FIND_SHOW_FILTERS":        "true",
+				"TEST_FIND_OUTPUT_FORMAT":       "json",
+				"TEST_FIND_OUTPUT_FILE":         "out.json",
+				"TEST_FIND_FAIL_ON_ERROR":       "true",
+				"TEST_FIND_FAIL_IF_WASTED_OVER": "100MB",
+				"TEST_FIND_FAIL_IF_GROUPS_OVER": "5",
+				"TEST_FIND_PROGRESS_FD":         "3",
+				"TEST_FIND_NO_PROGRESS":         "true",
+				"TEST_FIND_IO_PER_DEVICE":       "hdd=2",
+				"TEST_FIND_MAX_READ_RATE":       "50MB/s",
+				"TEST_FIND_MAX_OPEN_FILES":      "16",
+				"TEST_FIND_IDLE_IO":             "true",
+				"TEST_FIND_NICE":                "10",
+				"TEST_FIND_SAMPLES":             "middle",
+				"TEST_FIND_CHUNKED_COMPARE":     "64MB",
+				"TEST_FIND_MAX_MEMORY":          "2GB",
+				"TEST_FIND_SPILL_DIR":           "/var/tmp",
+				"TEST_FIND_CHECKPOINT":          "scan.ckpt",
 			},
 			prefix:   "TEST_",
 			priority: 1,
@@ -68,30 +86,142 @@ func TestEnvProvider(t *testing.T) {
 					ExcludeFileRegex: "^\\.",
 					MinSize:          "1MB",
 					MaxSize:          "100MB",
+					Owner:            "alice",
+					GID:              "100",
+					WritableOnly:     true,
 					ShowFilters:      true,
 					OutputFormat:     "json",
 					OutputFile:       "out.json",
+					FailOnError:      true,
+					FailIfWastedOver: "100MB",
+					FailIfGroupsOver: 5,
+					ProgressFD:       3,
+					NoProgress:       true,
+					IOPerDevice:      "hdd=2",
+					MaxReadRate:      "50MB/s",
+					MaxOpenFiles:     16,
+					IdleIO:           true,
+					Nice:             10,
+					Samples:          "middle",
+					ChunkedCompare:   "64MB",
+					MaxMemory:        "2GB",
+					SpillDir:         "/var/tmp",
+					Checkpoint:       "scan.ckpt",
 				},
 			},
 		},
 		{
 			name: "preset configuration",
 			env: map[string]string{
-				"TEST_PRESET_WORKERS":       "4",
-				"TEST_PRESET_VERBOSE":       "true",
-				"TEST_PRESET_SHOW_FILTERS":  "true",
-				"TEST_PRESET_OUTPUT_FORMAT": "json",
-				"TEST_PRESET_OUTPUT_FILE":   "out.json",
+				"TEST_PRESET_WORKERS":          "4",
+				"TEST_PRESET_VERBOSE":          "true",
+				"TEST_PRESET_SHOW_FILTERS":     "true",
+				"TEST_PRESET_OUTPUT_FORMAT":    "json",
+				"TEST_PRESET_OUTPUT_FILE":      "out.json",
+				"TEST_PRESET_FAIL_ON_ERROR":    "true",
+				"TEST_PRESET_METRICS_LISTEN":   ":9101",
+				"TEST_PRESET_METRICS_TEXTFILE": "doppel.prom",
 			},
 			prefix:   "TEST_",
 			priority: 1,
 			want: &Config{
 				Preset: PresetConfig{
-					Workers:      4,
-					Verbose:      true,
-					ShowFilters:  true,
-					OutputFormat: "json",
-					OutputFile:   "out.json",
+					Workers:         4,
+					Verbose:         true,
+					ShowFilters:     true,
+					OutputFormat:    "json",
+					OutputFile:      "out.json",
+					FailOnError:     true,
+					MetricsListen:   ":9101",
+					MetricsTextfile: "doppel.prom",
+				},
+			},
+		},
+		{
+			name: "manifest config",
+			env: map[string]string{
+				"TEST_MANIFEST_EXCLUDE_DIRS": ".git",
+				"TEST_MANIFEST_MIN_SIZE":     "1KB",
+				"TEST_MANIFEST_WORKERS":      "2",
+				"TEST_MANIFEST_VERBOSE":      "true",
+				"TEST_MANIFEST_OUTPUT_FILE":  "manifest.jsonl",
+			},
+			prefix:   "TEST_",
+			priority: 1,
+			want: &Config{
+				Manifest: ManifestConfig{
+					ExcludeDirs: ".git",
+					MinSize:     "1KB",
+					Workers:     2,
+					Verbose:     true,
+					OutputFile:  "manifest.jsonl",
+				},
+			},
+		},
+		{
+			name: "hash config",
+			env: map[string]string{
+				"TEST_HASH_EXCLUDE_FILES": "*.tmp",
+				"TEST_HASH_ALGORITHM":     "blake3",
+				"TEST_HASH_WORKERS":       "2",
+				"TEST_HASH_OUTPUT_FILE":   "B3SUMS",
+				"TEST_FIND_KNOWN_HASHES":  "SHA256SUMS",
+			},
+			prefix:   "TEST_",
+			priority: 1,
+			want: &Config{
+				Find: FindConfig{
+					KnownHashes: []string{"SHA256SUMS"},
+				},
+				Hash: HashConfig{
+					ExcludeFiles: "*.tmp",
+					Algorithm:    "blake3",
+					Workers:      2,
+					OutputFile:   "B3SUMS",
+				},
+			},
+		},
+		{
+			name: "watch config",
+			env: map[string]string{
+				"TEST_WATCH_EXCLUDE_DIRS":  ".git",
+				"TEST_WATCH_MAX_SIZE":      "1GB",
+				"TEST_WATCH_OWNER":         "alice",
+				"TEST_WATCH_WRITABLE_ONLY": "true",
+				"TEST_WATCH_EVENTS":        "ndjson",
+				"TEST_WATCH_OUTPUT_FILE":   "events.ndjson",
+			},
+			prefix:   "TEST_",
+			priority: 1,
+			want: &Config{
+				Watch: WatchConfig{
+					ExcludeDirs:  ".git",
+					MaxSize:      "1GB",
+					Owner:        "alice",
+					WritableOnly: true,
+					Events:       "ndjson",
+					OutputFile:   "events.ndjson",
+				},
+			},
+		},
+		{
+			name: "serve config",
+			env: map[string]string{
+				"TEST_SERVE_LISTEN":      ":9090",
+				"TEST_SERVE_JOBS":        "2",
+				"TEST_SERVE_QUEUE_SIZE":  "32",
+				"TEST_SERVE_DATA_DIR":    "/var/lib/doppel",
+				"TEST_SERVE_MAX_HISTORY": "10",
+			},
+			prefix:   "TEST_",
+			priority: 1,
+			want: &Config{
+				Serve: ServeConfig{
+					Listen:     ":9090",
+					Jobs:       2,
+					QueueSize:  32,
+					DataDir:    "/var/lib/doppel",
+					MaxHistory: 10,
 				},
 			},
 		},
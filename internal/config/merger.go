@@ -44,6 +44,48 @@ func (m *defaultMerger) Merge(base, override *Config) *Config {
 	if override.Find.MaxSize != "" {
 		result.Find.MaxSize = override.Find.MaxSize
 	}
+	if override.Find.Owner != "" {
+		result.Find.Owner = override.Find.Owner
+	}
+	if override.Find.Group != "" {
+		result.Find.Group = override.Find.Group
+	}
+	if override.Find.UID != "" {
+		result.Find.UID = override.Find.UID
+	}
+	if override.Find.GID != "" {
+		result.Find.GID = override.Find.GID
+	}
+	if override.Find.WritableOnly {
+		result.Find.WritableOnly = override.Find.WritableOnly
+	}
+	if override.Find.FollowSymlinks {
+		result.Find.FollowSymlinks = override.Find.FollowSymlinks
+	}
+	if override.Find.ReportSymlinks {
+		result.Find.ReportSymlinks = override.Find.ReportSymlinks
+	}
+	if override.Find.OneFileSystem {
+		result.Find.OneFileSystem = override.Find.OneFileSystem
+	}
+	if override.Find.ExcludeFSType != "" {
+		result.Find.ExcludeFSType = override.Find.ExcludeFSType
+	}
+	if override.Find.ScanArchives {
+		result.Find.ScanArchives = override.Find.ScanArchives
+	}
+	if len(override.Find.Reference) > 0 {
+		result.Find.Reference = override.Find.Reference
+	}
+	if len(override.Find.Against) > 0 {
+		result.Find.Against = override.Find.Against
+	}
+	if len(override.Find.KnownHashes) > 0 {
+		result.Find.KnownHashes = override.Find.KnownHashes
+	}
+	if override.Find.FilesFrom != "" {
+		result.Find.FilesFrom = override.Find.FilesFrom
+	}
 	if override.Find.ShowFilters {
 		result.Find.ShowFilters = override.Find.ShowFilters
 	}
@@ -53,6 +95,57 @@ func (m *defaultMerger) Merge(base, override *Config) *Config {
 	if override.Find.OutputFile != "" {
 		result.Find.OutputFile = override.Find.OutputFile
 	}
+	if override.Find.FailOnError {
+		result.Find.FailOnError = override.Find.FailOnError
+	}
+	if override.Find.FailIfWastedOver != "" {
+		result.Find.FailIfWastedOver = override.Find.FailIfWastedOver
+	}
+	if override.Find.FailIfGroupsOver != 0 {
+		result.Find.FailIfGroupsOver = override.Find.FailIfGroupsOver
+	}
+	if override.Find.MetricsListen != "" {
+		result.Find.MetricsListen = override.Find.MetricsListen
+	}
+	if override.Find.MetricsTextfile != "" {
+		result.Find.MetricsTextfile = override.Find.MetricsTextfile
+	}
+	if override.Find.ProgressFD != 0 {
+		result.Find.ProgressFD = override.Find.ProgressFD
+	}
+	if override.Find.NoProgress {
+		result.Find.NoProgress = override.Find.NoProgress
+	}
+	if override.Find.IOPerDevice != "" {
+		result.Find.IOPerDevice = override.Find.IOPerDevice
+	}
+	if override.Find.MaxReadRate != "" {
+		result.Find.MaxReadRate = override.Find.MaxReadRate
+	}
+	if override.Find.MaxOpenFiles != 0 {
+		result.Find.MaxOpenFiles = override.Find.MaxOpenFiles
+	}
+	if override.Find.IdleIO {
+		result.Find.IdleIO = override.Find.IdleIO
+	}
+	if override.Find.Nice != 0 {
+		result.Find.Nice = override.Find.Nice
+	}
+	if override.Find.Samples != "" {
+		result.Find.Samples = override.Find.Samples
+	}
+	if override.Find.ChunkedCompare != "" {
+		result.Find.ChunkedCompare = override.Find.ChunkedCompare
+	}
+	if override.Find.MaxMemory != "" {
+		result.Find.MaxMemory = override.Find.MaxMemory
+	}
+	if override.Find.SpillDir != "" {
+		result.Find.SpillDir = override.Find.SpillDir
+	}
+	if override.Find.Checkpoint != "" {
+		result.Find.Checkpoint = override.Find.Checkpoint
+	}
 
 	// Merge preset config
 	if override.Preset.Workers != 0 {
@@ -70,6 +163,161 @@ func (m *defaultMerger) Merge(base, override *Config) *Config {
 	if override.Preset.OutputFile != "" {
 		result.Preset.OutputFile = override.Preset.OutputFile
 	}
+	if override.Preset.FailOnError {
+		result.Preset.FailOnError = override.Preset.FailOnError
+	}
+	if override.Preset.FailIfWastedOver != "" {
+		result.Preset.FailIfWastedOver = override.Preset.FailIfWastedOver
+	}
+	if override.Preset.FailIfGroupsOver != 0 {
+		result.Preset.FailIfGroupsOver = override.Preset.FailIfGroupsOver
+	}
+	if override.Preset.MetricsListen != "" {
+		result.Preset.MetricsListen = override.Preset.MetricsListen
+	}
+	if override.Preset.MetricsTextfile != "" {
+		result.Preset.MetricsTextfile = override.Preset.MetricsTextfile
+	}
+	if override.Preset.ProgressFD != 0 {
+		result.Preset.ProgressFD = override.Preset.ProgressFD
+	}
+	if override.Preset.NoProgress {
+		result.Preset.NoProgress = override.Preset.NoProgress
+	}
+	if override.Preset.IOPerDevice != "" {
+		result.Preset.IOPerDevice = override.Preset.IOPerDevice
+	}
+	if override.Preset.MaxReadRate != "" {
+		result.Preset.MaxReadRate = override.Preset.MaxReadRate
+	}
+	if override.Preset.MaxOpenFiles != 0 {
+		result.Preset.MaxOpenFiles = override.Preset.MaxOpenFiles
+	}
+	if override.Preset.IdleIO {
+		result.Preset.IdleIO = override.Preset.IdleIO
+	}
+	if override.Preset.Nice != 0 {
+		result.Preset.Nice = override.Preset.Nice
+	}
+	if override.Preset.Samples != "" {
+		result.Preset.Samples = override.Preset.Samples
+	}
+	if override.Preset.ChunkedCompare != "" {
+		result.Preset.ChunkedCompare = override.Preset.ChunkedCompare
+	}
+	if override.Preset.MaxMemory != "" {
+		result.Preset.MaxMemory = override.Preset.MaxMemory
+	}
+	if override.Preset.SpillDir != "" {
+		result.Preset.SpillDir = override.Preset.SpillDir
+	}
+
+	// Merge manifest config
+	if override.Manifest.ExcludeDirs != "" {
+		result.Manifest.ExcludeDirs = override.Manifest.ExcludeDirs
+	}
+	if override.Manifest.ExcludeFiles != "" {
+		result.Manifest.ExcludeFiles = override.Manifest.ExcludeFiles
+	}
+	if override.Manifest.MinSize != "" {
+		result.Manifest.MinSize = override.Manifest.MinSize
+	}
+	if override.Manifest.MaxSize != "" {
+		result.Manifest.MaxSize = override.Manifest.MaxSize
+	}
+	if override.Manifest.Workers != 0 {
+		result.Manifest.Workers = override.Manifest.Workers
+	}
+	if override.Manifest.Verbose {
+		result.Manifest.Verbose = override.Manifest.Verbose
+	}
+	if override.Manifest.OutputFile != "" {
+		result.Manifest.OutputFile = override.Manifest.OutputFile
+	}
+
+	// Merge hash config
+	if override.Hash.ExcludeDirs != "" {
+		result.Hash.ExcludeDirs = override.Hash.ExcludeDirs
+	}
+	if override.Hash.ExcludeFiles != "" {
+		result.Hash.ExcludeFiles = override.Hash.ExcludeFiles
+	}
+	if override.Hash.MinSize != "" {
+		result.Hash.MinSize = override.Hash.MinSize
+	}
+	if override.Hash.MaxSize != "" {
+		result.Hash.MaxSize = override.Hash.MaxSize
+	}
+	if override.Hash.Algorithm != "" {
+		result.Hash.Algorithm = override.Hash.Algorithm
+	}
+	if override.Hash.Workers != 0 {
+		result.Hash.Workers = override.Hash.Workers
+	}
+	if override.Hash.Verbose {
+		result.Hash.Verbose = override.Hash.Verbose
+	}
+	if override.Hash.OutputFile != "" {
+		result.Hash.OutputFile = override.Hash.OutputFile
+	}
+
+	// Merge watch config
+	if override.Watch.ExcludeDirs != "" {
+		result.Watch.ExcludeDirs = override.Watch.ExcludeDirs
+	}
+	if override.Watch.ExcludeFiles != "" {
+		result.Watch.ExcludeFiles = override.Watch.ExcludeFiles
+	}
+	if override.Watch.MinSize != "" {
+		result.Watch.MinSize = override.Watch.MinSize
+	}
+	if override.Watch.MaxSize != "" {
+		result.Watch.MaxSize = override.Watch.MaxSize
+	}
+	if override.Watch.Owner != "" {
+		result.Watch.Owner = override.Watch.Owner
+	}
+	if override.Watch.Group != "" {
+		result.Watch.Group = override.Watch.Group
+	}
+	if override.Watch.UID != "" {
+		result.Watch.UID = override.Watch.UID
+	}
+	if override.Watch.GID != "" {
+		result.Watch.GID = override.Watch.GID
+	}
+	if override.Watch.WritableOnly {
+		result.Watch.WritableOnly = override.Watch.WritableOnly
+	}
+	if override.Watch.Workers != 0 {
+		result.Watch.Workers = override.Watch.Workers
+	}
+	if override.Watch.Verbose {
+		result.Watch.Verbose = override.Watch.Verbose
+	}
+	if override.Watch.Events != "" {
+		result.Watch.Events = override.Watch.Events
+	}
+	if override.Watch.OutputFile != "" {
+		result.Watch.OutputFile = override.Watch.OutputFile
+	}
+
+	// Merge serve config
+	if override.Serve.Listen != "" {
+		result.Serve.Listen = override.Serve.Listen
+	}
+	if override.Serve.Jobs != 0 {
+		result.Serve.Jobs = override.Serve.Jobs
+	}
+	if override.Serve.QueueSize != 0 {
+		result.Serve.QueueSize = override.Serve.QueueSize
+	}
+	if override.Serve.DataDir != "" {
+		result.Serve.DataDir = override.Serve.DataDir
+	}
+	if override.Serve.MaxHistory != 0 {
+		result.Serve.MaxHistory = override.Serve.MaxHistory
+	}
 
 	return &result
 }
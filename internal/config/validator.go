@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+
+	"github.com/dr8co/doppel/internal/checksum"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/scanner"
 )
 
 // defaultValidator provides comprehensive validation.
@@ -29,6 +34,22 @@ func (v *defaultValidator) Validate(config *Config) error {
 		return fmt.Errorf("preset config validation failed: %w", err)
 	}
 
+	if err := v.validateManifestConfig(&config.Manifest); err != nil {
+		return fmt.Errorf("manifest config validation failed: %w", err)
+	}
+
+	if err := v.validateHashConfig(&config.Hash); err != nil {
+		return fmt.Errorf("hash config validation failed: %w", err)
+	}
+
+	if err := v.validateWatchConfig(&config.Watch); err != nil {
+		return fmt.Errorf("watch config validation failed: %w", err)
+	}
+
+	if err := v.validateServeConfig(&config.Serve); err != nil {
+		return fmt.Errorf("serve config validation failed: %w", err)
+	}
+
 	return nil
 }
 
@@ -55,14 +76,83 @@ func (v *defaultValidator) validateLogConfig(config *LogConfig) error {
 
 // validateFindConfig validates the find configuration.
 func (v *defaultValidator) validateFindConfig(config *FindConfig) error {
+	if config.ProgressFD < 0 {
+		return fmt.Errorf("invalid progress file descriptor: %d", config.ProgressFD)
+	}
+	if err := validateIO(config.IOPerDevice, config.MaxReadRate, config.MaxOpenFiles, config.Nice); err != nil {
+		return err
+	}
+	if err := validateStages(config.Samples, config.ChunkedCompare); err != nil {
+		return err
+	}
+	if _, err := filter.ParseFileSize(config.MaxMemory); err != nil {
+		return fmt.Errorf("invalid memory ceiling: %w", err)
+	}
+	if err := validateThresholds(config.FailIfWastedOver, config.FailIfGroupsOver); err != nil {
+		return err
+	}
 	return validate(config.Workers, config.OutputFormat)
 }
 
 // validatePresetConfig validates the preset configuration.
 func (v *defaultValidator) validatePresetConfig(config *PresetConfig) error {
+	if config.ProgressFD < 0 {
+		return fmt.Errorf("invalid progress file descriptor: %d", config.ProgressFD)
+	}
+	if err := validateIO(config.IOPerDevice, config.MaxReadRate, config.MaxOpenFiles, config.Nice); err != nil {
+		return err
+	}
+	if err := validateStages(config.Samples, config.ChunkedCompare); err != nil {
+		return err
+	}
+	if _, err := filter.ParseFileSize(config.MaxMemory); err != nil {
+		return fmt.Errorf("invalid memory ceiling: %w", err)
+	}
+	if err := validateThresholds(config.FailIfWastedOver, config.FailIfGroupsOver); err != nil {
+		return err
+	}
 	return validate(config.Workers, config.OutputFormat)
 }
 
+// validateManifestConfig validates the manifest configuration.
+func (v *defaultValidator) validateManifestConfig(config *ManifestConfig) error {
+	return validate(config.Workers, "")
+}
+
+// validateHashConfig validates the hash configuration.
+func (v *defaultValidator) validateHashConfig(config *HashConfig) error {
+	if _, err := checksum.Lookup(config.Algorithm); err != nil {
+		return err
+	}
+	return validate(config.Workers, "")
+}
+
+// validateWatchConfig validates the watch configuration.
+func (v *defaultValidator) validateWatchConfig(config *WatchConfig) error {
+	validEvents := []string{"log", "ndjson"}
+	if !contains(validEvents, config.Events) {
+		return fmt.Errorf("invalid events format: %s, must be one of %v", config.Events, validEvents)
+	}
+	return validate(config.Workers, "")
+}
+
+// validateServeConfig validates the serve configuration.
+func (v *defaultValidator) validateServeConfig(config *ServeConfig) error {
+	if config.Listen == "" {
+		return errors.New("listen address is required")
+	}
+	if config.Jobs < 1 {
+		return fmt.Errorf("too few concurrent jobs: %d (min 1)", config.Jobs)
+	}
+	if config.QueueSize < 1 {
+		return fmt.Errorf("queue size too small: %d (min 1)", config.QueueSize)
+	}
+	if config.MaxHistory < 0 {
+		return fmt.Errorf("invalid max history: %d", config.MaxHistory)
+	}
+	return nil
+}
+
 // validate is a common validation function for both preset and find config.
 func validate(workers int, outputFormat string) error {
 	if workers < minWorkers {
@@ -82,6 +172,47 @@ func validate(workers int, outputFormat string) error {
 	return nil
 }
 
+// validateIO validates the options scheduling and throttling the reads of the files to hash.
+func validateIO(ioPerDevice, maxReadRate string, maxOpenFiles, nice int) error {
+	if ioPerDevice != "" {
+		if _, err := iosched.ParseLimits(ioPerDevice); err != nil {
+			return err
+		}
+	}
+	if _, err := iosched.ParseRate(maxReadRate); err != nil {
+		return err
+	}
+	if maxOpenFiles < 0 {
+		return fmt.Errorf("invalid maximum number of open files: %d", maxOpenFiles)
+	}
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("invalid nice level: %d (must be between -20 and 19)", nice)
+	}
+	return nil
+}
+
+// validateStages validates the options of the optional hashing stages.
+func validateStages(samples, chunkedCompare string) error {
+	if _, err := scanner.ParseSamples(samples); err != nil {
+		return err
+	}
+	if _, err := filter.ParseFileSize(chunkedCompare); err != nil {
+		return fmt.Errorf("invalid chunked comparison size: %w", err)
+	}
+	return nil
+}
+
+// validateThresholds validates the thresholds of duplication over which a scan counts as having found duplicates.
+func validateThresholds(wastedOver string, groupsOver int) error {
+	if _, err := filter.ParseFileSize(wastedOver); err != nil {
+		return fmt.Errorf("invalid wasted space threshold: %w", err)
+	}
+	if groupsOver < 0 {
+		return fmt.Errorf("invalid duplicate group threshold: %d", groupsOver)
+	}
+	return nil
+}
+
 // contains returns true if the given string is in the slice.
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
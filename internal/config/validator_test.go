@@ -32,6 +32,22 @@ func TestDefaultValidator(t *testing.T) {
 					Workers:      runtime.NumCPU(),
 					OutputFormat: "pretty",
 				},
+				Manifest: ManifestConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Hash: HashConfig{
+					Algorithm: "blake3",
+					Workers:   runtime.NumCPU(),
+				},
+				Watch: WatchConfig{
+					Workers: runtime.NumCPU(),
+					Events:  "ndjson",
+				},
+				Serve: ServeConfig{
+					Listen:    ":8080",
+					Jobs:      2,
+					QueueSize: 4,
+				},
 			},
 			wantErr: false,
 		},
@@ -113,6 +129,165 @@ func TestDefaultValidator(t *testing.T) {
 			wantErr:  true,
 			errField: "output format",
 		},
+		{
+			name: "negative progress file descriptor in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers:    runtime.NumCPU(),
+					ProgressFD: -1,
+				},
+			},
+			wantErr:  true,
+			errField: "progress file descriptor",
+		},
+		{
+			name: "invalid I/O limits in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers:     runtime.NumCPU(),
+					IOPerDevice: "tape=1",
+				},
+			},
+			wantErr:  true,
+			errField: "unknown device kind",
+		},
+		{
+			name: "invalid read rate in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers:     runtime.NumCPU(),
+					MaxReadRate: "fast",
+				},
+			},
+			wantErr:  true,
+			errField: "read rate",
+		},
+		{
+			name: "negative open files limit in preset config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers:      runtime.NumCPU(),
+					MaxOpenFiles: -1,
+				},
+			},
+			wantErr:  true,
+			errField: "open files",
+		},
+		{
+			name: "nice level out of range in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+					Nice:    20,
+				},
+			},
+			wantErr:  true,
+			errField: "nice level",
+		},
+		{
+			name: "invalid sample points in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+					Samples: "edges",
+				},
+			},
+			wantErr:  true,
+			errField: "sample points",
+		},
+		{
+			name: "invalid chunked comparison size in preset config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers:        runtime.NumCPU(),
+					ChunkedCompare: "big",
+				},
+			},
+			wantErr:  true,
+			errField: "chunked comparison",
+		},
+		{
+			name: "invalid memory ceiling in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers:   runtime.NumCPU(),
+					MaxMemory: "plenty",
+				},
+			},
+			wantErr:  true,
+			errField: "memory ceiling",
+		},
+		{
+			name: "invalid wasted space threshold in find config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers:          runtime.NumCPU(),
+					FailIfWastedOver: "lots",
+				},
+			},
+			wantErr:  true,
+			errField: "wasted space threshold",
+		},
+		{
+			name: "negative duplicate group threshold in preset config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers:          runtime.NumCPU(),
+					FailIfGroupsOver: -1,
+				},
+			},
+			wantErr:  true,
+			errField: "duplicate group threshold",
+		},
 		{
 			name: "too few workers in preset config",
 			config: &Config{
@@ -165,6 +340,110 @@ func TestDefaultValidator(t *testing.T) {
 			wantErr:  true,
 			errField: "output format",
 		},
+		{
+			name: "too few workers in manifest config",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Manifest: ManifestConfig{
+					Workers: 0,
+				},
+			},
+			wantErr:  true,
+			errField: "too few workers",
+		},
+		{
+			name: "unknown hash algorithm",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Manifest: ManifestConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Hash: HashConfig{
+					Algorithm: "crc32",
+					Workers:   runtime.NumCPU(),
+				},
+			},
+			wantErr:  true,
+			errField: "unknown checksum algorithm",
+		},
+		{
+			name: "invalid watch events format",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Manifest: ManifestConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Hash: HashConfig{
+					Algorithm: "sha256",
+					Workers:   runtime.NumCPU(),
+				},
+				Watch: WatchConfig{
+					Workers: runtime.NumCPU(),
+					Events:  "xml",
+				},
+			},
+			wantErr:  true,
+			errField: "invalid events format",
+		},
+		{
+			name: "invalid serve queue size",
+			config: &Config{
+				Log: LogConfig{
+					Level:  "info",
+					Format: "text",
+				},
+				Find: FindConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Preset: PresetConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Manifest: ManifestConfig{
+					Workers: runtime.NumCPU(),
+				},
+				Hash: HashConfig{
+					Algorithm: "sha256",
+					Workers:   runtime.NumCPU(),
+				},
+				Watch: WatchConfig{
+					Workers: runtime.NumCPU(),
+					Events:  "log",
+				},
+				Serve: ServeConfig{
+					Listen: ":8080",
+					Jobs:   1,
+				},
+			},
+			wantErr:  true,
+			errField: "queue size too small",
+		},
 	}
 
 	for _, tt := range tests {
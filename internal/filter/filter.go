@@ -4,10 +4,10 @@
 //   - Glob patterns for file and directory names
 //   - Regular expressions for file and directory paths
 //   - File size constraints (minimum and maximum sizes)
+//   - File ownership and removability (owner, group, writable parent directory)
 //   - Predefined filter presets for common use cases
 //
-// The package supports parsing human-readable file sizes (e.g., "10MB", "1.5GB")
-// and provides utilities to display active filter configurations.
+// The package supports parsing human-readable file sizes (e.g., "10MB", "1.5GB").
 package filter
 
 import (
@@ -21,7 +21,6 @@ import (
 	"strings"
 
 	"github.com/dr8co/doppel/internal/logger"
-	"github.com/dr8co/doppel/internal/output"
 )
 
 // Config defines criteria for excluding files and directories.
@@ -44,6 +43,18 @@ type Config struct {
 	// MaxSize is the maximum file size to include (0 means no maximum).
 	MaxSize int64 `json:"max_size" yaml:"max_size"`
 
+	// Owner is the user name or ID whose files are included (empty means any owner).
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+
+	// Group is the group name or ID whose files are included (empty means any group).
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+
+	// WritableOnly excludes files the current user cannot remove.
+	WritableOnly bool `json:"writable_only,omitempty" yaml:"writable_only,omitempty"`
+
+	// ownership holds the resolved ownership constraints (nil means no ownership filters).
+	ownership *ownership
+
 	// excludeFileRegex contains compiled regex patterns for files to exclude.
 	excludeFileRegex []*regexp.Regexp
 
@@ -51,8 +62,14 @@ type Config struct {
 	excludeDirRegex []*regexp.Regexp
 }
 
-// BuildConfig creates a [Config] from command line arguments.
+// BuildConfig creates a [Config] from command line arguments, holding comma-separated lists of patterns.
 func BuildConfig(excludeDirs, excludeFiles, excludeDirRegex, excludeFileRegex string, minSize, maxSize int64) (*Config, error) {
+	return NewConfig(parseCommaSeparated(excludeDirs), parseCommaSeparated(excludeFiles),
+		parseCommaSeparated(excludeDirRegex), parseCommaSeparated(excludeFileRegex), minSize, maxSize)
+}
+
+// NewConfig creates a [Config] from lists of glob patterns and regular expressions, and size limits.
+func NewConfig(excludeDirs, excludeFiles, excludeDirRegex, excludeFileRegex []string, minSize, maxSize int64) (*Config, error) {
 	// Handle negative values
 	if minSize < 0 {
 		logger.DebugAttrs(context.TODO(), "minSize is negative, setting to 0", slog.Int64("minSize", minSize))
@@ -73,54 +90,48 @@ func BuildConfig(excludeDirs, excludeFiles, excludeDirRegex, excludeFileRegex st
 		MaxSize: maxSize,
 	}
 
-	// Parse exclude directory patterns
-	if excludeDirs != "" {
-		config.ExcludeDirs = parseCommaSeparated(excludeDirs)
+	// Exclude directory patterns
+	if len(excludeDirs) > 0 {
+		config.ExcludeDirs = excludeDirs
 		logger.Debug("Parsed exclude directories", "dirs", config.ExcludeDirs)
 	}
 
-	// Parse exclude file patterns
-	if excludeFiles != "" {
-		config.ExcludeFiles = parseCommaSeparated(excludeFiles)
+	// Exclude file patterns
+	if len(excludeFiles) > 0 {
+		config.ExcludeFiles = excludeFiles
 		logger.Debug("Parsed exclude files", "files", config.ExcludeFiles)
 	}
 
-	// Parse exclude directory regex patterns
-	if excludeDirRegex != "" {
-		patterns := parseCommaSeparated(excludeDirRegex)
-		if len(patterns) > 0 {
-			logger.Debug("Parsing exclude directory regex", "patterns", patterns)
-
-			for _, pattern := range patterns {
-				regex, err := regexp.Compile(pattern)
-				if err != nil {
-					return nil, fmt.Errorf("invalid directory regex pattern '%s': %w", pattern, err)
-				}
-				config.excludeDirRegex = append(config.excludeDirRegex, regex)
-			}
+	// Compile exclude directory regex patterns
+	if len(excludeDirRegex) > 0 {
+		logger.Debug("Parsing exclude directory regex", "patterns", excludeDirRegex)
 
-			config.ExcludeDirRegexRaw = patterns
-			logger.Debug("Parsed exclude directory regex", "regex", config.excludeDirRegex)
+		for _, pattern := range excludeDirRegex {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid directory regex pattern '%s': %w", pattern, err)
+			}
+			config.excludeDirRegex = append(config.excludeDirRegex, regex)
 		}
+
+		config.ExcludeDirRegexRaw = excludeDirRegex
+		logger.Debug("Parsed exclude directory regex", "regex", config.excludeDirRegex)
 	}
 
-	// Parse exclude file regex patterns
-	if excludeFileRegex != "" {
-		patterns := parseCommaSeparated(excludeFileRegex)
-		if len(patterns) > 0 {
-			logger.Debug("Parsing exclude file regex", "patterns", patterns)
-
-			for _, pattern := range patterns {
-				regex, err := regexp.Compile(pattern)
-				if err != nil {
-					return nil, fmt.Errorf("invalid file regex pattern '%s': %w", pattern, err)
-				}
-				config.excludeFileRegex = append(config.excludeFileRegex, regex)
-			}
+	// Compile exclude file regex patterns
+	if len(excludeFileRegex) > 0 {
+		logger.Debug("Parsing exclude file regex", "patterns", excludeFileRegex)
 
-			config.ExcludeFileRegexRaw = patterns
-			logger.Debug("Parsed exclude file regex", "regex", config.excludeFileRegex)
+		for _, pattern := range excludeFileRegex {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid file regex pattern '%s': %w", pattern, err)
+			}
+			config.excludeFileRegex = append(config.excludeFileRegex, regex)
 		}
+
+		config.ExcludeFileRegexRaw = excludeFileRegex
+		logger.Debug("Parsed exclude file regex", "regex", config.excludeFileRegex)
 	}
 
 	return config, nil
@@ -206,40 +217,11 @@ func (fc *Config) ShouldExcludeFile(filePath string, size int64) bool {
 	return false
 }
 
-// DisplayActiveFilters prints the currently active file and directory filters from the provided configuration.
-func DisplayActiveFilters(config *Config) {
-	fmt.Println("🔧 Active filters:")
-	if len(config.ExcludeDirs) > 0 {
-		fmt.Printf("  📁 Exclude directories: %s\n", strings.Join(config.ExcludeDirs, ", "))
-	}
-
-	if len(config.ExcludeFiles) > 0 {
-		fmt.Printf("  📄 Exclude files: %s\n", strings.Join(config.ExcludeFiles, ", "))
-	}
-
-	if len(config.excludeDirRegex) > 0 {
-		fmt.Printf("  📁 Exclude directory regex: %q\n", config.ExcludeDirRegexRaw)
-	}
-
-	if len(config.excludeFileRegex) > 0 {
-		fmt.Printf("  📄 Exclude file regex: %q\n", config.ExcludeFileRegexRaw)
-	}
-
-	if config.MinSize > 0 {
-		fmt.Printf("  📏 Minimum file size: %s\n", output.FormatBytes(config.MinSize))
-	}
-
-	if config.MaxSize > 0 {
-		fmt.Printf("  📏 Maximum file size: %s\n", output.FormatBytes(config.MaxSize))
-	}
-
-	if len(config.ExcludeDirs) == 0 && len(config.ExcludeFiles) == 0 &&
-		len(config.excludeDirRegex) == 0 && len(config.excludeFileRegex) == 0 &&
-		config.MinSize == 0 && config.MaxSize == 0 {
-		fmt.Println("  ✅ No filters active")
-	}
-
-	fmt.Println()
+// Active reports whether any filter is set.
+func (fc *Config) Active() bool {
+	return len(fc.ExcludeDirs) > 0 || len(fc.ExcludeFiles) > 0 ||
+		len(fc.excludeDirRegex) > 0 || len(fc.excludeFileRegex) > 0 ||
+		fc.MinSize > 0 || fc.MaxSize > 0 || fc.hasOwnershipFilters()
 }
 
 // ParseFileSize parses a file size string with optional suffix and returns size in bytes.
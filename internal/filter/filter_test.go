@@ -198,6 +198,32 @@ func TestBuildFilterConfig(t *testing.T) {
 	}
 }
 
+// TestConfig_Active verifies that a configuration is active as soon as any filter is set.
+func TestConfig_Active(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func() (*Config, error)
+		want   bool
+	}{
+		{"no filters", func() (*Config, error) { return NewConfig(nil, nil, nil, nil, 0, 0) }, false},
+		{"exclude dirs", func() (*Config, error) { return NewConfig([]string{".git"}, nil, nil, nil, 0, 0) }, true},
+		{"file regex", func() (*Config, error) { return NewConfig(nil, nil, nil, []string{`\.tmp$`}, 0, 0) }, true},
+		{"max size", func() (*Config, error) { return NewConfig(nil, nil, nil, nil, 0, 1024) }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := tt.config()
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if got := config.Active(); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestShouldExcludeDir validates the behavior of ShouldExcludeDir by testing various exclusion configurations and scenarios.
 func TestShouldExcludeDir(t *testing.T) {
 	tests := []struct {
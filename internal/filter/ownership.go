@@ -0,0 +1,157 @@
+package filter
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Exclusion reasons reported by [Config.ShouldExcludeByOwnership].
+const (
+	ReasonOwnerMismatch = "owner mismatch"
+	ReasonGroupMismatch = "group mismatch"
+	ReasonNotRemovable  = "not removable by the current user"
+)
+
+// ownership holds the resolved ownership constraints of a [Config].
+type ownership struct {
+	// uid is the required owner ID, or -1 if any owner is allowed.
+	uid int64
+
+	// gid is the required group ID, or -1 if any group is allowed.
+	gid int64
+
+	// removable caches whether the current user can remove entries from a directory.
+	removable map[string]bool
+
+	mu sync.Mutex
+}
+
+// ApplyOwnership configures the ownership and permission filters.
+//
+// owner and group accept either a name or a numeric ID, while uid and gid must be numeric.
+// When both a name and an ID are given for the same constraint, they must resolve to the same ID.
+// Empty strings leave the corresponding constraint unset.
+func (fc *Config) ApplyOwnership(owner, group, uid, gid string, writableOnly bool) error {
+	owner, group = strings.TrimSpace(owner), strings.TrimSpace(group)
+	uid, gid = strings.TrimSpace(uid), strings.TrimSpace(gid)
+
+	if owner == "" && group == "" && uid == "" && gid == "" && !writableOnly {
+		return nil
+	}
+	if !ownershipSupported {
+		return errOwnershipUnsupported
+	}
+
+	ownerID, err := resolveID(owner, uid, "owner", lookupUserID)
+	if err != nil {
+		return err
+	}
+
+	groupID, err := resolveID(group, gid, "group", lookupGroupID)
+	if err != nil {
+		return err
+	}
+
+	fc.Owner = displayID(owner, uid)
+	fc.Group = displayID(group, gid)
+	fc.WritableOnly = writableOnly
+	fc.ownership = &ownership{uid: ownerID, gid: groupID}
+
+	return nil
+}
+
+// resolveID resolves a name-or-ID and a numeric ID into a single ID, or -1 if neither is set.
+func resolveID(name, id, kind string, lookup func(string) (int64, error)) (int64, error) {
+	resolved := int64(-1)
+
+	if name != "" {
+		if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+			resolved = int64(n)
+		} else {
+			resolved, err = lookup(name)
+			if err != nil {
+				return -1, fmt.Errorf("unknown %s '%s': %w", kind, name, err)
+			}
+		}
+	}
+
+	if id != "" {
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return -1, fmt.Errorf("invalid %s ID '%s': must be a non-negative integer", kind, id)
+		}
+		if resolved >= 0 && resolved != int64(n) {
+			return -1, fmt.Errorf("%s '%s' (ID %d) conflicts with %s ID %d", kind, name, resolved, kind, n)
+		}
+		resolved = int64(n)
+	}
+
+	return resolved, nil
+}
+
+// displayID returns the human-readable form of an ownership constraint.
+func displayID(name, id string) string {
+	if name != "" {
+		return name
+	}
+	return id
+}
+
+// hasOwnershipFilters reports whether any ownership or permission filter is active.
+func (fc *Config) hasOwnershipFilters() bool {
+	return fc.ownership != nil && (fc.ownership.uid >= 0 || fc.ownership.gid >= 0 || fc.WritableOnly)
+}
+
+// ShouldExcludeByOwnership checks if a file should be excluded based on its owner, group,
+// or whether the current user can remove it.
+// It returns the exclusion reason when the file should be excluded.
+func (fc *Config) ShouldExcludeByOwnership(filePath string, info fs.FileInfo) (bool, string) {
+	if !fc.hasOwnershipFilters() {
+		return false, ""
+	}
+
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		// No ownership data available; do not exclude what we cannot check.
+		return false, ""
+	}
+
+	if fc.ownership.uid >= 0 && int64(uid) != fc.ownership.uid {
+		return true, ReasonOwnerMismatch
+	}
+	if fc.ownership.gid >= 0 && int64(gid) != fc.ownership.gid {
+		return true, ReasonGroupMismatch
+	}
+
+	if fc.WritableOnly && !fc.canRemove(filepath.Dir(filePath), uid) {
+		return true, ReasonNotRemovable
+	}
+
+	return false, ""
+}
+
+// canRemove reports whether the current user can remove a file owned by fileUID from dir.
+func (fc *Config) canRemove(dir string, fileUID uint32) bool {
+	o := fc.ownership
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// The sticky bit makes the answer depend on the file owner, so only cache plain directories.
+	if ok, cached := o.removable[dir]; cached {
+		return ok
+	}
+
+	ok, sticky := dirAllowsRemoval(dir, fileUID)
+	if !sticky {
+		if o.removable == nil {
+			o.removable = make(map[string]bool)
+		}
+		o.removable[dir] = ok
+	}
+
+	return ok
+}
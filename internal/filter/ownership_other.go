@@ -0,0 +1,33 @@
+//go:build !unix
+
+package filter
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+)
+
+const ownershipSupported = false
+
+var errOwnershipUnsupported = errors.New("ownership and permission filters are not supported on " + runtime.GOOS)
+
+// fileOwner is not supported on this platform.
+func fileOwner(fs.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+// dirAllowsRemoval is not supported on this platform.
+func dirAllowsRemoval(string, uint32) (ok, sticky bool) {
+	return true, false
+}
+
+// lookupUserID is not supported on this platform.
+func lookupUserID(string) (int64, error) {
+	return -1, errOwnershipUnsupported
+}
+
+// lookupGroupID is not supported on this platform.
+func lookupGroupID(string) (int64, error) {
+	return -1, errOwnershipUnsupported
+}
@@ -0,0 +1,126 @@
+//go:build unix
+
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestApplyOwnership verifies that [Config.ApplyOwnership] resolves and validates ownership constraints.
+func TestApplyOwnership(t *testing.T) {
+	uid := strconv.Itoa(os.Getuid())
+	gid := strconv.Itoa(os.Getgid())
+
+	tests := []struct {
+		name     string
+		owner    string
+		group    string
+		uid      string
+		gid      string
+		writable bool
+		wantErr  bool
+		active   bool
+	}{
+		{name: "nothing set"},
+		{name: "numeric owner", owner: uid, active: true},
+		{name: "uid and gid", uid: uid, gid: gid, active: true},
+		{name: "matching owner and uid", owner: uid, uid: uid, active: true},
+		{name: "writable only", writable: true, active: true},
+		{name: "conflicting owner and uid", owner: "0", uid: "1", wantErr: true},
+		{name: "negative uid", uid: "-1", wantErr: true},
+		{name: "non-numeric gid", gid: "staff", wantErr: true},
+		{name: "unknown owner", owner: "no-such-user-doppel", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := &Config{}
+			err := fc.ApplyOwnership(tt.owner, tt.group, tt.uid, tt.gid, tt.writable)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyOwnership() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && fc.hasOwnershipFilters() != tt.active {
+				t.Errorf("hasOwnershipFilters() = %v, want %v", fc.hasOwnershipFilters(), tt.active)
+			}
+		})
+	}
+}
+
+// TestShouldExcludeByOwnership verifies owner, group, and removability checks against real files.
+func TestShouldExcludeByOwnership(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("content"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	info, err := os.Lstat(file)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+
+	uid := os.Getuid()
+	gid := os.Getgid()
+
+	tests := []struct {
+		name       string
+		uid        string
+		gid        string
+		writable   bool
+		shouldSkip bool
+		reason     string
+	}{
+		{name: "no filters"},
+		{name: "own files", uid: strconv.Itoa(uid)},
+		{name: "own group", gid: strconv.Itoa(gid)},
+		{name: "other owner", uid: strconv.Itoa(uid + 1), shouldSkip: true, reason: ReasonOwnerMismatch},
+		{name: "other group", gid: strconv.Itoa(gid + 1), shouldSkip: true, reason: ReasonGroupMismatch},
+		{name: "writable directory", writable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := &Config{}
+			if err := fc.ApplyOwnership("", "", tt.uid, tt.gid, tt.writable); err != nil {
+				t.Fatalf("ApplyOwnership() error = %v", err)
+			}
+			skip, reason := fc.ShouldExcludeByOwnership(file, info)
+			if skip != tt.shouldSkip || reason != tt.reason {
+				t.Errorf("ShouldExcludeByOwnership() = (%v, %q), want (%v, %q)", skip, reason, tt.shouldSkip, tt.reason)
+			}
+		})
+	}
+
+	t.Run("read-only directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can remove files from read-only directories")
+		}
+		roDir := filepath.Join(dir, "ro")
+		if err := os.Mkdir(roDir, 0o750); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		roFile := filepath.Join(roDir, "file.txt")
+		if err := os.WriteFile(roFile, []byte("content"), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Chmod(roDir, 0o500); err != nil {
+			t.Fatalf("Failed to chmod directory: %v", err)
+		}
+		t.Cleanup(func() { _ = os.Chmod(roDir, 0o750) })
+
+		roInfo, err := os.Lstat(roFile)
+		if err != nil {
+			t.Fatalf("Failed to stat file: %v", err)
+		}
+
+		fc := &Config{}
+		if err := fc.ApplyOwnership("", "", "", "", true); err != nil {
+			t.Fatalf("ApplyOwnership() error = %v", err)
+		}
+		if skip, reason := fc.ShouldExcludeByOwnership(roFile, roInfo); !skip || reason != ReasonNotRemovable {
+			t.Errorf("ShouldExcludeByOwnership() = (%v, %q), want (true, %q)", skip, reason, ReasonNotRemovable)
+		}
+	})
+}
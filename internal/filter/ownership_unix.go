@@ -0,0 +1,72 @@
+//go:build unix
+
+package filter
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const ownershipSupported = true
+
+var errOwnershipUnsupported error
+
+// fileOwner extracts the owner and group IDs from the stat data of a file.
+func fileOwner(info fs.FileInfo) (uid, gid uint32, ok bool) {
+	if info == nil {
+		return 0, 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st == nil {
+		return 0, 0, false
+	}
+	return st.Uid, st.Gid, true
+}
+
+// dirAllowsRemoval reports whether the current user can remove a file owned by fileUID from dir.
+// The sticky result reports whether dir has the sticky bit set.
+func dirAllowsRemoval(dir string, fileUID uint32) (ok, sticky bool) {
+	// Removing a directory entry requires write and search permission on the directory.
+	if err := unix.Access(dir, unix.W_OK|unix.X_OK); err != nil {
+		return false, false
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false, false
+	}
+	if info.Mode()&fs.ModeSticky == 0 {
+		return true, false
+	}
+
+	// In sticky directories (e.g. /tmp), only the file owner, the directory owner, or root may remove entries.
+	euid := uint32(os.Geteuid()) //nolint:gosec
+	if euid == 0 || euid == fileUID {
+		return true, true
+	}
+	dirUID, _, ok := fileOwner(info)
+	return ok && dirUID == euid, true
+}
+
+// lookupUserID resolves a user name to its ID.
+func lookupUserID(name string) (int64, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.ParseInt(u.Uid, 10, 64)
+}
+
+// lookupGroupID resolves a group name to its ID.
+func lookupGroupID(name string) (int64, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.ParseInt(g.Gid, 10, 64)
+}
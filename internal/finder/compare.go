@@ -0,0 +1,222 @@
+package finder
+
+import (
+	"context"
+	"hash"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zeebo/xxh3"
+
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// Sizes of the blocks compared by [compareChunks]. Blocks start small, so that files differing early
+// are dropped after little reading, and grow so that identical files are read in large blocks.
+const (
+	firstBlockSize = 256 * 1024
+	maxBlockSize   = 4 * 1024 * 1024
+)
+
+// comparedFile is a file compared block by block, with the full hash of the blocks read so far.
+type comparedFile struct {
+	file    scanner.FileInfo
+	hasher  hash.Hash
+	read    int64
+	elapsed time.Duration
+
+	// n, key, and err are the number of bytes read, the XXH3 hash, and the error of the last block read.
+	n   int
+	key uint64
+	err error
+}
+
+// compareChunks compares the files of the groups whose files hold at least o.compareSize bytes
+// block by block, with a worker per group. The files of a group are read at the same time, as many
+// as the per-device limits allow for their devices if set, while numWorkers blocks at most are read at a time
+// across groups, so that a single group of large files is not read by a single goroutine.
+// Files are dropped as soon as their blocks differ from those of all other files of their group,
+// so they are only read in full if they have duplicates.
+//
+// It returns the groups left to hash in full, and the files whose blocks all matched those of other files,
+// with their full hashes from newHash, computed along the way.
+// Groups holding files whose hashes are known in advance are left to hash in full, since those files
+// may not be readable.
+func compareChunks(ctx context.Context, groups [][]fileInfoQuickHash, numWorkers int, stats *model.Stats,
+	newHash func() hash.Hash, o *options,
+) ([][]fileInfoQuickHash, []scanner.FileInfo) {
+	var rest, compare [][]fileInfoQuickHash
+	var totalFiles, totalBytes int64
+	for _, files := range groups {
+		if files[0].file.Size < o.compareSize || precomputed(files) {
+			rest = append(rest, files)
+			continue
+		}
+		compare = append(compare, files)
+		totalFiles += int64(len(files))
+		totalBytes += int64(len(files)) * files[0].file.Size
+	}
+	if len(compare) == 0 {
+		return rest, nil
+	}
+
+	stats.StartStage(model.StageCompare, totalFiles, totalBytes)
+
+	// bufs holds a buffer for each block read at the same time, allocated once first needed.
+	bufs := make(chan []byte, max(numWorkers, 1))
+	for range cap(bufs) {
+		bufs <- nil
+	}
+
+	var mu sync.Mutex
+	var identical []scanner.FileInfo
+	wg := runWorkers(ctx, compare, numWorkers, o.perDevice,
+		func(files []fileInfoQuickHash) scanner.FileInfo { return files[0].file },
+		func() func([]fileInfoQuickHash) bool {
+			return func(files []fileInfoQuickHash) bool {
+				found := compareGroup(ctx, files, newHash, stats, o, bufs)
+				mu.Lock()
+				identical = append(identical, found...)
+				mu.Unlock()
+				return ctx.Err() == nil
+			}
+		})
+	wg.Wait()
+	stats.EndStage(model.StageCompare)
+
+	if o.recorder != nil {
+		for _, file := range identical {
+			o.recorder.Hashed(file, file.Hash)
+		}
+	}
+
+	return rest, identical
+}
+
+// compareGroup compares the files of a group block by block, splitting the group whenever blocks differ,
+// and returns the files left in groups at the end of the files, with their full hashes.
+// The blocks at the same offset are read at the same time with the buffers of bufs, as described
+// in [compareChunks]. Each block is read after opening the file again, so that files do not stay open
+// between blocks.
+func compareGroup(ctx context.Context, group []fileInfoQuickHash, newHash func() hash.Hash, stats *model.Stats,
+	o *options, bufs chan []byte,
+) []scanner.FileInfo {
+	size := group[0].file.Size
+	readers := len(group)
+	if o.perDevice != nil {
+		readers = 0
+		fileOf := func(item fileInfoQuickHash) scanner.FileInfo { return item.file }
+		for _, q := range deviceQueues(group, fileOf, o.perDevice) {
+			readers += q.workers
+		}
+	}
+	current := make([]*comparedFile, len(group))
+	for i, item := range group {
+		current[i] = &comparedFile{file: item.file, hasher: newHash()}
+	}
+
+	// done records the end of the comparison of a file.
+	done := func(c *comparedFile) {
+		stats.Advance(model.StageCompare, 1, 0)
+		stats.ObserveHash(model.StageCompare, c.elapsed, c.read)
+	}
+
+	subgroups := [][]*comparedFile{current}
+	blockSize := int64(firstBlockSize)
+	for offset := int64(0); offset < size; {
+		if ctx.Err() != nil {
+			return nil
+		}
+		length := int(min(blockSize, size-offset))
+		readBlocks(ctx, slices.Concat(subgroups...), offset, length, readers, bufs, o)
+
+		var next [][]*comparedFile
+		for _, files := range subgroups {
+			byBlock := make(map[uint64][]*comparedFile)
+			for _, c := range files {
+				stats.Advance(model.StageCompare, 0, int64(c.n))
+				if c.err != nil && ctx.Err() != nil {
+					return nil
+				}
+				if c.err != nil {
+					stats.Advance(model.StageCompare, 1, 0)
+					stats.ReportError(model.StageCompare, c.file.Path, c.err)
+					continue
+				}
+				byBlock[c.key] = append(byBlock[c.key], c)
+			}
+
+			for _, same := range byBlock {
+				if o.reportable(len(same), func(i int) scanner.Role { return same[i].file.Role }) {
+					next = append(next, same)
+					continue
+				}
+				for _, c := range same {
+					done(c)
+				}
+			}
+		}
+
+		subgroups = next
+		if len(subgroups) == 0 {
+			return nil
+		}
+		offset += int64(length)
+		blockSize = min(blockSize*2, maxBlockSize)
+	}
+
+	var identical []scanner.FileInfo
+	for _, files := range subgroups {
+		for _, c := range files {
+			done(c)
+			file := c.file
+			file.Hash = string(c.hasher.Sum(nil))
+			identical = append(identical, file)
+		}
+	}
+	return identical
+}
+
+// readBlocks reads the block of length bytes at offset of each file, readers files at a time, each with a buffer
+// taken from bufs. Each block is added to the full hash of its file, and its XXH3 hash and the bytes read
+// are recorded in the file, or the error met.
+func readBlocks(ctx context.Context, files []*comparedFile, offset int64, length, readers int, bufs chan []byte,
+	o *options,
+) {
+	sem := make(chan struct{}, max(readers, 1))
+	var wg sync.WaitGroup
+	for _, c := range files {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() {
+				<-sem
+			}()
+			buf := <-bufs
+			if buf == nil {
+				buf = make([]byte, maxBlockSize)
+			}
+			defer func() {
+				bufs <- buf
+			}()
+
+			block := buf[:length]
+			start := time.Now()
+			n, err := scanner.ReadFileInfoAt(o.read(ctx, c.file), block, offset)
+			c.elapsed += time.Since(start)
+			c.read += int64(n)
+			if err == nil && n < length {
+				// The file shrank since it was scanned.
+				err = io.ErrUnexpectedEOF
+			}
+			c.n, c.err = n, err
+			if err == nil {
+				_, _ = c.hasher.Write(block)
+				c.key = xxh3.Hash(block)
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,151 @@
+package finder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// TestCompareChunks verifies that files compared block by block get the same full hashes as when hashed
+// in full, that files that cannot be read are reported, and that groups with hashes known in advance,
+// or with smaller files, are left to hash in full.
+func TestCompareChunks(t *testing.T) {
+	const size = 3*firstBlockSize + 100
+	content := bytes.Repeat([]byte("block "), size/6+1)[:size]
+
+	mem := vfs.NewMemFS()
+	for _, name := range []string{"/a", "/b", "/c"} {
+		if err := mem.WriteFile(name, content); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	// /short lost its end since it was scanned.
+	if err := mem.WriteFile("/short", content[:firstBlockSize]); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	item := func(name string, size int64) fileInfoQuickHash {
+		return fileInfoQuickHash{file: scanner.FileInfo{Path: name, Size: size, FS: mem}}
+	}
+	known := item("/c", size)
+	known.file.QuickHashed = true
+	groups := [][]fileInfoQuickHash{
+		{item("/a", size), item("/b", size), item("/short", size), item("/missing", size)},
+		{item("/small1", 10), item("/small2", 10)},
+		{item("/a", size), known},
+	}
+
+	events := &eventRecorder{}
+	s := &model.Stats{Events: events}
+	o := &options{compareSize: 100}
+	rest, identical := compareChunks(context.Background(), groups, 2, s, scanner.NewHasher, o)
+
+	if len(rest) != 2 || rest[0][0].file.Path != "/small1" || rest[1][1].file.Path != "/c" {
+		t.Errorf("compareChunks() left %v to hash in full, want the groups of /small1 and /c", rest)
+	}
+
+	want, err := scanner.HashFileInfo(scanner.FileInfo{Path: "/a", FS: mem}, scanner.NewHasher(), make([]byte, chunkSize))
+	if err != nil {
+		t.Fatalf("HashFileInfo() error = %v", err)
+	}
+	var paths []string
+	for _, file := range identical {
+		paths = append(paths, file.Path)
+		if file.Hash != want {
+			t.Errorf("compareChunks() hash of %s = %x, want %x", file.Path, file.Hash, want)
+		}
+	}
+	if slices.Sort(paths); !slices.Equal(paths, []string{"/a", "/b"}) {
+		t.Errorf("compareChunks() identical files = %v, want [/a /b]", paths)
+	}
+
+	errs := slices.Sorted(slices.Values(events.errors))
+	if wantErrs := []string{"compare /missing", "compare /short"}; !slices.Equal(errs, wantErrs) {
+		t.Errorf("errors = %v, want %v", errs, wantErrs)
+	}
+	if wantStages := []string{"start compare", "end compare"}; !slices.Equal(events.stages, wantStages) {
+		t.Errorf("stages = %v, want %v", events.stages, wantStages)
+	}
+}
+
+// concurrentFS is a filesystem whose reads take a while, recording the most files read at the same time.
+type concurrentFS struct {
+	*vfs.MemFS
+	active, peak atomic.Int32
+}
+
+func (fsys *concurrentFS) Open(name string) (vfs.File, error) {
+	f, err := fsys.MemFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &concurrentFile{File: f, fs: fsys}, nil
+}
+
+type concurrentFile struct {
+	vfs.File
+	fs *concurrentFS
+}
+
+func (f *concurrentFile) ReadAt(p []byte, off int64) (int, error) {
+	n := f.fs.active.Add(1)
+	defer f.fs.active.Add(-1)
+	for {
+		peak := f.fs.peak.Load()
+		if n <= peak || f.fs.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return f.File.ReadAt(p, off)
+}
+
+// TestCompareChunks_Parallel verifies that the files of a single group are read at the same time,
+// as many as the workers allow, and that the per-device limits bound the reads of a group.
+func TestCompareChunks_Parallel(t *testing.T) {
+	const size = firstBlockSize + 100
+	content := bytes.Repeat([]byte("x"), size)
+
+	tests := []struct {
+		name      string
+		workers   int
+		perDevice iosched.Limits
+		want      int32
+	}{
+		{"workers", 3, nil, 3},
+		{"one worker", 1, nil, 1},
+		{"per device", 4, iosched.Limits{iosched.HDD: 1, iosched.SSD: 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &concurrentFS{MemFS: vfs.NewMemFS()}
+			var group []fileInfoQuickHash
+			for i := range 6 {
+				name := fmt.Sprintf("/%d", i)
+				if err := fsys.WriteFile(name, content); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+				group = append(group, fileInfoQuickHash{file: scanner.FileInfo{Path: name, Size: size, FS: fsys}})
+			}
+
+			o := &options{compareSize: 100, perDevice: tt.perDevice}
+			_, identical := compareChunks(context.Background(), [][]fileInfoQuickHash{group}, tt.workers,
+				&model.Stats{}, scanner.NewHasher, o)
+			if len(identical) != 6 {
+				t.Errorf("compareChunks() found %d identical files, want 6", len(identical))
+			}
+			if got := fsys.peak.Load(); got != tt.want {
+				t.Errorf("compareChunks() read %d files at the same time, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,25 +5,22 @@
 //  1. Quick hash: Fast partial XXH3 hashing to eliminate most non-duplicates
 //  2. Full hash: Complete Blake3 hashing for final duplicate confirmation
 //
+// Two optional stages may run in between, for large files that the quick hash does not tell apart:
+// hashing samples at more offsets ([WithSamples]), and comparing files block by block, dropping them
+// as soon as they differ ([WithChunkedCompare]).
+//
 // The package processes files in parallel using configurable worker goroutines and
 // maintains statistics about the duplicate detection process.
 package finder
 
 import (
 	"context"
-	"errors"
-	"fmt"
-	"log/slog"
-	"os"
+	"hash"
 	"slices"
-	"sync"
 	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/zeebo/xxh3"
-	"lukechampine.com/blake3"
 
-	"github.com/dr8co/doppel/internal/logger"
 	"github.com/dr8co/doppel/internal/model"
 	"github.com/dr8co/doppel/internal/scanner"
 )
@@ -35,98 +32,112 @@ const (
 
 // fileInfoQuickHash is a helper struct for quick hashing.
 type fileInfoQuickHash struct {
-	path string
-	size int64
+	file scanner.FileInfo
 	hash uint64
 }
 
 // FindDuplicatesByHash processes files with same sizes and returns a [model.DuplicateReport] directly.
+// The stages, the errors met, and the groups found are reported to the event observer of stats.
+// If ctx is canceled, the files being hashed are finished, and the report holds the groups of the files
+// hashed so far, marked incomplete.
 func FindDuplicatesByHash(ctx context.Context, sizeGroups map[int64][]scanner.FileInfo,
-	numWorkers int, stats *model.Stats, verbose bool) (*model.DuplicateReport, error,
+	numWorkers int, stats *model.Stats, opts ...Option) (*model.DuplicateReport, error,
 ) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	candidateFiles := make([]scanner.FileInfo, 0, len(sizeGroups))
 	for _, files := range sizeGroups {
-		if len(files) > 1 {
+		if o.reportable(len(files), func(i int) scanner.Role { return files[i].Role }) {
+			scanner.UseChecksums(files)
 			candidateFiles = append(candidateFiles, files...)
 		}
 	}
 
 	if len(candidateFiles) < 2 {
-		return &model.DuplicateReport{ScanDate: time.Now(), Stats: stats, Groups: nil}, nil
+		return &model.DuplicateReport{ScanDate: time.Now(), Complete: ctx.Err() == nil, Stats: stats, Groups: nil,
+			Errors: stats.Errors.List()}, nil
 	}
 
 	candidateFiles = slices.Clip(candidateFiles)
 
-	if verbose {
-		fmt.Printf("\n🔐 Multi-stage hashing %d candidate files with %d workers.\n\n", len(candidateFiles), numWorkers)
-	}
-
-	sp := spinner.New(spinner.CharSets[7], 100*time.Millisecond, spinner.WithSuffix(" processing..."))
-	_ = sp.Color("fgHiBlue", "bold")
-	sp.Start()
-
 	// Stage 1: Quick hashing
-	var now time.Time
-	if verbose {
-		fmt.Println("Stage 1: Quick hashing...")
-		now = time.Now()
-	}
-
-	quickHashGroups := quickHash(ctx, candidateFiles, numWorkers, stats)
-	sp.Stop()
+	quickHashGroups := quickHash(ctx, candidateFiles, numWorkers, stats, &o)
 
-	if verbose {
-		elapsed := time.Since(now).Round(time.Millisecond).String()
-		fmt.Printf("Quick hashing took %s.\n\n", elapsed)
-	}
-
-	// Stage 2: Full hashing only for files with matching quick hashes
-	fullHashCandidates := make([]fileInfoQuickHash, 0, len(candidateFiles))
+	candidateGroups := make([][]fileInfoQuickHash, 0, len(quickHashGroups))
 	for _, files := range quickHashGroups {
-		if len(files) > 1 {
-			fullHashCandidates = append(fullHashCandidates, files...)
+		if o.reportable(len(files), func(i int) scanner.Role { return files[i].file.Role }) {
+			candidateGroups = append(candidateGroups, files)
 		}
 	}
 
 	// If no candidates for full hashing, return early
-	if len(fullHashCandidates) < 2 {
-		return &model.DuplicateReport{ScanDate: time.Now(), Stats: stats, Groups: nil}, nil
+	if len(candidateGroups) == 0 {
+		return &model.DuplicateReport{ScanDate: time.Now(), Complete: ctx.Err() == nil, Stats: stats, Groups: nil,
+			Errors: stats.Errors.List()}, nil
 	}
 
-	fullHashCandidates = slices.Clip(fullHashCandidates)
-
-	if verbose {
-		fmt.Printf("Stage 2: Full hashing %d files with potential duplicates...\n", len(fullHashCandidates))
-		now = time.Now()
+	newHash := scanner.NewHasher
+	if o.newHash != nil {
+		newHash = o.newHash
+		for _, files := range candidateGroups {
+			for i := range files {
+				files[i].file.Hash = ""
+			}
+		}
 	}
 
-	sp2 := spinner.New(spinner.CharSets[69], 100*time.Millisecond, spinner.WithSuffix(" almost there..."))
-	_ = sp2.Color("fgHiBlue", "bold")
-	sp2.Start()
-	hashGroups := fullHash(ctx, fullHashCandidates, numWorkers, stats)
-	sp2.Stop()
+	// Optional stages, telling apart large files before reading them in full
+	if o.samples.Enabled() {
+		candidateGroups = sampleHash(ctx, candidateGroups, numWorkers, stats, &o)
+	}
+	var compared []scanner.FileInfo
+	if o.compareSize > 0 {
+		candidateGroups, compared = compareChunks(ctx, candidateGroups, numWorkers, stats, newHash, &o)
+	}
 
-	if verbose {
-		elapsed := time.Since(now).Round(time.Millisecond).String()
-		fmt.Printf("Full hashing took %s.\n", elapsed)
+	// Stage 2: Full hashing of the other files with matching quick hashes and samples
+	hashGroups := make(map[string][]scanner.FileInfo)
+	if fullHashCandidates := slices.Concat(candidateGroups...); len(fullHashCandidates) > 0 {
+		hashGroups = fullHash(ctx, fullHashCandidates, numWorkers, stats, newHash, &o)
+	}
+	for _, file := range compared {
+		hashGroups[file.Hash] = append(hashGroups[file.Hash], file)
 	}
 
 	groups := make([]model.DuplicateGroup, 0, len(hashGroups))
 	totalWasted := uint64(0)
-	groupID := 0
+	groupID := max(o.firstGroupID, 1) - 1
 
 	for _, files := range hashGroups {
-		if len(files) > 1 {
+		if o.reportable(len(files), func(i int) scanner.Role { return files[i].Role }) {
 			groupID++
 			filePaths := make([]string, len(files))
+			var symlinks, references, readOnly []string
+			kept := 0
 
 			for i, fi := range files {
 				filePaths[i] = fi.Path
+				symlinks = append(symlinks, fi.Symlinks...)
+				if fi.Role == scanner.RoleReference {
+					references = append(references, fi.Path)
+				}
+				if fi.ReadOnly {
+					readOnly = append(readOnly, fi.Path)
+				}
+				if fi.Role == scanner.RoleReference || fi.ReadOnly {
+					kept++
+				}
 			}
 
+			// At least one copy is kept, along with every file that cannot be removed.
+			kept = max(kept, 1)
+
 			size := files[0].Size
 			//nolint:gosec
-			wasted := uint64(size) * uint64(len(files)-1)
+			wasted := uint64(size) * uint64(len(files)-kept)
 			totalWasted += wasted
 
 			groups = append(groups, model.DuplicateGroup{
@@ -135,150 +146,159 @@ func FindDuplicatesByHash(ctx context.Context, sizeGroups map[int64][]scanner.Fi
 				Size:        size,
 				WastedSpace: wasted,
 				Files:       filePaths,
+				Symlinks:    symlinks,
+				References:  references,
+				ReadOnly:    readOnly,
 			})
 
 			stats.IncrementDuplicateGroups()
 			stats.AddDuplicateFiles(uint64(len(files)))
+			stats.FoundGroup(&groups[len(groups)-1])
 		}
 	}
 
-	return &model.DuplicateReport{ScanDate: time.Now(), Stats: stats, TotalWastedSpace: totalWasted, Groups: slices.Clip(groups)}, nil
+	return &model.DuplicateReport{
+		ScanDate:         time.Now(),
+		Complete:         ctx.Err() == nil,
+		Stats:            stats,
+		TotalWastedSpace: totalWasted,
+		Groups:           slices.Clip(groups),
+		Errors:           stats.Errors.List(),
+	}, nil
 }
 
 // quickHash performs quick hashing for a list of files using multiple workers and groups files by their quick hashes.
-func quickHash(ctx context.Context, candidateFiles []scanner.FileInfo, numWorkers int, stats *model.Stats) map[uint64][]fileInfoQuickHash {
+func quickHash(ctx context.Context, candidateFiles []scanner.FileInfo, numWorkers int, stats *model.Stats,
+	o *options,
+) map[uint64][]fileInfoQuickHash {
 	if len(candidateFiles) < 2 {
 		return map[uint64][]fileInfoQuickHash{}
 	}
 
-	if numWorkers > len(candidateFiles) {
-		numWorkers = len(candidateFiles)
-	}
-
-	quickWorkChan := make(chan scanner.FileInfo, len(candidateFiles))
-	quickResultChan := make(chan fileInfoQuickHash, len(candidateFiles))
-
-	// Start workers for quick hashing
-	var quickWg sync.WaitGroup
-	for range numWorkers {
-		quickWg.Go(func() {
+	results := hashPool(ctx, candidateFiles, numWorkers, o.perDevice, stats, quickHashStage, identity,
+		func() func(scanner.FileInfo) (fileInfoQuickHash, error) {
 			buf := make([]byte, quickHashSize)
 			hasher := xxh3.New()
-			for item := range quickWorkChan {
-				hash, err := scanner.QuickHashFile(item.Path, item.Size, hasher, buf)
-				if err != nil {
-					logError(ctx, err, "quick-hash", item.Path)
-					stats.IncrementErrorCount()
-					continue
-				}
-				select {
-				case quickResultChan <- fileInfoQuickHash{path: item.Path, size: item.Size, hash: hash}:
-				case <-ctx.Done():
-					return
-				}
+			return func(file scanner.FileInfo) (fileInfoQuickHash, error) {
+				hash, err := scanner.QuickHashFileInfo(o.read(ctx, file), hasher, buf)
+				return fileInfoQuickHash{file: file, hash: hash}, err
 			}
 		})
-	}
-
-	// Send work for quick hashing
-	go func() {
-		defer close(quickWorkChan)
-		for _, file := range candidateFiles {
-			select {
-			case quickWorkChan <- file:
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-
-	// Wait for quick hashing workers to finish
-	go func() {
-		quickWg.Wait()
-		close(quickResultChan)
-	}()
 
 	// Collect quick hash results and group by quick hash
 	quickHashGroups := make(map[uint64][]fileInfoQuickHash, len(candidateFiles))
-	for result := range quickResultChan {
+	for result := range results {
 		quickHashGroups[result.hash] = append(quickHashGroups[result.hash], result)
 		stats.IncrementProcessedFiles()
+		if o.recorder != nil && !result.file.QuickHashed {
+			o.recorder.QuickHashed(result.file, result.hash)
+		}
 	}
 
 	return quickHashGroups
 }
 
-// fullHash performs full hashing for candidates, groups by hash.
-func fullHash(ctx context.Context, fullHashCandidates []fileInfoQuickHash, numWorkers int, stats *model.Stats) map[string][]scanner.FileInfo {
-	if numWorkers > len(fullHashCandidates) {
-		numWorkers = len(fullHashCandidates)
+// sampleHash hashes samples of the files of each group, and splits the groups by the hashes of their samples.
+// Groups holding files whose hashes are known in advance are kept as they are, since those files may not be
+// readable, and so are the files too small to be sampled.
+func sampleHash(ctx context.Context, groups [][]fileInfoQuickHash, numWorkers int, stats *model.Stats,
+	o *options,
+) [][]fileInfoQuickHash {
+	type sampled struct {
+		group int
+		item  fileInfoQuickHash
+		hash  uint64
 	}
 
-	// Create channels for full hashing
-	fullWorkChan := make(chan fileInfoQuickHash, len(fullHashCandidates))
-	fullResultChan := make(chan scanner.FileInfo, len(fullHashCandidates))
-
-	// Start workers for full hashing
-	var fullWg sync.WaitGroup
-	for range numWorkers {
-		fullWg.Go(func() {
-			hasher := blake3.New(32, nil)
-			buf := make([]byte, chunkSize)
-			for item := range fullWorkChan {
-				hash, err := scanner.HashFile(item.path, hasher, buf)
-				if err != nil {
-					logError(ctx, err, "full-hash", item.path)
-					stats.IncrementErrorCount()
-					continue
-				}
+	var items []sampled
+	kept := make([][]fileInfoQuickHash, 0, len(groups))
+	for i, files := range groups {
+		if precomputed(files) {
+			kept = append(kept, files)
+			continue
+		}
+		for _, item := range files {
+			items = append(items, sampled{group: i, item: item})
+		}
+	}
+	if len(items) == 0 {
+		return groups
+	}
 
-				select {
-				case fullResultChan <- scanner.FileInfo{Path: item.path, Size: item.size, Hash: hash}:
-				case <-ctx.Done():
-					return
-				}
+	sampleStage := stage{name: model.StageSample, bytesRead: func(file scanner.FileInfo) int64 {
+		if n := len(o.samples.Offsets(file.Size)); n > 0 {
+			return int64(n) * quickHashSize
+		}
+		return -1
+	}}
+	results := hashPool(ctx, items, numWorkers, o.perDevice, stats, sampleStage,
+		func(s sampled) scanner.FileInfo { return s.item.file },
+		func() func(sampled) (sampled, error) {
+			buf := make([]byte, quickHashSize)
+			hasher := xxh3.New()
+			return func(s sampled) (sampled, error) {
+				var err error
+				offsets := o.samples.Offsets(s.item.file.Size)
+				s.hash, err = scanner.SampleHashFileInfo(o.read(ctx, s.item.file), offsets, hasher, buf)
+				return s, err
 			}
 		})
-	}
 
-	// Send work for full hashing
-	go func() {
-		defer close(fullWorkChan)
-		for _, file := range fullHashCandidates {
-			select {
-			case fullWorkChan <- file:
-			case <-ctx.Done():
-				return
-			}
+	type key struct {
+		group int
+		hash  uint64
+	}
+	split := make(map[key][]fileInfoQuickHash)
+	for result := range results {
+		k := key{group: result.group, hash: result.hash}
+		split[k] = append(split[k], result.item)
+	}
+	for _, files := range split {
+		if o.reportable(len(files), func(i int) scanner.Role { return files[i].file.Role }) {
+			kept = append(kept, files)
 		}
-	}()
+	}
+	return kept
+}
+
+// precomputed reports whether any of files has a hash known in advance.
+func precomputed(files []fileInfoQuickHash) bool {
+	return slices.ContainsFunc(files, func(item fileInfoQuickHash) bool {
+		return item.file.QuickHashed || item.file.Hash != ""
+	})
+}
 
-	// Wait for full hashing workers to finish
-	go func() {
-		fullWg.Wait()
-		close(fullResultChan)
-	}()
+// hashedFile is a file with its full hash. known is set if the hash was known in advance.
+type hashedFile struct {
+	file  scanner.FileInfo
+	known bool
+}
+
+// fullHash performs full hashing for candidates with hashers from newHash, groups by hash.
+func fullHash(ctx context.Context, fullHashCandidates []fileInfoQuickHash, numWorkers int, stats *model.Stats,
+	newHash func() hash.Hash, o *options,
+) map[string][]scanner.FileInfo {
+	results := hashPool(ctx, fullHashCandidates, numWorkers, o.perDevice, stats, fullHashStage,
+		func(item fileInfoQuickHash) scanner.FileInfo { return item.file },
+		func() func(fileInfoQuickHash) (hashedFile, error) {
+			hasher := newHash()
+			buf := make([]byte, chunkSize)
+			return func(item fileInfoQuickHash) (hashedFile, error) {
+				hash, err := scanner.HashFileInfo(o.read(ctx, item.file), hasher, buf)
+				result := hashedFile{file: item.file, known: item.file.Hash != ""}
+				result.file.Hash = hash
+				return result, err
+			}
+		})
 
 	// Collect results and group by full hash
 	hashGroups := make(map[string][]scanner.FileInfo)
-	for result := range fullResultChan {
-		hashGroups[result.Hash] = append(hashGroups[result.Hash], result)
+	for result := range results {
+		hashGroups[result.file.Hash] = append(hashGroups[result.file.Hash], result.file)
+		if o.recorder != nil && !result.known {
+			o.recorder.Hashed(result.file, result.file.Hash)
+		}
 	}
 
 	return hashGroups
 }
-
-// logError logs errors encountered during file processing.
-func logError(ctx context.Context, err error, action, filePath string) {
-	if errors.Is(err, os.ErrNotExist) {
-		logger.ErrorAttrs(ctx, "file (likely) removed after the scan but before hashing", slog.String("path", filePath), slog.String("err", err.Error()))
-		return
-	}
-	var filepathErr *os.PathError
-
-	if errors.As(err, &filepathErr) {
-		logger.ErrorAttrs(ctx, "failed to "+action+" a file", slog.String("path", filepathErr.Path), slog.String("op", filepathErr.Op), slog.String("err", filepathErr.Err.Error()))
-	} else {
-		logger.ErrorAttrs(ctx, "failed to "+action+" a file", slog.String("path", filePath), slog.String("err", err.Error()))
-	}
-}
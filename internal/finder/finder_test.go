@@ -1,14 +1,29 @@
 package finder
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/synctest"
+	"time"
 
+	"github.com/dr8co/doppel/internal/filter"
 	"github.com/dr8co/doppel/internal/model"
 	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
 )
 
 // TestFindDuplicatesByHash tests the functionality of finding duplicate files by hashing their contents.
@@ -77,7 +92,7 @@ func TestFindDuplicatesByHash(t *testing.T) {
 		ctx := context.Background()
 
 		// Call the function being tested
-		report, err := FindDuplicatesByHash(ctx, sizeGroups, 2, s, false)
+		report, err := FindDuplicatesByHash(ctx, sizeGroups, 2, s)
 		if err != nil {
 			t.Fatalf("FindDuplicatesByHash() error = %v", err)
 		}
@@ -136,7 +151,7 @@ func TestFindDuplicatesByHash(t *testing.T) {
 			int64(len(content2)): {scanner.FileInfo{Path: file4}},
 		}
 
-		report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s, false)
+		report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s)
 		if err != nil {
 			t.Fatalf("FindDuplicatesByHash() error = %v", err)
 		}
@@ -149,7 +164,7 @@ func TestFindDuplicatesByHash(t *testing.T) {
 			int64(len(content1)): {scanner.FileInfo{Path: file1}, scanner.FileInfo{Path: file2}, scanner.FileInfo{Path: file3}},
 		}
 
-		report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s, false)
+		report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s)
 		if err != nil {
 			t.Fatalf("FindDuplicatesByHash() error = %v", err)
 		}
@@ -161,7 +176,7 @@ func TestFindDuplicatesByHash(t *testing.T) {
 		sizeGroups = map[int64][]scanner.FileInfo{
 			int64(len(content1)): {scanner.FileInfo{Path: file1}},
 		}
-		report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s, false)
+		report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s)
 		if err != nil {
 			t.Fatalf("FindDuplicatesByHash() error = %v", err)
 		}
@@ -170,7 +185,7 @@ func TestFindDuplicatesByHash(t *testing.T) {
 		}
 
 		// Test with empty input
-		report, err = FindDuplicatesByHash(ctx, map[int64][]scanner.FileInfo{}, 2, s, false)
+		report, err = FindDuplicatesByHash(ctx, map[int64][]scanner.FileInfo{}, 2, s)
 		if err != nil {
 			t.Fatalf("FindDuplicatesByHash() error = %v", err)
 		}
@@ -181,6 +196,62 @@ func TestFindDuplicatesByHash(t *testing.T) {
 	})
 }
 
+// TestFindDuplicatesByHash_ReferenceMode verifies that only groups mixing reference and other files are reported,
+// and that reference files are not counted as wasted space.
+func TestFindDuplicatesByHash_ReferenceMode(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		tempDir := t.TempDir()
+		content1 := []byte("content kept in the reference tree")
+		content2 := []byte("content only found in the scanned tree")
+
+		write := func(name string, content []byte, role scanner.Role) scanner.FileInfo {
+			path := filepath.Join(tempDir, name)
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+			return scanner.FileInfo{Path: path, Size: int64(len(content)), Role: role}
+		}
+
+		ref := write("ref.txt", content1, scanner.RoleReference)
+		copy1 := write("copy1.txt", content1, scanner.RoleScan)
+		copy2 := write("copy2.txt", content1, scanner.RoleScan)
+		other1 := write("other1.txt", content2, scanner.RoleScan)
+		other2 := write("other2.txt", content2, scanner.RoleScan)
+
+		sizeGroups := map[int64][]scanner.FileInfo{
+			ref.Size:    {ref, copy1, copy2},
+			other1.Size: {other1, other2},
+		}
+
+		s := &model.Stats{}
+		report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, s, WithReferenceMode(true))
+		if err != nil {
+			t.Fatalf("FindDuplicatesByHash() error = %v", err)
+		}
+		if len(report.Groups) != 1 {
+			t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 1", len(report.Groups))
+		}
+
+		group := report.Groups[0]
+		if !containsAll(group.Files, []string{ref.Path, copy1.Path, copy2.Path}) {
+			t.Errorf("Duplicate group missing expected files: %v", group.Files)
+		}
+		if len(group.References) != 1 || group.References[0] != ref.Path {
+			t.Errorf("DuplicateGroup.References = %v, want [%s]", group.References, ref.Path)
+		}
+		//nolint:gosec
+		if want := uint64(ref.Size) * 2; group.WastedSpace != want {
+			t.Errorf("DuplicateGroup.WastedSpace = %d, want %d", group.WastedSpace, want)
+		}
+
+		// The scanned-only size group is never hashed.
+		if s.ProcessedFiles != 3 {
+			t.Errorf("Stats.ProcessedFiles = %d, want 3", s.ProcessedFiles)
+		}
+		synctest.Wait()
+	})
+}
+
 // A helper function to check if a slice contains all expected elements.
 func containsAll(slice, expected []string) bool {
 	if len(slice) != len(expected) {
@@ -202,3 +273,565 @@ func containsAll(slice, expected []string) bool {
 
 	return true
 }
+
+// TestFindDuplicatesByHash_Archives verifies that archive members are grouped with identical regular files
+// and are reported as read-only.
+func TestFindDuplicatesByHash_Archives(t *testing.T) {
+	tempDir := t.TempDir()
+	content := bytes.Repeat([]byte("archived dataset row\n"), 2000)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "data.csv"), content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// The same content stored in a zip archive and in a compressed tar archive
+	zf, err := os.Create(filepath.Join(tempDir, "data.zip"))
+	if err != nil {
+		t.Fatalf("Failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(zf)
+	w, err := zw.Create("export/data.csv")
+	if err != nil {
+		t.Fatalf("Failed to add zip member: %v", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Failed to write zip member: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	_ = zf.Close()
+
+	tf, err := os.Create(filepath.Join(tempDir, "data.tgz"))
+	if err != nil {
+		t.Fatalf("Failed to create tar: %v", err)
+	}
+	gw := gzip.NewWriter(tf)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "data.csv", Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatalf("Failed to add tar member: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("Failed to write tar member: %v", err)
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	_ = tf.Close()
+
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupFilesBySize(context.Background(), []string{tempDir}, &filter.Config{}, s,
+		scanner.WithScanArchives(true))
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+
+	report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, s)
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 1", len(report.Groups))
+	}
+
+	group := report.Groups[0]
+	zipMember := filepath.Join(tempDir, "data.zip") + "!/export/data.csv"
+	tarMember := filepath.Join(tempDir, "data.tgz") + "!/data.csv"
+	if !containsAll(group.Files, []string{filepath.Join(tempDir, "data.csv"), zipMember, tarMember}) {
+		t.Errorf("Duplicate group missing expected files: %v", group.Files)
+	}
+	if !containsAll(group.ReadOnly, []string{zipMember, tarMember}) || len(group.ReadOnly) != 2 {
+		t.Errorf("DuplicateGroup.ReadOnly = %v, want the archive members", group.ReadOnly)
+	}
+	// Only the regular file can be removed
+	if want := uint64(len(content)); group.WastedSpace != want {
+		t.Errorf("DuplicateGroup.WastedSpace = %d, want %d", group.WastedSpace, want)
+	}
+}
+
+// TestFindDuplicatesByHash_MemFS verifies that files on a filesystem other than the OS are scanned and hashed
+// through that filesystem, including archives stored on it.
+func TestFindDuplicatesByHash_MemFS(t *testing.T) {
+	content := bytes.Repeat([]byte("in-memory content "), 1500)
+
+	var zipData bytes.Buffer
+	zw := zip.NewWriter(&zipData)
+	w, err := zw.Create("inner/copy.bin")
+	if err != nil {
+		t.Fatalf("Failed to add zip member: %v", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Failed to write zip member: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+
+	mem := vfs.NewMemFS()
+	files := map[string][]byte{
+		"/data/a.bin":      content,
+		"/data/sub/b.bin":  content,
+		"/data/c.bin":      bytes.ToUpper(content),
+		"/data/bundle.zip": zipData.Bytes(),
+	}
+	for name, data := range files {
+		if err := mem.WriteFile(name, data); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupFilesBySize(context.Background(), []string{"/data"}, &filter.Config{}, s,
+		scanner.WithFS(mem), scanner.WithScanArchives(true))
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+	if s.TotalFiles != 5 {
+		t.Errorf("Stats.TotalFiles = %d, want 5", s.TotalFiles)
+	}
+
+	report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, s)
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 1", len(report.Groups))
+	}
+	want := []string{"/data/a.bin", "/data/sub/b.bin", "/data/bundle.zip!/inner/copy.bin"}
+	if group := report.Groups[0]; group.Count != len(want) || !containsAll(group.Files, want) {
+		t.Errorf("Duplicate group files = %v, want %v", group.Files, want)
+	}
+}
+
+// TestFindDuplicatesByHash_Spilled verifies that duplicates are found in size groups spilled to disk
+// and hashed a batch at a time, on the filesystems they were found on, with groups numbered across batches.
+func TestFindDuplicatesByHash_Spilled(t *testing.T) {
+	content := bytes.Repeat([]byte("spilled content "), 1000)
+
+	var zipData bytes.Buffer
+	zw := zip.NewWriter(&zipData)
+	w, err := zw.Create("copy.bin")
+	if err != nil {
+		t.Fatalf("Failed to add zip member: %v", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Failed to write zip member: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+
+	mem := vfs.NewMemFS()
+	files := map[string][]byte{
+		"/data/a.bin":      content,
+		"/data/sub/b.bin":  content,
+		"/data/small1":     []byte("small"),
+		"/data/small2":     []byte("small"),
+		"/data/unique":     []byte("unique"),
+		"/data/bundle.zip": zipData.Bytes(),
+	}
+	for name, data := range files {
+		if err := mem.WriteFile(name, data); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	s := &model.Stats{}
+	index, err := scanner.IndexRootsBySize(context.Background(), []scanner.Root{{Path: "/data"}}, &filter.Config{}, s,
+		scanner.WithFS(mem), scanner.WithScanArchives(true), scanner.WithSpill(t.TempDir(), 1))
+	if err != nil {
+		t.Fatalf("IndexRootsBySize() error = %v", err)
+	}
+	defer func() {
+		_ = index.Close()
+	}()
+
+	var groups []model.DuplicateGroup
+	for batch := range index.Batches(1, 2) {
+		report, err := FindDuplicatesByHash(context.Background(), batch, 2, s, WithFirstGroupID(len(groups)+1))
+		if err != nil {
+			t.Fatalf("FindDuplicatesByHash() error = %v", err)
+		}
+		groups = append(groups, report.Groups...)
+	}
+	if err := index.Err(); err != nil {
+		t.Fatalf("Batches() error = %v", err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 2", len(groups))
+	}
+	want := [][]string{{"/data/small1", "/data/small2"}, {"/data/a.bin", "/data/sub/b.bin", "/data/bundle.zip!/copy.bin"}}
+	for i, group := range groups {
+		if group.ID != i+1 || group.Count != len(want[i]) || !containsAll(group.Files, want[i]) {
+			t.Errorf("Duplicate group %d = %d: %v, want %d: %v", i, group.ID, group.Files, i+1, want[i])
+		}
+	}
+}
+
+// hashLog records the hashes computed by [FindDuplicatesByHash].
+type hashLog struct {
+	quick map[string]uint64
+	full  map[string]string
+}
+
+func (l *hashLog) QuickHashed(file scanner.FileInfo, hash uint64) { l.quick[file.Path] = hash }
+func (l *hashLog) Hashed(file scanner.FileInfo, hash string)      { l.full[file.Path] = hash }
+
+// TestFindDuplicatesByHash_Recorder verifies that the hashes computed are recorded, including those
+// computed while comparing files block by block, and that files given the hashes recorded are not read again.
+func TestFindDuplicatesByHash_Recorder(t *testing.T) {
+	content := bytes.Repeat([]byte("recorded "), 1000)
+	changed := slices.Clone(content)
+	changed[len(changed)-1] = '!'
+
+	mem := vfs.NewMemFS()
+	for name, data := range map[string][]byte{"/a": content, "/b": content, "/c": changed, "/d": []byte("d")} {
+		if err := mem.WriteFile(name, data); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	sizeGroups := func(fsys vfs.FS) map[int64][]scanner.FileInfo {
+		size := int64(len(content))
+		return map[int64][]scanner.FileInfo{
+			size: {{Path: "/a", Size: size, FS: fsys}, {Path: "/b", Size: size, FS: fsys}, {Path: "/c", Size: size, FS: fsys}},
+			1:    {{Path: "/d", Size: 1, FS: fsys}},
+		}
+	}
+
+	log := &hashLog{quick: make(map[string]uint64), full: make(map[string]string)}
+	report, err := FindDuplicatesByHash(context.Background(), sizeGroups(mem), 2, &model.Stats{},
+		WithChunkedCompare(1), WithRecorder(log))
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if len(report.Groups) != 1 || report.Groups[0].Count != 2 {
+		t.Fatalf("FindDuplicatesByHash() = %v, want a group of /a and /b", report.Groups)
+	}
+	if len(log.quick) != 3 {
+		t.Errorf("Quick hashes recorded = %v, want those of /a, /b, and /c", log.quick)
+	}
+	if _, ok := log.full["/c"]; len(log.full) != 2 || ok {
+		t.Errorf("Full hashes recorded for %d files, want those of /a and /b", len(log.full))
+	}
+
+	// /a and /b are gone: their hashes are all that is left of them.
+	rest := vfs.NewMemFS()
+	if err := rest.WriteFile("/c", changed); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	groups := sizeGroups(rest)
+	for i, file := range groups[int64(len(content))] {
+		file.QuickHash, file.QuickHashed = log.quick[file.Path], true
+		file.Hash = log.full[file.Path]
+		groups[int64(len(content))][i] = file
+	}
+
+	again := &hashLog{quick: make(map[string]uint64), full: make(map[string]string)}
+	s := &model.Stats{}
+	resumed, err := FindDuplicatesByHash(context.Background(), groups, 2, s, WithChunkedCompare(1), WithRecorder(again))
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if s.ErrorCount != 0 {
+		t.Errorf("FindDuplicatesByHash() met %d errors, want the files hashed not to be read again", s.ErrorCount)
+	}
+	if len(resumed.Groups) != 1 || !containsAll(resumed.Groups[0].Files, []string{"/a", "/b"}) {
+		t.Errorf("FindDuplicatesByHash() = %v, want a group of /a and /b", resumed.Groups)
+	}
+	if len(again.quick) != 0 || len(again.full) != 1 {
+		t.Errorf("Hashes recorded again = %v, %v, want the full hash of /c alone", again.quick, again.full)
+	}
+}
+
+// cancelOnHash cancels a scan once the first file is hashed in full.
+type cancelOnHash struct {
+	cancel context.CancelFunc
+}
+
+func (c cancelOnHash) QuickHashed(scanner.FileInfo, uint64) {}
+func (c cancelOnHash) Hashed(scanner.FileInfo, string)      { c.cancel() }
+
+// TestFindDuplicatesByHash_Canceled verifies that a scan canceled while hashing reports the groups
+// confirmed so far, marked incomplete, without counting the files left as errors.
+func TestFindDuplicatesByHash_Canceled(t *testing.T) {
+	mem := vfs.NewMemFS()
+	sizeGroups := make(map[int64][]scanner.FileInfo)
+	for i := range 20 {
+		data := bytes.Repeat([]byte{byte('a' + i)}, 100+i)
+		for j := range 3 {
+			path := fmt.Sprintf("/%d/%d", i, j)
+			if err := mem.WriteFile(path, data); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			size := int64(len(data))
+			sizeGroups[size] = append(sizeGroups[size], scanner.FileInfo{Path: path, Size: size, FS: mem})
+		}
+	}
+
+	report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, &model.Stats{})
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if !report.Complete || len(report.Groups) != 20 {
+		t.Fatalf("FindDuplicatesByHash() = %d groups, complete %v, want 20 groups, complete", len(report.Groups),
+			report.Complete)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &model.Stats{}
+	report, err = FindDuplicatesByHash(ctx, sizeGroups, 2, s, WithRecorder(cancelOnHash{cancel: cancel}))
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if report.Complete {
+		t.Error("FindDuplicatesByHash() complete = true, want the report of a canceled scan incomplete")
+	}
+	if s.ErrorCount != 0 {
+		t.Errorf("FindDuplicatesByHash() met %d errors, want none", s.ErrorCount)
+	}
+	for _, group := range report.Groups {
+		dir := path.Dir(group.Files[0])
+		for _, file := range group.Files {
+			if path.Dir(file) != dir {
+				t.Errorf("Group %v holds files of different contents", group.Files)
+				break
+			}
+		}
+	}
+}
+
+// TestFindDuplicatesByHash_Hasher verifies that full hashes are computed with the given hasher,
+// ignoring the hashes known in advance.
+func TestFindDuplicatesByHash_Hasher(t *testing.T) {
+	mem := vfs.NewMemFS()
+	for name, data := range map[string]string{"/a": "same", "/b": "same", "/c": "diff"} {
+		if err := mem.WriteFile(name, []byte(data)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	sizeGroups := map[int64][]scanner.FileInfo{
+		4: {
+			{Path: "/a", Size: 4, FS: mem},
+			{Path: "/b", Size: 4, FS: mem, QuickHashed: true, QuickHash: 1, Hash: "stale"},
+			{Path: "/c", Size: 4, FS: mem, QuickHashed: true, QuickHash: 1, Hash: "stale"},
+		},
+	}
+	// Give /a the same quick hash as the others, so that every file reaches the full hash stage.
+	sizeGroups[4][0].QuickHashed, sizeGroups[4][0].QuickHash = true, 1
+
+	var calls atomic.Int64
+	newHash := func() hash.Hash {
+		calls.Add(1)
+		return sha256.New()
+	}
+
+	report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, &model.Stats{}, WithHasher(newHash))
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if calls.Load() == 0 {
+		t.Error("WithHasher() hasher was never used")
+	}
+	if len(report.Groups) != 1 || !containsAll(report.Groups[0].Files, []string{"/a", "/b"}) || report.Groups[0].Count != 2 {
+		t.Errorf("FindDuplicatesByHash() groups = %+v, want /a and /b", report.Groups)
+	}
+}
+
+// eventRecorder records the errors and groups reported as events.
+type eventRecorder struct {
+	mu     sync.Mutex
+	stages []string
+	errors []string
+	groups []*model.DuplicateGroup
+}
+
+// StageStarted implements [model.EventObserver].
+func (r *eventRecorder) StageStarted(stage string, _, _ int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stages = append(r.stages, "start "+stage)
+}
+
+// StageFinished implements [model.EventObserver].
+func (r *eventRecorder) StageFinished(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stages = append(r.stages, "end "+stage)
+}
+
+// FileSkipped implements [model.EventObserver].
+func (r *eventRecorder) FileSkipped(string, string, string) {}
+
+// Error implements [model.EventObserver].
+func (r *eventRecorder) Error(stage, path string, _ error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, stage+" "+path)
+}
+
+// GroupFound implements [model.EventObserver].
+func (r *eventRecorder) GroupFound(group *model.DuplicateGroup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups = append(r.groups, group)
+}
+
+// TestFindDuplicatesByHash_Events verifies that the stages, the files that cannot be read,
+// and the groups found are reported as events, and that the files that cannot be read are listed in the report.
+func TestFindDuplicatesByHash_Events(t *testing.T) {
+	mem := vfs.NewMemFS()
+	for _, name := range []string{"/a", "/b"} {
+		if err := mem.WriteFile(name, []byte("same")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	sizeGroups := map[int64][]scanner.FileInfo{4: {
+		{Path: "/a", Size: 4, FS: mem},
+		{Path: "/b", Size: 4, FS: mem},
+		{Path: "/gone", Size: 4, FS: mem},
+	}}
+
+	r := &eventRecorder{}
+	s := &model.Stats{Events: r, Errors: &model.ErrorLog{}}
+	report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, s)
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+
+	wantStages := []string{"start quick-hash", "end quick-hash", "start full-hash", "end full-hash"}
+	if !slices.Equal(r.stages, wantStages) {
+		t.Errorf("stages = %v, want %v", r.stages, wantStages)
+	}
+	if want := []string{"quick-hash /gone"}; !slices.Equal(r.errors, want) {
+		t.Errorf("errors = %v, want %v", r.errors, want)
+	}
+	if s.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1", s.ErrorCount)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Path != "/gone" || report.Errors[0].Kind != model.ErrorNotFound {
+		t.Errorf("Errors = %+v, want /gone not found", report.Errors)
+	}
+	if len(r.groups) != 1 || len(report.Groups) != 1 || r.groups[0].ID != report.Groups[0].ID {
+		t.Errorf("groups found = %v, want the group of the report %v", r.groups, report.Groups)
+	}
+}
+
+// hashRecorder records the bytes read from each file in each stage, as reported to the hash observer.
+type hashRecorder struct {
+	mu    sync.Mutex
+	reads map[string][]int64
+}
+
+// ObserveHash implements [model.HashObserver].
+func (r *hashRecorder) ObserveHash(stage string, _ time.Duration, bytesRead int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads[stage] = append(r.reads[stage], bytesRead)
+}
+
+// TestFindDuplicatesByHash_Stages verifies that the optional stages find the same duplicates,
+// while reading less of the large files whose first and last bytes are the same but which differ elsewhere.
+func TestFindDuplicatesByHash_Stages(t *testing.T) {
+	const size = 1<<20 + 12345
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i * 7 / 5)
+	}
+	changed := func(offset int) []byte {
+		data := slices.Clone(content)
+		data[offset]++
+		return data
+	}
+
+	mem := vfs.NewMemFS()
+	files := map[string][]byte{
+		"/same1":  content,
+		"/same2":  content,
+		"/middle": changed(size / 2),
+		"/late":   changed(size - 20000),
+	}
+	var sizeGroup []scanner.FileInfo
+	for name, data := range files {
+		if err := mem.WriteFile(name, data); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		sizeGroup = append(sizeGroup, scanner.FileInfo{Path: name, Size: size, FS: mem})
+	}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantStages []string
+		// wantReads holds the bytes read from each file in the stage reading the most.
+		wantStage string
+		wantReads []int64
+	}{
+		{
+			name:       "quick and full hashes",
+			wantStages: []string{"quick-hash", "full-hash"},
+			wantStage:  "full-hash",
+			wantReads:  []int64{size, size, size, size},
+		},
+		{
+			name:       "middle sample",
+			opts:       []Option{WithSamples(scanner.Samples{Middle: true})},
+			wantStages: []string{"quick-hash", "sample", "full-hash"},
+			wantStage:  "full-hash",
+			wantReads:  []int64{size, size, size},
+		},
+		{
+			name:       "chunked comparison",
+			opts:       []Option{WithChunkedCompare(1 << 20)},
+			wantStages: []string{"quick-hash", "compare"},
+			wantStage:  "compare",
+			// Blocks of 256 KiB, 512 KiB, then the rest: the middle differs in the second block.
+			wantReads: []int64{768 << 10, size, size, size},
+		},
+		{
+			name:       "samples and chunked comparison",
+			opts:       []Option{WithSamples(scanner.Samples{Middle: true, Random: 2}), WithChunkedCompare(1 << 20)},
+			wantStages: []string{"quick-hash", "sample", "compare"},
+			wantStage:  "compare",
+			wantReads:  []int64{size, size, size},
+		},
+		{
+			name:       "files smaller than the chunked comparison",
+			opts:       []Option{WithChunkedCompare(2 << 20)},
+			wantStages: []string{"quick-hash", "full-hash"},
+			wantStage:  "full-hash",
+			wantReads:  []int64{size, size, size, size},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &eventRecorder{}
+			hashes := &hashRecorder{reads: make(map[string][]int64)}
+			s := &model.Stats{Events: events, Observer: hashes}
+			sizeGroups := map[int64][]scanner.FileInfo{size: slices.Clone(sizeGroup)}
+
+			report, err := FindDuplicatesByHash(context.Background(), sizeGroups, 2, s, tt.opts...)
+			if err != nil {
+				t.Fatalf("FindDuplicatesByHash() error = %v", err)
+			}
+			if len(report.Groups) != 1 || !containsAll(report.Groups[0].Files, []string{"/same1", "/same2"}) ||
+				report.Groups[0].Count != 2 {
+				t.Errorf("FindDuplicatesByHash() groups = %+v, want /same1 and /same2", report.Groups)
+			}
+
+			var stages []string
+			for _, event := range events.stages {
+				if stage, ok := strings.CutPrefix(event, "start "); ok {
+					stages = append(stages, stage)
+				}
+			}
+			if !slices.Equal(stages, tt.wantStages) {
+				t.Errorf("stages = %v, want %v", stages, tt.wantStages)
+			}
+			if got := slices.Sorted(slices.Values(hashes.reads[tt.wantStage])); !slices.Equal(got, tt.wantReads) {
+				t.Errorf("bytes read in %s = %v, want %v", tt.wantStage, got, tt.wantReads)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package finder
+
+import (
+	"context"
+	"hash"
+
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// Option configures [FindDuplicatesByHash], [HashFiles], and [ChecksumFiles].
+type Option func(*options)
+
+// options holds the optional settings of [FindDuplicatesByHash], [HashFiles], and [ChecksumFiles].
+type options struct {
+	referenceMode bool
+	newHash       func() hash.Hash
+	perDevice     iosched.Limits
+	throttle      *iosched.Throttle
+	samples       scanner.Samples
+	compareSize   int64
+	firstGroupID  int
+	recorder      Recorder
+}
+
+// Recorder records the hashes computed by [FindDuplicatesByHash], such as to resume an interrupted scan
+// without reading the files hashed again. Its methods are called from a single goroutine at a time.
+type Recorder interface {
+	// QuickHashed records the quick hash of a file.
+	QuickHashed(file scanner.FileInfo, hash uint64)
+
+	// Hashed records the full hash of a file.
+	Hashed(file scanner.FileInfo, hash string)
+}
+
+// WithReferenceMode only reports groups containing both reference files and files that may be removed.
+// Reference files are never counted as wasted space.
+func WithReferenceMode(enabled bool) Option {
+	return func(o *options) {
+		o.referenceMode = enabled
+	}
+}
+
+// WithHasher computes the full hashes with hashers from newHash instead of BLAKE3.
+// Full hashes known in advance, such as those of archive members, are then ignored and the files read again,
+// so files only known from manifests cannot be matched.
+func WithHasher(newHash func() hash.Hash) Option {
+	return func(o *options) {
+		o.newHash = newHash
+	}
+}
+
+// WithIOPerDevice reads the files of each device with workers of their own, as many as limits allows
+// for its kind, instead of sharing the given number of workers across devices.
+// The files on rotational disks are read in the order they are laid out on the disk.
+func WithIOPerDevice(limits iosched.Limits) Option {
+	return func(o *options) {
+		o.perDevice = limits
+	}
+}
+
+// WithThrottle reads the files through throttle, which limits the read rate and the number of open files
+// across all workers and stages sharing it.
+func WithThrottle(throttle *iosched.Throttle) Option {
+	return func(o *options) {
+		o.throttle = throttle
+	}
+}
+
+// WithSamples hashes samples of the files with the same quick hash, in a stage of its own,
+// before reading them in full. Files whose samples differ from all others are never read in full.
+func WithSamples(samples scanner.Samples) Option {
+	return func(o *options) {
+		o.samples = samples
+	}
+}
+
+// WithChunkedCompare compares the files of at least minSize bytes with the same samples block by block,
+// in a stage of its own, instead of hashing each of them in full: files stop being read as soon as
+// their blocks differ from those of all other files. The files of a group are read at the same time,
+// up to the number of workers and the per-device limits. Zero turns it off.
+func WithChunkedCompare(minSize int64) Option {
+	return func(o *options) {
+		o.compareSize = minSize
+	}
+}
+
+// WithFirstGroupID numbers the groups found from id instead of 1, to number groups across
+// the reports of several batches of size groups.
+func WithFirstGroupID(id int) Option {
+	return func(o *options) {
+		o.firstGroupID = id
+	}
+}
+
+// WithRecorder records the hashes computed, leaving out those known in advance, to recorder.
+func WithRecorder(recorder Recorder) Option {
+	return func(o *options) {
+		o.recorder = recorder
+	}
+}
+
+// read returns file with its filesystem throttled, for a worker to read it.
+func (o *options) read(ctx context.Context, file scanner.FileInfo) scanner.FileInfo {
+	if o.throttle != nil {
+		file.FS = o.throttle.FS(ctx, file.FS)
+	}
+	return file
+}
+
+// reportable reports whether a set of files may form a duplicate group under the options.
+func (o *options) reportable(count int, role func(i int) scanner.Role) bool {
+	if count < 2 {
+		return false
+	}
+	if !o.referenceMode {
+		return true
+	}
+
+	var hasReference, hasScan bool
+	for i := range count {
+		if role(i) == scanner.RoleReference {
+			hasReference = true
+		} else {
+			hasScan = true
+		}
+		if hasReference && hasScan {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package finder
+
+import (
+	"context"
+	"hash"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/xxh3"
+
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// stage is a hashing stage.
+type stage struct {
+	// name names the stage in logs, metrics, and progress reports.
+	name string
+
+	// bytesRead returns the number of bytes read to hash a file, or -1 if its hash is known without reading it.
+	bytesRead func(scanner.FileInfo) int64
+}
+
+var (
+	quickHashStage = stage{name: model.StageQuickHash, bytesRead: quickHashBytes}
+	fullHashStage  = stage{name: model.StageFullHash, bytesRead: fullHashBytes}
+	checksumStage  = stage{name: "checksum", bytesRead: fullHashBytes}
+	bothHashStage  = stage{name: "hash", bytesRead: func(file scanner.FileInfo) int64 {
+		if file.Hash != "" && file.QuickHashed {
+			return -1
+		}
+		return file.Size
+	}}
+)
+
+// quickHashBytes returns the number of bytes read to compute the quick hash of a file.
+func quickHashBytes(file scanner.FileInfo) int64 {
+	switch {
+	case file.QuickHashed:
+		return -1
+	case file.Size < quickHashSize*2:
+		return min(max(file.Size, 0), quickHashSize)
+	default:
+		return quickHashSize * 2
+	}
+}
+
+// fullHashBytes returns the number of bytes read to compute the full hash of a file.
+func fullHashBytes(file scanner.FileInfo) int64 {
+	if file.Hash != "" {
+		return -1
+	}
+	return file.Size
+}
+
+// hashPool hashes items with numWorkers workers and returns a channel of the results, in completion order.
+// If perDevice is set, the items of each device are hashed by workers of their own instead,
+// as many as perDevice allows for its kind, and in the order planned by [iosched.Plan].
+// newWorker is called once per worker to set up the hashers and buffers the returned function uses.
+// Items that fail are counted as errors and reported to the event observer of stats under the stage name;
+// the others are reported to the hash observer of stats.
+// The progress of the stage is reported to the progress observer of stats, which sees the stage end
+// before the channel is closed.
+// The channel is closed once every item is processed, or early if ctx is canceled, once the items being hashed
+// are done. Errors met after ctx is canceled are not reported.
+func hashPool[In, Out any](ctx context.Context, items []In, numWorkers int, perDevice iosched.Limits,
+	stats *model.Stats, stage stage, fileOf func(In) scanner.FileInfo, newWorker func() func(In) (Out, error),
+) <-chan Out {
+	var totalBytes int64
+	for _, item := range items {
+		totalBytes += max(stage.bytesRead(fileOf(item)), 0)
+	}
+	stats.StartStage(stage.name, int64(len(items)), totalBytes)
+
+	resultChan := make(chan Out, len(items))
+
+	wg := runWorkers(ctx, items, numWorkers, perDevice, fileOf, func() func(In) bool {
+		hash := newWorker()
+		return func(item In) bool {
+			file := fileOf(item)
+			n := stage.bytesRead(file)
+			start := time.Now()
+			result, err := hash(item)
+			stats.Advance(stage.name, 1, max(n, 0))
+			if err != nil {
+				if ctx.Err() != nil {
+					// The read was likely cut short by the cancellation.
+					return false
+				}
+				stats.ReportError(stage.name, file.Path, err)
+				return true
+			}
+			if n >= 0 {
+				stats.ObserveHash(stage.name, time.Since(start), n)
+			}
+			select {
+			case resultChan <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+	})
+
+	// Wait for the workers to finish
+	go func() {
+		wg.Wait()
+		stats.EndStage(stage.name)
+		close(resultChan)
+	}()
+
+	return resultChan
+}
+
+// runWorkers processes items with numWorkers workers, or with workers per device as described in [hashPool].
+// newWorker is called once per worker, and the function it returns is called with each item the worker takes,
+// until it returns false. The returned wait group is done once every item is processed, or early if ctx is canceled.
+func runWorkers[In any](ctx context.Context, items []In, numWorkers int, perDevice iosched.Limits,
+	fileOf func(In) scanner.FileInfo, newWorker func() func(In) bool,
+) *sync.WaitGroup {
+	queues := []queue[In]{{items: items, workers: numWorkers}}
+	if perDevice != nil {
+		queues = deviceQueues(items, fileOf, perDevice)
+	}
+
+	var wg sync.WaitGroup
+	for _, q := range queues {
+		workChan := make(chan In, len(q.items))
+
+		for range max(min(q.workers, len(q.items)), 1) {
+			wg.Go(func() {
+				process := newWorker()
+				for item := range workChan {
+					// Items taken before ctx was canceled are finished, the others left.
+					if ctx.Err() != nil || !process(item) {
+						return
+					}
+				}
+			})
+		}
+
+		// Send work
+		go func() {
+			defer close(workChan)
+			for _, item := range q.items {
+				select {
+				case workChan <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return &wg
+}
+
+// queue is a set of items hashed by workers of their own.
+type queue[In any] struct {
+	items   []In
+	workers int
+}
+
+// deviceQueues splits items into a queue per device, as planned by [iosched.Plan].
+func deviceQueues[In any](items []In, fileOf func(In) scanner.FileInfo, perDevice iosched.Limits) []queue[In] {
+	files := make([]iosched.File, len(items))
+	for i, item := range items {
+		file := fileOf(item)
+		_, local := file.FS.(vfs.OS)
+		files[i] = iosched.File{Path: file.Path, Dev: file.Dev, Ino: file.Ino, Local: local || file.FS == nil}
+	}
+
+	plan := iosched.Plan(files, perDevice)
+	queues := make([]queue[In], len(plan))
+	for i, q := range plan {
+		queues[i].workers = q.Workers
+		queues[i].items = make([]In, len(q.Files))
+		for j, index := range q.Files {
+			queues[i].items[j] = items[index]
+		}
+	}
+	return queues
+}
+
+// identity returns a file as is.
+func identity(file scanner.FileInfo) scanner.FileInfo {
+	return file
+}
+
+// HashFiles computes both the quick and the full hash of every file with numWorkers workers,
+// reading each file once. Files that cannot be read are reported as errors, and left out.
+// The hashed files are returned sorted by path.
+func HashFiles(ctx context.Context, files []scanner.FileInfo, numWorkers int, stats *model.Stats,
+	opts ...Option,
+) []scanner.FileInfo {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	results := hashPool(ctx, files, numWorkers, o.perDevice, stats, bothHashStage, identity,
+		func() func(scanner.FileInfo) (scanner.FileInfo, error) {
+			quick, full := xxh3.New(), scanner.NewHasher()
+			buf := make([]byte, chunkSize)
+			return func(file scanner.FileInfo) (scanner.FileInfo, error) {
+				quickHash, fullHash, err := scanner.HashFileInfoBoth(o.read(ctx, file), quick, full, buf)
+				file.QuickHash, file.QuickHashed, file.Hash = quickHash, true, fullHash
+				return file, err
+			}
+		})
+
+	hashed := make([]scanner.FileInfo, 0, len(files))
+	for file := range results {
+		hashed = append(hashed, file)
+		stats.IncrementProcessedFiles()
+	}
+
+	slices.SortFunc(hashed, func(a, b scanner.FileInfo) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return hashed
+}
+
+// Digest is the digest of a file.
+type Digest struct {
+	File scanner.FileInfo
+	Sum  []byte
+}
+
+// ChecksumFiles computes the digest of every file with numWorkers workers, using hashers from newHash.
+// Files that cannot be read are reported as errors, and left out.
+// The digests are returned sorted by path.
+func ChecksumFiles(ctx context.Context, files []scanner.FileInfo, numWorkers int, stats *model.Stats,
+	newHash func() hash.Hash, opts ...Option,
+) []Digest {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	results := hashPool(ctx, files, numWorkers, o.perDevice, stats, checksumStage, identity,
+		func() func(scanner.FileInfo) (Digest, error) {
+			hasher := newHash()
+			buf := make([]byte, chunkSize)
+			return func(file scanner.FileInfo) (Digest, error) {
+				sum, err := scanner.HashFileInfo(o.read(ctx, file), hasher, buf)
+				return Digest{File: file, Sum: []byte(sum)}, err
+			}
+		})
+
+	digests := make([]Digest, 0, len(files))
+	for digest := range results {
+		digests = append(digests, digest)
+		stats.IncrementProcessedFiles()
+	}
+
+	slices.SortFunc(digests, func(a, b Digest) int {
+		return strings.Compare(a.File.Path, b.File.Path)
+	})
+
+	return digests
+}
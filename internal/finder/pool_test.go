@@ -0,0 +1,240 @@
+package finder
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// TestChecksumFiles verifies that files are hashed with the given algorithm, sorted by path,
+// and that unreadable files are counted as errors and left out.
+func TestChecksumFiles(t *testing.T) {
+	mem := vfs.NewMemFS()
+	contents := map[string]string{
+		"/data/b.txt": "bravo",
+		"/data/a.txt": "alpha",
+		"/data/c.txt": "",
+	}
+	var files []scanner.FileInfo
+	for name, data := range contents {
+		if err := mem.WriteFile(name, []byte(data)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		files = append(files, scanner.FileInfo{Path: name, Size: int64(len(data)), FS: mem})
+	}
+	files = append(files, scanner.FileInfo{Path: "/data/missing.txt", Size: 1, FS: mem})
+
+	s := &model.Stats{}
+	digests := ChecksumFiles(context.Background(), files, 3, s, sha256.New)
+
+	if s.ErrorCount != 1 {
+		t.Errorf("Stats.ErrorCount = %d, want 1", s.ErrorCount)
+	}
+	if s.ProcessedFiles != 3 {
+		t.Errorf("Stats.ProcessedFiles = %d, want 3", s.ProcessedFiles)
+	}
+
+	var paths []string
+	for _, digest := range digests {
+		paths = append(paths, digest.File.Path)
+		want := sha256.Sum256([]byte(contents[digest.File.Path]))
+		if !slices.Equal(digest.Sum, want[:]) {
+			t.Errorf("ChecksumFiles() sum of %s = %x, want %x", digest.File.Path, digest.Sum, want)
+		}
+	}
+	if want := []string{"/data/a.txt", "/data/b.txt", "/data/c.txt"}; !slices.Equal(paths, want) {
+		t.Errorf("ChecksumFiles() paths = %v, want %v", paths, want)
+	}
+}
+
+// TestChecksumFiles_PerDevice verifies that files spread across devices are all hashed once
+// when each device is read by workers of its own, sharing a throttle.
+func TestChecksumFiles_PerDevice(t *testing.T) {
+	mem := vfs.NewMemFS()
+	var files []scanner.FileInfo
+	for i := range 12 {
+		name := fmt.Sprintf("/data/%02d.txt", i)
+		if err := mem.WriteFile(name, []byte(name)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		// Files without an inode number are on an unknown device.
+		files = append(files, scanner.FileInfo{Path: name, Size: int64(len(name)), FS: mem,
+			Dev: uint64(i % 3), Ino: uint64(i % 4 * (i + 1))})
+	}
+	files = append(files, scanner.FileInfo{Path: "/data/missing.txt", Size: 1, FS: mem, Dev: 1, Ino: 99})
+
+	s := &model.Stats{}
+	digests := ChecksumFiles(context.Background(), files, 2, s, sha256.New,
+		WithIOPerDevice(iosched.Limits{iosched.HDD: 1, iosched.SSD: 2}),
+		WithThrottle(iosched.NewThrottle(1<<30, 1)))
+
+	if s.ErrorCount != 1 {
+		t.Errorf("Stats.ErrorCount = %d, want 1", s.ErrorCount)
+	}
+	if len(digests) != 12 {
+		t.Fatalf("ChecksumFiles() returned %d digests, want 12", len(digests))
+	}
+	for i, digest := range digests {
+		if want := files[i].Path; digest.File.Path != want {
+			t.Errorf("ChecksumFiles()[%d] path = %s, want %s", i, digest.File.Path, want)
+		}
+		want := sha256.Sum256([]byte(digest.File.Path))
+		if !slices.Equal(digest.Sum, want[:]) {
+			t.Errorf("ChecksumFiles() sum of %s = %x, want %x", digest.File.Path, digest.Sum, want)
+		}
+	}
+}
+
+// TestFindDuplicatesByHash_Throttled verifies that throttled files without a filesystem are read
+// from the local OS filesystem.
+func TestFindDuplicatesByHash_Throttled(t *testing.T) {
+	dir := t.TempDir()
+	var files []scanner.FileInfo
+	for _, name := range []string{"a", "b"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("same content"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		files = append(files, scanner.FileInfo{Path: path, Size: 12})
+	}
+
+	s := &model.Stats{}
+	report, err := FindDuplicatesByHash(context.Background(), map[int64][]scanner.FileInfo{12: files}, 2, s,
+		WithThrottle(iosched.NewThrottle(1<<30, 1)))
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if len(report.Groups) != 1 || s.ErrorCount != 0 {
+		t.Errorf("FindDuplicatesByHash() = %d groups, %d errors, want 1 group", len(report.Groups), s.ErrorCount)
+	}
+}
+
+// recorder records the files hashed in each stage.
+type recorder struct {
+	mu    sync.Mutex
+	bytes map[string][]int64
+}
+
+// ObserveHash implements [model.HashObserver].
+func (r *recorder) ObserveHash(stage string, _ time.Duration, bytesRead int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bytes[stage] = append(r.bytes[stage], bytesRead)
+}
+
+// TestHashPool_Observer verifies that every file read in a stage is reported with the bytes read,
+// and that precomputed hashes and failures are not.
+func TestHashPool_Observer(t *testing.T) {
+	mem := vfs.NewMemFS()
+	sizes := map[string]int{"/small": 100, "/medium": quickHashSize + 1, "/large": quickHashSize * 3}
+	var files []scanner.FileInfo
+	for name, size := range sizes {
+		if err := mem.WriteFile(name, make([]byte, size)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		files = append(files, scanner.FileInfo{Path: name, Size: int64(size), FS: mem})
+	}
+	files = append(files,
+		scanner.FileInfo{Path: "/missing", Size: 1, FS: mem},
+		scanner.FileInfo{Path: "/known", Size: 1, QuickHashed: true, Hash: "known", FS: mem},
+	)
+
+	r := &recorder{bytes: make(map[string][]int64)}
+	s := &model.Stats{Observer: r}
+	quickHash(context.Background(), files, 2, s, &options{})
+	ChecksumFiles(context.Background(), files, 2, s, sha256.New)
+
+	tests := []struct {
+		stage string
+		want  []int64
+	}{
+		{stage: "quick-hash", want: []int64{100, quickHashSize, quickHashSize * 2}},
+		{stage: "checksum", want: []int64{100, quickHashSize + 1, quickHashSize * 3}},
+	}
+	for _, tt := range tests {
+		got := slices.Sorted(slices.Values(r.bytes[tt.stage]))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("bytes read in %s = %v, want %v", tt.stage, got, tt.want)
+		}
+	}
+}
+
+// progressRecorder records the progress of each stage.
+type progressRecorder struct {
+	mu                  sync.Mutex
+	started, advanced   map[string][2]int64
+	ended               []string
+	advancedAfterEnding bool
+}
+
+// StartStage implements [model.ProgressObserver].
+func (r *progressRecorder) StartStage(stage string, files, bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started[stage] = [2]int64{files, bytes}
+}
+
+// Advance implements [model.ProgressObserver].
+func (r *progressRecorder) Advance(stage string, files, bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.Contains(r.ended, stage) {
+		r.advancedAfterEnding = true
+	}
+	a := r.advanced[stage]
+	r.advanced[stage] = [2]int64{a[0] + files, a[1] + bytes}
+}
+
+// EndStage implements [model.ProgressObserver].
+func (r *progressRecorder) EndStage(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ended = append(r.ended, stage)
+}
+
+// TestHashPool_Progress verifies that a stage starts with the totals it then advances by,
+// failures included, and ends before its results are all collected.
+func TestHashPool_Progress(t *testing.T) {
+	mem := vfs.NewMemFS()
+	if err := mem.WriteFile("/a", make([]byte, quickHashSize*3)); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := mem.WriteFile("/b", make([]byte, 100)); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	files := []scanner.FileInfo{
+		{Path: "/a", Size: quickHashSize * 3, FS: mem},
+		{Path: "/b", Size: 100, FS: mem},
+		{Path: "/missing", Size: 10, FS: mem},
+		{Path: "/known", Size: 1, QuickHashed: true, Hash: "known", FS: mem},
+	}
+
+	r := &progressRecorder{started: make(map[string][2]int64), advanced: make(map[string][2]int64)}
+	s := &model.Stats{Progress: r}
+	quickHash(context.Background(), files, 2, s, &options{})
+
+	want := [2]int64{4, quickHashSize*2 + 100 + 10}
+	if got := r.started[model.StageQuickHash]; got != want {
+		t.Errorf("started %s with %v, want %v", model.StageQuickHash, got, want)
+	}
+	if got := r.advanced[model.StageQuickHash]; got != want {
+		t.Errorf("advanced %s by %v, want %v", model.StageQuickHash, got, want)
+	}
+	if !slices.Equal(r.ended, []string{model.StageQuickHash}) {
+		t.Errorf("ended stages = %v, want [%s]", r.ended, model.StageQuickHash)
+	}
+	if r.advancedAfterEnding {
+		t.Error("a stage advanced after it ended")
+	}
+}
@@ -0,0 +1,72 @@
+package iosched
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// rotational reports whether a device is a rotational disk, as told by the block layer in sysfs.
+// Partitions report the disk they are on. Devices without a block device, such as those of network or
+// virtual filesystems, are not rotational.
+func rotational(dev uint64) bool {
+	base := fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(dev), unix.Minor(dev))
+	for _, path := range []string{
+		filepath.Join(base, "queue", "rotational"),
+		filepath.Join(base, "..", "queue", "rotational"),
+	} {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data)) == "1"
+		}
+	}
+	return false
+}
+
+// fsIocFiemap is the FS_IOC_FIEMAP ioctl request: _IOWR('f', 11, struct fiemap).
+const fsIocFiemap = 0xC020660B
+
+// fiemap is struct fiemap, with room for a single extent.
+type fiemap struct {
+	start         uint64
+	length        uint64
+	flags         uint32
+	mappedExtents uint32
+	extentCount   uint32
+	reserved      uint32
+	extent        fiemapExtent
+}
+
+// fiemapExtent is struct fiemap_extent.
+type fiemapExtent struct {
+	logical    uint64
+	physical   uint64
+	length     uint64
+	reserved64 [2]uint64
+	flags      uint32
+	reserved   [3]uint32
+}
+
+// physicalOffset returns the offset on disk of the first extent of a file, with the FIEMAP ioctl.
+// It fails for empty files, and on filesystems that do not support FIEMAP.
+func physicalOffset(path string) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fm := fiemap{length: math.MaxUint64, extentCount: 1}
+	//nolint:gosec // the kernel fills fm, which outlives the call
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIocFiemap, uintptr(unsafe.Pointer(&fm)))
+	if errno != 0 || fm.mappedExtents == 0 {
+		return 0, false
+	}
+	return fm.extent.physical, true
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package iosched
+
+// rotational reports whether a device is a rotational disk. The kind of devices is only known on Linux,
+// so devices are treated as solid-state drives elsewhere.
+func rotational(uint64) bool {
+	return false
+}
+
+// physicalOffset returns the offset on disk of a file, which is only known on Linux.
+func physicalOffset(string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,180 @@
+// Package iosched schedules file reads across storage devices.
+//
+// Reading many files at once from a spinning disk makes its heads seek back and forth between them,
+// which costs far more than the reads themselves, while solid-state drives serve parallel reads best.
+// So the files on each device are read by workers of their own, as many as the kind of device serves well,
+// and the files on rotational disks are read in the order they are laid out on the disk.
+package iosched
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kinds of devices.
+const (
+	// HDD is a rotational disk.
+	HDD = "hdd"
+
+	// SSD is a solid-state drive, or a device of unknown kind such as a remote filesystem.
+	SSD = "ssd"
+)
+
+// Limits maps kinds of devices to the number of files read at the same time from each device of that kind.
+type Limits map[string]int
+
+// DefaultLimits are the limits of the kinds of devices left out of parsed limits.
+var DefaultLimits = Limits{HDD: 1, SSD: 8}
+
+// ParseLimits parses comma-separated limits such as "hdd=1,ssd=8".
+// Kinds left out take their value from [DefaultLimits].
+func ParseLimits(s string) (Limits, error) {
+	limits := Limits{HDD: DefaultLimits[HDD], SSD: DefaultLimits[SSD]}
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kind, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid I/O limit %q: expected kind=count", part)
+		}
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind != HDD && kind != SSD {
+			return nil, fmt.Errorf("invalid I/O limit %q: unknown device kind %q (expected hdd or ssd)", part, kind)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid I/O limit %q: the count must be a positive integer", part)
+		}
+		limits[kind] = n
+	}
+	return limits, nil
+}
+
+// String formats the limits as parsed by [ParseLimits].
+func (l Limits) String() string {
+	return fmt.Sprintf("%s=%d,%s=%d", HDD, l.workers(HDD), SSD, l.workers(SSD))
+}
+
+// workers returns the number of files read at the same time from a device of a kind.
+func (l Limits) workers(kind string) int {
+	if n := l[kind]; n > 0 {
+		return n
+	}
+	return DefaultLimits[kind]
+}
+
+// File is a file to read.
+type File struct {
+	// Path is the path of the file.
+	Path string
+
+	// Dev and Ino are the device and inode numbers of the file, or zero if unknown.
+	Dev, Ino uint64
+
+	// Local marks files on the local filesystem, whose layout on disk can be queried.
+	Local bool
+}
+
+// Queue is the files of a device, to be read by workers of their own.
+type Queue struct {
+	// Dev is the device the files are on, or zero for files on unknown devices.
+	Dev uint64
+
+	// Kind is the kind of the device.
+	Kind string
+
+	// Workers is the number of files to read at the same time.
+	Workers int
+
+	// Files holds the indexes of the files in the slice given to [Plan], in the order to read them.
+	Files []int
+}
+
+// Plan groups files by device, in queues to read with the workers the limits allow for each kind of device.
+// The files on rotational disks are ordered by their physical offset on the disk where it is known,
+// then by inode number.
+// Queues are returned by device number, with files on unknown devices in a single queue first.
+func Plan(files []File, limits Limits) []Queue {
+	return plan(files, limits, kindOf, physicalOffset)
+}
+
+// plan implements [Plan], with the kind of device and the physical offset of a file looked up
+// by kind and offset.
+func plan(files []File, limits Limits, kind func(dev uint64) string, offset func(path string) (uint64, bool)) []Queue {
+	byDev := make(map[uint64][]int)
+	for i, f := range files {
+		dev := f.Dev
+		if f.Ino == 0 {
+			dev = 0
+		}
+		byDev[dev] = append(byDev[dev], i)
+	}
+
+	queues := make([]Queue, 0, len(byDev))
+	for _, dev := range slices.Sorted(maps.Keys(byDev)) {
+		q := Queue{Dev: dev, Kind: SSD, Files: byDev[dev]}
+		if dev != 0 {
+			q.Kind = kind(dev)
+		}
+		q.Workers = limits.workers(q.Kind)
+		if q.Kind == HDD {
+			orderOnDisk(files, q.Files, offset)
+		}
+		queues = append(queues, q)
+	}
+	return queues
+}
+
+// orderOnDisk sorts the indexes of files on a rotational disk by the physical offset of their first extent,
+// so that the disk heads sweep across the disk once. Files whose offsets are unknown come last,
+// by inode number, which usually follows the order in which they were allocated.
+func orderOnDisk(files []File, indexes []int, offset func(path string) (uint64, bool)) {
+	type key struct {
+		known  bool
+		offset uint64
+		ino    uint64
+	}
+	keys := make(map[int]key, len(indexes))
+	for _, i := range indexes {
+		k := key{ino: files[i].Ino}
+		if files[i].Local {
+			k.offset, k.known = offset(files[i].Path)
+		}
+		keys[i] = k
+	}
+
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		ka, kb := keys[a], keys[b]
+		if ka.known != kb.known {
+			if ka.known {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(ka.offset, kb.offset), cmp.Compare(ka.ino, kb.ino))
+	})
+}
+
+// kinds caches the kinds of the devices looked up.
+var kinds sync.Map
+
+// kindOf returns the kind of a device, looking it up once.
+func kindOf(dev uint64) string {
+	if kind, ok := kinds.Load(dev); ok {
+		return kind.(string)
+	}
+	kind := SSD
+	if rotational(dev) {
+		kind = HDD
+	}
+	kinds.Store(dev, kind)
+	return kind
+}
@@ -0,0 +1,80 @@
+package iosched
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Limits
+		wantErr bool
+	}{
+		{"both kinds", "hdd=2,ssd=16", Limits{HDD: 2, SSD: 16}, false},
+		{"defaults for kinds left out", "hdd=1", Limits{HDD: 1, SSD: 8}, false},
+		{"spaces and case", " HDD = 3 , ", Limits{HDD: 3, SSD: 8}, false},
+		{"empty", "", Limits{HDD: 1, SSD: 8}, false},
+		{"missing count", "hdd", nil, true},
+		{"unknown kind", "tape=1", nil, true},
+		{"zero count", "ssd=0", nil, true},
+		{"invalid count", "ssd=many", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimits(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimits(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("ParseLimits(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimits_String(t *testing.T) {
+	if got, want := (Limits{HDD: 2}).String(), "hdd=2,ssd=8"; got != want {
+		t.Errorf("Limits.String() = %q, want %q", got, want)
+	}
+}
+
+// TestPlan verifies that files are queued by device, with the workers of the kind of each device,
+// and that the files on rotational disks are ordered by physical offset, then by inode number.
+func TestPlan(t *testing.T) {
+	files := []File{
+		{Path: "/ssd/a", Dev: 2, Ino: 30},
+		{Path: "/hdd/far", Dev: 1, Ino: 10, Local: true},
+		{Path: "/remote/a"},
+		{Path: "/hdd/unmapped-high", Dev: 1, Ino: 9, Local: true},
+		{Path: "/ssd/b", Dev: 2, Ino: 20},
+		{Path: "/hdd/near", Dev: 1, Ino: 50, Local: true},
+		{Path: "/hdd/unmapped-low", Dev: 1, Ino: 2, Local: true},
+		{Path: "/hdd/remote", Dev: 1, Ino: 1},
+	}
+	kind := func(dev uint64) string {
+		if dev == 1 {
+			return HDD
+		}
+		return SSD
+	}
+	offsets := map[string]uint64{"/hdd/far": 4096 * 100, "/hdd/near": 4096, "/hdd/remote": 0}
+	offset := func(path string) (uint64, bool) {
+		off, ok := offsets[path]
+		return off, ok
+	}
+
+	got := plan(files, Limits{HDD: 1, SSD: 4}, kind, offset)
+	want := []Queue{
+		{Dev: 0, Kind: SSD, Workers: 4, Files: []int{2}},
+		// Offsets are only looked up for local files, so the remote file is ordered by inode.
+		{Dev: 1, Kind: HDD, Workers: 1, Files: []int{5, 1, 7, 6, 3}},
+		{Dev: 2, Kind: SSD, Workers: 4, Files: []int{0, 4}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package iosched
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// I/O priority constants from linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// LowerPriority makes the process yield to others: with idleIO, it only gets disk time when no other
+// process needs the disk (the idle I/O scheduling class), and with a nonzero nice, its threads are reniced.
+//
+// Both are set on every thread of the process, as Linux keeps them per thread.
+// Threads started later inherit them from the thread starting them.
+func LowerPriority(idleIO bool, nice int) error {
+	if !idleIO && nice == 0 {
+		return nil
+	}
+
+	tids, err := threads()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tid := range tids {
+		if idleIO {
+			_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid),
+				ioprioClassIdle<<ioprioClassShift)
+			if errno != 0 && errno != unix.ESRCH {
+				errs = append(errs, fmt.Errorf("failed to set the idle I/O priority: %w", errno))
+				idleIO = false
+			}
+		}
+		if nice != 0 {
+			if err := unix.Setpriority(unix.PRIO_PROCESS, tid, nice); err != nil && !errors.Is(err, unix.ESRCH) {
+				errs = append(errs, fmt.Errorf("failed to set the nice level to %d: %w", nice, err))
+				nice = 0
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// threads returns the IDs of the threads of the process.
+func threads() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
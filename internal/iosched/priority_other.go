@@ -0,0 +1,16 @@
+//go:build !linux
+
+package iosched
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LowerPriority makes the process yield to others, which is only supported on Linux.
+func LowerPriority(idleIO bool, nice int) error {
+	if !idleIO && nice == 0 {
+		return nil
+	}
+	return fmt.Errorf("lowering the I/O priority and nice level: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,169 @@
+package iosched
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Throttle limits the rate at which files are read and the number of files open at the same time,
+// across all the readers sharing it, so that scans leave I/O bandwidth to other users of the disks.
+// A nil Throttle does not limit anything.
+type Throttle struct {
+	// bucket limits the bytes read per second, if set.
+	bucket *bucket
+
+	// open holds a token per open file, if the number of open files is limited.
+	open chan struct{}
+}
+
+// NewThrottle returns a throttle reading at most bytesPerSecond bytes per second,
+// from at most maxOpenFiles files at the same time. Zero leaves a limit out.
+// It returns nil if both are zero.
+func NewThrottle(bytesPerSecond int64, maxOpenFiles int) *Throttle {
+	if bytesPerSecond <= 0 && maxOpenFiles <= 0 {
+		return nil
+	}
+	t := &Throttle{}
+	if bytesPerSecond > 0 {
+		t.bucket = newBucket(bytesPerSecond, time.Now)
+	}
+	if maxOpenFiles > 0 {
+		t.open = make(chan struct{}, maxOpenFiles)
+	}
+	return t
+}
+
+// FS returns fsys with the files it opens throttled by t. A nil fsys is the local OS filesystem.
+// Waiting for an open file or for the bandwidth to read stops with an error when ctx is canceled.
+func (t *Throttle) FS(ctx context.Context, fsys vfs.FS) vfs.FS {
+	if t == nil {
+		return fsys
+	}
+	if fsys == nil {
+		fsys = vfs.OS{}
+	}
+	return &throttledFS{FS: fsys, ctx: ctx, t: t}
+}
+
+// throttledFS is a filesystem whose files are throttled.
+type throttledFS struct {
+	vfs.FS
+	ctx context.Context
+	t   *Throttle
+}
+
+// Open opens the named file once fewer files than the limit are open.
+func (fsys *throttledFS) Open(name string) (vfs.File, error) {
+	if fsys.t.open != nil {
+		select {
+		case fsys.t.open <- struct{}{}:
+		case <-fsys.ctx.Done():
+			return nil, fsys.ctx.Err()
+		}
+	}
+
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		fsys.release()
+		return nil, err
+	}
+	return &throttledFile{File: f, fsys: fsys}, nil
+}
+
+// release frees the slot of a file that was open.
+func (fsys *throttledFS) release() {
+	if fsys.t.open != nil {
+		<-fsys.t.open
+	}
+}
+
+// throttledFile is a file whose reads are throttled.
+type throttledFile struct {
+	vfs.File
+	fsys      *throttledFS
+	closeOnce sync.Once
+}
+
+// Read reads from the file, then waits until the bytes read fit in the bandwidth.
+func (f *throttledFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	return n, f.wait(n, err)
+}
+
+// ReadAt reads from the file at an offset, then waits until the bytes read fit in the bandwidth.
+func (f *throttledFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off)
+	return n, f.wait(n, err)
+}
+
+// wait waits until n bytes fit in the bandwidth, and returns err, or the error of the context
+// if it is canceled first.
+func (f *throttledFile) wait(n int, err error) error {
+	if f.fsys.t.bucket == nil || n <= 0 {
+		return err
+	}
+	if d := f.fsys.t.bucket.take(int64(n)); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-f.fsys.ctx.Done():
+			return f.fsys.ctx.Err()
+		}
+	}
+	return err
+}
+
+// Close closes the file, freeing its slot for another file.
+func (f *throttledFile) Close() error {
+	err := f.File.Close()
+	f.closeOnce.Do(f.fsys.release)
+	return err
+}
+
+// bucket is a token bucket holding up to a second's worth of bytes.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newBucket returns a full bucket refilled with rate bytes per second, as told by the clock now.
+func newBucket(rate int64, now func() time.Time) *bucket {
+	return &bucket{rate: float64(rate), tokens: float64(rate), last: now(), now: now}
+}
+
+// take takes n bytes from the bucket, and returns how long to wait for them to be refilled.
+// The bucket may go into debt, so that reads larger than the bucket are paid for by waiting longer.
+func (b *bucket) take(n int64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// ParseRate parses a read rate such as "50MB/s" or "1.5GiB", in bytes per second.
+func ParseRate(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "/s"), "/S")
+	rate, err := filter.ParseFileSize(trimmed)
+	if err != nil {
+		return 0, fmt.Errorf("invalid read rate %q: %w", s, err)
+	}
+	return rate, nil
+}
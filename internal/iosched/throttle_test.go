@@ -0,0 +1,158 @@
+package iosched
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"50MB/s", 50 * 1000 * 1000, false},
+		{"1KiB/s", 1024, false},
+		{" 2 MB ", 2 * 1000 * 1000, false},
+		{"4096", 4096, false},
+		{"fast", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRate(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBucket verifies that bytes within the bucket are read at once, that reads beyond it wait
+// for the bytes to be refilled, and that the bucket never holds more than a second's worth.
+func TestBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBucket(1000, func() time.Time { return now })
+
+	steps := []struct {
+		advance time.Duration
+		take    int64
+		want    time.Duration
+	}{
+		{0, 600, 0},
+		{0, 400, 0},
+		{0, 500, 500 * time.Millisecond},
+		{500 * time.Millisecond, 1000, time.Second},
+		{10 * time.Second, 1000, 0},
+		{0, 2000, 2 * time.Second},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		if got := b.take(step.take); got != step.want {
+			t.Errorf("step %d: take(%d) = %s, want %s", i, step.take, got, step.want)
+		}
+	}
+}
+
+// TestThrottle_MaxOpenFiles verifies that files beyond the limit wait for others to be closed,
+// and that closing a file twice frees a single slot.
+func TestThrottle_MaxOpenFiles(t *testing.T) {
+	mem := vfs.NewMemFS()
+	for _, name := range []string{"/a", "/b", "/c"} {
+		if err := mem.WriteFile(name, []byte(name)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	fsys := NewThrottle(0, 2).FS(ctx, mem)
+
+	// Files that fail to open do not take a slot.
+	if _, err := fsys.Open("/missing"); err == nil {
+		t.Errorf("Open(/missing) error = nil, want an error")
+	}
+	a, err := fsys.Open("/a")
+	if err != nil {
+		t.Fatalf("Open(/a) error = %v", err)
+	}
+	b, err := fsys.Open("/b")
+	if err != nil {
+		t.Fatalf("Open(/b) error = %v", err)
+	}
+
+	opened := make(chan error)
+	go func() {
+		f, err := fsys.Open("/c")
+		if err == nil {
+			_ = f.Close()
+		}
+		opened <- err
+	}()
+	select {
+	case err := <-opened:
+		t.Fatalf("Open(/c) returned %v while 2 files were open", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_ = a.Close()
+	_ = a.Close()
+	if err := <-opened; err != nil {
+		t.Fatalf("Open(/c) error = %v", err)
+	}
+
+	// b and a new file fill the slots again, so the next open waits until ctx is canceled.
+	c, err := fsys.Open("/c")
+	if err != nil {
+		t.Fatalf("Open(/c) error = %v", err)
+	}
+	data, err := io.ReadAll(c)
+	if err != nil || string(data) != "/c" {
+		t.Errorf("ReadAll(/c) = %q, %v, want %q", data, err, "/c")
+	}
+	cancel()
+	if _, err := fsys.Open("/a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Open(/a) error = %v, want %v", err, context.Canceled)
+	}
+	_ = b.Close()
+	_ = c.Close()
+}
+
+func TestNewThrottle_Unlimited(t *testing.T) {
+	if throttle := NewThrottle(0, 0); throttle != nil {
+		t.Errorf("NewThrottle(0, 0) = %v, want nil", throttle)
+	}
+	mem := vfs.NewMemFS()
+	var throttle *Throttle
+	if fsys := throttle.FS(t.Context(), mem); fsys != vfs.FS(mem) {
+		t.Errorf("nil Throttle.FS() = %v, want the filesystem as is", fsys)
+	}
+}
+
+// TestThrottle_LocalFS verifies that a nil filesystem is throttled as the local OS filesystem.
+func TestThrottle_LocalFS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a")
+	if err := os.WriteFile(path, []byte("content"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	fsys := NewThrottle(0, 1).FS(t.Context(), nil)
+	f, err := fsys.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "content" {
+		t.Errorf("ReadAll() = %q, %v, want %q", data, err, "content")
+	}
+	_ = f.Close()
+}
@@ -24,6 +24,25 @@ import (
 
 var defaultLogger atomic.Pointer[Logger]
 
+// stdoutTarget holds the destination of the logs sent to standard output.
+var stdoutTarget atomic.Pointer[io.Writer]
+
+// stdout writes the logs sent to standard output to their current destination.
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) {
+	if w := stdoutTarget.Load(); w != nil {
+		return (*w).Write(p)
+	}
+	return os.Stdout.Write(p)
+}
+
+// RedirectStdout writes the logs configured to go to standard output to w instead,
+// so that commands writing machine-readable output there can keep it clean.
+func RedirectStdout(w io.Writer) {
+	stdoutTarget.Store(&w)
+}
+
 // Config holds the configuration for the logger.
 type Config struct {
 	// Format specifies the log format (e.g., "text", "json", "pretty", etc.)
@@ -217,7 +236,7 @@ func NewConfig(opts *slog.HandlerOptions, format, output string) (Config, io.Clo
 
 	switch strings.ToLower(output) {
 	case "stdout", "":
-		config.Writer = os.Stdout
+		config.Writer = stdout{}
 	case "stderr":
 		config.Writer = os.Stderr
 	case "null", "discard":
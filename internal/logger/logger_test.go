@@ -209,6 +209,27 @@ func TestNewConfig(t *testing.T) {
 	}
 }
 
+// TestRedirectStdout verifies that logs configured to go to standard output can be moved elsewhere.
+func TestRedirectStdout(t *testing.T) {
+	config, _, err := NewConfig(nil, "text", "stdout")
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	l, err := New(&config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	RedirectStdout(&buf)
+	t.Cleanup(func() { RedirectStdout(os.Stdout) })
+
+	l.Info("redirected")
+	if !strings.Contains(buf.String(), "redirected") {
+		t.Errorf("log written to %q, want the redirected writer", buf.String())
+	}
+}
+
 // TestLogLevels tests all log levels work correctly.
 func TestLogLevels(t *testing.T) {
 	var buf bytes.Buffer
@@ -0,0 +1,192 @@
+// Package manifest reads and writes hash manifests: JSON Lines files recording the size and hashes
+// of every file in a tree, so that files on one machine can be matched against files on another
+// without both being reachable at once.
+//
+// The first line of a manifest is a [Header]; each following line is an [Entry].
+// Hashes are those computed by the scanner: XXH3 quick hashes and BLAKE3-256 full hashes.
+package manifest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+const (
+	// Version is the manifest format version written by [NewWriter].
+	Version = 1
+
+	// Algorithm names the hashes recorded in manifests.
+	Algorithm = "xxh3-quick+blake3-256"
+)
+
+// Header describes a manifest.
+type Header struct {
+	Version   int       `json:"version"`
+	Algorithm string    `json:"algorithm"`
+	Host      string    `json:"host,omitempty"`
+	Roots     []string  `json:"roots,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// Entry records a file in a manifest.
+type Entry struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	QuickHash string `json:"quick_hash"`
+	Hash      string `json:"hash"`
+}
+
+// Writer writes a manifest.
+type Writer struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewWriter writes the header of a manifest listing the given roots, and returns a writer for its entries.
+// The header records the host name of this machine.
+func NewWriter(w io.Writer, roots []string) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	mw := &Writer{w: bw, enc: json.NewEncoder(bw)}
+	mw.enc.SetEscapeHTML(false)
+
+	host, _ := os.Hostname()
+	header := Header{Version: Version, Algorithm: Algorithm, Host: host, Roots: roots, Created: time.Now().UTC()}
+	if err := mw.enc.Encode(header); err != nil {
+		return nil, fmt.Errorf("error writing manifest header: %w", err)
+	}
+
+	return mw, nil
+}
+
+// Write records a hashed file.
+func (w *Writer) Write(file scanner.FileInfo) error {
+	if file.Hash == "" || !file.QuickHashed {
+		return fmt.Errorf("file %s is not hashed", file.Path)
+	}
+	return w.enc.Encode(Entry{
+		Path:      file.Path,
+		Size:      file.Size,
+		QuickHash: fmt.Sprintf("%016x", file.QuickHash),
+		Hash:      hex.EncodeToString([]byte(file.Hash)),
+	})
+}
+
+// Flush writes any buffered entries.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Load reads the manifest at path and returns its files whose sizes are accepted by keep (all files if nil),
+// as references with precomputed hashes. The files are named "source:path", where source is the
+// host the manifest was created on or, if unknown, the manifest file name.
+func Load(path string, keep func(size int64) bool) ([]scanner.FileInfo, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening manifest: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	header, files, err := Read(f, keep)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest %s: %w", path, err)
+	}
+
+	source := header.Host
+	if source == "" {
+		source = filepath.Base(path)
+	}
+	for i := range files {
+		files[i].Path = source + ":" + files[i].Path
+	}
+
+	return files, nil
+}
+
+// Read reads a manifest and returns its header and its files whose sizes are accepted by keep
+// (all files if nil), as references with precomputed hashes.
+func Read(r io.Reader, keep func(size int64) bool) (Header, []scanner.FileInfo, error) {
+	var (
+		header Header
+		files  []scanner.FileInfo
+	)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; sc.Scan(); line++ {
+		data := sc.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		if line == 1 {
+			if err := json.Unmarshal(data, &header); err != nil {
+				return Header{}, nil, fmt.Errorf("line 1: invalid header: %w", err)
+			}
+			if header.Version != Version {
+				return Header{}, nil, fmt.Errorf("unsupported manifest version %d", header.Version)
+			}
+			if header.Algorithm != Algorithm {
+				return Header{}, nil, fmt.Errorf("unsupported hash algorithm '%s'", header.Algorithm)
+			}
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return Header{}, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if keep != nil && !keep(entry.Size) {
+			continue
+		}
+
+		file, err := entry.fileInfo()
+		if err != nil {
+			return Header{}, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		files = append(files, file)
+	}
+	if err := sc.Err(); err != nil {
+		return Header{}, nil, err
+	}
+	if header.Version == 0 {
+		return Header{}, nil, errors.New("empty manifest")
+	}
+
+	return header, files, nil
+}
+
+// fileInfo converts an entry into a reference file with precomputed hashes.
+func (e Entry) fileInfo() (scanner.FileInfo, error) {
+	quickHash, err := strconv.ParseUint(e.QuickHash, 16, 64)
+	if err != nil {
+		return scanner.FileInfo{}, fmt.Errorf("invalid quick hash of %s: %w", e.Path, err)
+	}
+	hash, err := hex.DecodeString(e.Hash)
+	if err != nil || len(hash) != 32 {
+		return scanner.FileInfo{}, fmt.Errorf("invalid hash of %s", e.Path)
+	}
+
+	return scanner.FileInfo{
+		Path:        e.Path,
+		Size:        e.Size,
+		Hash:        string(hash),
+		QuickHash:   quickHash,
+		QuickHashed: true,
+		Role:        scanner.RoleReference,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// writeTree creates files under dir and returns the directory.
+func writeTree(t *testing.T, dir string, files map[string][]byte) string {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+// hashTree scans and hashes every file under dir.
+func hashTree(t *testing.T, dir string) []scanner.FileInfo {
+	t.Helper()
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupFilesBySize(context.Background(), []string{dir}, &filter.Config{}, s)
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+	var files []scanner.FileInfo
+	for _, group := range sizeGroups {
+		files = append(files, group...)
+	}
+	return finder.HashFiles(context.Background(), files, 2, s)
+}
+
+// TestRoundTrip verifies that files read back from a manifest keep their sizes and hashes, as references.
+func TestRoundTrip(t *testing.T) {
+	dir := writeTree(t, t.TempDir(), map[string][]byte{
+		"a.txt":     []byte("alpha"),
+		"sub/b.bin": bytes.Repeat([]byte("beta "), 10000),
+		"empty":     nil,
+	})
+	hashed := hashTree(t, dir)
+	if len(hashed) != 3 {
+		t.Fatalf("HashFiles() returned %d files, want 3", len(hashed))
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []string{dir})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, file := range hashed {
+		if err := w.Write(file); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := w.Write(scanner.FileInfo{Path: "unhashed"}); err == nil {
+		t.Error("Write() of an unhashed file should return an error")
+	}
+
+	header, files, err := Read(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if header.Version != Version || !slices.Equal(header.Roots, []string{dir}) {
+		t.Errorf("Read() header = %+v, want version %d and roots [%s]", header, Version, dir)
+	}
+	if len(files) != len(hashed) {
+		t.Fatalf("Read() returned %d files, want %d", len(files), len(hashed))
+	}
+	for i, file := range files {
+		want := hashed[i]
+		if file.Path != want.Path || file.Size != want.Size || file.Hash != want.Hash || file.QuickHash != want.QuickHash {
+			t.Errorf("Read() file %d = %+v, want %+v", i, file, want)
+		}
+		if file.Role != scanner.RoleReference || !file.QuickHashed {
+			t.Errorf("Read() file %s is not a hashed reference", file.Path)
+		}
+	}
+
+	// Only the accepted sizes are kept.
+	_, files, err = Read(bytes.NewReader(buf.Bytes()), func(size int64) bool { return size == 5 })
+	if err != nil || len(files) != 1 || files[0].Path != filepath.Join(dir, "a.txt") {
+		t.Errorf("Read() with a size filter = %v, %v, want only a.txt", files, err)
+	}
+}
+
+// TestRead_Invalid verifies that malformed manifests are rejected.
+func TestRead_Invalid(t *testing.T) {
+	header := `{"version":1,"algorithm":"` + Algorithm + `","created":"2025-01-01T00:00:00Z"}` + "\n"
+	tests := map[string]string{
+		"empty":             "",
+		"unknown version":   `{"version":2,"algorithm":"` + Algorithm + `"}`,
+		"unknown algorithm": `{"version":1,"algorithm":"md5"}`,
+		"invalid entry":     header + "not json\n",
+		"invalid hash":      header + `{"path":"/a","size":1,"quick_hash":"00","hash":"abc"}` + "\n",
+		"invalid quick":     header + `{"path":"/a","size":1,"quick_hash":"xyz","hash":"` + strings.Repeat("0", 64) + `"}` + "\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := Read(strings.NewReader(data), nil); err == nil {
+				t.Error("Read() should return an error")
+			}
+		})
+	}
+}
+
+// TestFindAgainst verifies that local files are matched against the files of a manifest created elsewhere,
+// without reading the original files again.
+func TestFindAgainst(t *testing.T) {
+	content := bytes.Repeat([]byte("archived content "), 3000)
+	remoteDir := writeTree(t, t.TempDir(), map[string][]byte{
+		"archive/photo.jpg": content,
+		"archive/other.jpg": bytes.ToUpper(content),
+	})
+
+	manifestPath := filepath.Join(t.TempDir(), "manifest.jsonl")
+	f, err := os.Create(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+	w, err := NewWriter(f, []string{remoteDir})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, file := range hashTree(t, remoteDir) {
+		if err := w.Write(file); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	_ = f.Close()
+
+	// The machine the manifest was created on is no longer reachable.
+	if err := os.RemoveAll(remoteDir); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+
+	localDir := writeTree(t, t.TempDir(), map[string][]byte{
+		"copy.jpg":   content,
+		"unique.jpg": bytes.Repeat([]byte("u"), len(content)),
+	})
+
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupFilesBySize(context.Background(), []string{localDir}, &filter.Config{}, s)
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+	files, err := Load(manifestPath, func(size int64) bool { return len(sizeGroups[size]) > 0 })
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, file := range files {
+		sizeGroups[file.Size] = append(sizeGroups[file.Size], file)
+	}
+
+	report, err := finder.FindDuplicatesByHash(context.Background(), sizeGroups, 2, s, finder.WithReferenceMode(true))
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if s.ErrorCount != 0 {
+		t.Errorf("Stats.ErrorCount = %d, want 0", s.ErrorCount)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 1", len(report.Groups))
+	}
+
+	group := report.Groups[0]
+	host, _ := os.Hostname()
+	wantRef := host + ":" + filepath.Join(remoteDir, "archive", "photo.jpg")
+	if host == "" {
+		wantRef = "manifest.jsonl:" + filepath.Join(remoteDir, "archive", "photo.jpg")
+	}
+	if !slices.Contains(group.Files, filepath.Join(localDir, "copy.jpg")) || !slices.Equal(group.References, []string{wantRef}) {
+		t.Errorf("Duplicate group = %v (references %v), want copy.jpg matched with %s", group.Files, group.References, wantRef)
+	}
+	if group.WastedSpace != uint64(len(content)) {
+		t.Errorf("Duplicate group WastedSpace = %d, want %d", group.WastedSpace, len(content))
+	}
+}
@@ -0,0 +1,272 @@
+// Package metrics exposes the statistics of a scan as Prometheus metrics.
+//
+// A [Metrics] reads the counters of a [model.Stats] as the scan runs, and records histograms of
+// the time taken to hash each file and of the bytes read from it, for each hashing stage.
+// The metrics are written in the Prometheus text format, either over HTTP while the scan runs,
+// or to a file for the textfile collector of node_exporter once it is done.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/model"
+)
+
+var (
+	// latencyBuckets are the upper bounds of the hash latency buckets, in seconds.
+	latencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 60}
+
+	// sizeBuckets are the upper bounds of the bytes read buckets.
+	sizeBuckets = []float64{4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20,
+		256 << 20, 1 << 30, 4 << 30, 16 << 30}
+)
+
+// Label is a label attached to every metric.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Metrics holds the metrics of a scan.
+// It implements [model.HashObserver], and is safe for concurrent use.
+type Metrics struct {
+	stats  *model.Stats
+	labels []Label
+
+	mu       sync.Mutex
+	latency  map[string]*histogram
+	bytes    map[string]*histogram
+	report   *model.DuplicateReport
+	finished bool
+}
+
+// New returns the metrics of a scan updating stats, with labels attached to every metric.
+// The metrics are set as the observer of stats.
+func New(stats *model.Stats, labels ...Label) *Metrics {
+	m := &Metrics{
+		stats:   stats,
+		labels:  labels,
+		latency: make(map[string]*histogram),
+		bytes:   make(map[string]*histogram),
+	}
+	stats.Observer = m
+	return m
+}
+
+// ObserveHash records the time taken to hash a file in a stage, and the bytes read from it.
+func (m *Metrics) ObserveHash(stage string, duration time.Duration, bytesRead int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.latency[stage] == nil {
+		m.latency[stage] = newHistogram(latencyBuckets)
+		m.bytes[stage] = newHistogram(sizeBuckets)
+	}
+	m.latency[stage].observe(duration.Seconds())
+	m.bytes[stage].observe(float64(bytesRead))
+}
+
+// Finish records the report of the scan, once it is done.
+func (m *Metrics) Finish(report *model.DuplicateReport) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.report, m.finished = report, true
+}
+
+// WriteText writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteText(w io.Writer) error {
+	stats := m.stats.Snapshot()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	duration := time.Since(stats.StartTime)
+	var wasted uint64
+	if m.finished {
+		duration = m.stats.Duration
+		if m.report != nil {
+			wasted = m.report.TotalWastedSpace
+		}
+	}
+
+	ew := &errWriter{w: w}
+	for _, c := range []struct {
+		name, help, kind string
+		value            float64
+	}{
+		{"doppel_files_scanned_total", "Files found by the scan.", "counter", float64(stats.TotalFiles)},
+		{"doppel_files_processed_total", "Files hashed by the scan.", "counter", float64(stats.ProcessedFiles)},
+		{"doppel_skipped_dirs_total", "Directories skipped due to filters.", "counter", float64(stats.SkippedDirs)},
+		{"doppel_skipped_files_total", "Files skipped due to filters.", "counter", float64(stats.SkippedFiles)},
+		{"doppel_skipped_mounts_total", "Mount points skipped because of their filesystem.", "counter", float64(stats.SkippedMounts)},
+		{"doppel_dangling_symlinks_total", "Symlinks whose targets do not exist.", "counter", float64(stats.DanglingSymlinks)},
+		{"doppel_errors_total", "Errors encountered by the scan.", "counter", float64(stats.ErrorCount)},
+		{"doppel_duplicate_groups", "Groups of duplicate files found.", "gauge", float64(stats.DuplicateGroups)},
+		{"doppel_duplicate_files", "Files in groups of duplicates.", "gauge", float64(stats.DuplicateFiles)},
+		{"doppel_wasted_bytes", "Space taken by duplicate copies, once the scan is complete.", "gauge", float64(wasted)},
+		{"doppel_scan_start_time_seconds", "Start time of the scan, in seconds since the Unix epoch.", "gauge", float64(stats.StartTime.UnixNano()) / 1e9},
+		{"doppel_scan_duration_seconds", "Time taken by the scan so far.", "gauge", duration.Seconds()},
+		{"doppel_scan_complete", "Whether the scan is complete (1) or still running (0).", "gauge", boolValue(m.finished)},
+	} {
+		ew.printf("# HELP %s %s\n# TYPE %s %s\n", c.name, c.help, c.name, c.kind)
+		ew.printf("%s%s %s\n", c.name, formatLabels(m.labels), formatValue(c.value))
+	}
+
+	m.writeHistograms(ew, "doppel_hash_duration_seconds", "Time taken to hash a file, by stage.", m.latency)
+	m.writeHistograms(ew, "doppel_hash_read_bytes", "Bytes read to hash a file, by stage.", m.bytes)
+	return ew.err
+}
+
+// writeHistograms writes a histogram for each stage.
+func (m *Metrics) writeHistograms(ew *errWriter, name, help string, byStage map[string]*histogram) {
+	ew.printf("# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, stage := range slices.Sorted(maps.Keys(byStage)) {
+		h := byStage[stage]
+		labels := append(slices.Clone(m.labels), Label{Name: "stage", Value: stage})
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i]
+			le := append(slices.Clone(labels), Label{Name: "le", Value: formatValue(bound)})
+			ew.printf("%s_bucket%s %d\n", name, formatLabels(le), cumulative)
+		}
+		le := append(slices.Clone(labels), Label{Name: "le", Value: "+Inf"})
+		ew.printf("%s_bucket%s %d\n", name, formatLabels(le), h.count)
+		ew.printf("%s_sum%s %s\n", name, formatLabels(labels), formatValue(h.sum))
+		ew.printf("%s_count%s %d\n", name, formatLabels(labels), h.count)
+	}
+}
+
+// ServeHTTP writes the metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WriteText(w)
+}
+
+// Serve serves the metrics on ln at /metrics until ctx is canceled.
+func (m *Metrics) Serve(ctx context.Context, ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// WriteFile writes the metrics to the file at path, replacing it atomically
+// so that the textfile collector of node_exporter never reads a partial file.
+func (m *Metrics) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing metrics: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	err = m.WriteText(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644) //nolint:gosec
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing metrics: %w", err)
+	}
+	return nil
+}
+
+// histogram counts observations in buckets. It is guarded by the mutex of its [Metrics].
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// newHistogram returns an empty histogram with the given bucket upper bounds.
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// observe adds an observation.
+func (h *histogram) observe(v float64) {
+	if i, _ := slices.BinarySearch(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// formatLabels formats labels as {name="value",...}, or as nothing if there are none.
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(l.Value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapes label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatValue formats a sample value.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// errWriter writes formatted text, keeping the first error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+// printf writes formatted text, unless an earlier write failed.
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// TestMetrics_WriteText verifies that counters, gauges and histograms are written
+// in the Prometheus text format, with the labels escaped.
+func TestMetrics_WriteText(t *testing.T) {
+	s := &model.Stats{StartTime: time.Now()}
+	m := New(s, Label{Name: "roots", Value: `/srv/"a"`})
+	if s.Observer != m {
+		t.Fatal("New() did not set the observer of the stats")
+	}
+
+	s.IncrementTotalFiles()
+	s.IncrementTotalFiles()
+	s.IncrementErrorCount()
+	s.ObserveHash("quick-hash", 2*time.Millisecond, 8192)
+	s.ObserveHash("quick-hash", 20*time.Second, 3<<20)
+	s.ObserveHash("full-hash", 0, 100)
+
+	var buf bytes.Buffer
+	if err := m.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE doppel_files_scanned_total counter\n",
+		`doppel_files_scanned_total{roots="/srv/\"a\""} 2` + "\n",
+		`doppel_errors_total{roots="/srv/\"a\""} 1` + "\n",
+		`doppel_scan_complete{roots="/srv/\"a\""} 0` + "\n",
+		"# TYPE doppel_hash_duration_seconds histogram\n",
+		`doppel_hash_duration_seconds_bucket{roots="/srv/\"a\"",stage="quick-hash",le="0.001"} 0` + "\n",
+		`doppel_hash_duration_seconds_bucket{roots="/srv/\"a\"",stage="quick-hash",le="0.005"} 1` + "\n",
+		`doppel_hash_duration_seconds_bucket{roots="/srv/\"a\"",stage="quick-hash",le="10"} 1` + "\n",
+		`doppel_hash_duration_seconds_bucket{roots="/srv/\"a\"",stage="quick-hash",le="60"} 2` + "\n",
+		`doppel_hash_duration_seconds_bucket{roots="/srv/\"a\"",stage="quick-hash",le="+Inf"} 2` + "\n",
+		`doppel_hash_duration_seconds_count{roots="/srv/\"a\"",stage="quick-hash"} 2` + "\n",
+		`doppel_hash_read_bytes_bucket{roots="/srv/\"a\"",stage="quick-hash",le="16384"} 1` + "\n",
+		`doppel_hash_read_bytes_sum{roots="/srv/\"a\"",stage="quick-hash"} 3153920` + "\n",
+		`doppel_hash_read_bytes_count{roots="/srv/\"a\"",stage="full-hash"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteText() output is missing %q", want)
+		}
+	}
+
+	// Stages are written in a stable order.
+	if strings.Index(out, `stage="full-hash"`) > strings.Index(out, `stage="quick-hash"`) {
+		t.Error("WriteText() did not sort the stages")
+	}
+
+	s.Duration = 3 * time.Second
+	m.Finish(&model.DuplicateReport{TotalWastedSpace: 4096})
+	buf.Reset()
+	if err := m.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{
+		`doppel_wasted_bytes{roots="/srv/\"a\""} 4096` + "\n",
+		`doppel_scan_duration_seconds{roots="/srv/\"a\""} 3` + "\n",
+		`doppel_scan_complete{roots="/srv/\"a\""} 1` + "\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteText() output after Finish() is missing %q", want)
+		}
+	}
+}
+
+// TestMetrics_WriteFile verifies that the textfile is written and replaced without leftovers.
+func TestMetrics_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doppel.prom")
+	m := New(&model.Stats{StartTime: time.Now()})
+
+	for range 2 {
+		if err := m.WriteFile(path); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), "doppel_files_scanned_total 0\n") {
+		t.Errorf("WriteFile() wrote %q", data)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("WriteFile() left %d files in the directory, want 1", len(entries))
+	}
+
+	if err := m.WriteFile(filepath.Join(dir, "missing", "doppel.prom")); err == nil {
+		t.Error("WriteFile() into a missing directory: expected an error")
+	}
+}
+
+// TestMetrics_Serve verifies that the metrics are served at /metrics until the context is canceled.
+func TestMetrics_Serve(t *testing.T) {
+	ln, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	m := New(&model.Stats{StartTime: time.Now()})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Serve(ctx, ln)
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/metrics") //nolint:noctx
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /metrics status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("GET /metrics Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "doppel_scan_complete 0\n") {
+		t.Errorf("GET /metrics body = %q", body)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after the context was canceled")
+	}
+}
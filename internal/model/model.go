@@ -4,13 +4,21 @@
 //   - DuplicateGroup: Represents a group of duplicate files with metadata
 //   - DuplicateReport: Contains the complete scan results and statistics
 //   - Stats: Thread-safe statistics tracking for the scanning process
+//   - FileError: An error that left a file out of a scan, with its classified kind
 //
 // All structures are designed to be serializable to JSON and YAML for output formatting,
 // and the Stats type provides atomic operations for safe concurrent updates.
 package model
 
 import (
+	"cmp"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -30,6 +38,16 @@ type DuplicateGroup struct {
 
 	// Files contains the paths of the files in this group.
 	Files []string `json:"files" yaml:"files"`
+
+	// Symlinks contains the paths of symlinks that resolve to files in this group.
+	Symlinks []string `json:"symlinks,omitempty" yaml:"symlinks,omitempty"`
+
+	// References contains the paths of the files in this group that are under reference directories.
+	// These files are never suggested for removal.
+	References []string `json:"references,omitempty" yaml:"references,omitempty"`
+
+	// ReadOnly contains the paths of the files in this group that cannot be removed, such as archive members.
+	ReadOnly []string `json:"read_only,omitempty" yaml:"read_only,omitempty"`
 }
 
 // DuplicateReport represents the report of duplicate files found during a scan.
@@ -37,6 +55,10 @@ type DuplicateReport struct {
 	// ScanDate is the date and time when the scan was performed.
 	ScanDate time.Time `json:"scan_date" yaml:"scan_date"`
 
+	// Complete is set if the scan ran to its end. The report of an interrupted scan only holds
+	// the groups confirmed before it was interrupted.
+	Complete bool `json:"complete" yaml:"complete"`
+
 	// Stats contain various statistics about the scan.
 	Stats *Stats `json:"stats" yaml:"stats"`
 
@@ -45,6 +67,97 @@ type DuplicateReport struct {
 
 	// Groups contain the list of duplicate file groups found.
 	Groups []DuplicateGroup `json:"groups" yaml:"groups"`
+
+	// Errors contain the errors that left files out of the scan, sorted by path.
+	// They are only listed if the scan collected them in its [ErrorLog].
+	Errors []FileError `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Kinds of errors met by a scan, as classified by [ClassifyError].
+const (
+	// ErrorPermission is a file or directory the scan is not allowed to read.
+	ErrorPermission = "permission-denied"
+
+	// ErrorNotFound is a file or directory that vanished while the scan was running.
+	ErrorNotFound = "not-found"
+
+	// ErrorIO is a failed read, or a file that changed size while it was read.
+	ErrorIO = "io"
+
+	// ErrorOther is any other error.
+	ErrorOther = "other"
+)
+
+// FileError is an error that left a file out of a stage of a scan.
+type FileError struct {
+	// Path is the path of the file or directory.
+	Path string `json:"path" yaml:"path"`
+
+	// Stage is the stage of the scan that failed (such as [StageWalk]).
+	Stage string `json:"stage" yaml:"stage"`
+
+	// Op is the operation that failed (such as "open" or "read"), if known.
+	Op string `json:"op,omitempty" yaml:"op,omitempty"`
+
+	// Kind is the kind of the error (such as [ErrorPermission]).
+	Kind string `json:"kind" yaml:"kind"`
+
+	// Message is the error message, without the operation and the path if they are known.
+	Message string `json:"message" yaml:"message"`
+}
+
+// NewFileError describes an error that left the file at path out of a stage.
+func NewFileError(stage, path string, err error) FileError {
+	fileErr := FileError{Path: path, Stage: stage, Kind: ClassifyError(err), Message: err.Error()}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		fileErr.Op, fileErr.Message = pathErr.Op, pathErr.Err.Error()
+	}
+	return fileErr
+}
+
+// ClassifyError returns the kind of an error met by a scan.
+func ClassifyError(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return ErrorPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrorNotFound
+	case errors.Is(err, syscall.EIO), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorIO
+	default:
+		return ErrorOther
+	}
+}
+
+// ErrorLog collects the errors met by a scan, for its report. It is safe for concurrent use.
+type ErrorLog struct {
+	mu     sync.Mutex
+	errors []FileError
+}
+
+// Add records an error.
+func (l *ErrorLog) Add(err FileError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, err)
+}
+
+// List returns the errors recorded, sorted by path, then by stage. A nil ErrorLog holds no errors.
+func (l *ErrorLog) List() []FileError {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.errors) == 0 {
+		return nil
+	}
+	list := slices.Clone(l.errors)
+	slices.SortStableFunc(list, func(a, b FileError) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Stage, b.Stage))
+	})
+	return list
 }
 
 // Stats track various statistics during the duplicate file finding process.
@@ -61,6 +174,15 @@ type Stats struct {
 	// SkippedFiles is the number of files skipped due to filters.
 	SkippedFiles uint64 `json:"skipped_files" yaml:"skipped_files"`
 
+	// SkippedMounts is the number of mount points skipped because of their filesystem.
+	SkippedMounts uint64 `json:"skipped_mounts" yaml:"skipped_mounts"`
+
+	// SkippedMountPoints contains the paths of the skipped mount points.
+	SkippedMountPoints []string `json:"skipped_mount_points,omitempty" yaml:"skipped_mount_points,omitempty"`
+
+	// DanglingSymlinks is the number of symlinks whose targets do not exist.
+	DanglingSymlinks uint64 `json:"dangling_symlinks" yaml:"dangling_symlinks"`
+
 	// ErrorCount is the number of errors encountered during processing.
 	ErrorCount uint64 `json:"error_count" yaml:"error_count"`
 
@@ -75,6 +197,182 @@ type Stats struct {
 
 	// Duration is the total duration of the scan.
 	Duration time.Duration `json:"duration" yaml:"duration"`
+
+	// Observer is notified of every file hashed, if set.
+	Observer HashObserver `json:"-" yaml:"-"`
+
+	// Progress is notified of the progress of each stage of the scan, if set.
+	Progress ProgressObserver `json:"-" yaml:"-"`
+
+	// Events is notified of the events of the scan, if set.
+	Events EventObserver `json:"-" yaml:"-"`
+
+	// Errors collects the errors of the scan, for its report, if set.
+	Errors *ErrorLog `json:"-" yaml:"-"`
+}
+
+// Stages of a scan, as reported to observers.
+const (
+	// StageWalk is the walk of the directory trees.
+	StageWalk = "walk"
+
+	// StageQuickHash is the hashing of the first and last bytes of files with the same size.
+	StageQuickHash = "quick-hash"
+
+	// StageSample is the hashing of extra samples of files with the same quick hash, if enabled.
+	StageSample = "sample"
+
+	// StageCompare is the block-by-block comparison of large files with the same samples, if enabled.
+	// Files stop being read as soon as they differ from all others.
+	StageCompare = "compare"
+
+	// StageFullHash is the hashing of the whole content of the other files with the same samples.
+	StageFullHash = "full-hash"
+)
+
+// ProgressObserver is notified of the progress of each stage of a scan.
+// Advance is called concurrently by the walker or the hashing workers.
+type ProgressObserver interface {
+	// StartStage records the start of a stage that processes files files holding bytes bytes in total.
+	// Both are zero when the totals are not known in advance, as when walking directories.
+	StartStage(stage string, files, bytes int64)
+
+	// Advance records files and bytes processed by a stage.
+	Advance(stage string, files, bytes int64)
+
+	// EndStage records the end of a stage.
+	EndStage(stage string)
+}
+
+// HashObserver is notified of every file hashed during a scan.
+// It is called concurrently by the hashing workers.
+type HashObserver interface {
+	// ObserveHash records the time taken to hash a file in a stage, and the bytes read from it.
+	ObserveHash(stage string, duration time.Duration, bytesRead int64)
+}
+
+// Kinds of entries skipped during a scan, as reported to an [EventObserver].
+const (
+	// SkipFile is a file excluded by the filters.
+	SkipFile = "file"
+
+	// SkipDir is a directory excluded by the filters, or already walked.
+	SkipDir = "directory"
+
+	// SkipMount is a mount point not crossed.
+	SkipMount = "mount point"
+
+	// SkipSymlink is a dangling symlink, or a symlinked directory already walked.
+	SkipSymlink = "symlink"
+)
+
+// EventObserver is notified of the events of a scan, to present them to users.
+// The scanning core never writes to the terminal itself.
+// FileSkipped is called by the walker, while Error is also called concurrently by the hashing workers.
+type EventObserver interface {
+	// StageStarted records the start of a stage that processes files files holding bytes bytes in total,
+	// as in [ProgressObserver.StartStage].
+	StageStarted(stage string, files, bytes int64)
+
+	// StageFinished records the end of a stage.
+	StageFinished(stage string)
+
+	// FileSkipped records an entry of a kind (such as [SkipFile]) left out of the scan, and the reason why.
+	FileSkipped(kind, path, reason string)
+
+	// Error records an error that left a file out of a stage, without stopping the scan.
+	Error(stage, path string, err error)
+
+	// GroupFound records a group of duplicates found.
+	GroupFound(group *DuplicateGroup)
+}
+
+// ObserveHash notifies the observer, if any, that a file was hashed.
+func (s *Stats) ObserveHash(stage string, duration time.Duration, bytesRead int64) {
+	if s.Observer != nil {
+		s.Observer.ObserveHash(stage, duration, bytesRead)
+	}
+}
+
+// StartStage notifies the progress and event observers, if any, that a stage started.
+func (s *Stats) StartStage(stage string, files, bytes int64) {
+	if s.Events != nil {
+		s.Events.StageStarted(stage, files, bytes)
+	}
+	if s.Progress != nil {
+		s.Progress.StartStage(stage, files, bytes)
+	}
+}
+
+// Advance notifies the progress observer, if any, that files and bytes were processed by a stage.
+func (s *Stats) Advance(stage string, files, bytes int64) {
+	if s.Progress != nil {
+		s.Progress.Advance(stage, files, bytes)
+	}
+}
+
+// EndStage notifies the progress and event observers, if any, that a stage ended.
+// The progress observer is notified first, so that a progress bar is done with its line.
+func (s *Stats) EndStage(stage string) {
+	if s.Progress != nil {
+		s.Progress.EndStage(stage)
+	}
+	if s.Events != nil {
+		s.Events.StageFinished(stage)
+	}
+}
+
+// Skip notifies the event observer, if any, that an entry was left out of the scan.
+// The entry is not counted: the caller increments the relevant counter.
+func (s *Stats) Skip(kind, path, reason string) {
+	if s.Events != nil {
+		s.Events.FileSkipped(kind, path, reason)
+	}
+}
+
+// ReportError counts an error that left a file out of a stage, records it in the error log, if any,
+// and notifies the event observer, if any.
+func (s *Stats) ReportError(stage, path string, err error) {
+	atomic.AddUint64(&s.ErrorCount, 1)
+	if s.Errors != nil {
+		s.Errors.Add(NewFileError(stage, path, err))
+	}
+	if s.Events != nil {
+		s.Events.Error(stage, path, err)
+	}
+}
+
+// FoundGroup notifies the event observer, if any, that a group of duplicates was found.
+// The group is not counted: the caller increments the relevant counters.
+func (s *Stats) FoundGroup(group *DuplicateGroup) {
+	if s.Events != nil {
+		s.Events.GroupFound(group)
+	}
+}
+
+// IncrementTotalFiles atomically increments the total files count.
+func (s *Stats) IncrementTotalFiles() {
+	atomic.AddUint64(&s.TotalFiles, 1)
+}
+
+// IncrementSkippedDirs atomically increments the skipped directories count.
+func (s *Stats) IncrementSkippedDirs() {
+	atomic.AddUint64(&s.SkippedDirs, 1)
+}
+
+// IncrementSkippedFiles atomically increments the skipped files count.
+func (s *Stats) IncrementSkippedFiles() {
+	atomic.AddUint64(&s.SkippedFiles, 1)
+}
+
+// IncrementSkippedMounts atomically increments the skipped mount points count.
+func (s *Stats) IncrementSkippedMounts() {
+	atomic.AddUint64(&s.SkippedMounts, 1)
+}
+
+// IncrementDanglingSymlinks atomically increments the dangling symlinks count.
+func (s *Stats) IncrementDanglingSymlinks() {
+	atomic.AddUint64(&s.DanglingSymlinks, 1)
 }
 
 // IncrementErrorCount atomically increments the error count.
@@ -116,3 +414,21 @@ func (s *Stats) GetDuplicateGroups() uint64 {
 func (s *Stats) GetDuplicateFiles() uint64 {
 	return atomic.LoadUint64(&s.DuplicateFiles)
 }
+
+// Snapshot atomically reads the counters, so that the progress of a running scan can be reported.
+// The skipped mount points, the duration, and the observers are left out,
+// since they are only set by the scanning goroutine.
+func (s *Stats) Snapshot() Stats {
+	return Stats{
+		TotalFiles:       atomic.LoadUint64(&s.TotalFiles),
+		ProcessedFiles:   atomic.LoadUint64(&s.ProcessedFiles),
+		SkippedDirs:      atomic.LoadUint64(&s.SkippedDirs),
+		SkippedFiles:     atomic.LoadUint64(&s.SkippedFiles),
+		SkippedMounts:    atomic.LoadUint64(&s.SkippedMounts),
+		DanglingSymlinks: atomic.LoadUint64(&s.DanglingSymlinks),
+		ErrorCount:       atomic.LoadUint64(&s.ErrorCount),
+		DuplicateGroups:  atomic.LoadUint64(&s.DuplicateGroups),
+		DuplicateFiles:   atomic.LoadUint64(&s.DuplicateFiles),
+		StartTime:        s.StartTime,
+	}
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestNewFileError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FileError
+	}{
+		{
+			name: "permission denied",
+			err:  &fs.PathError{Op: "open", Path: "/a", Err: fs.ErrPermission},
+			want: FileError{Path: "/a", Stage: StageQuickHash, Op: "open", Kind: ErrorPermission,
+				Message: "permission denied"},
+		},
+		{
+			name: "vanished",
+			err:  fmt.Errorf("error reading /a: %w", &fs.PathError{Op: "lstat", Path: "/a", Err: fs.ErrNotExist}),
+			want: FileError{Path: "/a", Stage: StageQuickHash, Op: "lstat", Kind: ErrorNotFound,
+				Message: "file does not exist"},
+		},
+		{
+			name: "I/O error",
+			err:  &fs.PathError{Op: "read", Path: "/a", Err: syscall.EIO},
+			want: FileError{Path: "/a", Stage: StageQuickHash, Op: "read", Kind: ErrorIO, Message: syscall.EIO.Error()},
+		},
+		{
+			name: "shrank",
+			err:  io.ErrUnexpectedEOF,
+			want: FileError{Path: "/a", Stage: StageQuickHash, Kind: ErrorIO, Message: "unexpected EOF"},
+		},
+		{
+			name: "other",
+			err:  errors.New("bad archive"),
+			want: FileError{Path: "/a", Stage: StageQuickHash, Kind: ErrorOther, Message: "bad archive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFileError(StageQuickHash, "/a", tt.err); got != tt.want {
+				t.Errorf("NewFileError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStats_ReportError verifies that errors are recorded in the error log, and listed sorted by path and stage.
+func TestStats_ReportError(t *testing.T) {
+	s := &Stats{Errors: &ErrorLog{}}
+	s.ReportError(StageFullHash, "/b", fs.ErrPermission)
+	s.ReportError(StageWalk, "/b", fs.ErrNotExist)
+	s.ReportError(StageQuickHash, "/a", io.ErrUnexpectedEOF)
+
+	var got []string
+	for _, fileErr := range s.Errors.List() {
+		got = append(got, fileErr.Path+" "+fileErr.Stage+" "+fileErr.Kind)
+	}
+	want := []string{"/a quick-hash io", "/b full-hash permission-denied", "/b walk not-found"}
+	if !reflect.DeepEqual(got, want) || s.ErrorCount != 3 {
+		t.Errorf("List() = %v with %d errors counted, want %v", got, s.ErrorCount, want)
+	}
+
+	var none *ErrorLog
+	if list := none.List(); list != nil {
+		t.Errorf("List() of a nil log = %v, want nil", list)
+	}
+}
@@ -15,12 +15,13 @@ import (
 func TestJSONFormatter_Format(t *testing.T) {
 	report := &model.DuplicateReport{
 		ScanDate: time.Now().UTC(),
+		Complete: true,
 		Stats: &model.Stats{
 			TotalFiles:      10,
 			ProcessedFiles:  8,
 			SkippedDirs:     1,
 			SkippedFiles:    1,
-			ErrorCount:      0,
+			ErrorCount:      1,
 			DuplicateFiles:  4,
 			DuplicateGroups: 2,
 			StartTime:       time.Now().UTC(),
@@ -43,6 +44,10 @@ func TestJSONFormatter_Format(t *testing.T) {
 				Files:       []string{"/tmp/bar1.txt", "/tmp/bar2.txt"},
 			},
 		},
+		Errors: []model.FileError{
+			{Path: "/tmp/locked.txt", Stage: model.StageQuickHash, Op: "open", Kind: model.ErrorPermission,
+				Message: "permission denied"},
+		},
 	}
 
 	var buf bytes.Buffer
@@ -67,10 +72,18 @@ func TestJSONFormatter_Format(t *testing.T) {
 		t.Errorf("Groups mismatch: got %v, want %v", got.Groups, report.Groups)
 	}
 
+	if !reflect.DeepEqual(got.Errors, report.Errors) {
+		t.Errorf("Errors mismatch: got %v, want %v", got.Errors, report.Errors)
+	}
+
 	if got.TotalWastedSpace != report.TotalWastedSpace {
 		t.Errorf("TotalWastedSpace mismatch: got %d, want %d", got.TotalWastedSpace, report.TotalWastedSpace)
 	}
 
+	if got.Complete != report.Complete {
+		t.Errorf("Complete mismatch: got %v, want %v", got.Complete, report.Complete)
+	}
+
 	if got.ScanDate != report.ScanDate {
 		t.Errorf("ScanDate mismatch: got %v, want %v", got.ScanDate, report.ScanDate)
 	}
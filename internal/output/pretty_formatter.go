@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
@@ -82,19 +83,58 @@ func (f *PrettyFormatter) Format(report *model.DuplicateReport, w io.Writer) err
 			return err
 		}
 
-		// Print files
+		// Print files, marking the ones that are kept
 		for _, file := range group.Files {
 			fileLine := fileStyle.Render(fmt.Sprintf("📄 \"%s\"", file))
+			switch {
+			case slices.Contains(group.References, file):
+				fileLine = statValueStyle.Render(fmt.Sprintf("📌 \"%s\" (reference)", file))
+			case slices.Contains(group.ReadOnly, file):
+				fileLine = statLabelStyle.Render(fmt.Sprintf("🔒 \"%s\" (read-only)", file))
+			}
 			if _, err := lipgloss.Fprintf(w, "   %s\n", fileLine); err != nil {
 				return err
 			}
 		}
+
+		// Print symlinks resolving to files in this group
+		for _, link := range group.Symlinks {
+			linkLine := statLabelStyle.Render(fmt.Sprintf("🔗 symlink \"%s\"", link))
+			if _, err := lipgloss.Fprintf(w, "   %s\n", linkLine); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Errors
+	if len(report.Errors) > 0 {
+		header := errorStyle.Bold(true).Render(fmt.Sprintf("\n⚠️ Files with errors (%d):", len(report.Errors)))
+		if _, err := lipgloss.Fprintln(w, header); err != nil {
+			return err
+		}
+	}
+	for _, fileErr := range report.Errors {
+		where := fileErr.Stage
+		if fileErr.Op != "" {
+			where += ", " + fileErr.Op
+		}
+		line := errorStyle.Render(fmt.Sprintf("❌ \"%s\"", fileErr.Path)) + " " +
+			statLabelStyle.Render(fmt.Sprintf("[%s] %s: %s", where, fileErr.Kind, fileErr.Message))
+		if _, err := lipgloss.Fprintf(w, "   %s\n", line); err != nil {
+			return err
+		}
 	}
 
 	// Summary
 	if _, err := lipgloss.Fprintln(w, summaryHeaderStyle.Render("\n📊 Summary:")); err != nil {
 		return err
 	}
+	if !report.Complete {
+		interrupted := wastedStyle.Render("⚠️ The scan was interrupted: only the duplicates confirmed so far are listed.")
+		if _, err := lipgloss.Fprintf(w, "   %s\n", interrupted); err != nil {
+			return err
+		}
+	}
 
 	if report.Stats.DuplicateFiles > 0 {
 		found := statLabelStyle.Render("🔗 Duplicate files found:") + " " + statValueStyle.Render(strconv.FormatUint(report.Stats.DuplicateFiles, 10)) +
@@ -130,6 +170,16 @@ func (f *PrettyFormatter) Format(report *model.DuplicateReport, w io.Writer) err
 	if _, err := lipgloss.Fprintf(w, "   %s %s\n", statLabelStyle.Render("⏭️ Files skipped:"), statValueStyle.Render(strconv.FormatUint(report.Stats.SkippedFiles, 10))); err != nil {
 		return err
 	}
+	if report.Stats.SkippedMounts > 0 {
+		if _, err := lipgloss.Fprintf(w, "   %s %s\n", statLabelStyle.Render("⏭️ Mount points skipped:"), statValueStyle.Render(strconv.FormatUint(report.Stats.SkippedMounts, 10))); err != nil {
+			return err
+		}
+	}
+	if report.Stats.DanglingSymlinks > 0 {
+		if _, err := lipgloss.Fprintf(w, "   %s %s\n", statLabelStyle.Render("🔗 Dangling symlinks:"), statValueStyle.Render(strconv.FormatUint(report.Stats.DanglingSymlinks, 10))); err != nil {
+			return err
+		}
+	}
 	if _, err := lipgloss.Fprintf(w, "   %s %s\n", statLabelStyle.Render("❌ Files with errors:"), errorStyle.Render(strconv.FormatUint(report.Stats.ErrorCount, 10))); err != nil {
 		return err
 	}
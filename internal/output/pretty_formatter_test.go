@@ -15,6 +15,7 @@ import (
 func TestPrettyFormatter_Format(t *testing.T) {
 	report := &model.DuplicateReport{
 		ScanDate: time.Now().UTC(),
+		Complete: true,
 		Stats: &model.Stats{
 			TotalFiles:      10,
 			ProcessedFiles:  8,
@@ -78,4 +79,55 @@ func TestPrettyFormatter_Format(t *testing.T) {
 			t.Errorf("Output missing expected phrase: %q", phrase)
 		}
 	}
+
+	if strings.Contains(output, "interrupted") {
+		t.Error("Output of a complete scan mentions an interruption")
+	}
+}
+
+// TestPrettyFormatter_Format_Errors verifies that the files that could not be read are listed.
+func TestPrettyFormatter_Format_Errors(t *testing.T) {
+	report := &model.DuplicateReport{
+		ScanDate: time.Now().UTC(),
+		Complete: true,
+		Stats:    &model.Stats{TotalFiles: 3, ErrorCount: 2},
+		Errors: []model.FileError{
+			{Path: "/tmp/gone.txt", Stage: model.StageWalk, Op: "lstat", Kind: model.ErrorNotFound,
+				Message: "no such file or directory"},
+			{Path: "/tmp/locked.txt", Stage: model.StageFullHash, Kind: model.ErrorOther, Message: "failed"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewPrettyFormatter().Format(report, &buf); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	output := buf.String()
+	checks := []string{
+		"Files with errors (2):",
+		`"/tmp/gone.txt" [walk, lstat] not-found: no such file or directory`,
+		`"/tmp/locked.txt" [full-hash] other: failed`,
+		"Files with errors: 2",
+	}
+	for _, phrase := range checks {
+		if !strings.Contains(output, phrase) {
+			t.Errorf("Output missing expected phrase: %q", phrase)
+		}
+	}
+}
+
+// TestPrettyFormatter_Format_Interrupted verifies that the report of an interrupted scan is marked partial.
+func TestPrettyFormatter_Format_Interrupted(t *testing.T) {
+	report := &model.DuplicateReport{
+		ScanDate: time.Now().UTC(),
+		Stats:    &model.Stats{TotalFiles: 3},
+	}
+
+	var buf bytes.Buffer
+	if err := NewPrettyFormatter().Format(report, &buf); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "The scan was interrupted") {
+		t.Errorf("Output missing the interruption notice: %q", buf.String())
+	}
 }
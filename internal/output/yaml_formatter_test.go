@@ -19,7 +19,7 @@ func TestYAMLFormatter_Format(t *testing.T) {
 			ProcessedFiles:  8,
 			SkippedDirs:     1,
 			SkippedFiles:    1,
-			ErrorCount:      0,
+			ErrorCount:      1,
 			DuplicateFiles:  4,
 			DuplicateGroups: 2,
 			StartTime:       time.Now().UTC(),
@@ -43,6 +43,10 @@ func TestYAMLFormatter_Format(t *testing.T) {
 				Files:       []string{"file3.txt", "file4.txt"},
 			},
 		},
+		Errors: []model.FileError{
+			{Path: "/tmp/locked.txt", Stage: model.StageQuickHash, Op: "open", Kind: model.ErrorPermission,
+				Message: "permission denied"},
+		},
 	}
 
 	var buf bytes.Buffer
@@ -66,6 +70,10 @@ func TestYAMLFormatter_Format(t *testing.T) {
 		t.Errorf("Groups mismatch: got %v, want %v", got.Groups, report.Groups)
 	}
 
+	if !reflect.DeepEqual(got.Errors, report.Errors) {
+		t.Errorf("Errors mismatch: got %v, want %v", got.Errors, report.Errors)
+	}
+
 	if got.TotalWastedSpace != report.TotalWastedSpace {
 		t.Errorf("TotalWastedSpace mismatch: got %d, want %d", got.TotalWastedSpace, report.TotalWastedSpace)
 	}
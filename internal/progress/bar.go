@@ -0,0 +1,108 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dr8co/doppel/internal/output"
+)
+
+// barWidth is the number of cells of the bar itself.
+const barWidth = 24
+
+// Bar renders the progress of the stage in progress as a bar redrawn in place on a terminal,
+// and leaves a summary line behind for each stage that ends.
+type Bar struct {
+	w     io.Writer
+	width int
+
+	// ended is the number of stages whose summary line was written.
+	ended int
+
+	// drawn reports whether a line of the stage in progress is on the screen.
+	drawn bool
+}
+
+// NewBar returns a bar writing to w, a terminal width columns wide (0 if unknown).
+func NewBar(w io.Writer, width int) *Bar {
+	return &Bar{w: w, width: width}
+}
+
+// Update redraws the bar, after writing the summary of the stages that ended since the last update.
+func (b *Bar) Update(s Snapshot) {
+	for ; b.ended < len(s.Stages) && s.Stages[b.ended].Done; b.ended++ {
+		b.draw(s.Stages[b.ended])
+		_, _ = io.WriteString(b.w, "\n")
+		b.drawn = false
+	}
+	if p, ok := s.Current(); ok {
+		b.draw(p)
+		b.drawn = true
+	}
+}
+
+// Finish writes the final progress, leaving the cursor on a new line.
+func (b *Bar) Finish(s Snapshot) {
+	b.Update(s)
+	if b.drawn {
+		_, _ = io.WriteString(b.w, "\n")
+		b.drawn = false
+	}
+}
+
+// draw replaces the current line with the progress of a stage.
+func (b *Bar) draw(p StageProgress) {
+	line := b.line(p)
+	if b.width > 0 {
+		if runes := []rune(line); len(runes) >= b.width {
+			line = string(runes[:b.width-1])
+		}
+	}
+	_, _ = io.WriteString(b.w, "\r\x1b[2K"+line)
+}
+
+// line formats the progress of a stage.
+func (b *Bar) line(p StageProgress) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-10s", p.Stage)
+
+	if f, ok := p.Fraction(); ok {
+		filled := int(f * barWidth)
+		fmt.Fprintf(&sb, " [%s%s] %3.0f%%", strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), f*100)
+	}
+
+	if p.TotalFiles > 0 {
+		fmt.Fprintf(&sb, "  %d/%d files", p.Files, p.TotalFiles)
+	} else {
+		fmt.Fprintf(&sb, "  %d files", p.Files)
+	}
+	if p.TotalBytes > 0 {
+		fmt.Fprintf(&sb, "  %s/%s", output.FormatBytes(p.Bytes), output.FormatBytes(p.TotalBytes))
+	} else {
+		fmt.Fprintf(&sb, "  %s", output.FormatBytes(p.Bytes))
+	}
+
+	if p.TotalBytes > 0 || p.TotalFiles == 0 {
+		fmt.Fprintf(&sb, "  %s/s", output.FormatBytes(int64(p.BytesPerSecond())))
+	} else {
+		fmt.Fprintf(&sb, "  %.0f files/s", p.FilesPerSecond())
+	}
+
+	if p.Done {
+		fmt.Fprintf(&sb, "  in %s", formatDuration(p.Elapsed))
+	} else if eta, ok := p.ETA(); ok {
+		fmt.Fprintf(&sb, "  ETA %s", formatDuration(eta))
+	}
+
+	return sb.String()
+}
+
+// formatDuration rounds a duration for display.
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
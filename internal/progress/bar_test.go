@@ -0,0 +1,66 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBar verifies that the bar is redrawn in place, and that a summary line is left for each ended stage.
+func TestBar(t *testing.T) {
+	clock := newFakeClock()
+	tr := newTracker(clock.Now)
+	var buf bytes.Buffer
+	b := NewBar(&buf, 0)
+
+	tr.StartStage("quick-hash", 4, 4000)
+	tr.Advance("quick-hash", 2, 2000)
+	clock.Advance(2 * time.Second)
+	b.Update(tr.Snapshot())
+
+	line := buf.String()
+	if !strings.HasPrefix(line, "\r\x1b[2K") || strings.Contains(line, "\n") {
+		t.Errorf("Update() wrote %q, want a single line redrawn in place", line)
+	}
+	for _, want := range []string{"quick-hash", " 50%", "2/4 files", "2.0 KB/4.0 KB", "1.0 KB/s", "ETA 2s"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Update() wrote %q, missing %q", line, want)
+		}
+	}
+
+	buf.Reset()
+	tr.Advance("quick-hash", 2, 2000)
+	tr.EndStage("quick-hash")
+	b.Update(tr.Snapshot())
+	summary := buf.String()
+	if !strings.HasSuffix(summary, "\n") || !strings.Contains(summary, "100%") || !strings.Contains(summary, "in 2s") {
+		t.Errorf("Update() after the stage ended wrote %q, want a summary line", summary)
+	}
+
+	buf.Reset()
+	b.Update(tr.Snapshot())
+	b.Finish(tr.Snapshot())
+	if buf.Len() != 0 {
+		t.Errorf("Update() and Finish() with no stage in progress wrote %q", buf.String())
+	}
+}
+
+// TestBar_Width verifies that lines are cut to the width of the terminal.
+func TestBar_Width(t *testing.T) {
+	tr := NewTracker()
+	var buf bytes.Buffer
+	b := NewBar(&buf, 20)
+
+	tr.StartStage("walk", 0, 0)
+	tr.Advance("walk", 12345, 1<<30)
+	b.Finish(tr.Snapshot())
+
+	line := strings.TrimSuffix(strings.TrimPrefix(buf.String(), "\r\x1b[2K"), "\n")
+	if n := len([]rune(line)); n != 19 {
+		t.Errorf("Finish() wrote a line of %d characters (%q), want 19", n, line)
+	}
+	if !strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("Finish() wrote %q, want the cursor left on a new line", buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Reporter renders the progress of a scan.
+// Calls are serialized by the [Display] that owns the reporter.
+type Reporter interface {
+	// Update renders the progress so far.
+	// It is called periodically, and as soon as a stage ends.
+	Update(s Snapshot)
+
+	// Finish renders the final progress, once the scan is done or abandoned.
+	Finish(s Snapshot)
+}
+
+// Display renders the progress of a tracker with reporters.
+type Display struct {
+	tracker   *Tracker
+	reporters []Reporter
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start updates reporters with the progress tracked by t every interval, and whenever a stage ends,
+// until [Display.Stop] is called.
+func Start(t *Tracker, interval time.Duration, reporters ...Reporter) *Display {
+	d := &Display{
+		tracker:   t,
+		reporters: reporters,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	t.onStageEnd(d.update)
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.update()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+
+	return d
+}
+
+// update hands a snapshot of the progress to the reporters.
+func (d *Display) update() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.tracker.Snapshot()
+	for _, r := range d.reporters {
+		r.Update(s)
+	}
+}
+
+// Stop stops the periodic updates, and hands the final progress to the reporters.
+func (d *Display) Stop() {
+	close(d.stop)
+	<-d.done
+	d.tracker.onStageEnd(nil)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.tracker.Snapshot()
+	for _, r := range d.reporters {
+		r.Finish(s)
+	}
+}
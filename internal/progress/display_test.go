@@ -0,0 +1,61 @@
+package progress
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the snapshots it is handed.
+type recorder struct {
+	mu       sync.Mutex
+	updates  []Snapshot
+	finished []Snapshot
+}
+
+func (r *recorder) Update(s Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates = append(r.updates, s)
+}
+
+func (r *recorder) Finish(s Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, s)
+}
+
+// TestDisplay verifies that reporters are updated as soon as a stage ends, and finished once stopped.
+func TestDisplay(t *testing.T) {
+	tr := NewTracker()
+	r := &recorder{}
+	d := Start(tr, time.Hour, r)
+
+	tr.StartStage("walk", 0, 0)
+	tr.Advance("walk", 1, 1)
+	tr.EndStage("walk")
+
+	r.mu.Lock()
+	updates := len(r.updates)
+	var ended bool
+	if updates > 0 {
+		last := r.updates[updates-1]
+		ended = len(last.Stages) == 1 && last.Stages[0].Done
+	}
+	r.mu.Unlock()
+	if !ended {
+		t.Errorf("reporter was not updated with the ended stage (%d updates)", updates)
+	}
+
+	d.Stop()
+	if len(r.finished) != 1 {
+		t.Fatalf("reporter finished %d times, want 1", len(r.finished))
+	}
+
+	// The tracker no longer signals the display once stopped.
+	tr.StartStage("quick-hash", 1, 1)
+	tr.EndStage("quick-hash")
+	if len(r.updates) != updates {
+		t.Errorf("reporter updated after the display stopped")
+	}
+}
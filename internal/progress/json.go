@@ -0,0 +1,121 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSON writes the progress as newline-delimited JSON events:
+//   - "progress" events with the progress of the stage in progress, at most once per interval;
+//   - a "stage_done" event with the final progress of each stage, as soon as it ends;
+//   - a "done" event with the final progress of every stage, at the end of the scan.
+//
+// Writing stops at the first error, so that a reader going away never affects the scan.
+type JSON struct {
+	enc      *json.Encoder
+	interval time.Duration
+	last     time.Time
+	ended    int
+	err      error
+}
+
+// jsonEvent is a progress event.
+type jsonEvent struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Elapsed float64   `json:"elapsed_seconds"`
+
+	*jsonStage
+
+	Stages []jsonStage `json:"stages,omitempty"`
+}
+
+// jsonStage is the progress of a stage in an event.
+type jsonStage struct {
+	Stage          string   `json:"stage"`
+	Files          int64    `json:"files"`
+	TotalFiles     int64    `json:"total_files,omitempty"`
+	Bytes          int64    `json:"bytes"`
+	TotalBytes     int64    `json:"total_bytes,omitempty"`
+	Percent        *float64 `json:"percent,omitempty"`
+	FilesPerSecond float64  `json:"files_per_second"`
+	BytesPerSecond float64  `json:"bytes_per_second"`
+	StageElapsed   float64  `json:"stage_elapsed_seconds"`
+	ETA            *float64 `json:"eta_seconds,omitempty"`
+	Done           bool     `json:"done"`
+}
+
+// NewJSON returns a reporter writing events to w, with progress events at most once per interval.
+func NewJSON(w io.Writer, interval time.Duration) *JSON {
+	return &JSON{enc: json.NewEncoder(w), interval: interval}
+}
+
+// Err returns the first error writing the events, if any.
+func (j *JSON) Err() error {
+	return j.err
+}
+
+// Update writes an event for each stage that ended since the last update,
+// and a progress event if the interval elapsed since the last one.
+func (j *JSON) Update(s Snapshot) {
+	j.writeEnded(s)
+
+	p, ok := s.Current()
+	if !ok || s.Time.Sub(j.last) < j.interval {
+		return
+	}
+	j.last = s.Time
+	st := newJSONStage(p)
+	j.write(jsonEvent{Event: "progress", Time: s.Time, Elapsed: s.Elapsed.Seconds(), jsonStage: &st})
+}
+
+// Finish writes the events of the stages that ended, followed by the "done" event.
+func (j *JSON) Finish(s Snapshot) {
+	j.writeEnded(s)
+
+	stages := make([]jsonStage, 0, len(s.Stages))
+	for _, p := range s.Stages {
+		stages = append(stages, newJSONStage(p))
+	}
+	j.write(jsonEvent{Event: "done", Time: s.Time, Elapsed: s.Elapsed.Seconds(), Stages: stages})
+}
+
+// writeEnded writes an event for each stage that ended since the last call.
+func (j *JSON) writeEnded(s Snapshot) {
+	for ; j.ended < len(s.Stages) && s.Stages[j.ended].Done; j.ended++ {
+		st := newJSONStage(s.Stages[j.ended])
+		j.write(jsonEvent{Event: "stage_done", Time: s.Time, Elapsed: s.Elapsed.Seconds(), jsonStage: &st})
+	}
+}
+
+// write writes an event, unless an earlier write failed.
+func (j *JSON) write(e jsonEvent) {
+	if j.err == nil {
+		j.err = j.enc.Encode(e)
+	}
+}
+
+// newJSONStage returns the event form of the progress of a stage.
+func newJSONStage(p StageProgress) jsonStage {
+	st := jsonStage{
+		Stage:          p.Stage,
+		Files:          p.Files,
+		TotalFiles:     p.TotalFiles,
+		Bytes:          p.Bytes,
+		TotalBytes:     p.TotalBytes,
+		FilesPerSecond: p.FilesPerSecond(),
+		BytesPerSecond: p.BytesPerSecond(),
+		StageElapsed:   p.Elapsed.Seconds(),
+		Done:           p.Done,
+	}
+	if f, ok := p.Fraction(); ok {
+		percent := f * 100
+		st.Percent = &percent
+	}
+	if eta, ok := p.ETA(); ok {
+		seconds := eta.Seconds()
+		st.ETA = &seconds
+	}
+	return st
+}
@@ -0,0 +1,114 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestJSON verifies the events written as the stages of a scan progress and end.
+func TestJSON(t *testing.T) {
+	clock := newFakeClock()
+	tr := newTracker(clock.Now)
+	var buf bytes.Buffer
+	j := NewJSON(&buf, time.Second)
+
+	tr.StartStage("walk", 0, 0)
+	tr.Advance("walk", 2, 200)
+	clock.Advance(time.Second)
+	j.Update(tr.Snapshot())
+
+	// Throttled: less than the interval since the last progress event.
+	clock.Advance(100 * time.Millisecond)
+	j.Update(tr.Snapshot())
+
+	tr.EndStage("walk")
+	tr.StartStage("full-hash", 4, 400)
+	tr.Advance("full-hash", 1, 100)
+	clock.Advance(time.Second)
+	j.Update(tr.Snapshot())
+	tr.EndStage("full-hash")
+	j.Finish(tr.Snapshot())
+
+	if err := j.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	type event struct {
+		Event      string     `json:"event"`
+		Stage      string     `json:"stage"`
+		Files      int64      `json:"files"`
+		TotalFiles int64      `json:"total_files"`
+		Percent    *float64   `json:"percent"`
+		ETA        *float64   `json:"eta_seconds"`
+		Done       bool       `json:"done"`
+		Stages     []struct{} `json:"stages"`
+	}
+	var events []event
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var e event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("invalid event %q: %v", sc.Text(), err)
+		}
+		events = append(events, e)
+	}
+
+	wantEvents := []string{"progress", "stage_done", "progress", "stage_done", "done"}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("got %d events %+v, want %v", len(events), events, wantEvents)
+	}
+	for i, want := range wantEvents {
+		if events[i].Event != want {
+			t.Errorf("event %d = %q, want %q", i, events[i].Event, want)
+		}
+	}
+
+	walk := events[0]
+	if walk.Stage != "walk" || walk.Files != 2 || walk.Percent != nil || walk.ETA != nil {
+		t.Errorf("walk progress event = %+v, want 2 files without percent or ETA", walk)
+	}
+	hash := events[2]
+	if hash.Stage != "full-hash" || hash.TotalFiles != 4 || hash.Percent == nil || *hash.Percent != 25 ||
+		hash.ETA == nil || *hash.ETA != 3 {
+		t.Errorf("full-hash progress event = %+v, want 25%% with an ETA of 3s", hash)
+	}
+	if !events[3].Done || events[3].Stage != "full-hash" {
+		t.Errorf("stage_done event = %+v", events[3])
+	}
+	if len(events[4].Stages) != 2 {
+		t.Errorf("done event has %d stages, want 2", len(events[4].Stages))
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+// TestJSON_WriteError verifies that writing stops at the first error.
+func TestJSON_WriteError(t *testing.T) {
+	tr := NewTracker()
+	w := &failingWriter{}
+	j := NewJSON(w, 0)
+
+	tr.StartStage("walk", 0, 0)
+	j.Update(tr.Snapshot())
+	tr.EndStage("walk")
+	j.Finish(tr.Snapshot())
+
+	if j.Err() == nil {
+		t.Error("Err() = nil, want the write error")
+	}
+	if w.writes != 1 {
+		t.Errorf("%d writes, want 1", w.writes)
+	}
+}
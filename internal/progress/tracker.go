@@ -0,0 +1,228 @@
+// Package progress tracks and reports the progress of a scan.
+//
+// A [Tracker] counts the files and bytes processed by each stage of a scan (walking the directories,
+// quick hashing, full hashing) as a [model.ProgressObserver]. A [Display] periodically takes a
+// [Snapshot] of it, from which throughput and time estimates are derived, and hands it to reporters:
+// a [Bar] for terminals, and [JSON] events for scripts and graphical front ends.
+package progress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tracker tracks the files and bytes processed by each stage of a scan.
+// It implements [model.ProgressObserver], and is safe for concurrent use.
+type Tracker struct {
+	start time.Time
+	now   func() time.Time
+
+	// current is the stage in progress, so that advancing it does not take the lock.
+	current atomic.Pointer[stageState]
+
+	mu     sync.Mutex
+	stages []*stageState
+	onEnd  func()
+}
+
+// stageState holds the counters of a stage.
+type stageState struct {
+	name       string
+	totalFiles int64
+	totalBytes int64
+	started    time.Time
+
+	files atomic.Int64
+	bytes atomic.Int64
+
+	// ended is guarded by the mutex of the tracker.
+	ended time.Time
+}
+
+// NewTracker returns a tracker for a scan starting now.
+func NewTracker() *Tracker {
+	return newTracker(time.Now)
+}
+
+// newTracker returns a tracker reading the time from now.
+func newTracker(now func() time.Time) *Tracker {
+	return &Tracker{start: now(), now: now}
+}
+
+// StartStage records the start of a stage processing files files and bytes bytes in total,
+// or an unknown amount if they are zero.
+func (t *Tracker) StartStage(stage string, files, bytes int64) {
+	st := &stageState{name: stage, totalFiles: files, totalBytes: bytes, started: t.now()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stages = append(t.stages, st)
+	t.current.Store(st)
+}
+
+// Advance records files and bytes processed by a stage. Stages that were not started are ignored.
+func (t *Tracker) Advance(stage string, files, bytes int64) {
+	st := t.current.Load()
+	if st == nil || st.name != stage {
+		t.mu.Lock()
+		st = t.lookup(stage)
+		t.mu.Unlock()
+		if st == nil {
+			return
+		}
+	}
+	st.files.Add(files)
+	st.bytes.Add(bytes)
+}
+
+// EndStage records the end of a stage.
+// The function set by [Tracker.onStageEnd] is then called, before EndStage returns.
+func (t *Tracker) EndStage(stage string) {
+	t.mu.Lock()
+	st := t.lookup(stage)
+	if st != nil && st.ended.IsZero() {
+		st.ended = t.now()
+	}
+	if t.current.Load() == st {
+		t.current.Store(nil)
+	}
+	onEnd := t.onEnd
+	t.mu.Unlock()
+
+	if onEnd != nil {
+		onEnd()
+	}
+}
+
+// onStageEnd sets a function to call whenever a stage ends.
+func (t *Tracker) onStageEnd(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEnd = f
+}
+
+// lookup returns the latest stage with a name, or nil. The mutex must be held.
+func (t *Tracker) lookup(stage string) *stageState {
+	for i := len(t.stages) - 1; i >= 0; i-- {
+		if t.stages[i].name == stage {
+			return t.stages[i]
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the progress so far.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	s := Snapshot{Time: now, Elapsed: now.Sub(t.start), Stages: make([]StageProgress, 0, len(t.stages))}
+	for _, st := range t.stages {
+		end, done := now, !st.ended.IsZero()
+		if done {
+			end = st.ended
+		}
+		s.Stages = append(s.Stages, StageProgress{
+			Stage:      st.name,
+			Files:      st.files.Load(),
+			TotalFiles: st.totalFiles,
+			Bytes:      st.bytes.Load(),
+			TotalBytes: st.totalBytes,
+			Elapsed:    end.Sub(st.started),
+			Done:       done,
+		})
+	}
+	return s
+}
+
+// Snapshot is the progress of a scan at some point in time.
+type Snapshot struct {
+	// Time is the time the snapshot was taken.
+	Time time.Time
+
+	// Elapsed is the time since the scan started.
+	Elapsed time.Duration
+
+	// Stages holds the progress of the stages started so far, in the order they started.
+	Stages []StageProgress
+}
+
+// Current returns the progress of the stage in progress, if any.
+func (s Snapshot) Current() (StageProgress, bool) {
+	if n := len(s.Stages); n > 0 && !s.Stages[n-1].Done {
+		return s.Stages[n-1], true
+	}
+	return StageProgress{}, false
+}
+
+// StageProgress is the progress of a stage.
+type StageProgress struct {
+	// Stage names the stage.
+	Stage string
+
+	// Files is the number of files processed so far.
+	Files int64
+
+	// TotalFiles is the number of files to process, or zero if unknown.
+	TotalFiles int64
+
+	// Bytes is the number of bytes processed so far.
+	Bytes int64
+
+	// TotalBytes is the number of bytes to process, or zero if unknown.
+	TotalBytes int64
+
+	// Elapsed is the time since the stage started, or the time it took if it is done.
+	Elapsed time.Duration
+
+	// Done reports whether the stage ended.
+	Done bool
+}
+
+// Fraction returns the fraction of the stage done, measured in bytes if the total is known, in files otherwise.
+// It returns false if neither total is known.
+func (p StageProgress) Fraction() (float64, bool) {
+	switch {
+	case p.Done:
+		return 1, true
+	case p.TotalBytes > 0:
+		return min(float64(p.Bytes)/float64(p.TotalBytes), 1), true
+	case p.TotalFiles > 0:
+		return min(float64(p.Files)/float64(p.TotalFiles), 1), true
+	default:
+		return 0, false
+	}
+}
+
+// FilesPerSecond returns the average number of files processed per second.
+func (p StageProgress) FilesPerSecond() float64 {
+	return perSecond(p.Files, p.Elapsed)
+}
+
+// BytesPerSecond returns the average number of bytes processed per second.
+func (p StageProgress) BytesPerSecond() float64 {
+	return perSecond(p.Bytes, p.Elapsed)
+}
+
+// ETA returns the estimated time until the stage ends, extrapolated from its average throughput.
+// It returns false when there is not enough progress to estimate it.
+func (p StageProgress) ETA() (time.Duration, bool) {
+	if p.Done {
+		return 0, true
+	}
+	f, ok := p.Fraction()
+	if !ok || f <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(p.Elapsed) * (1 - f) / f), true
+}
+
+// perSecond returns n divided by the elapsed seconds, or zero before any time elapsed.
+func perSecond(n int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
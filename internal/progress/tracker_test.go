@@ -0,0 +1,171 @@
+package progress
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced by hand.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestTracker verifies that stages are counted separately, in the order they started,
+// and that concurrent advances are all counted.
+func TestTracker(t *testing.T) {
+	clock := newFakeClock()
+	tr := newTracker(clock.Now)
+
+	tr.StartStage("walk", 0, 0)
+	for range 3 {
+		tr.Advance("walk", 1, 100)
+	}
+	clock.Advance(2 * time.Second)
+	tr.EndStage("walk")
+
+	tr.StartStage("quick-hash", 100, 1000)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			for range 5 {
+				tr.Advance("quick-hash", 1, 10)
+			}
+		})
+	}
+	wg.Wait()
+	tr.Advance("missing", 1, 1)
+	clock.Advance(5 * time.Second)
+
+	s := tr.Snapshot()
+	if s.Elapsed != 7*time.Second {
+		t.Errorf("Snapshot().Elapsed = %v, want 7s", s.Elapsed)
+	}
+	want := []StageProgress{
+		{Stage: "walk", Files: 3, Bytes: 300, Elapsed: 2 * time.Second, Done: true},
+		{Stage: "quick-hash", Files: 50, TotalFiles: 100, Bytes: 500, TotalBytes: 1000, Elapsed: 5 * time.Second},
+	}
+	if len(s.Stages) != len(want) {
+		t.Fatalf("Snapshot().Stages = %+v, want %+v", s.Stages, want)
+	}
+	for i := range want {
+		if s.Stages[i] != want[i] {
+			t.Errorf("Snapshot().Stages[%d] = %+v, want %+v", i, s.Stages[i], want[i])
+		}
+	}
+
+	current, ok := s.Current()
+	if !ok || current.Stage != "quick-hash" {
+		t.Errorf("Snapshot().Current() = %+v, %v, want the quick-hash stage", current, ok)
+	}
+
+	tr.EndStage("quick-hash")
+	if _, ok := tr.Snapshot().Current(); ok {
+		t.Error("Snapshot().Current() reports a stage after every stage ended")
+	}
+}
+
+// TestTracker_OnStageEnd verifies that the end of a stage is signaled before EndStage returns.
+func TestTracker_OnStageEnd(t *testing.T) {
+	tr := NewTracker()
+	var ended []string
+	tr.onStageEnd(func() {
+		s := tr.Snapshot()
+		ended = append(ended, s.Stages[len(s.Stages)-1].Stage)
+	})
+
+	tr.StartStage("walk", 0, 0)
+	tr.EndStage("walk")
+	tr.StartStage("full-hash", 2, 2)
+	tr.EndStage("full-hash")
+
+	if len(ended) != 2 || ended[0] != "walk" || ended[1] != "full-hash" {
+		t.Errorf("stages signaled as ended = %v, want [walk full-hash]", ended)
+	}
+}
+
+// TestStageProgress verifies the fractions, rates, and estimates derived from the progress of a stage.
+func TestStageProgress(t *testing.T) {
+	tests := []struct {
+		name         string
+		progress     StageProgress
+		wantFraction float64
+		wantKnown    bool
+		wantETA      time.Duration
+		wantETAKnown bool
+	}{
+		{
+			name:     "unknown totals",
+			progress: StageProgress{Files: 10, Bytes: 100, Elapsed: time.Second},
+		},
+		{
+			name:         "measured in bytes",
+			progress:     StageProgress{Files: 90, TotalFiles: 100, Bytes: 250, TotalBytes: 1000, Elapsed: 10 * time.Second},
+			wantFraction: 0.25,
+			wantKnown:    true,
+			wantETA:      30 * time.Second,
+			wantETAKnown: true,
+		},
+		{
+			name:         "measured in files",
+			progress:     StageProgress{Files: 5, TotalFiles: 10, Elapsed: 4 * time.Second},
+			wantFraction: 0.5,
+			wantKnown:    true,
+			wantETA:      4 * time.Second,
+			wantETAKnown: true,
+		},
+		{
+			name:      "nothing done yet",
+			progress:  StageProgress{TotalFiles: 10, TotalBytes: 100, Elapsed: time.Second},
+			wantKnown: true,
+		},
+		{
+			name:         "done",
+			progress:     StageProgress{Files: 3, TotalFiles: 10, Elapsed: time.Second, Done: true},
+			wantFraction: 1,
+			wantKnown:    true,
+			wantETAKnown: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := tt.progress.Fraction()
+			if f != tt.wantFraction || ok != tt.wantKnown {
+				t.Errorf("Fraction() = %v, %v, want %v, %v", f, ok, tt.wantFraction, tt.wantKnown)
+			}
+			eta, ok := tt.progress.ETA()
+			if eta != tt.wantETA || ok != tt.wantETAKnown {
+				t.Errorf("ETA() = %v, %v, want %v, %v", eta, ok, tt.wantETA, tt.wantETAKnown)
+			}
+		})
+	}
+
+	p := StageProgress{Files: 30, Bytes: 3000, Elapsed: 3 * time.Second}
+	if got := p.FilesPerSecond(); got != 10 {
+		t.Errorf("FilesPerSecond() = %v, want 10", got)
+	}
+	if got := p.BytesPerSecond(); got != 1000 {
+		t.Errorf("BytesPerSecond() = %v, want 1000", got)
+	}
+	if got := (StageProgress{Files: 1}).FilesPerSecond(); got != 0 {
+		t.Errorf("FilesPerSecond() before any time elapsed = %v, want 0", got)
+	}
+}
@@ -0,0 +1,380 @@
+// Package s3 scans objects in Amazon S3 and S3-compatible object storage.
+//
+// It provides a minimal client for the few read-only operations the scanner needs (listing, stat, and
+// ranged reads), signed with AWS Signature Version 4, and a [vfs.FS] over the objects of a bucket.
+// Objects are named by URLs such as "s3://bucket/prefix/key", and "/" in keys acts as a directory separator.
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures a [Client].
+type Config struct {
+	// Endpoint is the base URL of an S3-compatible service, such as "http://localhost:9000".
+	// If empty, Amazon S3 is used.
+	Endpoint string
+
+	// Region is the region requests are signed for, "us-east-1" by default.
+	Region string
+
+	// Credentials sign the requests. Requests are sent unsigned if they are empty.
+	Credentials Credentials
+
+	// PathStyle addresses buckets as part of the path instead of the host name.
+	// It is always used with a custom endpoint.
+	PathStyle bool
+
+	// HTTPClient sends the requests, [http.DefaultClient] by default.
+	HTTPClient *http.Client
+
+	// IgnoreETags stops ETags from being reported as content checksums,
+	// for buckets whose objects are encrypted with SSE-KMS or SSE-C.
+	IgnoreETags bool
+}
+
+// ConfigFromEnv returns the configuration from the standard AWS environment variables:
+// AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL), AWS_REGION (or AWS_DEFAULT_REGION),
+// and AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN.
+// DOPPEL_S3_IGNORE_ETAGS=true sets [Config.IgnoreETags].
+// If no keys are set in the environment, they are read from the shared credentials file
+// (AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials) for the AWS_PROFILE profile ("default" if unset).
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Endpoint: firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Region:   firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		Credentials: Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+	}
+	cfg.IgnoreETags, _ = strconv.ParseBool(os.Getenv("DOPPEL_S3_IGNORE_ETAGS"))
+
+	if cfg.Credentials.anonymous() {
+		creds, err := sharedCredentials()
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Credentials = creds
+	}
+
+	return cfg, nil
+}
+
+// firstEnv returns the value of the first set environment variable.
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Object describes an object in a bucket.
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// Client sends read-only requests to S3.
+type Client struct {
+	cfg      Config
+	endpoint *url.URL
+	http     *http.Client
+
+	// regions records the regions of buckets that live outside the configured region.
+	regions   map[string]string
+	regionsMu sync.Mutex
+}
+
+// NewClient returns a client for the given configuration.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	c := &Client{cfg: cfg, http: cfg.HTTPClient, regions: make(map[string]string)}
+	if cfg.Endpoint != "" {
+		endpoint, err := url.Parse(cfg.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint '%s'", cfg.Endpoint)
+		}
+		c.endpoint = endpoint
+		c.cfg.PathStyle = true
+	}
+
+	return c, nil
+}
+
+// List returns the objects whose keys start with prefix. If delimiter is not empty, keys containing
+// the delimiter after the prefix are rolled up into common prefixes, like subdirectories.
+func (c *Client) List(ctx context.Context, bucket, prefix, delimiter string) ([]Object, []string, error) {
+	var (
+		objects  []Object
+		prefixes []string
+		token    string
+	)
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, bucket, "", query, "")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding object list: %w", err)
+		}
+
+		for _, o := range result.Contents {
+			objects = append(objects, Object{Key: o.Key, Size: o.Size, ETag: o.ETag, LastModified: o.LastModified})
+		}
+		for _, p := range result.CommonPrefixes {
+			prefixes = append(prefixes, p.Prefix)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, prefixes, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Head returns the metadata of an object.
+func (c *Client) Head(ctx context.Context, bucket, key string) (Object, error) {
+	resp, err := c.do(ctx, http.MethodHead, bucket, key, nil, "")
+	if err != nil {
+		return Object{}, err
+	}
+	_ = resp.Body.Close()
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return Object{}, fmt.Errorf("invalid size of s3://%s/%s: %w", bucket, key, err)
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	return Object{Key: key, Size: size, ETag: resp.Header.Get("ETag"), LastModified: modified}, nil
+}
+
+// Get reads an object from offset. A negative length reads to the end of the object.
+func (c *Client) Get(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	var byteRange string
+	switch {
+	case length >= 0:
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	case offset > 0:
+		byteRange = fmt.Sprintf("bytes=%d-", offset)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, bucket, key, nil, byteRange)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// do sends a request and returns the response if it succeeded.
+// Requests for buckets in another region are retried in that region.
+func (c *Client) do(ctx context.Context, method, bucket, key string, query url.Values, byteRange string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		region := c.region(bucket)
+		req, err := c.newRequest(ctx, method, bucket, key, query, region)
+		if err != nil {
+			return nil, err
+		}
+		if byteRange != "" {
+			req.Header.Set("Range", byteRange)
+		}
+		if !c.cfg.Credentials.anonymous() {
+			sign(req, c.cfg.Credentials, region, time.Now())
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < http.StatusMultipleChoices {
+			return resp, nil
+		}
+
+		respErr := responseError(resp, bucket, key)
+		_ = resp.Body.Close()
+
+		if actual := resp.Header.Get("X-Amz-Bucket-Region"); attempt == 0 && actual != "" && actual != region {
+			c.regionsMu.Lock()
+			c.regions[bucket] = actual
+			c.regionsMu.Unlock()
+			continue
+		}
+		return nil, respErr
+	}
+}
+
+// region returns the region of a bucket.
+func (c *Client) region(bucket string) string {
+	c.regionsMu.Lock()
+	defer c.regionsMu.Unlock()
+	if region, ok := c.regions[bucket]; ok {
+		return region
+	}
+	return c.cfg.Region
+}
+
+// newRequest builds a request for an object, or for the bucket itself if key is empty.
+func (c *Client) newRequest(ctx context.Context, method, bucket, key string, query url.Values, region string) (*http.Request, error) {
+	u := &url.URL{Scheme: "https", Host: "s3." + region + ".amazonaws.com"}
+	if c.endpoint != nil {
+		u.Scheme, u.Host, u.Path = c.endpoint.Scheme, c.endpoint.Host, strings.TrimSuffix(c.endpoint.Path, "/")
+	}
+
+	// Bucket names with dots do not match the wildcard certificate of virtual-hosted endpoints.
+	objectPath := "/" + key
+	if c.cfg.PathStyle || strings.Contains(bucket, ".") {
+		objectPath = "/" + bucket + objectPath
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	u.Path += objectPath
+	u.RawPath = uriEncode(u.Path, false)
+	if len(query) > 0 {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	return http.NewRequestWithContext(ctx, method, u.String(), http.NoBody)
+}
+
+// listBucketResult is the response of ListObjectsV2.
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// errorResponse is the body of an S3 error response.
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// responseError converts an error response into an error, mapping missing objects and denied access
+// to [fs.ErrNotExist] and [fs.ErrPermission].
+func responseError(resp *http.Response, bucket, key string) error {
+	var body errorResponse
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+
+	err := fmt.Errorf("S3 request failed: %s", resp.Status)
+	if body.Code != "" {
+		err = fmt.Errorf("S3 request failed: %s: %s", body.Code, body.Message)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		err = errors.Join(fs.ErrNotExist, err)
+	case http.StatusForbidden:
+		err = errors.Join(fs.ErrPermission, err)
+	}
+
+	return &fs.PathError{Op: strings.ToLower(resp.Request.Method), Path: "s3://" + bucket + "/" + key, Err: err}
+}
+
+// sharedCredentials reads the keys of the current profile from the shared credentials file.
+// A missing file yields empty credentials.
+func sharedCredentials() (Credentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Credentials{}, nil
+		}
+		path = home + string(os.PathSeparator) + ".aws" + string(os.PathSeparator) + "credentials"
+	}
+
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Credentials{}, nil
+	}
+	if err != nil {
+		return Credentials{}, fmt.Errorf("error reading AWS credentials: %w", err)
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	return parseCredentials(string(data), profile), nil
+}
+
+// parseCredentials extracts the keys of a profile from an INI-style shared credentials file.
+func parseCredentials(data, profile string) Credentials {
+	var creds Credentials
+	inProfile := false
+
+	for line := range strings.Lines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+		if !inProfile {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+
+	return creds
+}
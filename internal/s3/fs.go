@@ -0,0 +1,316 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Scheme is the URL scheme of S3 objects.
+const Scheme = "s3"
+
+// FS is a read-only filesystem over the objects of a bucket.
+// Names are URLs like "s3://bucket/dir/key", and "/" in keys separates directories.
+// Directories are the common prefixes of keys; keys ending in "/" (folder markers) are not listed as files.
+type FS struct {
+	ctx    context.Context
+	client *Client
+	bucket string
+}
+
+// NewFS returns a filesystem over bucket. Requests are canceled with ctx.
+func NewFS(ctx context.Context, client *Client, bucket string) *FS {
+	return &FS{ctx: ctx, client: client, bucket: bucket}
+}
+
+// Opener returns a [vfs.Opener] for "s3://" URLs that share client.
+func Opener(client *Client) vfs.Opener {
+	return func(ctx context.Context, u *url.URL) (vfs.FS, error) {
+		if u.Host == "" {
+			return nil, fmt.Errorf("missing bucket in '%s'", u.Redacted())
+		}
+		return NewFS(ctx, client, u.Host), nil
+	}
+}
+
+// OpenerFromEnv returns a [vfs.Opener] for "s3://" URLs that share a client configured by [ConfigFromEnv].
+// The client is set up when the first URL is opened, so scans of local paths never depend on the S3 configuration.
+func OpenerFromEnv() vfs.Opener {
+	client := sync.OnceValues(func() (*Client, error) {
+		cfg, err := ConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewClient(cfg)
+	})
+	return func(ctx context.Context, u *url.URL) (vfs.FS, error) {
+		c, err := client()
+		if err != nil {
+			return nil, err
+		}
+		return Opener(c)(ctx, u)
+	}
+}
+
+// Root returns the name of the bucket's root directory.
+func (f *FS) Root() string {
+	return Scheme + "://" + f.bucket
+}
+
+// NameOf returns the name of the object or directory a URL refers to.
+func (f *FS) NameOf(u *url.URL) string {
+	return f.Join(f.Root(), u.Path)
+}
+
+// Join joins name elements, keeping the "s3://" prefix of the first.
+func (f *FS) Join(elem ...string) string {
+	if len(elem) == 0 {
+		return ""
+	}
+
+	rest, isURL := strings.CutPrefix(elem[0], Scheme+"://")
+	joined := path.Join(append([]string{rest}, elem[1:]...)...)
+	if isURL {
+		return Scheme + "://" + joined
+	}
+	return joined
+}
+
+// key returns the object key of a name, without leading or trailing slashes.
+func (f *FS) key(op, name string) (string, error) {
+	rest, ok := strings.CutPrefix(name, f.Root())
+	if !ok || rest != "" && rest[0] != '/' {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return strings.Trim(rest, "/"), nil
+}
+
+// Stat returns information about an object or a directory.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	key, err := f.key("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return &objectInfo{name: f.bucket, dir: true}, nil
+	}
+
+	object, err := f.client.Head(f.ctx, f.bucket, key)
+	if err == nil {
+		return f.objectInfo(object), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// Not an object; a directory exists if any key is under it.
+	objects, prefixes, listErr := f.client.List(f.ctx, f.bucket, key+"/", "/")
+	if listErr != nil {
+		return nil, listErr
+	}
+	if len(objects) == 0 && len(prefixes) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return &objectInfo{name: path.Base(key), dir: true}, nil
+}
+
+// Lstat is the same as [FS.Stat], since objects cannot be symlinks.
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	return f.Stat(name)
+}
+
+// EvalSymlinks returns the cleaned name of an existing object or directory.
+func (f *FS) EvalSymlinks(name string) (string, error) {
+	if _, err := f.Stat(name); err != nil {
+		return "", err
+	}
+	return f.Join(name), nil
+}
+
+// ReadDir lists the objects and subdirectories directly under a directory, sorted by name.
+// An object and a directory with the same name are listed as the object.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	key, err := f.key("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+
+	objects, prefixes, err := f.client.List(f.ctx, f.bucket, prefix, "/")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(objects)+len(prefixes))
+	names := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		base := object.Key[len(prefix):]
+		if base == "" || strings.HasSuffix(base, "/") {
+			continue
+		}
+		names[base] = true
+		object.Key = base
+		entries = append(entries, fs.FileInfoToDirEntry(f.objectInfo(object)))
+	}
+	for _, p := range prefixes {
+		base := strings.TrimSuffix(p[len(prefix):], "/")
+		if base == "" || names[base] {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(&objectInfo{name: base, dir: true}))
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// Open opens an object. Reads stream the object, and ReadAt issues ranged requests.
+func (f *FS) Open(name string) (vfs.File, error) {
+	key, err := f.key("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+
+	return &file{fs: f, key: key, info: info}, nil
+}
+
+// file is an open object.
+type file struct {
+	fs   *FS
+	key  string
+	info fs.FileInfo
+
+	body   io.ReadCloser
+	offset int64
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		body, err := f.fs.client.Get(f.fs.ctx, f.fs.bucket, f.key, f.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		f.body = body
+	}
+
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	size := f.info.Size()
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.fs.Root() + "/" + f.key, Err: fs.ErrInvalid}
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	length := min(int64(len(p)), size-off)
+	body, err := f.fs.client.Get(f.fs.ctx, f.fs.bucket, f.key, off, length)
+	if err != nil {
+		return 0, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(body)
+
+	n, err := io.ReadFull(body, p[:length])
+	if err == nil && int64(n) < int64(len(p)) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	err := f.body.Close()
+	f.body = nil
+	return err
+}
+
+// objectInfo describes an object or a directory.
+type objectInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	etag    string
+	dir     bool
+}
+
+// objectInfo describes an object, named by the last element of its key.
+func (f *FS) objectInfo(object Object) *objectInfo {
+	info := &objectInfo{
+		name:    path.Base(object.Key),
+		size:    object.Size,
+		modTime: object.LastModified,
+	}
+	if !f.client.cfg.IgnoreETags {
+		info.etag = strings.Trim(object.ETag, `"`)
+	}
+	return info
+}
+
+func (i *objectInfo) Name() string       { return i.name }
+func (i *objectInfo) Size() int64        { return i.size }
+func (i *objectInfo) ModTime() time.Time { return i.modTime }
+func (i *objectInfo) IsDir() bool        { return i.dir }
+func (i *objectInfo) Sys() any           { return i }
+
+func (i *objectInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// Checksum returns the MD5 of the object's content from its ETag.
+// ETags of multipart uploads are not content hashes and are not returned.
+// Objects encrypted with SSE-KMS or SSE-C have ETags that look like, but are not, MD5 hashes;
+// see [Config.IgnoreETags].
+func (i *objectInfo) Checksum() string {
+	if len(i.etag) != 32 {
+		return ""
+	}
+	for _, c := range i.etag {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return ""
+		}
+	}
+	return "md5:" + strings.ToLower(i.etag)
+}
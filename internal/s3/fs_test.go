@@ -0,0 +1,202 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// TestFS verifies listing, stat data, and reads of objects through [FS].
+func TestFS(t *testing.T) {
+	srv := newTestServer(t)
+	content := []byte("hello, object storage")
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/c.txt", "dir/sub/d.txt", "dir/e/", "z.txt"} {
+		srv.put("bucket", key, content)
+	}
+	srv.putETag("bucket", "multipart.bin", content, "0123456789abcdef0123456789abcdef-2")
+
+	fsys := NewFS(context.Background(), srv.client(t), "bucket")
+
+	entries, err := fsys.ReadDir("s3://bucket/dir")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"b.txt", "c.txt", "e", "sub"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir() = %v, want %v", names, want)
+	}
+
+	var walked []string
+	err = vfs.WalkDir(fsys, fsys.Root(), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			walked = append(walked, name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+	want := []string{"s3://bucket/a.txt", "s3://bucket/dir/b.txt", "s3://bucket/dir/c.txt",
+		"s3://bucket/dir/sub/d.txt", "s3://bucket/multipart.bin", "s3://bucket/z.txt"}
+	if !slices.Equal(walked, want) {
+		t.Errorf("WalkDir() visited %v, want %v", walked, want)
+	}
+
+	info, err := fsys.Stat("s3://bucket/dir/sub")
+	if err != nil || !info.IsDir() {
+		t.Errorf("Stat() of a prefix = %v, %v, want a directory", info, err)
+	}
+	if _, err := fsys.Stat("s3://bucket/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a missing key error = %v, want not exist", err)
+	}
+
+	info, err = fsys.Stat("s3://bucket/a.txt")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != int64(len(content)) || info.IsDir() {
+		t.Errorf("Stat() = size %d, dir %v, want size %d", info.Size(), info.IsDir(), len(content))
+	}
+	sum := md5.Sum(content)
+	if got, want := vfs.Checksum(info), "md5:"+hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("Checksum() = %q, want %q", got, want)
+	}
+	if info, err := fsys.Stat("s3://bucket/multipart.bin"); err != nil || vfs.Checksum(info) != "" {
+		t.Errorf("Checksum() of a multipart object = %q, %v, want none", vfs.Checksum(info), err)
+	}
+
+	f, err := fsys.Open("s3://bucket/dir/b.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func(f vfs.File) {
+		_ = f.Close()
+	}(f)
+
+	data, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("ReadAll() = %q, %v, want %q", data, err, content)
+	}
+
+	buf := make([]byte, 6)
+	if n, err := f.ReadAt(buf, 7); err != nil || string(buf[:n]) != "object" {
+		t.Errorf("ReadAt() = %q, %v, want %q", buf[:n], err, "object")
+	}
+	if n, err := f.ReadAt(buf, int64(len(content))-3); err != io.EOF || string(buf[:n]) != "age" {
+		t.Errorf("ReadAt() at the end = %q, %v, want %q and EOF", buf[:n], err, "age")
+	}
+
+	if _, err := NewFS(context.Background(), srv.client(t), "missing").ReadDir("s3://missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir() of a missing bucket error = %v, want not exist", err)
+	}
+}
+
+// TestFindDuplicates verifies that objects are compared with each other and with local files in one scan,
+// and that ETags spare ranged reads of objects that can only be duplicates of other objects.
+func TestFindDuplicates(t *testing.T) {
+	content := bytes.Repeat([]byte("duplicated content "), 2000)
+	other := bytes.Repeat([]byte("other content "), 2000)
+
+	srv := newTestServer(t)
+	srv.put("bucket", "photos/a.jpg", content)
+	srv.put("bucket", "photos/2024/b.jpg", content)
+	srv.put("bucket", "photos/c.jpg", other)
+	srv.put("bucket", "photos/d.jpg", other)
+	srv.put("bucket", "docs/e.txt", []byte("x"))
+
+	localDir := t.TempDir()
+	localFile := filepath.Join(localDir, "a.jpg")
+	if err := os.WriteFile(localFile, content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	backends := vfs.NewRegistry()
+	backends.Register(Scheme, Opener(srv.client(t)))
+	fsys, name, err := backends.Open(context.Background(), "s3://bucket/photos/")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if name != "s3://bucket/photos" {
+		t.Errorf("Open() name = %q, want %q", name, "s3://bucket/photos")
+	}
+
+	roots := []scanner.Root{{Path: localDir}, {Path: name, FS: fsys}}
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupRootsBySize(context.Background(), roots, &filter.Config{}, s)
+	if err != nil {
+		t.Fatalf("GroupRootsBySize() error = %v", err)
+	}
+	if s.TotalFiles != 5 {
+		t.Errorf("Stats.TotalFiles = %d, want 5", s.TotalFiles)
+	}
+
+	report, err := finder.FindDuplicatesByHash(context.Background(), sizeGroups, 2, s)
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 2", len(report.Groups))
+	}
+
+	var groups [][]string
+	for _, group := range report.Groups {
+		files := slices.Clone(group.Files)
+		slices.Sort(files)
+		groups = append(groups, files)
+	}
+	slices.SortFunc(groups, func(a, b []string) int { return len(b) - len(a) })
+
+	want := [][]string{
+		{localFile, "s3://bucket/photos/2024/b.jpg", "s3://bucket/photos/a.jpg"},
+		{"s3://bucket/photos/c.jpg", "s3://bucket/photos/d.jpg"},
+	}
+	slices.Sort(want[0])
+	for i := range want {
+		if !slices.Equal(groups[i], want[i]) {
+			t.Errorf("Duplicate group %d = %v, want %v", i, groups[i], want[i])
+		}
+	}
+
+	// Only the objects sized like the local file are quick-hashed, with two ranged reads each;
+	// the ETags of the others stand in for their quick hashes.
+	if got := srv.rangeReads.Load(); got != 4 {
+		t.Errorf("Server received %d ranged reads, want 4", got)
+	}
+}
+
+// TestOpenerFromEnv verifies that a malformed S3 configuration only fails the opening of S3 URLs.
+func TestOpenerFromEnv(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "localhost:9000")
+
+	backends := vfs.NewRegistry()
+	backends.Register(Scheme, OpenerFromEnv())
+	if _, _, err := backends.Open(context.Background(), "s3://bucket/key"); err == nil {
+		t.Fatal("Open() error = nil, want the endpoint rejected")
+	}
+
+	roots, err := scanner.GetRoots(context.Background(), []string{t.TempDir()}, "", nil, backends)
+	if err != nil || len(roots) != 1 {
+		t.Errorf("GetRoots() = %v, %v, want the local directory", roots, err)
+	}
+}
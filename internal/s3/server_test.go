@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal in-memory stand-in for an S3-compatible service, serving path-style requests.
+// It pages object listings two keys at a time to exercise continuation.
+type testServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string]testObject
+
+	// rangeReads counts the ranged GET requests.
+	rangeReads atomic.Int64
+}
+
+type testObject struct {
+	data []byte
+	etag string
+}
+
+// newTestServer starts a stand-in server, stopped when the test ends.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{buckets: make(map[string]map[string]testObject)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// put stores an object with its MD5 ETag.
+func (s *testServer) put(bucket, key string, data []byte) {
+	sum := md5.Sum(data)
+	s.putETag(bucket, key, data, hex.EncodeToString(sum[:]))
+}
+
+// putETag stores an object with the given ETag, such as that of a multipart upload.
+func (s *testServer) putETag(bucket, key string, data []byte, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]testObject)
+	}
+	s.buckets[bucket][key] = testObject{data: data, etag: `"` + etag + `"`}
+}
+
+// client returns a client for the server with test credentials.
+func (s *testServer) client(t *testing.T) *Client {
+	t.Helper()
+	c, err := NewClient(Config{
+		Endpoint:    s.URL,
+		Credentials: Credentials{AccessKeyID: "test", SecretAccessKey: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func (s *testServer) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test/") {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	s.mu.Lock()
+	bucket, ok := s.buckets[bucketName]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	if key == "" && r.Method == http.MethodGet {
+		s.list(w, r, bucket)
+		return
+	}
+
+	s.mu.Lock()
+	object, ok := bucket[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("ETag", object.etag)
+	w.Header().Set("Last-Modified", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+
+	data, status := object.data, http.StatusOK
+	if byteRange := r.Header.Get("Range"); byteRange != "" {
+		s.rangeReads.Add(1)
+		var start, end int
+		spec := strings.TrimPrefix(byteRange, "bytes=")
+		first, last, _ := strings.Cut(spec, "-")
+		start, _ = strconv.Atoi(first)
+		end = len(data) - 1
+		if last != "" {
+			end, _ = strconv.Atoi(last)
+		}
+		data, status = data[start:min(end+1, len(data))], http.StatusPartialContent
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+func (s *testServer) list(w http.ResponseWriter, r *http.Request, bucket map[string]testObject) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+
+	s.mu.Lock()
+	keys := make([]string, 0, len(bucket))
+	for key := range bucket {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+	slices.Sort(keys)
+
+	// Collect the matching keys and common prefixes in order; common prefixes end with the delimiter.
+	type entry struct {
+		name     string
+		isPrefix bool
+	}
+	var entries []entry
+	for _, key := range keys {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+			commonPrefix := prefix + rest[:i+len(delimiter)]
+			if len(entries) == 0 || entries[len(entries)-1].name != commonPrefix {
+				entries = append(entries, entry{name: commonPrefix, isPrefix: true})
+			}
+			continue
+		}
+		entries = append(entries, entry{name: key})
+	}
+
+	start, _ := strconv.Atoi(query.Get("continuation-token"))
+	end := min(start+2, len(entries))
+
+	var result listBucketResult
+	s.mu.Lock()
+	for _, e := range entries[start:end] {
+		if e.isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, struct {
+				Prefix string `xml:"Prefix"`
+			}{Prefix: e.name})
+			continue
+		}
+		result.Contents = append(result.Contents, struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			ETag         string    `xml:"ETag"`
+			LastModified time.Time `xml:"LastModified"`
+		}{Key: e.name, Size: int64(len(bucket[e.name].data)), ETag: bucket[e.name].etag})
+	}
+	s.mu.Unlock()
+	if end < len(entries) {
+		result.IsTruncated, result.NextContinuationToken = true, strconv.Itoa(end)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, http.StatusText(status))
+}
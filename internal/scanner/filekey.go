@@ -0,0 +1,26 @@
+package scanner
+
+import (
+	"io/fs"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// fileKey identifies a file or directory independently of the path used to reach it.
+type fileKey struct {
+	dev, ino uint64
+
+	// path is the resolved path, used where device and inode numbers are unavailable.
+	path string
+}
+
+// keyOf returns the identity of the file at path on fsys with the given stat data.
+func keyOf(fsys vfs.FS, path string, info fs.FileInfo) fileKey {
+	if dev, ino, ok := fileID(info); ok {
+		return fileKey{dev: dev, ino: ino}
+	}
+	if resolved, err := fsys.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return fileKey{path: path}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package scanner
+
+import "io/fs"
+
+// fileID is not supported on this platform.
+func fileID(fs.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package scanner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID extracts the device and inode numbers from the stat data of a file.
+func fileID(info fs.FileInfo) (dev, ino uint64, ok bool) {
+	if info == nil {
+		return 0, 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st == nil {
+		return 0, 0, false
+	}
+	//nolint:unconvert,gosec // the field types differ between platforms
+	return uint64(st.Dev), uint64(st.Ino), true
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"hash"
 	"io"
-	"os"
+	"strings"
 
 	"github.com/zeebo/xxh3"
+	"lukechampine.com/blake3"
+
+	"github.com/dr8co/doppel/internal/vfs"
 )
 
 const quickHashSize = 8 * 1024 // 8 KB for quick hash
@@ -16,20 +19,111 @@ type FileInfo struct {
 	Path string `json:"path" yaml:"path"`
 	Size int64  `json:"size" yaml:"size"`
 	Hash string `json:"hash" yaml:"hash"`
+
+	// Target is the resolved path if the file was reached through a symlink.
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+
+	// Symlinks lists the symlinks resolving to this file, when symlink reporting is enabled.
+	Symlinks []string `json:"symlinks,omitempty" yaml:"symlinks,omitempty"`
+
+	// Role is the role of the root the file was found under.
+	Role Role `json:"role,omitempty" yaml:"role,omitempty"`
+
+	// ReadOnly marks files that cannot be removed, such as archive members.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+
+	// QuickHash is the precomputed quick hash, valid only when QuickHashed is set.
+	QuickHash uint64 `json:"quick_hash,omitempty" yaml:"quick_hash,omitempty"`
+
+	// QuickHashed marks QuickHash as precomputed.
+	QuickHashed bool `json:"-" yaml:"-"`
+
+	// Checksum is the content checksum stored by the file's backend, prefixed by its algorithm.
+	Checksum string `json:"-" yaml:"-"`
+
+	// FS is the filesystem the file is read from. A nil FS means the local OS filesystem.
+	FS vfs.FS `json:"-" yaml:"-"`
+
+	// Dev and Ino are the device and inode numbers of the file, or zero where the filesystem does not have them.
+	Dev, Ino uint64 `json:"-" yaml:"-"`
+}
+
+// NewHasher returns a hasher for full-content hashes, as used by [HashFile].
+func NewHasher() hash.Hash {
+	return blake3.New(32, nil)
 }
 
 // HashFile computes the hash of an entire file.
 func HashFile(filePath string, hasher hash.Hash, buf []byte) (string, error) {
+	return hashFS(vfs.OS{}, filePath, hasher, buf)
+}
+
+// QuickHashFile computes a XXH3 hash of the first and the last portions of a file
+// This is used as a quick preliminary check before computing the full hash.
+func QuickHashFile(filePath string, size int64, hasher *xxh3.Hasher, buf []byte) (uint64, error) {
+	return quickHashFS(vfs.OS{}, filePath, size, hasher, buf)
+}
+
+// HashFileInfo computes the full hash of a file on its filesystem.
+// Hashes precomputed by the scanner are returned as is.
+func HashFileInfo(file FileInfo, hasher hash.Hash, buf []byte) (string, error) {
+	if file.Hash != "" {
+		return file.Hash, nil
+	}
+	return hashFS(file.fs(), file.Path, hasher, buf)
+}
+
+// QuickHashFileInfo computes the quick hash of a file on its filesystem.
+// Precomputed hashes are returned as is.
+func QuickHashFileInfo(file FileInfo, hasher *xxh3.Hasher, buf []byte) (uint64, error) {
+	if file.QuickHashed {
+		return file.QuickHash, nil
+	}
+	return quickHashFS(file.fs(), file.Path, file.Size, hasher, buf)
+}
+
+// HashFileInfoBoth computes both the quick and the full hash of a file in a single pass.
+// Precomputed hashes are returned as is.
+func HashFileInfoBoth(file FileInfo, quick *xxh3.Hasher, full hash.Hash, buf []byte) (uint64, string, error) {
+	if file.Hash != "" && file.QuickHashed {
+		return file.QuickHash, file.Hash, nil
+	}
+	if quick == nil || full == nil {
+		return 0, "", errors.New("hasher is nil")
+	}
+
+	f, err := file.fs().Open(file.Path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	defer func(f vfs.File) {
+		_ = f.Close()
+	}(f)
+
+	return hashStream(f, file.Size, quick, full, buf)
+}
+
+// fs returns the filesystem of the file.
+func (f FileInfo) fs() vfs.FS {
+	if f.FS == nil {
+		return vfs.OS{}
+	}
+	return f.FS
+}
+
+// hashFS computes the hash of an entire file on fsys.
+func hashFS(fsys vfs.FS, filePath string, hasher hash.Hash, buf []byte) (string, error) {
 	if hasher == nil {
 		return "", errors.New("hasher is nil")
 	}
-	//nolint:gosec
-	file, err := os.Open(filePath)
+
+	file, err := fsys.Open(filePath)
 	if err != nil {
 		return "", err
 	}
 
-	defer func(file *os.File) {
+	defer func(file vfs.File) {
 		_ = file.Close()
 	}(file)
 
@@ -41,9 +135,8 @@ func HashFile(filePath string, hasher hash.Hash, buf []byte) (string, error) {
 	return string(hasher.Sum(nil)), nil
 }
 
-// QuickHashFile computes a XXH3 hash of the first and the last portions of a file
-// This is used as a quick preliminary check before computing the full hash.
-func QuickHashFile(filePath string, size int64, hasher *xxh3.Hasher, buf []byte) (uint64, error) {
+// quickHashFS computes the quick hash of a file on fsys.
+func quickHashFS(fsys vfs.FS, filePath string, size int64, hasher *xxh3.Hasher, buf []byte) (uint64, error) {
 	if size <= 0 {
 		return 0, nil
 	}
@@ -51,19 +144,18 @@ func QuickHashFile(filePath string, size int64, hasher *xxh3.Hasher, buf []byte)
 		return 0, errors.New("hasher is nil")
 	}
 
-	//nolint:gosec
-	file, err := os.Open(filePath)
+	file, err := fsys.Open(filePath)
 	if err != nil {
 		return 0, err
 	}
 
-	defer func(file *os.File) {
+	defer func(file vfs.File) {
 		_ = file.Close()
 	}(file)
 
 	hasher.Reset()
 
-	n, err := file.Read(buf)
+	n, err := file.ReadAt(buf, 0)
 	if err != nil && !errors.Is(err, io.EOF) {
 		return 0, err
 	}
@@ -90,3 +182,90 @@ func QuickHashFile(filePath string, size int64, hasher *xxh3.Hasher, buf []byte)
 	}
 	return hasher.Sum64(), nil
 }
+
+// quickHashReader computes the same hash as [QuickHashFile] from a stream of size bytes.
+// The stream is read up to its last portion, without seeking.
+func quickHashReader(r io.Reader, size int64, hasher *xxh3.Hasher, buf []byte) (uint64, error) {
+	if size <= 0 {
+		return 0, nil
+	}
+	if hasher == nil {
+		return 0, errors.New("hasher is nil")
+	}
+
+	hasher.Reset()
+
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, err
+	}
+
+	if size < quickHashSize*2 {
+		// For small files, hash the entire content
+		if n > 0 {
+			return xxh3.Hash(buf[:n]), nil
+		}
+		return hasher.Sum64(), nil
+	}
+
+	// Hash first quickHashSize bytes
+	if n > 0 {
+		_, _ = hasher.Write(buf[:n])
+	}
+
+	// Skip to the last quickHashSize bytes and hash them
+	if _, err = io.CopyN(io.Discard, r, size-int64(n)-int64(len(buf))); err != nil {
+		return 0, err
+	}
+	n, err = io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, err
+	}
+	if n > 0 {
+		_, _ = hasher.Write(buf[:n])
+	}
+
+	return hasher.Sum64(), nil
+}
+
+// UseChecksums stands in the checksums stored by the files' backends for their quick hashes,
+// if all files of a size group have checksums of the same algorithm.
+// Checksums cover the whole content, so they separate files at least as well as quick hashes do.
+func UseChecksums(files []FileInfo) {
+	if len(files) == 0 {
+		return
+	}
+
+	algorithm, _, _ := strings.Cut(files[0].Checksum, ":")
+	for _, file := range files {
+		if file.QuickHashed {
+			return
+		}
+		if alg, _, ok := strings.Cut(file.Checksum, ":"); !ok || alg != algorithm {
+			return
+		}
+	}
+
+	for i := range files {
+		files[i].QuickHash = xxh3.HashString(files[i].Checksum)
+		files[i].QuickHashed = true
+	}
+}
+
+// hashStream computes both the quick and the full hash of a stream of size bytes in a single pass.
+func hashStream(r io.Reader, size int64, quick *xxh3.Hasher, full hash.Hash, buf []byte) (uint64, string, error) {
+	full.Reset()
+	tee := io.TeeReader(r, full)
+
+	quickHash, err := quickHashReader(tee, size, quick, buf[:quickHashSize])
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Hash whatever the quick hash did not read
+	if _, err = io.CopyBuffer(full, r, buf); err != nil {
+		return 0, "", err
+	}
+
+	return quickHash, string(full.Sum(nil)), nil
+}
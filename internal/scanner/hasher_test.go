@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -315,3 +316,75 @@ func TestQuickHashConsistency(t *testing.T) {
 		t.Errorf("HashFile() produced same hashes for different files: %v", fullHash1)
 	}
 }
+
+// TestHashStream verifies that hashes computed from a stream match [QuickHashFile] and [HashFile].
+func TestHashStream(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for _, size := range []int{0, 100, quickHashSize + 10, quickHashSize * 2, quickHashSize*5 + 123} {
+		t.Run(fmt.Sprintf("size %d", size), func(t *testing.T) {
+			content := make([]byte, size)
+			for i := range content {
+				content[i] = byte(i * 7 % 251)
+			}
+			path := filepath.Join(tempDir, fmt.Sprintf("file%d", size))
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+
+			wantQuick, err := quickHashFile(path, int64(size))
+			if err != nil {
+				t.Fatalf("QuickHashFile() error = %v", err)
+			}
+			wantFull, err := hashFile(path)
+			if err != nil {
+				t.Fatalf("HashFile() error = %v", err)
+			}
+
+			quick, full, err := hashStream(bytes.NewReader(content), int64(size), xxh3.New(), NewHasher(), make([]byte, chunkSize))
+			if err != nil {
+				t.Fatalf("hashStream() error = %v", err)
+			}
+			if quick != wantQuick {
+				t.Errorf("hashStream() quick hash = %x, want %x", quick, wantQuick)
+			}
+			if full != wantFull {
+				t.Errorf("hashStream() full hash = %x, want %x", full, wantFull)
+			}
+		})
+	}
+}
+
+// TestHashFileInfoBoth verifies that both hashes computed in one pass match [QuickHashFile] and [HashFile].
+func TestHashFileInfoBoth(t *testing.T) {
+	content := make([]byte, quickHashSize*3+17)
+	for i := range content {
+		content[i] = byte(i * 13 % 251)
+	}
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	wantQuick, err := quickHashFile(path, int64(len(content)))
+	if err != nil {
+		t.Fatalf("QuickHashFile() error = %v", err)
+	}
+	wantFull, err := hashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+
+	file := FileInfo{Path: path, Size: int64(len(content))}
+	quick, full, err := HashFileInfoBoth(file, xxh3.New(), NewHasher(), make([]byte, chunkSize))
+	if err != nil {
+		t.Fatalf("HashFileInfoBoth() error = %v", err)
+	}
+	if quick != wantQuick || full != wantFull {
+		t.Errorf("HashFileInfoBoth() = %x, %x, want %x, %x", quick, full, wantQuick, wantFull)
+	}
+
+	if _, _, err := HashFileInfoBoth(FileInfo{Path: path + ".missing", Size: 1}, xxh3.New(), NewHasher(), make([]byte, chunkSize)); err == nil {
+		t.Error("HashFileInfoBoth() expected error for non-existent file, got nil")
+	}
+}
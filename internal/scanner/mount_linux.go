@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// mountInfoPath is the kernel's table of mounts visible to this process.
+const mountInfoPath = "/proc/self/mountinfo"
+
+// mountTable maps mounted filesystems to their types.
+type mountTable struct {
+	// byDev maps device numbers to filesystem types.
+	byDev map[uint64]string
+
+	// byPath maps mount points to filesystem types.
+	byPath map[string]string
+}
+
+// readMountTable reads the mount table of the current process.
+func readMountTable() (*mountTable, error) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading mount table: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	return parseMountInfo(f)
+}
+
+// parseMountInfo parses the mountinfo format described in proc(5):
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func parseMountInfo(r io.Reader) (*mountTable, error) {
+	table := &mountTable{byDev: make(map[uint64]string), byPath: make(map[string]string)}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 7 {
+			continue
+		}
+
+		// The optional fields end with a single hyphen, followed by the filesystem type.
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+		fsType := fields[sep+1]
+
+		if major, minor, ok := strings.Cut(fields[2], ":"); ok {
+			maj, err1 := strconv.ParseUint(major, 10, 32)
+			mnr, err2 := strconv.ParseUint(minor, 10, 32)
+			if err1 == nil && err2 == nil {
+				table.byDev[unix.Mkdev(uint32(maj), uint32(mnr))] = fsType
+			}
+		}
+		table.byPath[unescapeMountPath(fields[4])] = fsType
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("error parsing mount table: %w", err)
+	}
+
+	return table, nil
+}
+
+// fsType returns the type of the filesystem on device dev, mounted at (or containing) path.
+func (t *mountTable) fsType(dev uint64, path string) string {
+	if fsType, ok := t.byDev[dev]; ok {
+		return fsType
+	}
+	// Some filesystems (e.g. btrfs subvolumes) report device numbers that differ from the mount table.
+	return t.byPath[path]
+}
+
+// unescapeMountPath decodes the octal escapes (e.g. "\040" for a space) used in mount points.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
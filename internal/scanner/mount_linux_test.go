@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// TestParseMountInfo verifies parsing of the mountinfo format, including optional fields and escapes.
+func TestParseMountInfo(t *testing.T) {
+	const mountInfo = `23 28 0:22 / /proc rw,relatime - proc proc rw
+36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 shared:2 - ext3 /dev/root rw,errors=continue
+40 28 0:45 / /mnt/my\040share rw,relatime - nfs server:/export rw
+malformed line
+`
+	table, err := parseMountInfo(strings.NewReader(mountInfo))
+	if err != nil {
+		t.Fatalf("parseMountInfo() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		dev  uint64
+		path string
+		want string
+	}{
+		{name: "by device", dev: unix.Mkdev(0, 22), want: "proc"},
+		{name: "with optional fields", dev: unix.Mkdev(98, 0), want: "ext3"},
+		{name: "by escaped path", dev: unix.Mkdev(1, 1), path: "/mnt/my share", want: "nfs"},
+		{name: "unknown", dev: unix.Mkdev(1, 1), path: "/nowhere", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := table.fsType(tt.dev, tt.path); got != tt.want {
+				t.Errorf("fsType(%d, %q) = %q, want %q", tt.dev, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGroupFilesBySize_ExcludeFSTypes verifies that directories on excluded filesystem types are skipped.
+func TestGroupFilesBySize_ExcludeFSTypes(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("content"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	table, err := readMountTable()
+	if err != nil {
+		t.Skipf("Mount table not available: %v", err)
+	}
+	info, err := os.Stat(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to stat temp directory: %v", err)
+	}
+	dev, _, _ := fileID(info)
+	fsType := table.fsType(dev, tempDir)
+	if fsType == "" {
+		t.Skip("Could not determine the filesystem type of the temp directory")
+	}
+
+	ctx := context.Background()
+	s := &model.Stats{}
+	sizeGroups, err := GroupFilesBySize(ctx, []string{tempDir}, &filter.Config{}, s,
+		WithExcludeFSTypes([]string{fsType}))
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+	if len(sizeGroups) != 0 || s.SkippedMounts != 1 {
+		t.Errorf("Got %d size groups and %d skipped mounts, want 0 and 1", len(sizeGroups), s.SkippedMounts)
+	}
+
+	// Staying on one filesystem never skips the root itself.
+	s = &model.Stats{}
+	sizeGroups, err = GroupFilesBySize(ctx, []string{tempDir}, &filter.Config{}, s, WithOneFileSystem(true))
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+	if len(sizeGroups) != 1 || s.SkippedMounts != 0 {
+		t.Errorf("Got %d size groups and %d skipped mounts, want 1 and 0", len(sizeGroups), s.SkippedMounts)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"errors"
+	"runtime"
+)
+
+// mountTable is not available on this platform.
+type mountTable struct{}
+
+// readMountTable is not supported on this platform.
+func readMountTable() (*mountTable, error) {
+	return nil, errors.New("excluding filesystem types is not supported on " + runtime.GOOS)
+}
+
+// fsType is not supported on this platform.
+func (*mountTable) fsType(uint64, string) string {
+	return ""
+}
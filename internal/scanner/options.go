@@ -0,0 +1,96 @@
+package scanner
+
+import "github.com/dr8co/doppel/internal/vfs"
+
+// Option is a functional option for configuring the scanner.
+type Option func(*options)
+
+// options holds the optional scanner settings.
+type options struct {
+	// followSymlinks enables descending into symlinked directories and scanning symlinked files.
+	followSymlinks bool
+
+	// reportSymlinks enables recording which symlinks resolve to scanned files.
+	reportSymlinks bool
+
+	// oneFileSystem restricts the scan to the filesystem of each root.
+	oneFileSystem bool
+
+	// excludeFSTypes lists the filesystem types to skip.
+	excludeFSTypes []string
+
+	// scanArchives enables scanning the members of zip and tar archives as read-only files.
+	scanArchives bool
+
+	// fs is the filesystem to scan, the local OS filesystem by default.
+	fs vfs.FS
+
+	// spillDir and spillLimit set where and from which memory use [IndexRootsBySize] spills size groups to disk.
+	spillDir   string
+	spillLimit int64
+
+	// checkpoint records the progress of the walk, and resume is the progress of an interrupted walk to resume.
+	checkpoint Checkpointer
+	resume     *Resume
+}
+
+// WithFollowSymlinks makes the scanner follow symlinks to files and directories.
+// Directories are tracked by device and inode, so symlink loops and directories
+// reachable through several paths are only walked once.
+func WithFollowSymlinks(follow bool) Option {
+	return func(opts *options) {
+		opts.followSymlinks = follow
+	}
+}
+
+// WithReportSymlinks makes the scanner record symlinks to regular files on the files they resolve to,
+// so that symlinks whose targets duplicate other files can be flagged in the report.
+func WithReportSymlinks(report bool) Option {
+	return func(opts *options) {
+		opts.reportSymlinks = report
+	}
+}
+
+// WithOneFileSystem restricts the scan to the filesystem of each root,
+// skipping directories on a different device than the root they were reached from.
+func WithOneFileSystem(oneFS bool) Option {
+	return func(opts *options) {
+		opts.oneFileSystem = oneFS
+	}
+}
+
+// WithExcludeFSTypes skips directories on filesystems of the given types (e.g. "nfs", "proc", "tmpfs").
+// The filesystem types are read from /proc/self/mountinfo, so this option is only supported on Linux.
+func WithExcludeFSTypes(fsTypes []string) Option {
+	return func(opts *options) {
+		opts.excludeFSTypes = fsTypes
+	}
+}
+
+// WithScanArchives scans the members of zip and tar archives as read-only virtual files,
+// with paths like "archive.zip!/inner/file".
+func WithScanArchives(enabled bool) Option {
+	return func(o *options) {
+		o.scanArchives = enabled
+	}
+}
+
+// WithFS scans fsys instead of the local OS filesystem.
+// Symlink following, mount points, and ownership filters only apply to backends exposing OS stat data.
+func WithFS(fsys vfs.FS) Option {
+	return func(o *options) {
+		o.fs = fsys
+	}
+}
+
+// WithSpill bounds the memory taken by the size groups of [IndexRootsBySize]: whenever they take more than
+// about memoryLimit bytes, they are written to a run file sorted by size, in a temporary directory
+// created in dir (the default directory for temporary files if empty). A memoryLimit of zero keeps
+// the size groups in memory.
+//
+// Symlinks followed or reported still take memory for every file found, to recognize the files they resolve to.
+func WithSpill(dir string, memoryLimit int64) Option {
+	return func(o *options) {
+		o.spillDir, o.spillLimit = dir, memoryLimit
+	}
+}
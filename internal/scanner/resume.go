@@ -0,0 +1,345 @@
+package scanner
+
+import (
+	"io/fs"
+	"iter"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dr8co/doppel/internal/archive"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Checkpointer persists the progress of a walk, so that an interrupted walk can be resumed with [WithResume].
+// Files and directories are recorded as they are found, and each snapshot of the walk covers
+// those recorded before it.
+type Checkpointer interface {
+	// AddFile records a file found.
+	AddFile(file FileRecord)
+
+	// AddDir records a directory walked, when following symlinks.
+	AddDir(dir DirID)
+
+	// AddSymlink records a symlink to a file recorded before, found once every directory was walked.
+	AddSymlink(path, link string)
+
+	// Due reports whether a snapshot of the walk is due.
+	Due() bool
+
+	// Snapshot records the state of the walk.
+	Snapshot(state WalkState) error
+
+	// Walked records the end of the walk, with its final statistics.
+	Walked(stats WalkStats) error
+}
+
+// FileRecord is a file found by a walk, as recorded by a [Checkpointer].
+type FileRecord struct {
+	// File is the file found. Its FS is left out: it is the filesystem of Root, or of Archive within it.
+	File FileInfo
+
+	// Root is the index of the root whose filesystem the file is on.
+	Root int
+
+	// Archive is the path of the archive the file is a member of, if any.
+	Archive string
+
+	// ModTime is the modification time of the file when it was found, or of its archive for archive members.
+	// The hashes recorded for a file are dropped when resuming if it was modified since.
+	ModTime time.Time
+}
+
+// DirID identifies a directory walked, by device and inode numbers, or by resolved path where they are unavailable.
+type DirID struct {
+	Dev  uint64 `json:"dev,omitempty"`
+	Ino  uint64 `json:"ino,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// WalkState is a snapshot of a walk in progress.
+type WalkState struct {
+	// Unit is the index of the tree being walked: the roots come first, then the symlinked directories
+	// in the order they were found.
+	Unit int `json:"unit"`
+
+	// Cursor is the path of the last regular file visited in the tree being walked.
+	Cursor string `json:"cursor"`
+
+	// PendingDirs holds the symlinked directories left to walk, starting with the one being walked, if any.
+	PendingDirs []PendingSymlink `json:"pending_dirs,omitempty"`
+
+	// PendingLinks holds the symlinked files left to resolve.
+	PendingLinks []PendingSymlink `json:"pending_links,omitempty"`
+
+	// Stats are the statistics of the walk so far.
+	Stats WalkStats `json:"stats"`
+}
+
+// PendingSymlink is a symlink found by a walk and left to follow.
+type PendingSymlink struct {
+	Path    string `json:"path"`
+	Target  string `json:"target"`
+	Root    int    `json:"root"`
+	RootDev uint64 `json:"root_dev,omitempty"`
+	Role    Role   `json:"role"`
+}
+
+// WalkStats are the statistics of a walk.
+type WalkStats struct {
+	TotalFiles         uint64   `json:"total_files"`
+	SkippedDirs        uint64   `json:"skipped_dirs"`
+	SkippedFiles       uint64   `json:"skipped_files"`
+	SkippedMounts      uint64   `json:"skipped_mounts"`
+	SkippedMountPoints []string `json:"skipped_mount_points,omitempty"`
+	DanglingSymlinks   uint64   `json:"dangling_symlinks"`
+	ErrorCount         uint64   `json:"error_count"`
+}
+
+// Resume is the progress of an interrupted walk, as recorded by a [Checkpointer].
+type Resume struct {
+	// Walked is set if the walk was done, in which case nothing is walked again.
+	Walked bool
+
+	// State is the last snapshot of the walk, or holds the final statistics of the walk if it was done.
+	State WalkState
+
+	// Files yields the files recorded before the snapshot, with the symlinks recorded to them.
+	Files iter.Seq[FileRecord]
+
+	// Dirs holds the directories recorded before the snapshot.
+	Dirs []DirID
+}
+
+// WithCheckpoint records the progress of the walk to cp.
+func WithCheckpoint(cp Checkpointer) Option {
+	return func(o *options) {
+		o.checkpoint = cp
+	}
+}
+
+// WithResume resumes an interrupted walk: the files found before the interruption are added back as they were
+// recorded, and only the entries visited after the snapshot are walked, given the same roots and options.
+// Entries are visited in the same order by every walk, since directories are read in the order of their names.
+func WithResume(r *Resume) Option {
+	return func(o *options) {
+		o.resume = r
+	}
+}
+
+// restore adds back the files and directories found before the walk was interrupted, and sets the walk
+// to resume from its snapshot.
+func (w *walker) restore(r *Resume, roots []Root) error {
+	type archiveKey struct {
+		root int
+		path string
+	}
+	archives := make(map[archiveKey]vfs.FS)
+	for rec := range r.Files {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+
+		file := rec.File
+		file.FS = w.rootFS(roots, rec.Root)
+		if (file.QuickHashed || file.Hash != "") && modified(file.FS, rec) {
+			file.QuickHash, file.QuickHashed, file.Hash = 0, false, ""
+		}
+		if rec.Archive != "" {
+			key := archiveKey{root: rec.Root, path: rec.Archive}
+			if archives[key] == nil {
+				archives[key] = archive.NewFS(file.FS, rec.Archive)
+			}
+			file.FS = archives[key]
+		} else if w.seen != nil {
+			ref := fileRef{size: file.Size, index: len(w.sizeGroups[file.Size])}
+			if w.spill != nil {
+				ref.run, ref.path = len(w.spill.runs), file.Path
+			}
+			w.seen[restoredKey(file)] = ref
+		}
+
+		w.sizeGroups[file.Size] = append(w.sizeGroups[file.Size], file)
+		if w.spill != nil {
+			w.spill.used += memoryOf(file)
+		}
+		w.stats.Advance(model.StageWalk, 1, file.Size)
+		if w.spill.full() {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if w.visitedDirs != nil {
+		for _, dir := range r.Dirs {
+			w.visitedDirs[fileKey{dev: dir.Dev, ino: dir.Ino, path: dir.Path}] = struct{}{}
+		}
+	}
+
+	stats := r.State.Stats
+	atomic.StoreUint64(&w.stats.TotalFiles, stats.TotalFiles)
+	atomic.StoreUint64(&w.stats.SkippedDirs, stats.SkippedDirs)
+	atomic.StoreUint64(&w.stats.SkippedFiles, stats.SkippedFiles)
+	atomic.StoreUint64(&w.stats.SkippedMounts, stats.SkippedMounts)
+	atomic.StoreUint64(&w.stats.DanglingSymlinks, stats.DanglingSymlinks)
+	atomic.StoreUint64(&w.stats.ErrorCount, stats.ErrorCount)
+	w.stats.SkippedMountPoints = stats.SkippedMountPoints
+
+	if r.Walked {
+		return nil
+	}
+
+	dirs := r.State.PendingDirs
+	w.resume = &r.State
+	if r.State.Unit >= len(roots) && len(dirs) > 0 {
+		// The symlinked directory being walked comes first, and is followed again as the same unit,
+		// unless it is gone.
+		w.followed = r.State.Unit - len(roots)
+		current, ok := w.restoreSymlink(roots, dirs[0])
+		if ok {
+			w.pendingDirs = append(w.pendingDirs, current)
+		} else {
+			w.followed++
+			w.resume = nil
+		}
+		dirs = dirs[1:]
+	}
+	for _, link := range dirs {
+		if restored, ok := w.restoreSymlink(roots, link); ok {
+			w.pendingDirs = append(w.pendingDirs, restored)
+		}
+	}
+	for _, link := range r.State.PendingLinks {
+		if restored, ok := w.restoreSymlink(roots, link); ok {
+			w.pendingLinks = append(w.pendingLinks, restored)
+		}
+	}
+	return nil
+}
+
+// modified reports whether a file recorded before the walk was interrupted was modified since, by its size
+// and modification time, or those of its archive for archive members. Files that cannot be stat'ed anymore
+// are not reported as modified: they keep the hashes recorded, as in the report of the scan interrupted.
+func modified(fsys vfs.FS, rec FileRecord) bool {
+	if rec.Archive != "" {
+		info, err := fsys.Stat(rec.Archive)
+		return err == nil && !info.ModTime().Equal(rec.ModTime)
+	}
+	info, err := fsys.Stat(rec.File.Path)
+	return err == nil && (info.Size() != rec.File.Size || !info.ModTime().Equal(rec.ModTime))
+}
+
+// restoredKey returns the identity of a file added back to the size groups, as computed by [keyOf].
+func restoredKey(file FileInfo) fileKey {
+	if file.Dev != 0 || file.Ino != 0 {
+		return fileKey{dev: file.Dev, ino: file.Ino}
+	}
+	return keyOf(file.fs(), file.Path, nil)
+}
+
+// restoreSymlink stats a pending symlink again. Symlinks that cannot be resolved anymore are reported as errors.
+func (w *walker) restoreSymlink(roots []Root, link PendingSymlink) (symlink, bool) {
+	fsys := w.rootFS(roots, link.Root)
+	info, err := fsys.Stat(link.Path)
+	if err != nil {
+		w.stats.ReportError(model.StageWalk, link.Path, err)
+		return symlink{}, false
+	}
+	return symlink{path: link.Path, target: link.Target, info: info, fs: fsys,
+		rootIndex: link.Root, rootDev: link.RootDev, role: link.Role}, true
+}
+
+// rootFS returns the filesystem of the root at index i.
+func (w *walker) rootFS(roots []Root, i int) vfs.FS {
+	if i >= 0 && i < len(roots) && roots[i].FS != nil {
+		return roots[i].FS
+	}
+	return w.defaultFS
+}
+
+// resumed reports whether an entry was visited before the walk was interrupted, and if so, what to return for it:
+// directories holding the cursor are walked again without being counted twice, while the other entries
+// visited before the cursor are skipped.
+func (w *walker) resumed(path string, dirEnt fs.DirEntry) (bool, error) {
+	if w.resume == nil || w.unit != w.resume.Unit {
+		return false, nil
+	}
+
+	order, ancestor := walkOrder(path, w.resume.Cursor)
+	switch {
+	case order > 0:
+		w.resume = nil
+		return false, nil
+	case dirEnt != nil && dirEnt.IsDir() && !ancestor:
+		return true, fs.SkipDir
+	default:
+		return true, nil
+	}
+}
+
+// walkOrder compares the order in which paths are visited by a walk, which reads directories in the order
+// of their names: a path comes after the directories holding it, and before the paths following it in its directory.
+// It also reports whether a is b or a directory holding it.
+func walkOrder(a, b string) (order int, ancestor bool) {
+	as, bs := splitPath(a), splitPath(b)
+	for i := range min(len(as), len(bs)) {
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c, false
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1, true
+	case len(as) > len(bs):
+		return 1, false
+	default:
+		return 0, true
+	}
+}
+
+// splitPath splits a path into its elements.
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == filepath.Separator })
+}
+
+// snapshot records the state of the walk, with the cursor at path, if a snapshot is due.
+func (w *walker) snapshot(path string) error {
+	cp := w.opts.checkpoint
+	if cp == nil || !cp.Due() {
+		return nil
+	}
+
+	state := WalkState{Unit: w.unit, Cursor: path, Stats: w.walkStats()}
+	if w.current != nil {
+		state.PendingDirs = append(state.PendingDirs, w.current.pending())
+	}
+	for _, link := range w.pendingDirs {
+		state.PendingDirs = append(state.PendingDirs, link.pending())
+	}
+	for _, link := range w.pendingLinks {
+		state.PendingLinks = append(state.PendingLinks, link.pending())
+	}
+	return cp.Snapshot(state)
+}
+
+// walkStats returns the statistics of the walk so far.
+func (w *walker) walkStats() WalkStats {
+	s := w.stats.Snapshot()
+	return WalkStats{
+		TotalFiles:         s.TotalFiles,
+		SkippedDirs:        s.SkippedDirs,
+		SkippedFiles:       s.SkippedFiles,
+		SkippedMounts:      s.SkippedMounts,
+		SkippedMountPoints: w.stats.SkippedMountPoints,
+		DanglingSymlinks:   s.DanglingSymlinks,
+		ErrorCount:         s.ErrorCount,
+	}
+}
+
+// pending returns the symlink as recorded in a snapshot.
+func (l *symlink) pending() PendingSymlink {
+	return PendingSymlink{Path: l.path, Target: l.target, Root: l.rootIndex, RootDev: l.rootDev, Role: l.role}
+}
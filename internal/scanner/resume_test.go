@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// checkpointLog records the progress of a walk in memory, with a snapshot after every file.
+type checkpointLog struct {
+	files  []FileRecord
+	dirs   []DirID
+	links  map[string][]string
+	states []checkpointState
+	walked *WalkStats
+}
+
+// checkpointState is a snapshot, with the number of files and directories recorded before it.
+type checkpointState struct {
+	state       WalkState
+	files, dirs int
+}
+
+func (l *checkpointLog) AddFile(file FileRecord) { l.files = append(l.files, file) }
+func (l *checkpointLog) AddDir(dir DirID)        { l.dirs = append(l.dirs, dir) }
+func (l *checkpointLog) Due() bool               { return true }
+
+func (l *checkpointLog) AddSymlink(path, link string) {
+	if l.links == nil {
+		l.links = make(map[string][]string)
+	}
+	l.links[path] = append(l.links[path], link)
+}
+
+func (l *checkpointLog) Snapshot(state WalkState) error {
+	l.states = append(l.states, checkpointState{state: state, files: len(l.files), dirs: len(l.dirs)})
+	return nil
+}
+
+func (l *checkpointLog) Walked(stats WalkStats) error {
+	l.walked = &stats
+	return nil
+}
+
+// resumeAt returns the progress recorded up to the snapshot at index i, or up to the end of the walk if i is -1.
+func (l *checkpointLog) resumeAt(i int) *Resume {
+	if i < 0 {
+		return &Resume{Walked: true, State: WalkState{Stats: *l.walked}, Files: l.records(len(l.files), true), Dirs: l.dirs}
+	}
+	s := l.states[i]
+	return &Resume{State: s.state, Files: l.records(s.files, false), Dirs: l.dirs[:s.dirs]}
+}
+
+// records yields the first n files recorded, with the symlinks recorded to them if links is set.
+func (l *checkpointLog) records(n int, links bool) func(func(FileRecord) bool) {
+	return func(yield func(FileRecord) bool) {
+		for _, rec := range l.files[:n] {
+			if links {
+				rec.File.Symlinks = slices.Concat(rec.File.Symlinks, l.links[rec.File.Path])
+			}
+			if !yield(rec) {
+				return
+			}
+		}
+	}
+}
+
+// TestWithResume verifies that a walk resumed from any of its snapshots finds the same files,
+// with the same statistics, as the walk it resumes, and records the files left to find.
+func TestWithResume(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	files := map[string]int{
+		"a/1.txt":         10,
+		"a/2.txt":         20,
+		"a/b/3.txt":       10,
+		"a/b/skip.log":    30,
+		"a.txt":           20,
+		"c/4.txt":         40,
+		"c/d/e/5.txt":     10,
+		"other/6.txt":     40,
+		"other/sub/7.txt": 20,
+	}
+	for name, size := range files {
+		base := dir
+		if filepath.Dir(name) == "other" || filepath.Dir(filepath.Dir(name)) == "other" {
+			base = outside
+		}
+		path := filepath.Join(base, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "other"), filepath.Join(dir, "a", "linked")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "c", "4.txt"), filepath.Join(dir, "a", "link.txt")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	second := filepath.Join(outside, "second")
+	if err := os.MkdirAll(second, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(second, "8.txt"), make([]byte, 10), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	roots := rootsOf([]string{dir, second}, RoleScan)
+	filterConfig := &filter.Config{ExcludeFiles: []string{"*.log"}}
+	opts := []Option{WithFollowSymlinks(true), WithReportSymlinks(true)}
+
+	log := &checkpointLog{}
+	stats := &model.Stats{}
+	want, err := GroupRootsBySize(t.Context(), roots, filterConfig, stats, append(opts, WithCheckpoint(log))...)
+	if err != nil {
+		t.Fatalf("GroupRootsBySize() error = %v", err)
+	}
+	if len(log.states) != 10 || log.walked == nil {
+		t.Fatalf("Recorded %d snapshots, walked %v, want a snapshot per regular file and the end of the walk",
+			len(log.states), log.walked != nil)
+	}
+	if last := log.states[len(log.states)-1].state; last.Unit != 2 {
+		t.Errorf("Last snapshot unit = %d, want the symlinked directory following both roots", last.Unit)
+	}
+
+	for i := -1; i < len(log.states); i++ {
+		t.Run(fmt.Sprintf("snapshot %d", i), func(t *testing.T) {
+			resume := log.resumeAt(i)
+			rest := &checkpointLog{}
+			resumedStats := &model.Stats{}
+			got, err := GroupRootsBySize(t.Context(), roots, filterConfig, resumedStats,
+				append(opts, WithResume(resume), WithCheckpoint(rest))...)
+			if err != nil {
+				t.Fatalf("GroupRootsBySize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GroupRootsBySize() = %v, want %v", got, want)
+			}
+			if resumedStats.TotalFiles != stats.TotalFiles || resumedStats.SkippedFiles != stats.SkippedFiles {
+				t.Errorf("Stats = %d files, %d skipped, want %d, %d", resumedStats.TotalFiles, resumedStats.SkippedFiles,
+					stats.TotalFiles, stats.SkippedFiles)
+			}
+
+			if i < 0 {
+				if len(rest.files) != 0 || rest.walked != nil {
+					t.Errorf("Resuming a walk that was done recorded %d files", len(rest.files))
+				}
+				return
+			}
+			var paths, wantPaths []string
+			for _, rec := range slices.Concat(log.files[:log.states[i].files], rest.files) {
+				paths = append(paths, rec.File.Path)
+			}
+			for _, rec := range log.files {
+				wantPaths = append(wantPaths, rec.File.Path)
+			}
+			if !slices.Equal(paths, wantPaths) {
+				t.Errorf("Files recorded = %v, want %v", paths, wantPaths)
+			}
+		})
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		order    int
+		ancestor bool
+	}{
+		{"/r/a", "/r/a/b/c", -1, true},
+		{"/r/a/b/c", "/r/a/b/c", 0, true},
+		{"/r/a/b/d", "/r/a/b/c", 1, false},
+		{"/r/a/a", "/r/a/b/c", -1, false},
+		{"/r/a/c", "/r/a/b/c", 1, false},
+		{"/r/a.txt", "/r/a/b", 1, false},
+		{"/r/a/b/c/d", "/r/a/b/c", 1, false},
+	}
+	for _, tt := range tests {
+		order, ancestor := walkOrder(tt.a, tt.b)
+		if order != tt.order || ancestor != tt.ancestor {
+			t.Errorf("walkOrder(%q, %q) = %d, %v, want %d, %v", tt.a, tt.b, order, ancestor, tt.order, tt.ancestor)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Role describes how the files under a scan root are treated.
+type Role uint8
+
+const (
+	// RoleScan marks files that may be suggested for removal.
+	RoleScan Role = iota
+
+	// RoleReference marks files in a canonical tree, which are never suggested for removal.
+	RoleReference
+)
+
+// String returns the name of the role.
+func (r Role) String() string {
+	switch r {
+	case RoleScan:
+		return "scan"
+	case RoleReference:
+		return "reference"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the role as its name.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a role from its name.
+func (r *Role) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "scan", "":
+		*r = RoleScan
+	case "reference":
+		*r = RoleReference
+	default:
+		return fmt.Errorf("unknown role '%s'", text)
+	}
+	return nil
+}
+
+// Root is a path to scan, tagged with the role of the files under it.
+type Root struct {
+	Path string
+	Role Role
+
+	// FS is the filesystem the root is on. A nil FS means the scanner's default filesystem.
+	FS vfs.FS
+}
+
+// GetRootsFromArgs returns the roots to scan from command arguments, the file list named by filesFrom (if any),
+// and the reference directories.
+//
+// Arguments and reference directories may be URLs like "s3://bucket/prefix" or "sftp://user@host/path",
+// which are opened with backends.
+// The current directory is not scanned by default if any URL is given.
+func GetRootsFromArgs(ctx context.Context, c *cli.Command, filesFrom string, references []string,
+	backends *vfs.Registry,
+) ([]Root, error) {
+	return GetRoots(ctx, c.Args().Slice(), filesFrom, references, backends)
+}
+
+// GetRoots is like [GetRootsFromArgs], with the paths to scan given as a slice.
+func GetRoots(ctx context.Context, paths []string, filesFrom string, references []string,
+	backends *vfs.Registry,
+) ([]Root, error) {
+	args, urls := splitURLs(paths)
+	refs, refURLs := splitURLs(references)
+
+	var local []string
+	if len(args) > 0 || len(urls) == 0 || filesFrom != "" {
+		var err error
+		if local, err = readPaths(args, filesFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	roots, err := processRoots(local, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(urls) > 0 || len(refURLs) > 0 {
+		if backends == nil {
+			backends = vfs.NewRegistry()
+		}
+		remote, err := openURLRoots(ctx, backends, urls, refURLs)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, remote...)
+	}
+
+	return roots, nil
+}
+
+// splitURLs separates URLs from local paths.
+func splitURLs(paths []string) (local, urls []string) {
+	for _, path := range paths {
+		if vfs.IsURL(path) {
+			urls = append(urls, path)
+		} else {
+			local = append(local, path)
+		}
+	}
+	return local, urls
+}
+
+// openURLRoots opens URL roots and checks that each one is a directory or a regular file.
+// As with local paths, scan roots within reference roots or other scan roots on the same filesystem are dropped.
+func openURLRoots(ctx context.Context, backends *vfs.Registry, urls, refURLs []string) ([]Root, error) {
+	var refs, roots []Root
+	for i, rawURL := range slices.Concat(refURLs, urls) {
+		fsys, name, err := backends.Open(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := fsys.Stat(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("path does not exist: %s", name)
+			}
+			return nil, fmt.Errorf("error accessing path %s: %w", name, err)
+		} else if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("not a directory or regular file: %s", name)
+		}
+
+		if i < len(refURLs) {
+			refs = append(refs, Root{Path: name, Role: RoleReference, FS: fsys})
+		} else {
+			roots = append(roots, Root{Path: name, Role: RoleScan, FS: fsys})
+		}
+	}
+
+	slices.SortFunc(roots, func(a, b Root) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	result := refs
+	for _, root := range roots {
+		if slices.ContainsFunc(result, func(r Root) bool { return r.FS == root.FS && withinURL(root.Path, r.Path) }) {
+			continue
+		}
+		result = append(result, root)
+	}
+
+	slices.SortFunc(result, func(a, b Root) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return result, nil
+}
+
+// withinURL reports whether name is parent or a path under it.
+func withinURL(name, parent string) bool {
+	return name == parent || strings.HasPrefix(name, strings.TrimSuffix(parent, "/")+"/")
+}
+
+// processRoots validates the reference directories and tags each root with its role.
+// The scan paths are expected to be processed already.
+//
+// Reference directories take precedence: scan paths inside a reference directory are scanned as references,
+// while reference directories inside a scan path are skipped when walking that path.
+func processRoots(paths, references []string) ([]Root, error) {
+	var refPaths []string
+	if len(references) > 0 {
+		var err error
+		refPaths, err = processDirectories(references)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	roots := make([]Root, 0, len(paths)+len(refPaths))
+	for _, ref := range refPaths {
+		roots = append(roots, Root{Path: ref, Role: RoleReference})
+	}
+
+	for _, path := range paths {
+		if slices.ContainsFunc(refPaths, func(ref string) bool { return path == ref || isSubdirectory(path, ref) }) {
+			continue
+		}
+		roots = append(roots, Root{Path: path, Role: RoleScan})
+	}
+
+	slices.SortFunc(roots, func(a, b Root) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return roots, nil
+}
+
+// rootsOf tags every path with the same role.
+func rootsOf(paths []string, role Role) []Root {
+	roots := make([]Root, len(paths))
+	for i, path := range paths {
+		roots[i] = Root{Path: path, Role: role}
+	}
+	return roots
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// TestProcessRoots verifies that roots are tagged with their roles and that reference directories take precedence.
+func TestProcessRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+	scan := filepath.Join(tmpDir, "scan")
+	ref := filepath.Join(tmpDir, "ref")
+	inRef := filepath.Join(ref, "inner")
+	for _, dir := range []string{scan, inRef} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+	}
+
+	roots, err := processRoots([]string{scan, inRef}, []string{ref})
+	if err != nil {
+		t.Fatalf("processRoots() error = %v", err)
+	}
+
+	want := []Root{{Path: ref, Role: RoleReference}, {Path: scan, Role: RoleScan}}
+	if len(roots) != len(want) {
+		t.Fatalf("processRoots() = %v, want %v", roots, want)
+	}
+	for i := range want {
+		if roots[i] != want[i] {
+			t.Errorf("processRoots()[%d] = %v, want %v", i, roots[i], want[i])
+		}
+	}
+
+	if _, err := processRoots([]string{scan}, []string{filepath.Join(tmpDir, "missing")}); err == nil {
+		t.Error("processRoots() with a missing reference directory should return an error")
+	}
+}
+
+// TestGroupRootsBySize verifies that files are tagged with the role of their root,
+// and that reference directories nested in a scan root are only scanned as references.
+func TestGroupRootsBySize(t *testing.T) {
+	tmpDir := t.TempDir()
+	ref := filepath.Join(tmpDir, "ref")
+	if err := os.MkdirAll(ref, 0o750); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	files := map[string]Role{
+		filepath.Join(tmpDir, "copy.txt"): RoleScan,
+		filepath.Join(ref, "orig.txt"):    RoleReference,
+	}
+	for path := range files {
+		if err := os.WriteFile(path, make([]byte, 100), 0o644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	roots := []Root{{Path: ref, Role: RoleReference}, {Path: tmpDir, Role: RoleScan}}
+	sizeGroups, err := GroupRootsBySize(context.Background(), roots, &filter.Config{}, &model.Stats{})
+	if err != nil {
+		t.Fatalf("GroupRootsBySize() error = %v", err)
+	}
+
+	group := sizeGroups[100]
+	if len(group) != len(files) {
+		t.Fatalf("Size group 100 has %d files, want %d", len(group), len(files))
+	}
+	for _, file := range group {
+		if want := files[file.Path]; file.Role != want {
+			t.Errorf("File %s has role %v, want %v", file.Path, file.Role, want)
+		}
+	}
+}
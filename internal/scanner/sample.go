@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/xxh3"
+)
+
+// sampleSeed is mixed with the size of files to seed the random sample offsets,
+// so that files of the same size are sampled at the same offsets.
+const sampleSeed = 0x646f7070656c // "doppel"
+
+// Samples are the portions of files hashed after the quick hash, to tell apart large files whose first
+// and last bytes are the same, such as disk images or padded videos, before reading them in full.
+// Each sample is as large as the portions hashed by the quick hash.
+type Samples struct {
+	// Middle samples the middle of files.
+	Middle bool
+
+	// Random is the number of samples taken at random offsets, which only depend on the size of files.
+	Random int
+}
+
+// ParseSamples parses comma-separated sample points: "middle", and "random=N" for N random offsets.
+// An empty string and "none" mean no samples.
+func ParseSamples(s string) (Samples, error) {
+	var samples Samples
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "" || part == "none":
+		case part == "middle":
+			samples.Middle = true
+		case strings.HasPrefix(part, "random="):
+			n, err := strconv.Atoi(strings.TrimPrefix(part, "random="))
+			if err != nil || n < 1 {
+				return Samples{}, fmt.Errorf("invalid sample points %q: the number of random samples must be a positive integer", part)
+			}
+			samples.Random = n
+		default:
+			return Samples{}, fmt.Errorf("invalid sample points %q: expected middle or random=N", part)
+		}
+	}
+	return samples, nil
+}
+
+// Enabled reports whether any sample is taken.
+func (s Samples) Enabled() bool {
+	return s.Middle || s.Random > 0
+}
+
+// String formats the samples as parsed by [ParseSamples].
+func (s Samples) String() string {
+	var parts []string
+	if s.Middle {
+		parts = append(parts, "middle")
+	}
+	if s.Random > 0 {
+		parts = append(parts, "random="+strconv.Itoa(s.Random))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ",")
+}
+
+// Offsets returns the offsets of the samples of a file of a size, in increasing order.
+// Samples lie between the portions read by the quick hash, so files too small to hold
+// a sample between them have none.
+func (s Samples) Offsets(size int64) []int64 {
+	low, high := int64(quickHashSize), size-2*quickHashSize
+	if high < low {
+		return nil
+	}
+
+	var offsets []int64
+	if s.Middle {
+		offsets = append(offsets, size/2-quickHashSize/2)
+	}
+	if s.Random > 0 {
+		r := rand.New(rand.NewPCG(uint64(size), sampleSeed))
+		for range s.Random {
+			offsets = append(offsets, low+r.Int64N(high-low+1))
+		}
+	}
+	slices.Sort(offsets)
+	return slices.Compact(offsets)
+}
+
+// SampleHashFileInfo computes a XXH3 hash of the portions of a file at offsets, on its filesystem.
+// Files without offsets are not read, and hash to zero.
+func SampleHashFileInfo(file FileInfo, offsets []int64, hasher *xxh3.Hasher, buf []byte) (uint64, error) {
+	if len(offsets) == 0 {
+		return 0, nil
+	}
+	if hasher == nil {
+		return 0, errors.New("hasher is nil")
+	}
+
+	f, err := file.fs().Open(file.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	hasher.Reset()
+	buf = buf[:quickHashSize]
+	for _, offset := range offsets {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		_, _ = hasher.Write(buf[:n])
+	}
+	return hasher.Sum64(), nil
+}
+
+// ReadFileInfoAt reads len(buf) bytes of a file at an offset, on its filesystem.
+// It returns the number of bytes read, which is less than len(buf) only at the end of the file.
+func ReadFileInfoAt(file FileInfo, buf []byte, offset int64) (int, error) {
+	f, err := file.fs().Open(file.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, err
+	}
+	return n, nil
+}
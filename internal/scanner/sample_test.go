@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/zeebo/xxh3"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+func TestParseSamples(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Samples
+		wantErr bool
+	}{
+		{"", Samples{}, false},
+		{"none", Samples{}, false},
+		{"middle", Samples{Middle: true}, false},
+		{"random=4", Samples{Random: 4}, false},
+		{" Middle , random=2 ", Samples{Middle: true, Random: 2}, false},
+		{"random=0", Samples{}, true},
+		{"random", Samples{}, true},
+		{"edges", Samples{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSamples(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSamples(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSamples(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			if !tt.wantErr {
+				if again, err := ParseSamples(got.String()); err != nil || again != got {
+					t.Errorf("ParseSamples(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+				}
+			}
+		})
+	}
+}
+
+// TestSamples_Offsets verifies that samples lie between the portions read by the quick hash,
+// at the same offsets for files of the same size.
+func TestSamples_Offsets(t *testing.T) {
+	samples := Samples{Middle: true, Random: 8}
+
+	if offsets := samples.Offsets(3*quickHashSize - 1); offsets != nil {
+		t.Errorf("Offsets() of a small file = %v, want none", offsets)
+	}
+	if offsets, want := (Samples{Middle: true}).Offsets(1<<20), []int64{1<<19 - quickHashSize/2}; !slices.Equal(offsets, want) {
+		t.Errorf("Offsets() of the middle = %v, want %v", offsets, want)
+	}
+
+	for _, size := range []int64{3 * quickHashSize, 1 << 20, 1<<30 + 7} {
+		offsets := samples.Offsets(size)
+		if len(offsets) == 0 || len(offsets) > 9 {
+			t.Errorf("Offsets(%d) = %v, want between 1 and 9 offsets", size, offsets)
+		}
+		if !slices.IsSorted(offsets) {
+			t.Errorf("Offsets(%d) = %v, want sorted offsets", size, offsets)
+		}
+		for _, offset := range offsets {
+			if offset < quickHashSize || offset+quickHashSize > size-quickHashSize {
+				t.Errorf("Offsets(%d) = %v, want samples between the first and last %d bytes", size, offsets, quickHashSize)
+			}
+		}
+		if again := samples.Offsets(size); !slices.Equal(again, offsets) {
+			t.Errorf("Offsets(%d) = %v, then %v, want the same offsets", size, offsets, again)
+		}
+	}
+}
+
+// TestSampleHashFileInfo verifies that files with the same first and last bytes are told apart
+// by their middle, and that files without samples are not read.
+func TestSampleHashFileInfo(t *testing.T) {
+	const size = 64 * 1024
+	content := bytes.Repeat([]byte{'x'}, size)
+	changed := slices.Clone(content)
+	changed[size/2] = 'y'
+
+	mem := vfs.NewMemFS()
+	for name, data := range map[string][]byte{"/a": content, "/b": content, "/c": changed} {
+		if err := mem.WriteFile(name, data); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	offsets := Samples{Middle: true}.Offsets(size)
+	hasher := xxh3.New()
+	buf := make([]byte, quickHashSize)
+	hashOf := func(name string) uint64 {
+		t.Helper()
+		h, err := SampleHashFileInfo(FileInfo{Path: name, Size: size, FS: mem}, offsets, hasher, buf)
+		if err != nil {
+			t.Fatalf("SampleHashFileInfo(%s) error = %v", name, err)
+		}
+		return h
+	}
+	if hashOf("/a") != hashOf("/b") {
+		t.Error("SampleHashFileInfo() of identical files differ")
+	}
+	if hashOf("/a") == hashOf("/c") {
+		t.Error("SampleHashFileInfo() of files differing in the middle are the same")
+	}
+
+	if _, err := SampleHashFileInfo(FileInfo{Path: "/missing", Size: size, FS: mem}, offsets, hasher, buf); err == nil {
+		t.Error("SampleHashFileInfo() of a missing file error = nil, want an error")
+	}
+	if h, err := SampleHashFileInfo(FileInfo{Path: "/missing", Size: 1, FS: mem}, nil, hasher, buf); err != nil || h != 0 {
+		t.Errorf("SampleHashFileInfo() without offsets = %d, %v, want 0 without reading", h, err)
+	}
+}
+
+func TestReadFileInfoAt(t *testing.T) {
+	mem := vfs.NewMemFS()
+	if err := mem.WriteFile("/f", []byte("0123456789")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	file := FileInfo{Path: "/f", Size: 10, FS: mem}
+
+	buf := make([]byte, 4)
+	if n, err := ReadFileInfoAt(file, buf, 3); err != nil || string(buf[:n]) != "3456" {
+		t.Errorf("ReadFileInfoAt(3) = %q, %v, want %q", buf[:n], err, "3456")
+	}
+	if n, err := ReadFileInfoAt(file, buf, 8); err != nil || string(buf[:n]) != "89" {
+		t.Errorf("ReadFileInfoAt(8) = %q, %v, want %q at the end of the file", buf[:n], err, "89")
+	}
+}
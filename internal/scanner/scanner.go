@@ -1,193 +1,738 @@
 // Package scanner provides file system scanning capabilities for the doppel duplicate file finder.
 //
 // This package handles the initial phase of duplicate detection by:
-//   - Recursively traversing directory structures
+//   - Recursively traversing directory structures on any [vfs.FS] backend (the local OS filesystem by default),
+//     optionally following symlinks
 //   - Applying filters to exclude unwanted files and directories
-//   - Grouping files by size to optimize duplicate detection
-//   - Processing command-line directory arguments and removing subdirectories
+//   - Optionally listing the members of archives as read-only virtual files
+//   - Grouping files by size to optimize duplicate detection, optionally spilling the size groups
+//     to sorted run files on disk to bound memory use
+//   - Optionally recording the progress of the walk, to resume an interrupted walk where it stopped
+//   - Processing command-line directory and file arguments (or file lists) and removing subdirectories
 //
 // The scanner works in conjunction with the filter package to efficiently
 // collect candidate files for duplicate detection.
 package scanner
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"io/fs"
-	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v3"
+	"github.com/zeebo/xxh3"
 
+	"github.com/dr8co/doppel/internal/archive"
 	"github.com/dr8co/doppel/internal/filter"
-	"github.com/dr8co/doppel/internal/logger"
 	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/vfs"
 )
 
 // GroupFilesBySize scans directories and groups files by their size.
 func GroupFilesBySize(ctx context.Context,
-	directories []string, filterConfig *filter.Config, stats *model.Stats, verbose bool) (map[int64][]FileInfo, error,
+	directories []string, filterConfig *filter.Config, stats *model.Stats, opts ...Option) (map[int64][]FileInfo, error,
 ) {
-	sizeGroups := make(map[int64][]FileInfo, len(directories))
-	for _, dir := range directories {
-		err := filepath.WalkDir(dir, func(path string, dirEnt fs.DirEntry, err error) error {
-			if err != nil {
-				if verbose {
-					var filepathErr *os.PathError
-					if errors.As(err, &filepathErr) {
-						logger.ErrorAttrs(ctx, "error accessing file",
-							slog.String("path", filepathErr.Path), slog.String("op", filepathErr.Op),
-							slog.String("err", filepathErr.Err.Error()))
-					} else {
-						logger.ErrorAttrs(ctx, "error accessing file", slog.String("path", path),
-							slog.String("err", err.Error()))
-					}
-				}
-				stats.ErrorCount++
-				return nil
-			}
+	return GroupRootsBySize(ctx, rootsOf(directories, RoleScan), filterConfig, stats, opts...)
+}
 
-			// Check if we should skip this directory
-			if dirEnt.IsDir() && filterConfig.ShouldExcludeDir(path) {
-				if verbose {
-					logger.InfoAttrs(ctx, "skipping directory", slog.String("path", path),
-						slog.String("exclusion reason", "filter match"))
-				}
-				stats.SkippedDirs++
-				return filepath.SkipDir
-			}
+// GroupRootsBySize scans roots and groups files by their size, tagging each file with the role of its root.
+// The entries skipped and the errors met are reported to the event observer of stats.
+// Canceling ctx stops the walk, and the error of ctx is returned.
+func GroupRootsBySize(ctx context.Context,
+	roots []Root, filterConfig *filter.Config, stats *model.Stats, opts ...Option) (map[int64][]FileInfo, error,
+) {
+	w, err := scanRoots(ctx, roots, filterConfig, stats, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return w.sizeGroups, nil
+}
 
-			if dirEnt.Type().IsRegular() {
-				info, err := dirEnt.Info()
-				if err != nil {
-					if verbose {
-						var filepathErr *os.PathError
-						if errors.As(err, &filepathErr) {
-							logger.ErrorAttrs(ctx, "error getting file info",
-								slog.String("path", filepathErr.Path), slog.String("op", filepathErr.Op),
-								slog.String("err", filepathErr.Err.Error()))
-						} else {
-							logger.ErrorAttrs(ctx, "error getting file info", slog.String("path", path),
-								slog.String("err", err.Error()))
-						}
-					}
-					stats.ErrorCount++
-					return nil
-				}
-
-				size := info.Size()
-
-				// Check if we should skip this file
-				if filterConfig.ShouldExcludeFile(path, size) {
-					if verbose {
-						logger.InfoAttrs(ctx, "skipping file", slog.String("path", path),
-							slog.String("exclusion reason", "filter match"))
-					}
-					stats.SkippedFiles++
-					return nil
-				}
-
-				sizeGroups[size] = append(sizeGroups[size], FileInfo{Path: path, Size: size})
-				stats.TotalFiles++
-			}
+// scanRoots walks roots with a new walker, as the walk stage of stats, and returns the walker holding the files found.
+// If spill is set, the size groups are spilled to disk as set by [WithSpill], and the rest of them flushed
+// to a last run once anything was spilled; the run files are removed if the walk fails.
+func scanRoots(ctx context.Context, roots []Root, filterConfig *filter.Config, stats *model.Stats, spill bool,
+	opts ...Option,
+) (*walker, error) {
+	w, err := newWalker(ctx, filterConfig, stats, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if spill && w.opts.spillLimit > 0 {
+		w.spill = newSpill(w.opts.spillDir, w.opts.spillLimit)
+	}
+
+	for _, root := range roots {
+		if root.Role == RoleReference {
+			w.referenceRoots[root.Path] = true
+		}
+	}
+
+	stats.StartStage(model.StageWalk, 0, 0)
+	err = w.walkRoots(roots)
+	stats.EndStage(model.StageWalk)
+	if err == nil && w.spill != nil && len(w.spill.runs) > 0 && len(w.sizeGroups) > 0 {
+		err = w.flush()
+	}
+	if err != nil {
+		if w.spill != nil {
+			_ = w.spill.remove()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// walkRoots walks every root, then the symlinked directories and files found along the way.
+// With [WithResume], the walk starts from where the interrupted walk stopped.
+func (w *walker) walkRoots(roots []Root) error {
+	if r := w.opts.resume; r != nil {
+		if err := w.restore(r, roots); err != nil {
+			return err
+		}
+		if r.Walked {
 			return nil
-		})
-		if err != nil {
-			return nil, fmt.Errorf("error walking directory %s: %w", dir, err)
 		}
 	}
 
-	printSummary(stats, verbose)
+	for i, root := range roots {
+		if r := w.opts.resume; r != nil && i < r.State.Unit {
+			continue
+		}
+		w.unit, w.rootIndex, w.role = i, i, root.Role
+		w.fs = w.rootFS(roots, i)
+		if err := w.walkRoot(root.Path); err != nil {
+			return fmt.Errorf("error walking directory %s: %w", root.Path, err)
+		}
+	}
 
-	return sizeGroups, nil
+	if err := w.followPendingDirs(len(roots)); err != nil {
+		return err
+	}
+	if err := w.resolvePendingLinks(); err != nil {
+		return err
+	}
+
+	if cp := w.opts.checkpoint; cp != nil {
+		return cp.Walked(w.walkStats())
+	}
+	return nil
 }
 
-func printSummary(stats *model.Stats, verbose bool) {
-	if verbose && (stats.SkippedDirs > 0 || stats.SkippedFiles > 0) {
-		fmt.Print("\n⏭️ Skipped ")
-		if stats.SkippedDirs > 0 {
-			fmt.Printf("%d director%s ", stats.SkippedDirs, pluralize(stats.SkippedDirs, true))
-			if stats.SkippedFiles > 0 {
-				fmt.Print("and ")
+// walker holds the state of a single scan.
+type walker struct {
+	fs         vfs.FS
+	defaultFS  vfs.FS
+	ctx        context.Context
+	filter     *filter.Config
+	stats      *model.Stats
+	opts       options
+	sizeGroups map[int64][]FileInfo
+
+	// spill writes the size groups to disk when they take too much memory, or is nil to keep them in memory.
+	spill *spill
+
+	// seen maps file identities to their entries, to avoid counting a file reached through symlinks twice.
+	// It holds an entry for every file found, and is not spilled with the size groups.
+	seen map[fileKey]fileRef
+
+	// visitedDirs records the identities of walked directories, for symlink loop detection.
+	visitedDirs map[fileKey]struct{}
+
+	// pendingDirs holds symlinked directories to follow once all roots have been walked.
+	pendingDirs []symlink
+
+	// pendingLinks holds symlinked files to resolve once all directories have been walked.
+	pendingLinks []symlink
+
+	// mounts maps devices to filesystem types, when excluding filesystem types.
+	mounts *mountTable
+
+	// root is the root path being walked, or empty while following symlinked directories.
+	root string
+
+	// rootDev is the device of the root the current tree was reached from.
+	rootDev uint64
+
+	// role is the role of the root the current tree was reached from.
+	role Role
+
+	// referenceRoots holds the reference roots, which are skipped while walking scan roots.
+	referenceRoots map[string]bool
+
+	// unit is the index of the tree being walked, as recorded in snapshots: the roots come first,
+	// then the symlinked directories in the order they are followed.
+	unit int
+
+	// rootIndex is the index of the root whose filesystem the current tree is on.
+	rootIndex int
+
+	// followed counts the symlinked directories taken from pendingDirs.
+	followed int
+
+	// current is the symlinked directory being walked, if any.
+	current *symlink
+
+	// resume is the snapshot the walk resumes from, until the first entry visited after it.
+	resume *WalkState
+}
+
+// fileRef locates a [FileInfo] within the size groups.
+type fileRef struct {
+	size  int64
+	index int
+
+	// run is the number of runs spilled before the file was added, and path the path of the file, when spilling.
+	run  int
+	path string
+}
+
+// symlink is a symbolic link together with its resolved target.
+type symlink struct {
+	path   string
+	target string
+	info   fs.FileInfo
+
+	// fs is the filesystem the symlink is on.
+	fs vfs.FS
+
+	// rootIndex is the index of the root whose filesystem the symlink is on.
+	rootIndex int
+
+	// rootDev is the device of the root the symlink was found under.
+	rootDev uint64
+
+	// role is the role of the root the symlink was found under.
+	role Role
+}
+
+func newWalker(ctx context.Context, filterConfig *filter.Config, stats *model.Stats, opts ...Option) (*walker, error) {
+	w := &walker{
+		fs:             vfs.OS{},
+		ctx:            ctx,
+		filter:         filterConfig,
+		stats:          stats,
+		sizeGroups:     make(map[int64][]FileInfo),
+		referenceRoots: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+	if w.opts.fs != nil {
+		w.fs = w.opts.fs
+	}
+	w.defaultFS = w.fs
+
+	if w.opts.followSymlinks || w.opts.reportSymlinks {
+		w.seen = make(map[fileKey]fileRef)
+	}
+	if w.opts.followSymlinks {
+		w.visitedDirs = make(map[fileKey]struct{})
+	}
+
+	if len(w.opts.excludeFSTypes) > 0 {
+		mounts, err := readMountTable()
+		if err != nil {
+			return nil, err
+		}
+		w.mounts = mounts
+	}
+
+	return w, nil
+}
+
+// walkRoot walks a root path. Roots that are symlinks to directories are always followed.
+func (w *walker) walkRoot(root string) error {
+	walkPath := root
+	info, err := w.fs.Lstat(root)
+	if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		if target, err := w.fs.EvalSymlinks(root); err == nil {
+			if targetInfo, err := w.fs.Stat(target); err == nil && targetInfo.IsDir() {
+				walkPath = target
 			}
 		}
-		if stats.SkippedFiles > 0 {
-			fmt.Printf("%d file%s ", stats.SkippedFiles, pluralize(stats.SkippedFiles, false))
+	}
+
+	var rootDev uint64
+	if info, err := w.fs.Stat(walkPath); err == nil {
+		rootDev, _, _ = fileID(info)
+	}
+	w.root = root
+	return w.walk(walkPath, root, rootDev)
+}
+
+// walk walks the directory tree at walkPath, reporting paths as if it were rooted at displayPath.
+// rootDev is the device of the root the tree was reached from.
+func (w *walker) walk(walkPath, displayPath string, rootDev uint64) error {
+	w.rootDev = rootDev
+	return vfs.WalkDir(w.fs, walkPath, func(path string, dirEnt fs.DirEntry, err error) error {
+		if walkPath != displayPath {
+			path = displayPath + strings.TrimPrefix(path, walkPath)
+		}
+		return w.visit(path, dirEnt, err)
+	})
+}
+
+// visit processes a single directory entry. It stops the walk once ctx is canceled.
+func (w *walker) visit(path string, dirEnt fs.DirEntry, err error) error {
+	if ctxErr := w.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if skip, result := w.resumed(path, dirEnt); skip {
+		return result
+	}
+	if err != nil {
+		w.stats.ReportError(model.StageWalk, path, err)
+		return nil
+	}
+
+	switch {
+	case dirEnt.IsDir():
+		return w.visitDir(path, dirEnt)
+	case dirEnt.Type().IsRegular():
+		info, err := dirEnt.Info()
+		if err != nil {
+			w.stats.ReportError(model.StageWalk, path, err)
+			return nil
+		}
+		w.addFile(path, info, "")
+		if w.spill.full() {
+			if err := w.flush(); err != nil {
+				return err
+			}
 		}
-		fmt.Println("due to filters.")
+		return w.snapshot(path)
+	case dirEnt.Type()&fs.ModeSymlink != 0:
+		w.visitSymlink(path)
 	}
+
+	return nil
 }
 
-func pluralize(num uint64, ies bool) string {
-	if ies {
-		if num < 2 {
-			return "y"
+// visitDir decides whether to descend into a directory.
+func (w *walker) visitDir(path string, dirEnt fs.DirEntry) error {
+	// Check if we should skip this directory
+	if w.filter.ShouldExcludeDir(path) {
+		w.stats.Skip(model.SkipDir, path, "filter match")
+		w.stats.IncrementSkippedDirs()
+		return fs.SkipDir
+	}
+
+	// Reference directories nested in a scan root are walked on their own, as references.
+	if w.role == RoleScan && path != w.root && w.referenceRoots[path] {
+		return fs.SkipDir
+	}
+
+	if w.visitedDirs == nil && !w.opts.oneFileSystem && w.mounts == nil {
+		return nil
+	}
+
+	info, err := dirEnt.Info()
+	if err != nil {
+		w.stats.ReportError(model.StageWalk, path, err)
+		return fs.SkipDir
+	}
+
+	if reason := w.mountExclusion(path, info); reason != "" {
+		w.stats.Skip(model.SkipMount, path, reason)
+		w.stats.IncrementSkippedMounts()
+		w.stats.SkippedMountPoints = append(w.stats.SkippedMountPoints, path)
+		return fs.SkipDir
+	}
+
+	if w.visitedDirs != nil {
+		key := keyOf(w.fs, path, info)
+		if _, visited := w.visitedDirs[key]; visited {
+			w.stats.Skip(model.SkipDir, path, "already visited")
+			return fs.SkipDir
+		}
+		w.visitedDirs[key] = struct{}{}
+		if w.opts.checkpoint != nil {
+			w.opts.checkpoint.AddDir(DirID{Dev: key.dev, Ino: key.ino, Path: key.path})
 		}
-		return "ies"
 	}
 
-	if num < 2 {
+	return nil
+}
+
+// mountExclusion returns the reason to skip a directory because of the filesystem it is on,
+// or an empty string if it should be scanned.
+func (w *walker) mountExclusion(path string, info fs.FileInfo) string {
+	dev, _, ok := fileID(info)
+	if !ok {
 		return ""
 	}
-	return "s"
+
+	if w.opts.oneFileSystem && path != w.root && dev != w.rootDev {
+		return "different filesystem"
+	}
+
+	if w.mounts != nil {
+		fsType := w.mounts.fsType(dev, path)
+		if fsType != "" && slices.Contains(w.opts.excludeFSTypes, fsType) {
+			return "excluded filesystem type: " + fsType
+		}
+	}
+
+	return ""
+}
+
+// addFile applies the file filters and adds a regular file to the size groups.
+// target is the resolved path if the file was reached through a symlink.
+func (w *walker) addFile(path string, info fs.FileInfo, target string) {
+	size := info.Size()
+
+	// Check if we should skip this file
+	if w.filter.ShouldExcludeFile(path, size) {
+		w.stats.Skip(model.SkipFile, path, "filter match")
+		w.stats.IncrementSkippedFiles()
+		return
+	}
+
+	if skip, reason := w.filter.ShouldExcludeByOwnership(path, info); skip {
+		w.stats.Skip(model.SkipFile, path, reason)
+		w.stats.IncrementSkippedFiles()
+		return
+	}
+
+	file := FileInfo{Path: path, Size: size, Target: target, Role: w.role, Checksum: vfs.Checksum(info), FS: w.fs}
+	file.Dev, file.Ino, _ = fileID(info)
+	if target != "" && w.opts.reportSymlinks {
+		file.Symlinks = []string{path}
+	}
+
+	if w.seen != nil {
+		ref := fileRef{size: size, index: len(w.sizeGroups[size])}
+		if w.spill != nil {
+			ref.run, ref.path = len(w.spill.runs), path
+		}
+		w.seen[keyOf(w.fs, path, info)] = ref
+	}
+	w.add(file, "", info.ModTime())
+
+	if w.opts.scanArchives && archive.IsArchive(path) {
+		w.addArchiveMembers(path, info.ModTime())
+	}
+}
+
+// addArchiveMembers adds the regular files inside an archive as read-only virtual files.
+// Members of compressed tar archives are hashed while listing them,
+// since reaching a member requires decompressing everything before it anyway.
+// modTime is the modification time of the archive.
+func (w *walker) addArchiveMembers(path string, modTime time.Time) {
+	var (
+		quick *xxh3.Hasher
+		full  hash.Hash
+		buf   []byte
+	)
+	if archive.Streamed(path) {
+		quick, full, buf = xxh3.New(), NewHasher(), make([]byte, 64*1024)
+	}
+
+	fsys := archive.NewFS(w.fs, path)
+	err := archive.Walk(w.fs, path, func(m archive.Member, r io.Reader) error {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+
+		memberPath := archive.JoinPath(path, m.Name)
+		if w.filter.ShouldExcludeFile(memberPath, m.Size) {
+			w.stats.Skip(model.SkipFile, memberPath, "filter match")
+			w.stats.IncrementSkippedFiles()
+			return nil
+		}
+
+		file := FileInfo{Path: memberPath, Size: m.Size, Role: w.role, ReadOnly: true, FS: fsys}
+		if r != nil {
+			quickHash, fullHash, err := hashStream(r, m.Size, quick, full, buf)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", memberPath, err)
+			}
+			file.QuickHash, file.QuickHashed, file.Hash = quickHash, true, fullHash
+		}
+
+		w.add(file, path, modTime)
+		return nil
+	})
+	if err != nil && w.ctx.Err() == nil {
+		w.stats.ReportError(model.StageWalk, path, err)
+	}
+}
+
+// add adds a file to the size groups. archivePath is the path of the archive the file is a member of, if any,
+// and modTime the modification time of the file, or of its archive.
+func (w *walker) add(file FileInfo, archivePath string, modTime time.Time) {
+	w.sizeGroups[file.Size] = append(w.sizeGroups[file.Size], file)
+	if w.spill != nil {
+		w.spill.used += memoryOf(file)
+	}
+	w.stats.IncrementTotalFiles()
+	w.stats.Advance(model.StageWalk, 1, file.Size)
+	if w.opts.checkpoint != nil {
+		w.opts.checkpoint.AddFile(FileRecord{File: file, Root: w.rootIndex, Archive: archivePath, ModTime: modTime})
+	}
+}
+
+// visitSymlink resolves a symlink, counting it if dangling and queueing it if it should be followed.
+func (w *walker) visitSymlink(path string) {
+	info, err := w.fs.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			w.stats.Skip(model.SkipSymlink, path, "dangling")
+			w.stats.IncrementDanglingSymlinks()
+			return
+		}
+		w.stats.ReportError(model.StageWalk, path, err)
+		return
+	}
+
+	if !w.opts.followSymlinks && !w.opts.reportSymlinks {
+		return
+	}
+	if info.IsDir() && !w.opts.followSymlinks {
+		return
+	}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return
+	}
+
+	target, err := w.fs.EvalSymlinks(path)
+	if err != nil {
+		w.stats.ReportError(model.StageWalk, path, err)
+		return
+	}
+
+	if info.IsDir() {
+		w.pendingDirs = append(w.pendingDirs, symlink{path: path, target: target, info: info, fs: w.fs,
+			rootIndex: w.rootIndex, rootDev: w.rootDev, role: w.role})
+	} else {
+		w.pendingLinks = append(w.pendingLinks, symlink{path: path, target: target, info: info, fs: w.fs,
+			rootIndex: w.rootIndex, role: w.role})
+	}
+}
+
+// followPendingDirs walks the symlinked directories found so far, including any found along the way.
+// Directories that were already visited (including symlink loops) are skipped.
+// firstUnit is the unit of the first symlinked directory, following the roots.
+func (w *walker) followPendingDirs(firstUnit int) error {
+	w.root = ""
+	defer func() { w.current = nil }()
+	for len(w.pendingDirs) > 0 {
+		link := w.pendingDirs[0]
+		w.pendingDirs = w.pendingDirs[1:]
+		w.unit = firstUnit + w.followed
+		w.followed++
+
+		// The directory the walk resumes from was recorded as visited by the interrupted walk.
+		w.fs = link.fs
+		resumed := w.resume != nil && w.unit == w.resume.Unit
+		if _, visited := w.visitedDirs[keyOf(w.fs, link.target, link.info)]; visited && !resumed {
+			w.stats.Skip(model.SkipSymlink, link.path, "already visited: "+link.target)
+			continue
+		}
+
+		w.role, w.rootIndex, w.current = link.role, link.rootIndex, &link
+		if err := w.walk(link.target, link.path, link.rootDev); err != nil {
+			return fmt.Errorf("error walking symlinked directory %s: %w", link.path, err)
+		}
+	}
+	return nil
+}
+
+// resolvePendingLinks adds symlinked files to the size groups.
+// A symlink to a file that is already part of the scan is recorded on that file instead of being added again,
+// so that a file and a link to it are never reported as duplicates of each other.
+func (w *walker) resolvePendingLinks() error {
+	for _, link := range w.pendingLinks {
+		w.fs = link.fs
+		key := keyOf(w.fs, link.target, link.info)
+		if ref, ok := w.seen[key]; ok {
+			if !w.opts.reportSymlinks {
+				continue
+			}
+			path := ref.path
+			if w.spill.flushed(ref) {
+				// The file was spilled to disk: the symlink is added when reading it back.
+				w.spill.links[ref.path] = append(w.spill.links[ref.path], link.path)
+			} else {
+				file := &w.sizeGroups[ref.size][ref.index]
+				file.Symlinks = append(file.Symlinks, link.path)
+				path = file.Path
+			}
+			if w.opts.checkpoint != nil {
+				w.opts.checkpoint.AddSymlink(path, link.path)
+			}
+			continue
+		}
+		w.role = link.role
+		w.addFile(link.path, link.info, link.target)
+		if w.spill.full() {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	w.pendingLinks = nil
+	return nil
 }
 
-// GetDirectoriesFromArgs returns the directories to scan from command arguments.
+// GetDirectoriesFromArgs returns the directories and files to scan from command arguments.
 func GetDirectoriesFromArgs(c *cli.Command) ([]string, error) {
 	return processDirectories(c.Args().Slice())
 }
 
-// processDirectories receives a list of directories, resolves absolute paths, validates them, and returns unique paths.
+// GetPathsFromArgs returns the directories and files to scan from command arguments and,
+// if filesFrom is not empty, from the file list it names ("-" reads the list from standard input).
+// When a file list is given, the current directory is only scanned if it is named explicitly.
+func GetPathsFromArgs(c *cli.Command, filesFrom string) ([]string, error) {
+	return readPaths(c.Args().Slice(), filesFrom)
+}
+
+// readPaths returns the paths to scan from args and the file list named by filesFrom, if not empty.
+func readPaths(args []string, filesFrom string) ([]string, error) {
+	if filesFrom == "" {
+		return processDirectories(args)
+	}
+
+	var r io.Reader = os.Stdin
+	if filesFrom != "-" {
+		//nolint:gosec
+		f, err := os.Open(filesFrom)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file list: %w", err)
+		}
+		defer func(f *os.File) {
+			_ = f.Close()
+		}(f)
+		r = f
+	}
+
+	fileList, err := ReadFileList(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file list %s: %w", filesFrom, err)
+	}
+
+	return processPaths(args, fileList)
+}
+
+// ReadFileList reads a list of paths, one per entry.
+// Entries are separated by NUL bytes if the input contains any (as produced by "find -print0"
+// or "git ls-files -z"), and by newlines otherwise. Empty entries are ignored.
+func ReadFileList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "\n"
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = "\x00"
+	}
+
+	var paths []string
+	for entry := range strings.SplitSeq(string(data), sep) {
+		if sep == "\n" {
+			entry = strings.TrimSuffix(entry, "\r")
+		}
+		if entry != "" {
+			paths = append(paths, entry)
+		}
+	}
+	return paths, nil
+}
+
+// processDirectories receives a list of directories and regular files, resolves absolute paths, validates them,
+// and returns unique paths. The current directory is used if the list is empty.
 // Handles subdirectory elimination and ensures consistent output by sorting the final result.
-// Returns an error if any directory is invalid or inaccessible.
+// Returns an error if any path is invalid or inaccessible.
 func processDirectories(directories []string) ([]string, error) {
 	if len(directories) == 0 {
 		absDot, err := filepath.Abs(".")
 		return []string{absDot}, err
 	}
 
-	// Use a map to track unique absolute paths for deduplication
-	uniqueDirs := make(map[string]bool, len(directories))
-	absDirs := make([]string, 0, len(directories))
+	absPaths, err := resolvePaths(directories, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return removeSubdirectories(absPaths), nil
+}
+
+// processPaths combines validated command-line paths with the entries of a file list.
+// File list entries are not validated upfront: entries that vanished or cannot be read
+// are reported as errors during the scan, like any other inaccessible file.
+func processPaths(args, fileList []string) ([]string, error) {
+	seen := make(map[string]bool, len(args)+len(fileList))
 
-	for _, dir := range directories {
+	absPaths, err := resolvePaths(args, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range fileList {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("error converting to absolute path %s: %w", path, err)
+		}
+		if !seen[absPath] {
+			seen[absPath] = true
+			absPaths = append(absPaths, absPath)
+		}
+	}
+
+	return removeSubdirectories(absPaths), nil
+}
+
+// resolvePaths makes paths absolute and checks that each one is a directory or a regular file.
+// Paths already present in seen are skipped; seen may be nil.
+func resolvePaths(paths []string, seen map[string]bool) ([]string, error) {
+	if seen == nil {
+		seen = make(map[string]bool, len(paths))
+	}
+	absPaths := make([]string, 0, len(paths))
+
+	for _, path := range paths {
 		// Make the path absolute
-		absDir, err := filepath.Abs(dir)
+		absPath, err := filepath.Abs(path)
 		if err != nil {
-			return nil, fmt.Errorf("error converting to absolute path %s: %w", dir, err)
+			return nil, fmt.Errorf("error converting to absolute path %s: %w", path, err)
 		}
 
 		// skip if we've already processed this path
-		if uniqueDirs[absDir] {
+		if seen[absPath] {
 			continue
 		}
 
-		// Check if the directory exists and is valid
-		info, err := os.Stat(absDir)
+		// Check if the path exists and is valid
+		info, err := os.Stat(absPath)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
-				return nil, fmt.Errorf("path does not exist: %s", absDir)
+				return nil, fmt.Errorf("path does not exist: %s", absPath)
 			}
-			return nil, fmt.Errorf("error accessing directory %s: %w", absDir, err)
-		} else if !info.IsDir() {
-			return nil, fmt.Errorf("not a directory: %s", absDir)
+			return nil, fmt.Errorf("error accessing path %s: %w", absPath, err)
+		} else if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("not a directory or regular file: %s", absPath)
 		}
 
-		// Add to the result only if not already present
-		if !uniqueDirs[absDir] {
-			uniqueDirs[absDir] = true
-			absDirs = append(absDirs, absDir)
-		}
+		seen[absPath] = true
+		absPaths = append(absPaths, absPath)
 	}
 
-	return removeSubdirectories(absDirs), nil
+	return absPaths, nil
 }
 
-// removeSubdirectories removes paths that are subdirectories of other paths.
+// removeSubdirectories removes paths that are subdirectories of (or files within) other paths.
 // The paths are expected to be absolute.
 func removeSubdirectories(dirs []string) []string {
 	if len(dirs) <= 1 {
@@ -40,7 +40,7 @@ func TestGroupFilesBySize(t *testing.T) {
 	ctx := context.Background()
 
 	// Test a directory tree without any files
-	sizeGroups, err := GroupFilesBySize(ctx, []string{tempDir}, &filter.Config{}, &model.Stats{}, false)
+	sizeGroups, err := GroupFilesBySize(ctx, []string{tempDir}, &filter.Config{}, &model.Stats{})
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -75,7 +75,7 @@ func TestGroupFilesBySize(t *testing.T) {
 	s := &model.Stats{}
 
 	// Test GroupFilesBySize
-	sizeGroups, err = GroupFilesBySize(ctx, []string{tempDir}, filterConfig, s, false)
+	sizeGroups, err = GroupFilesBySize(ctx, []string{tempDir}, filterConfig, s)
 	if err != nil {
 		t.Fatalf("GroupFilesBySize() error = %v", err)
 	}
@@ -125,7 +125,7 @@ func TestGroupFilesBySize(t *testing.T) {
 
 	// All files skipped due to size
 	filterConfig2 := &filter.Config{MinSize: 1000}
-	sizeGroups, err = GroupFilesBySize(ctx, []string{tempDir}, filterConfig2, &model.Stats{}, false)
+	sizeGroups, err = GroupFilesBySize(ctx, []string{tempDir}, filterConfig2, &model.Stats{})
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -160,19 +160,43 @@ func TestProcessDirectories_NonExistentDir(t *testing.T) {
 	}
 }
 
-// TestProcessDirectories_NotADirectory tests processDirectories to ensure it returns an error when a file is passed instead of a directory.
-func TestProcessDirectories_NotADirectory(t *testing.T) {
-	tmpFile, err := os.CreateTemp("", "notadir")
+// TestProcessDirectories_RegularFile verifies that processDirectories accepts regular files,
+// and drops files that are inside a directory that is also being scanned.
+func TestProcessDirectories_RegularFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "file.txt")
+	if err := os.WriteFile(file, []byte("content"), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	paths, err := processDirectories([]string{file})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Equal(paths, []string{file}) {
+		t.Errorf("Expected [%s], got %v", file, paths)
+	}
+
+	paths, err = processDirectories([]string{file, tmpDir})
 	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Equal(paths, []string{tmpDir}) {
+		t.Errorf("Expected [%s], got %v", tmpDir, paths)
+	}
+}
+
+// TestProcessDirectories_NotARegularFile tests processDirectories to ensure it returns an error
+// when a path is neither a directory nor a regular file.
+func TestProcessDirectories_NotARegularFile(t *testing.T) {
+	info, err := os.Stat(os.DevNull)
+	if err != nil || info.Mode().IsRegular() {
+		t.Skipf("%s is not available as a special file", os.DevNull)
 	}
-	defer func(name string) {
-		_ = os.Remove(name)
-	}(tmpFile.Name())
 
-	_, err = processDirectories([]string{tmpFile.Name()})
-	if err == nil || !strings.Contains(err.Error(), "not a directory") {
-		t.Errorf("Expected error for file input, got: %v", err)
+	_, err = processDirectories([]string{os.DevNull})
+	if err == nil || !strings.Contains(err.Error(), "not a directory or regular file") {
+		t.Errorf("Expected error for special file input, got: %v", err)
 	}
 }
 
@@ -639,3 +663,290 @@ func TestIsSubdirectory_CommonPrefix(t *testing.T) {
 		}
 	}
 }
+
+// TestGroupFilesBySize_Symlinks verifies symlink handling: dangling symlinks are counted,
+// symlinks are ignored by default, and followed with loop detection when requested.
+func TestGroupFilesBySize_Symlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	scanDir := filepath.Join(tempDir, "scan")
+	outside := filepath.Join(tempDir, "outside")
+	for _, dir := range []string{filepath.Join(scanDir, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+	}
+
+	files := map[string]int{
+		filepath.Join(scanDir, "a.txt"):        100,
+		filepath.Join(scanDir, "sub", "b.txt"): 200,
+		filepath.Join(outside, "c.txt"):        300,
+	}
+	for path, size := range files {
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	links := map[string]string{
+		filepath.Join(scanDir, "link-to-a"):       filepath.Join(scanDir, "a.txt"),
+		filepath.Join(scanDir, "link-to-outside"): outside,
+		filepath.Join(scanDir, "sub", "loop"):     scanDir,
+		filepath.Join(scanDir, "dangling"):        filepath.Join(tempDir, "missing"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+
+	t.Run("default", func(t *testing.T) {
+		s := &model.Stats{}
+		sizeGroups, err := GroupFilesBySize(ctx, []string{scanDir}, &filter.Config{}, s)
+		if err != nil {
+			t.Fatalf("GroupFilesBySize() error = %v", err)
+		}
+		if s.TotalFiles != 2 {
+			t.Errorf("Stats.TotalFiles = %d, want 2", s.TotalFiles)
+		}
+		if s.DanglingSymlinks != 1 {
+			t.Errorf("Stats.DanglingSymlinks = %d, want 1", s.DanglingSymlinks)
+		}
+		if _, ok := sizeGroups[300]; ok {
+			t.Errorf("Symlinked directory was followed without --follow-symlinks")
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		s := &model.Stats{}
+		sizeGroups, err := GroupFilesBySize(ctx, []string{scanDir}, &filter.Config{}, s,
+			WithFollowSymlinks(true))
+		if err != nil {
+			t.Fatalf("GroupFilesBySize() error = %v", err)
+		}
+		// a.txt, sub/b.txt and link-to-outside/c.txt; link-to-a is the same file as a.txt and the loop is skipped.
+		if s.TotalFiles != 3 {
+			t.Errorf("Stats.TotalFiles = %d, want 3", s.TotalFiles)
+		}
+		if got := len(sizeGroups[100]); got != 1 {
+			t.Errorf("Size group 100 has %d files, want 1 (a file and its symlink are the same file)", got)
+		}
+		want := filepath.Join(scanDir, "link-to-outside", "c.txt")
+		if files := sizeGroups[300]; len(files) != 1 || files[0].Path != want {
+			t.Errorf("Size group 300 = %v, want a single file at %s", files, want)
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		sizeGroups, err := GroupFilesBySize(ctx, []string{scanDir}, &filter.Config{}, &model.Stats{},
+			WithReportSymlinks(true))
+		if err != nil {
+			t.Fatalf("GroupFilesBySize() error = %v", err)
+		}
+		files := sizeGroups[100]
+		if len(files) != 1 {
+			t.Fatalf("Size group 100 has %d files, want 1", len(files))
+		}
+		if !slices.Equal(files[0].Symlinks, []string{filepath.Join(scanDir, "link-to-a")}) {
+			t.Errorf("FileInfo.Symlinks = %v, want [%s]", files[0].Symlinks, filepath.Join(scanDir, "link-to-a"))
+		}
+	})
+}
+
+// TestReadFileList verifies that file lists are split on NUL bytes when present, and on newlines otherwise.
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "empty", input: "", want: nil},
+		{name: "newlines", input: "a.txt\nb c.txt\n\nd.txt", want: []string{"a.txt", "b c.txt", "d.txt"}},
+		{name: "CRLF", input: "a.txt\r\nb.txt\r\n", want: []string{"a.txt", "b.txt"}},
+		{name: "NUL", input: "a.txt\x00b\nc.txt\x00", want: []string{"a.txt", "b\nc.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFileList(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadFileList() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ReadFileList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProcessPaths verifies that file list entries are combined with arguments without being validated upfront.
+func TestProcessPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	sub := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	missing := filepath.Join(tmpDir, "missing.txt")
+
+	paths, err := processPaths([]string{sub}, []string{missing, filepath.Join(sub, "a.txt"), missing})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Equal(paths, []string{missing, sub}) {
+		t.Errorf("Expected [%s %s], got %v", missing, sub, paths)
+	}
+
+	// With only a file list, the current directory is not scanned.
+	paths, err = processPaths(nil, []string{missing})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Equal(paths, []string{missing}) {
+		t.Errorf("Expected [%s], got %v", missing, paths)
+	}
+}
+
+// TestGroupFilesBySize_FileRoots verifies that individual files can be scanned alongside directories,
+// and that missing files are counted as errors instead of aborting the scan.
+func TestGroupFilesBySize_FileRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, make([]byte, 100), 0o644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	s := &model.Stats{}
+	roots := []string{a, b, filepath.Join(tmpDir, "missing.txt")}
+	sizeGroups, err := GroupFilesBySize(context.Background(), roots, &filter.Config{}, s)
+	if err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+	if got := len(sizeGroups[100]); got != 2 {
+		t.Errorf("Size group 100 has %d files, want 2", got)
+	}
+	if s.ErrorCount != 1 {
+		t.Errorf("Stats.ErrorCount = %d, want 1", s.ErrorCount)
+	}
+}
+
+// walkProgress records the progress of the walk stage.
+type walkProgress struct {
+	events       []string
+	files, bytes int64
+}
+
+func (p *walkProgress) StartStage(stage string, files, bytes int64) {
+	p.events = append(p.events, "start "+stage)
+	if files != 0 || bytes != 0 {
+		p.events = append(p.events, "known totals")
+	}
+}
+
+func (p *walkProgress) Advance(stage string, files, bytes int64) {
+	if stage == model.StageWalk {
+		p.files += files
+		p.bytes += bytes
+	}
+}
+
+func (p *walkProgress) EndStage(stage string) {
+	p.events = append(p.events, "end "+stage)
+}
+
+// TestGroupFilesBySize_Progress verifies that the walk reports every file added, with its size.
+func TestGroupFilesBySize_Progress(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"a": 10, "b": 20, "skip.log": 40} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	filterConfig, err := filter.BuildConfig("", "*.log", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("BuildConfig() error = %v", err)
+	}
+
+	p := &walkProgress{}
+	if _, err := GroupFilesBySize(context.Background(), []string{dir}, filterConfig, &model.Stats{Progress: p}); err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+
+	if want := []string{"start walk", "end walk"}; !slices.Equal(p.events, want) {
+		t.Errorf("progress events = %v, want %v", p.events, want)
+	}
+	if p.files != 2 || p.bytes != 30 {
+		t.Errorf("walk advanced by %d files and %d bytes, want 2 and 30", p.files, p.bytes)
+	}
+}
+
+// walkEvents records the events of a walk.
+type walkEvents struct {
+	events []string
+}
+
+func (e *walkEvents) StageStarted(stage string, _, _ int64) {
+	e.events = append(e.events, "start "+stage)
+}
+
+func (e *walkEvents) StageFinished(stage string) {
+	e.events = append(e.events, "end "+stage)
+}
+
+func (e *walkEvents) FileSkipped(kind, path, reason string) {
+	e.events = append(e.events, "skip "+kind+" "+filepath.Base(path)+": "+reason)
+}
+
+func (e *walkEvents) Error(stage, path string, _ error) {
+	e.events = append(e.events, "error "+stage+" "+filepath.Base(path))
+}
+
+func (e *walkEvents) GroupFound(*model.DuplicateGroup) {
+	e.events = append(e.events, "group")
+}
+
+// TestGroupFilesBySize_Events verifies that the entries skipped and the errors met are reported as events.
+func TestGroupFilesBySize_Events(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "skip.log", "node_modules/b"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "dangling")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	filterConfig, err := filter.BuildConfig("node_modules", "*.log", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("BuildConfig() error = %v", err)
+	}
+
+	e := &walkEvents{}
+	s := &model.Stats{Events: e}
+	roots := []string{dir, filepath.Join(dir, "gone")}
+	if _, err := GroupFilesBySize(context.Background(), roots, filterConfig, s); err != nil {
+		t.Fatalf("GroupFilesBySize() error = %v", err)
+	}
+
+	want := []string{
+		"start walk",
+		"skip symlink dangling: dangling",
+		"skip directory node_modules: filter match",
+		"skip file skip.log: filter match",
+		"error walk gone",
+		"end walk",
+	}
+	if !slices.Equal(e.events, want) {
+		t.Errorf("events = %v, want %v", e.events, want)
+	}
+	if s.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1", s.ErrorCount)
+	}
+}
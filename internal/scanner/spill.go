@@ -0,0 +1,449 @@
+package scanner
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// fileOverhead is the estimated memory taken by a [FileInfo] in the size groups, besides its strings.
+const fileOverhead = 192
+
+// SizeIndex holds the files found by a scan, grouped by size. With [WithSpill], the size groups are
+// written to sorted run files on disk whenever they take too much memory, and read back one size at a time.
+//
+// An index must be closed to remove its run files.
+type SizeIndex struct {
+	// groups holds the size groups when nothing was spilled.
+	groups map[int64][]FileInfo
+
+	// spill holds the run files, or nil when nothing was spilled.
+	spill *spill
+
+	err error
+}
+
+// IndexRootsBySize scans roots like [GroupRootsBySize], into a [SizeIndex].
+func IndexRootsBySize(ctx context.Context,
+	roots []Root, filterConfig *filter.Config, stats *model.Stats, opts ...Option) (*SizeIndex, error,
+) {
+	w, err := scanRoots(ctx, roots, filterConfig, stats, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if w.spill != nil && len(w.spill.runs) > 0 {
+		return &SizeIndex{spill: w.spill}, nil
+	}
+	return &SizeIndex{groups: w.sizeGroups}, nil
+}
+
+// Runs returns the number of run files the size groups were spilled to.
+func (x *SizeIndex) Runs() int {
+	if x.spill == nil {
+		return 0
+	}
+	return len(x.spill.runs)
+}
+
+// Buckets returns an iterator over the size groups, in increasing order of size.
+// Within a group, files are in the order they were found.
+// Reading the run files stops at the first error, returned by [SizeIndex.Err].
+func (x *SizeIndex) Buckets() iter.Seq2[int64, []FileInfo] {
+	return func(yield func(int64, []FileInfo) bool) {
+		if x.spill == nil {
+			for _, size := range slices.Sorted(maps.Keys(x.groups)) {
+				if !yield(size, x.groups[size]) {
+					return
+				}
+			}
+			return
+		}
+		x.err = x.spill.merge(yield)
+	}
+}
+
+// Batches returns an iterator over batches of consecutive size groups, each taking about memoryLimit bytes
+// at most, so that the files of a single batch are held in memory at a time.
+// Groups larger than memoryLimit make batches of their own, and groups of fewer than minFiles files are skipped.
+func (x *SizeIndex) Batches(memoryLimit int64, minFiles int) iter.Seq[map[int64][]FileInfo] {
+	return func(yield func(map[int64][]FileInfo) bool) {
+		batch := make(map[int64][]FileInfo)
+		var used int64
+		for size, files := range x.Buckets() {
+			if len(files) < minFiles {
+				continue
+			}
+			var n int64
+			for _, file := range files {
+				n += memoryOf(file)
+			}
+			if len(batch) > 0 && used+n > memoryLimit {
+				if !yield(batch) {
+					return
+				}
+				batch, used = make(map[int64][]FileInfo), 0
+			}
+			batch[size] = files
+			used += n
+		}
+		if len(batch) > 0 {
+			yield(batch)
+		}
+	}
+}
+
+// Err returns the error that stopped the last iteration over the size groups, if any.
+func (x *SizeIndex) Err() error {
+	return x.err
+}
+
+// Close removes the run files of the index.
+func (x *SizeIndex) Close() error {
+	if x.spill == nil {
+		return nil
+	}
+	return x.spill.remove()
+}
+
+// memoryOf estimates the memory taken by a file in the size groups.
+func memoryOf(file FileInfo) int64 {
+	n := fileOverhead + len(file.Path) + len(file.Target) + len(file.Hash) + len(file.Checksum)
+	for _, link := range file.Symlinks {
+		n += 16 + len(link)
+	}
+	return int64(n)
+}
+
+// spill writes size groups to run files sorted by size, interning the directories of paths and the filesystems
+// of files in tables kept in memory.
+type spill struct {
+	// dir is the directory the temporary directory holding the runs is created in, or empty for the default.
+	dir string
+
+	// tmp is the temporary directory holding the runs, created along with the first run.
+	tmp string
+
+	// limit is the memory the size groups may take before they are spilled, and used the memory they take.
+	limit, used int64
+
+	// runs holds the paths of the run files.
+	runs []string
+
+	prefixes   []string
+	prefixIDs  map[string]uint64
+	filesystem []vfs.FS
+	fsIDs      map[vfs.FS]uint64
+
+	// links holds the symlinks found to files already spilled, by path of the files.
+	links map[string][]string
+}
+
+func newSpill(dir string, limit int64) *spill {
+	return &spill{
+		dir:       dir,
+		limit:     limit,
+		prefixIDs: make(map[string]uint64),
+		fsIDs:     make(map[vfs.FS]uint64),
+		links:     make(map[string][]string),
+	}
+}
+
+// full reports whether the size groups take more memory than allowed.
+func (s *spill) full() bool {
+	return s != nil && s.used > s.limit
+}
+
+// flushed reports whether the run a file was added to has been written.
+func (s *spill) flushed(ref fileRef) bool {
+	return s != nil && ref.run < len(s.runs)
+}
+
+// flush writes the size groups to a new run file and empties them.
+func (w *walker) flush() error {
+	if err := w.spill.write(w.sizeGroups); err != nil {
+		return fmt.Errorf("error spilling size groups: %w", err)
+	}
+	w.sizeGroups = make(map[int64][]FileInfo)
+	w.spill.used = 0
+	return nil
+}
+
+// write writes size groups to a new run file, in increasing order of size.
+func (s *spill) write(sizeGroups map[int64][]FileInfo) (err error) {
+	if s.tmp == "" {
+		tmp, err := os.MkdirTemp(s.dir, "doppel-spill-")
+		if err != nil {
+			return err
+		}
+		s.tmp = tmp
+	}
+
+	path := filepath.Join(s.tmp, fmt.Sprintf("run-%06d", len(s.runs)))
+	//nolint:gosec
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	bw := bufio.NewWriterSize(f, 256*1024)
+	var buf []byte
+	for _, size := range slices.Sorted(maps.Keys(sizeGroups)) {
+		for _, file := range sizeGroups[size] {
+			buf = s.appendRecord(buf[:0], file)
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	s.runs = append(s.runs, path)
+	return nil
+}
+
+// appendRecord appends the record of a file to buf. Paths are stored as the ID of their directory,
+// including the trailing separator, and the rest of the path.
+func (s *spill) appendRecord(buf []byte, file FileInfo) []byte {
+	prefix, name := splitPrefix(file.Path)
+	prefixID, ok := s.prefixIDs[prefix]
+	if !ok {
+		prefixID = uint64(len(s.prefixes))
+		s.prefixes = append(s.prefixes, prefix)
+		s.prefixIDs[prefix] = prefixID
+	}
+	fsID, ok := s.fsIDs[file.FS]
+	if !ok {
+		fsID = uint64(len(s.filesystem))
+		s.filesystem = append(s.filesystem, file.FS)
+		s.fsIDs[file.FS] = fsID
+	}
+
+	var flags uint64
+	if file.ReadOnly {
+		flags |= 1
+	}
+	if file.QuickHashed {
+		flags |= 2
+	}
+
+	//nolint:gosec
+	buf = binary.AppendUvarint(buf, uint64(file.Size))
+	buf = binary.AppendUvarint(buf, prefixID)
+	buf = appendString(buf, name)
+	buf = appendString(buf, file.Target)
+	buf = binary.AppendUvarint(buf, uint64(file.Role))
+	buf = binary.AppendUvarint(buf, flags)
+	buf = binary.AppendUvarint(buf, file.QuickHash)
+	buf = appendString(buf, file.Hash)
+	buf = appendString(buf, file.Checksum)
+	buf = binary.AppendUvarint(buf, file.Dev)
+	buf = binary.AppendUvarint(buf, file.Ino)
+	buf = binary.AppendUvarint(buf, fsID)
+	buf = binary.AppendUvarint(buf, uint64(len(file.Symlinks)))
+	for _, link := range file.Symlinks {
+		buf = appendString(buf, link)
+	}
+	return buf
+}
+
+// splitPrefix splits a path after its last separator.
+func splitPrefix(path string) (prefix, name string) {
+	i := strings.LastIndexAny(path, "/"+string(filepath.Separator))
+	return path[:i+1], path[i+1:]
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// merge reads the run files back, merging them by size, and yields the files of each size in turn.
+// Files of the same size are yielded in the order of the runs, so in the order they were found.
+func (s *spill) merge(yield func(int64, []FileInfo) bool) (err error) {
+	readers := make(runHeap, 0, len(s.runs))
+	defer func() {
+		for _, r := range readers {
+			_ = r.f.Close()
+		}
+	}()
+	for i, path := range s.runs {
+		//nolint:gosec
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r := &runReader{run: i, f: f, r: bufio.NewReaderSize(f, 64*1024)}
+		readers = append(readers, r)
+		if err := s.next(r); err != nil {
+			return err
+		}
+	}
+
+	h := slices.DeleteFunc(slices.Clone(readers), func(r *runReader) bool { return r.done })
+	heap.Init(&h)
+
+	var bucket []FileInfo
+	for h.Len() > 0 {
+		r := h[0]
+		if len(bucket) > 0 && r.file.Size != bucket[0].Size {
+			if !yield(bucket[0].Size, bucket) {
+				return nil
+			}
+			bucket = nil
+		}
+		bucket = append(bucket, r.file)
+
+		if err := s.next(r); err != nil {
+			return err
+		}
+		if r.done {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	if len(bucket) > 0 {
+		yield(bucket[0].Size, bucket)
+	}
+	return nil
+}
+
+// runReader reads the records of a run file.
+type runReader struct {
+	run  int
+	f    *os.File
+	r    *bufio.Reader
+	file FileInfo
+	done bool
+}
+
+// next reads the next record of a run into r.file, or sets r.done at the end of the run.
+func (s *spill) next(r *runReader) error {
+	size, err := binary.ReadUvarint(r.r)
+	if errors.Is(err, io.EOF) {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		return s.corrupt(r, err)
+	}
+
+	d := decoder{r: r.r}
+	prefixID := d.uvarint()
+	name := d.string()
+	//nolint:gosec
+	file := FileInfo{Size: int64(size), Target: d.string(), Role: Role(d.uvarint())}
+	flags := d.uvarint()
+	file.ReadOnly, file.QuickHashed = flags&1 != 0, flags&2 != 0
+	file.QuickHash = d.uvarint()
+	file.Hash, file.Checksum = d.string(), d.string()
+	file.Dev, file.Ino = d.uvarint(), d.uvarint()
+	fsID := d.uvarint()
+	if n := d.uvarint(); n > 0 && d.err == nil {
+		file.Symlinks = make([]string, 0, min(n, 1024))
+		for range n {
+			file.Symlinks = append(file.Symlinks, d.string())
+		}
+	}
+	if d.err != nil {
+		return s.corrupt(r, d.err)
+	}
+	if prefixID >= uint64(len(s.prefixes)) || fsID >= uint64(len(s.filesystem)) {
+		return s.corrupt(r, errors.New("unknown path prefix or filesystem"))
+	}
+
+	file.Path = s.prefixes[prefixID] + name
+	file.FS = s.filesystem[fsID]
+	file.Symlinks = append(file.Symlinks, s.links[file.Path]...)
+	r.file = file
+	return nil
+}
+
+func (s *spill) corrupt(r *runReader, err error) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("error reading spilled size groups from %s: %w", s.runs[r.run], err)
+}
+
+// remove removes the run files.
+func (s *spill) remove() error {
+	if s.tmp == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.tmp)
+	s.tmp, s.runs = "", nil
+	return err
+}
+
+// decoder reads the fields of a record, keeping the first error.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil || n == 0 {
+		return ""
+	}
+	if n > 1<<20 {
+		d.err = fmt.Errorf("string of %d bytes", n)
+		return ""
+	}
+	buf := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, buf)
+	return string(buf)
+}
+
+// runHeap orders run readers by the size of their next file, then by run.
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+
+func (h runHeap) Less(i, j int) bool {
+	if h[i].file.Size != h[j].file.Size {
+		return h[i].file.Size < h[j].file.Size
+	}
+	return h[i].run < h[j].run
+}
+
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *runHeap) Push(x any) { *h = append(*h, x.(*runReader)) }
+
+func (h *runHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// TestIndexRootsBySize_Spill verifies that size groups spilled to run files are read back in increasing
+// order of size, as found by a scan held in memory, including the symlinks found after the files were spilled,
+// and that closing the index removes the run files.
+func TestIndexRootsBySize_Spill(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 20 {
+		sub := filepath.Join(dir, fmt.Sprintf("dir%d", i%3))
+		if err := os.MkdirAll(sub, 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		path := filepath.Join(sub, fmt.Sprintf("file%02d", i))
+		if err := os.WriteFile(path, make([]byte, 10*(i%4)+1), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "dir0", "file00"), filepath.Join(dir, "link")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	roots := rootsOf([]string{dir}, RoleScan)
+	want, err := GroupRootsBySize(t.Context(), roots, &filter.Config{}, &model.Stats{}, WithReportSymlinks(true))
+	if err != nil {
+		t.Fatalf("GroupRootsBySize() error = %v", err)
+	}
+
+	spillDir := t.TempDir()
+	index, err := IndexRootsBySize(t.Context(), roots, &filter.Config{}, &model.Stats{},
+		WithReportSymlinks(true), WithSpill(spillDir, 1))
+	if err != nil {
+		t.Fatalf("IndexRootsBySize() error = %v", err)
+	}
+	if index.Runs() != 20 {
+		t.Errorf("Runs() = %d, want a run per file", index.Runs())
+	}
+
+	var sizes []int64
+	got := make(map[int64][]FileInfo)
+	for size, files := range index.Buckets() {
+		sizes = append(sizes, size)
+		got[size] = files
+	}
+	if err := index.Err(); err != nil {
+		t.Fatalf("Buckets() error = %v", err)
+	}
+	if wantSizes := slices.Sorted(maps.Keys(want)); !slices.Equal(sizes, wantSizes) {
+		t.Errorf("Buckets() sizes = %v, want %v", sizes, wantSizes)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Buckets() = %v, want %v", got, want)
+	}
+	if links := got[1][0].Symlinks; !slices.Equal(links, []string{filepath.Join(dir, "link")}) {
+		t.Errorf("Symlinks of a spilled file = %v, want the link found after spilling it", links)
+	}
+
+	if err := index.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(spillDir); len(entries) != 0 {
+		t.Errorf("Close() left %d entries in the spill directory", len(entries))
+	}
+}
+
+// TestSizeIndex_Batches verifies that batches hold consecutive size groups within the memory limit,
+// skipping the groups with too few files.
+func TestSizeIndex_Batches(t *testing.T) {
+	file := func(size int64) FileInfo { return FileInfo{Path: "/f", Size: size} }
+	index := &SizeIndex{groups: map[int64][]FileInfo{
+		1: {file(1), file(1)},
+		2: {file(2)},
+		3: {file(3), file(3)},
+		4: {file(4), file(4), file(4), file(4), file(4)},
+		5: {file(5), file(5)},
+	}}
+	limit := 4 * memoryOf(file(1))
+
+	var got [][]int64
+	for batch := range index.Batches(limit, 2) {
+		got = append(got, slices.Sorted(maps.Keys(batch)))
+	}
+	want := [][]int64{{1, 3}, {4}, {5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Batches() = %v, want %v", got, want)
+	}
+}
+
+func TestSplitPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix, name string
+	}{
+		{"/a/b/c.txt", "/a/b/", "c.txt"},
+		{"c.txt", "", "c.txt"},
+		{"/a/b.zip!/inner/c.txt", "/a/b.zip!/inner/", "c.txt"},
+	}
+	for _, tt := range tests {
+		prefix, name := splitPrefix(tt.path)
+		if prefix != tt.prefix || name != tt.name {
+			t.Errorf("splitPrefix(%q) = %q, %q, want %q, %q", tt.path, prefix, name, tt.prefix, tt.name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// State is the state of a job.
+type State string
+
+const (
+	// StateQueued marks a job waiting for a free worker.
+	StateQueued State = "queued"
+
+	// StateRunning marks a job being scanned.
+	StateRunning State = "running"
+
+	// StateDone marks a job whose report is ready.
+	StateDone State = "done"
+
+	// StateFailed marks a job that stopped with an error, or was interrupted by the server stopping.
+	StateFailed State = "failed"
+
+	// StateCanceled marks a job canceled by a client.
+	StateCanceled State = "canceled"
+)
+
+// Finished reports whether a job in this state will not change anymore.
+func (s State) Finished() bool {
+	return s == StateDone || s == StateFailed || s == StateCanceled
+}
+
+// Request is the body of a request to start a scan: the paths to scan, along with the options of the find command.
+// Options left out take their values from the find configuration of the server.
+type Request struct {
+	// Paths are the directories, files, or URLs to scan.
+	Paths []string `json:"paths"`
+
+	config.FindConfig
+}
+
+// Job is a scan started through the API.
+type Job struct {
+	// ID identifies the job.
+	ID string `json:"id"`
+
+	// State is the state of the job.
+	State State `json:"state"`
+
+	// Request is the request that started the job.
+	Request Request `json:"request"`
+
+	// CreatedAt is when the job was queued.
+	CreatedAt time.Time `json:"created_at"`
+
+	// StartedAt is when the scan started.
+	StartedAt time.Time `json:"started_at,omitzero"`
+
+	// FinishedAt is when the scan finished.
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+
+	// Error is the reason the job failed.
+	Error string `json:"error,omitempty"`
+
+	// Progress holds the statistics of the scan: live while the job runs, and final once it is done.
+	Progress *model.Stats `json:"progress,omitempty"`
+}
+
+// job is a job tracked by the server, with the state of its scan.
+type job struct {
+	Job
+
+	// stats are updated by the scan while the job runs.
+	stats *model.Stats
+
+	// cancel cancels the scan, and canceled records that a client asked for it.
+	cancel   context.CancelFunc
+	canceled bool
+
+	// report is the report of a finished job, when reports are not persisted.
+	report *model.DuplicateReport
+}
+
+// snapshot returns the public view of the job, with its live progress if it is running.
+func (j *job) snapshot() Job {
+	v := j.Job
+	if j.State == StateRunning && j.stats != nil {
+		progress := j.stats.Snapshot()
+		progress.Duration = time.Since(j.StartedAt)
+		v.Progress = &progress
+	}
+	return v
+}
+
+// newID returns a random job ID.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,548 @@
+// Package server exposes duplicate scans over an HTTP API.
+//
+// Clients start scans by posting a [Request], poll the progress of the resulting [Job],
+// cancel it, and fetch its report in any format of an [output.FormatterRegistry].
+// Jobs wait in a bounded queue for one of a fixed number of workers,
+// and the jobs and their reports can be persisted in a directory so that the history survives restarts.
+//
+// The API has the following endpoints:
+//   - POST /jobs: start a scan
+//   - GET /jobs: list the jobs, newest first
+//   - GET /jobs/{id}: get a job and its progress
+//   - POST /jobs/{id}/cancel: cancel a queued or running job
+//   - DELETE /jobs/{id}: remove a finished job from the history
+//   - GET /jobs/{id}/report?format=json: get the report of a finished job
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/output"
+)
+
+// maxRequestSize is the maximum size of a request body.
+const maxRequestSize = 1 << 20
+
+// Runner runs the scan of a job, updating stats as it goes, and returns its report.
+// It should return promptly once ctx is canceled.
+type Runner func(ctx context.Context, req Request, stats *model.Stats) (*model.DuplicateReport, error)
+
+// Option configures a [Server].
+type Option func(*options)
+
+// options holds the settings of a server.
+type options struct {
+	concurrency int
+	queueSize   int
+	dataDir     string
+	maxHistory  int
+	defaults    config.FindConfig
+}
+
+// WithConcurrency sets the number of jobs that run at the same time. The default is 1.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithQueueSize sets the number of jobs that can wait for a worker. The default is 16.
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithDataDir sets the directory where jobs and their reports are persisted.
+// By default, the history is kept in memory only.
+func WithDataDir(dir string) Option {
+	return func(o *options) {
+		o.dataDir = dir
+	}
+}
+
+// WithMaxHistory sets the number of finished jobs that are kept, dropping the oldest ones.
+// Zero keeps every job. The default is 100.
+func WithMaxHistory(n int) Option {
+	return func(o *options) {
+		o.maxHistory = n
+	}
+}
+
+// WithDefaults sets the options of the find command used for the fields that requests leave out.
+func WithDefaults(cfg config.FindConfig) Option {
+	return func(o *options) {
+		o.defaults = cfg
+	}
+}
+
+// Server runs scans requested over HTTP.
+type Server struct {
+	run        Runner
+	formatters *output.FormatterRegistry
+	store      *store
+	opts       options
+	mux        *http.ServeMux
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+}
+
+// New returns a server running scans with run, and formatting reports with formatters.
+// If a data directory is set, the jobs persisted there are loaded: queued jobs are queued again,
+// and jobs that were running are marked as failed.
+func New(run Runner, formatters *output.FormatterRegistry, opts ...Option) (*Server, error) {
+	o := options{concurrency: 1, queueSize: 16, maxHistory: 100}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		return nil, fmt.Errorf("invalid number of concurrent jobs: %d", o.concurrency)
+	}
+	if o.queueSize < 1 {
+		return nil, fmt.Errorf("invalid queue size: %d", o.queueSize)
+	}
+
+	s := &Server{
+		run:        run,
+		formatters: formatters,
+		opts:       o,
+		jobs:       make(map[string]*job),
+		queue:      make(chan *job, o.queueSize),
+	}
+
+	if o.dataDir != "" {
+		st, err := newStore(o.dataDir)
+		if err != nil {
+			return nil, err
+		}
+		s.store = st
+		if err := s.loadHistory(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /jobs", s.handleCreate)
+	s.mux.HandleFunc("GET /jobs", s.handleList)
+	s.mux.HandleFunc("GET /jobs/{id}", s.handleGet)
+	s.mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancel)
+	s.mux.HandleFunc("DELETE /jobs/{id}", s.handleDelete)
+	s.mux.HandleFunc("GET /jobs/{id}/report", s.handleReport)
+	return s, nil
+}
+
+// ServeHTTP handles API requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve accepts API requests on ln and runs the queued jobs until ctx is canceled.
+// Running jobs are then canceled, and marked as failed.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		s.Process(ctx)
+	})
+	defer wg.Wait()
+
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// Process runs the queued jobs with the configured number of workers until ctx is canceled.
+func (s *Server) Process(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range s.opts.concurrency {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-s.queue:
+					s.runJob(ctx, j)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// runJob runs the scan of a queued job.
+func (s *Server) runJob(ctx context.Context, j *job) {
+	s.mu.Lock()
+	if j.State != StateQueued {
+		// Canceled while waiting.
+		s.mu.Unlock()
+		return
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	j.cancel = cancel
+	j.stats = &model.Stats{StartTime: time.Now(), Errors: &model.ErrorLog{}}
+	j.State, j.StartedAt = StateRunning, j.stats.StartTime
+	req := j.Request
+	s.persist(ctx, j)
+	s.mu.Unlock()
+
+	logger.InfoAttrs(ctx, "job started", slog.String("id", j.ID), slog.Any("paths", req.Paths))
+	report, err := s.run(jobCtx, req, j.stats)
+	if err == nil && jobCtx.Err() == nil && s.store != nil {
+		if err = s.store.saveReport(j.ID, report); err != nil {
+			err = fmt.Errorf("error saving the report: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.FinishedAt = time.Now()
+	progress := *j.stats
+	if progress.Duration == 0 {
+		progress.Duration = j.FinishedAt.Sub(j.StartedAt)
+	}
+	j.Progress = &progress
+
+	switch {
+	case j.canceled:
+		j.State = StateCanceled
+	case jobCtx.Err() != nil:
+		j.State, j.Error = StateFailed, "interrupted by the server stopping"
+	case err != nil:
+		j.State, j.Error = StateFailed, err.Error()
+	default:
+		j.State = StateDone
+		if s.store == nil {
+			j.report = report
+		}
+	}
+	j.stats, j.cancel = nil, nil
+	s.persist(ctx, j)
+	s.prune(ctx)
+
+	attrs := []slog.Attr{slog.String("id", j.ID), slog.String("state", string(j.State))}
+	if j.Error != "" {
+		attrs = append(attrs, slog.String("err", j.Error))
+	}
+	logger.InfoAttrs(ctx, "job finished", attrs...)
+}
+
+// loadHistory loads the persisted jobs.
+func (s *Server) loadHistory() error {
+	jobs, err := s.store.loadJobs()
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	ctx := context.Background()
+	for _, v := range jobs {
+		j := &job{Job: v}
+		s.jobs[j.ID] = j
+		switch j.State {
+		case StateQueued:
+			select {
+			case s.queue <- j:
+				continue
+			default:
+				j.State, j.Error = StateFailed, "the queue was full when the server restarted"
+			}
+		case StateRunning:
+			j.State, j.Error = StateFailed, "interrupted by the server stopping"
+		default:
+			continue
+		}
+		j.FinishedAt = time.Now()
+		s.persist(ctx, j)
+	}
+	s.prune(ctx)
+	return nil
+}
+
+// persist saves a job, if jobs are persisted. The caller holds s.mu.
+func (s *Server) persist(ctx context.Context, j *job) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.saveJob(j.Job); err != nil {
+		logger.WarnAttrs(ctx, "failed to save a job", slog.String("id", j.ID), slog.String("err", err.Error()))
+	}
+}
+
+// prune drops the oldest finished jobs beyond the maximum history. The caller holds s.mu.
+func (s *Server) prune(ctx context.Context) {
+	if s.opts.maxHistory <= 0 {
+		return
+	}
+	var finished []*job
+	for _, j := range s.jobs {
+		if j.State.Finished() {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= s.opts.maxHistory {
+		return
+	}
+
+	slices.SortFunc(finished, func(a, b *job) int {
+		return cmp.Or(a.FinishedAt.Compare(b.FinishedAt), strings.Compare(a.ID, b.ID))
+	})
+	for _, j := range finished[:len(finished)-s.opts.maxHistory] {
+		s.remove(ctx, j)
+	}
+}
+
+// remove drops a job from the history. The caller holds s.mu.
+func (s *Server) remove(ctx context.Context, j *job) {
+	delete(s.jobs, j.ID)
+	if s.store != nil {
+		if err := s.store.remove(j.ID); err != nil {
+			logger.WarnAttrs(ctx, "failed to remove a job", slog.String("id", j.ID), slog.String("err", err.Error()))
+		}
+	}
+}
+
+// handleCreate queues a new job.
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	req := Request{FindConfig: s.opts.defaults}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if err := checkRequest(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	j := &job{Job: Job{ID: newID(), State: StateQueued, Request: req, CreatedAt: time.Now()}}
+
+	s.mu.Lock()
+	select {
+	case s.queue <- j:
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "the job queue is full")
+		return
+	}
+	s.jobs[j.ID] = j
+	s.persist(r.Context(), j)
+	v := j.snapshot()
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/jobs/"+j.ID)
+	writeJSON(w, http.StatusAccepted, v)
+}
+
+// checkRequest validates a request, and turns off the options that only make sense on the command line.
+func checkRequest(req *Request) error {
+	if len(req.Paths) == 0 && req.FilesFrom == "" {
+		return errors.New("no paths to scan")
+	}
+	if req.FilesFrom == "-" {
+		return errors.New("files_from cannot be standard input")
+	}
+	if req.Workers < 1 {
+		return fmt.Errorf("too few workers: %d", req.Workers)
+	}
+	req.Verbose, req.ShowFilters, req.OutputFile = false, false, ""
+	// Exit statuses mean nothing to a job.
+	req.FailOnError, req.FailIfWastedOver, req.FailIfGroupsOver = false, "", 0
+	req.MetricsListen, req.MetricsTextfile, req.ProgressFD = "", "", 0
+	// The priority is that of the whole server, not of a job.
+	req.IdleIO, req.Nice = false, 0
+	// Files spilled in low-memory mode go to the temporary directory of the server.
+	req.SpillDir = ""
+	// Jobs are not resumed across restarts of the server.
+	req.Checkpoint = ""
+	return nil
+}
+
+// handleList lists the jobs, newest first.
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.snapshot())
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleGet returns a job.
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	j, ok := s.jobs[r.PathValue("id")]
+	var v Job
+	if ok {
+		v = j.snapshot()
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// handleCancel cancels a queued or running job.
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	j, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	switch j.State {
+	case StateQueued:
+		j.State, j.FinishedAt = StateCanceled, time.Now()
+		s.persist(r.Context(), j)
+	case StateRunning:
+		// The worker records the cancellation once the scan returns.
+		j.canceled = true
+		j.cancel()
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is already %s", j.State))
+		return
+	}
+	v := j.snapshot()
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, v)
+}
+
+// handleDelete removes a finished job from the history.
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if !j.State.Finished() {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s, cancel it first", j.State))
+		return
+	}
+	s.remove(r.Context(), j)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReport returns the report of a finished job, in the format named by the "format" query parameter.
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	formatter, ok := s.formatters.Get(format)
+	if !ok {
+		names := s.formatters.List()
+		slices.Sort(names)
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("unknown format '%s', must be one of %s", format, strings.Join(names, ", ")))
+		return
+	}
+
+	s.mu.Lock()
+	j, ok := s.jobs[r.PathValue("id")]
+	var state State
+	var report *model.DuplicateReport
+	if ok {
+		state, report = j.State, j.report
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	case state != StateDone:
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s, no report is available", state))
+		return
+	}
+
+	if report == nil {
+		var err error
+		if report, err = s.store.loadReport(j.ID); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("error loading the report: %v", err))
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(report, &buf); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("error formatting the report: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType(format))
+	_, _ = buf.WriteTo(w)
+}
+
+// contentType returns the media type of a report format.
+func contentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "yaml":
+		return "application/yaml"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
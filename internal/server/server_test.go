@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/config"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/output"
+)
+
+// testReport returns a report with a single duplicate group.
+func testReport(stats *model.Stats) *model.DuplicateReport {
+	stats.TotalFiles, stats.DuplicateGroups, stats.DuplicateFiles = 3, 1, 2
+	return &model.DuplicateReport{
+		ScanDate:         time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Stats:            stats,
+		TotalWastedSpace: 10,
+		Groups: []model.DuplicateGroup{
+			{ID: 1, Count: 2, Size: 10, WastedSpace: 10, Files: []string{"/data/a", "/data/b"}},
+		},
+	}
+}
+
+// reportRunner returns a report right away.
+func reportRunner(_ context.Context, _ Request, stats *model.Stats) (*model.DuplicateReport, error) {
+	return testReport(stats), nil
+}
+
+// blockingRunner returns a runner that reports each started job on started, then waits for ctx to be canceled.
+func blockingRunner(started chan<- string) Runner {
+	return func(ctx context.Context, req Request, stats *model.Stats) (*model.DuplicateReport, error) {
+		stats.IncrementTotalFiles()
+		started <- strings.Join(req.Paths, ",")
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+// startServer starts a server processing jobs until the test ends.
+func startServer(t *testing.T, run Runner, opts ...Option) *httptest.Server {
+	t.Helper()
+	reg, err := output.InitFormatters()
+	if err != nil {
+		t.Fatalf("InitFormatters() error = %v", err)
+	}
+	opts = append([]Option{WithDefaults(config.FindConfig{Workers: 2, OutputFormat: "pretty"})}, opts...)
+	s, err := New(run, reg, opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Process(ctx)
+		close(done)
+	}()
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+		<-done
+	})
+	return ts
+}
+
+// do sends a request and returns the status and body of the response.
+func do(t *testing.T, method, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the response error = %v", err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+// createJob starts a job and returns it.
+func createJob(t *testing.T, ts *httptest.Server, body string) Job {
+	t.Helper()
+	status, resp := do(t, http.MethodPost, ts.URL+"/jobs", body)
+	if status != http.StatusAccepted {
+		t.Fatalf("POST /jobs status = %d, want %d: %s", status, http.StatusAccepted, resp)
+	}
+	var j Job
+	if err := json.Unmarshal([]byte(resp), &j); err != nil {
+		t.Fatalf("decoding the job error = %v", err)
+	}
+	return j
+}
+
+// waitFor polls a job until it reaches the wanted state.
+func waitFor(t *testing.T, ts *httptest.Server, id string, want State) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, resp := do(t, http.MethodGet, ts.URL+"/jobs/"+id, "")
+		if status != http.StatusOK {
+			t.Fatalf("GET /jobs/%s status = %d: %s", id, status, resp)
+		}
+		var j Job
+		if err := json.Unmarshal([]byte(resp), &j); err != nil {
+			t.Fatalf("decoding the job error = %v", err)
+		}
+		if j.State == want {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job state = %s, want %s", j.State, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestServer_Job verifies that a job runs to completion and that its report can be fetched in every format.
+func TestServer_Job(t *testing.T) {
+	var got Request
+	ts := startServer(t, func(ctx context.Context, req Request, stats *model.Stats) (*model.DuplicateReport, error) {
+		got = req
+		return reportRunner(ctx, req, stats)
+	})
+
+	j := createJob(t, ts, `{"paths": ["/data"], "min_size": "1KB", "verbose": true}`)
+	if j.State != StateQueued && j.State != StateRunning {
+		t.Errorf("new job state = %s", j.State)
+	}
+	j = waitFor(t, ts, j.ID, StateDone)
+
+	if got.Workers != 2 || got.MinSize != "1KB" || got.Verbose {
+		t.Errorf("request = %+v, want the defaults with min_size set and verbose off", got.FindConfig)
+	}
+	if j.Progress == nil || j.Progress.TotalFiles != 3 {
+		t.Errorf("progress = %+v, want 3 files", j.Progress)
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		contentType string
+		contains    string
+	}{
+		{name: "default", query: "", wantStatus: http.StatusOK, contentType: "application/json", contains: `"/data/b"`},
+		{name: "yaml", query: "?format=yaml", wantStatus: http.StatusOK, contentType: "application/yaml", contains: "- /data/b"},
+		{name: "pretty", query: "?format=pretty", wantStatus: http.StatusOK, contentType: "text/plain", contains: "/data/b"},
+		{name: "unknown", query: "?format=xml", wantStatus: http.StatusBadRequest, contentType: "application/json", contains: "json, pretty, yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/jobs/" + j.ID + "/report" + tt.query) //nolint:noctx
+			if err != nil {
+				t.Fatalf("GET report error = %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			if !strings.Contains(string(body), tt.contains) {
+				t.Errorf("body = %s, want it to contain %q", body, tt.contains)
+			}
+		})
+	}
+
+	status, resp := do(t, http.MethodGet, ts.URL+"/jobs", "")
+	var jobs []Job
+	if err := json.Unmarshal([]byte(resp), &jobs); status != http.StatusOK || err != nil || len(jobs) != 1 {
+		t.Errorf("GET /jobs = %d %s, want one job", status, resp)
+	}
+
+	if status, _ := do(t, http.MethodDelete, ts.URL+"/jobs/"+j.ID, ""); status != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want %d", status, http.StatusNoContent)
+	}
+	if status, _ := do(t, http.MethodGet, ts.URL+"/jobs/"+j.ID, ""); status != http.StatusNotFound {
+		t.Errorf("GET deleted job status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+// TestServer_BadRequests verifies that invalid requests are rejected.
+func TestServer_BadRequests(t *testing.T) {
+	ts := startServer(t, reportRunner)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid JSON", method: http.MethodPost, path: "/jobs", body: `{"paths":`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", method: http.MethodPost, path: "/jobs", body: `{"pahts": ["/data"]}`, wantStatus: http.StatusBadRequest},
+		{name: "file list on stdin", method: http.MethodPost, path: "/jobs", body: `{"files_from": "-"}`, wantStatus: http.StatusBadRequest},
+		{name: "no paths", method: http.MethodPost, path: "/jobs", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "no workers", method: http.MethodPost, path: "/jobs", body: `{"paths": ["/data"], "workers": 0}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown job", method: http.MethodGet, path: "/jobs/nope", wantStatus: http.StatusNotFound},
+		{name: "cancel unknown job", method: http.MethodPost, path: "/jobs/nope/cancel", wantStatus: http.StatusNotFound},
+		{name: "report of unknown job", method: http.MethodGet, path: "/jobs/nope/report", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := do(t, tt.method, ts.URL+tt.path, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if !strings.Contains(resp, `"error"`) {
+				t.Errorf("body = %s, want an error", resp)
+			}
+		})
+	}
+}
+
+// TestServer_Cancel verifies that running and queued jobs can be canceled, and that the queue is bounded.
+func TestServer_Cancel(t *testing.T) {
+	started := make(chan string, 4)
+	ts := startServer(t, blockingRunner(started), WithQueueSize(1))
+
+	running := createJob(t, ts, `{"paths": ["/running"]}`)
+	<-started
+	waitFor(t, ts, running.ID, StateRunning)
+	queued := createJob(t, ts, `{"paths": ["/queued"]}`)
+
+	if status, _ := do(t, http.MethodPost, ts.URL+"/jobs", `{"paths": ["/full"]}`); status != http.StatusServiceUnavailable {
+		t.Errorf("POST /jobs on a full queue status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if status, _ := do(t, http.MethodGet, ts.URL+"/jobs/"+running.ID+"/report", ""); status != http.StatusConflict {
+		t.Errorf("report of a running job status = %d, want %d", status, http.StatusConflict)
+	}
+	if status, _ := do(t, http.MethodDelete, ts.URL+"/jobs/"+running.ID, ""); status != http.StatusConflict {
+		t.Errorf("DELETE of a running job status = %d, want %d", status, http.StatusConflict)
+	}
+
+	if status, _ := do(t, http.MethodPost, ts.URL+"/jobs/"+queued.ID+"/cancel", ""); status != http.StatusAccepted {
+		t.Errorf("cancel of a queued job status = %d, want %d", status, http.StatusAccepted)
+	}
+	if status, _ := do(t, http.MethodPost, ts.URL+"/jobs/"+running.ID+"/cancel", ""); status != http.StatusAccepted {
+		t.Errorf("cancel of a running job status = %d, want %d", status, http.StatusAccepted)
+	}
+	j := waitFor(t, ts, running.ID, StateCanceled)
+	if j.Progress == nil || j.Progress.TotalFiles != 1 {
+		t.Errorf("progress = %+v, want 1 file", j.Progress)
+	}
+	waitFor(t, ts, queued.ID, StateCanceled)
+
+	if status, _ := do(t, http.MethodPost, ts.URL+"/jobs/"+running.ID+"/cancel", ""); status != http.StatusConflict {
+		t.Errorf("cancel of a canceled job status = %d, want %d", status, http.StatusConflict)
+	}
+
+	// The canceled job is skipped, and the next one runs.
+	next := createJob(t, ts, `{"paths": ["/next"]}`)
+	select {
+	case paths := <-started:
+		if paths != "/next" {
+			t.Errorf("started job paths = %s, want /next", paths)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the next job")
+	}
+	waitFor(t, ts, next.ID, StateRunning)
+}
+
+// TestServer_History verifies that jobs and reports are persisted, and that old jobs are dropped.
+func TestServer_History(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := startServer(t, reportRunner, WithDataDir(dir), WithMaxHistory(2))
+	var ids []string
+	for range 3 {
+		j := createJob(t, ts, `{"paths": ["/data"]}`)
+		waitFor(t, ts, j.ID, StateDone)
+		ids = append(ids, j.ID)
+	}
+	ts.Close()
+
+	// A job that was running when the server stopped, and one that was still queued.
+	interrupted := Job{ID: "interrupted", State: StateRunning, CreatedAt: time.Now()}
+	queued := Job{ID: "queued", State: StateQueued, Request: Request{Paths: []string{"/data"}}, CreatedAt: time.Now()}
+	for _, j := range []Job{interrupted, queued} {
+		data, _ := json.Marshal(j)
+		if err := os.WriteFile(filepath.Join(dir, j.ID+jobSuffix), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts = startServer(t, reportRunner, WithDataDir(dir), WithMaxHistory(3))
+	if status, _ := do(t, http.MethodGet, ts.URL+"/jobs/"+ids[0], ""); status != http.StatusNotFound {
+		t.Errorf("oldest job status = %d, want it dropped", status)
+	}
+	status, resp := do(t, http.MethodGet, ts.URL+"/jobs/"+ids[2]+"/report", "")
+	if status != http.StatusOK || !strings.Contains(resp, `"/data/b"`) {
+		t.Errorf("persisted report = %d %s", status, resp)
+	}
+
+	j := waitFor(t, ts, "interrupted", StateFailed)
+	if !strings.Contains(j.Error, "interrupted") {
+		t.Errorf("interrupted job error = %q", j.Error)
+	}
+	waitFor(t, ts, "queued", StateDone)
+
+	// Three finished jobs are kept: the two oldest runs were dropped.
+	status, resp = do(t, http.MethodGet, ts.URL+"/jobs", "")
+	var jobs []Job
+	if err := json.Unmarshal([]byte(resp), &jobs); status != http.StatusOK || err != nil || len(jobs) != 3 {
+		t.Errorf("GET /jobs = %d %s, want three jobs", status, resp)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dr8co/doppel/internal/model"
+)
+
+const (
+	jobSuffix    = ".job.json"
+	reportSuffix = ".report.json"
+)
+
+// store persists jobs and their reports as JSON files in a directory.
+type store struct {
+	dir string
+}
+
+// newStore returns a store in dir, creating the directory if needed.
+func newStore(dir string) (*store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating the job directory: %w", err)
+	}
+	return &store{dir: dir}, nil
+}
+
+// loadJobs returns the persisted jobs. Files that cannot be parsed are skipped.
+func (st *store) loadJobs() ([]Job, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the job directory: %w", err)
+	}
+
+	var jobs []Job
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), jobSuffix) {
+			continue
+		}
+		var j Job
+		if err := readJSONFile(filepath.Join(st.dir, entry.Name()), &j); err != nil || j.ID == "" {
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// saveJob persists a job.
+func (st *store) saveJob(j Job) error {
+	return writeJSONFile(filepath.Join(st.dir, j.ID+jobSuffix), j)
+}
+
+// saveReport persists the report of a job.
+func (st *store) saveReport(id string, report *model.DuplicateReport) error {
+	return writeJSONFile(filepath.Join(st.dir, id+reportSuffix), report)
+}
+
+// loadReport returns the report of a job.
+func (st *store) loadReport(id string) (*model.DuplicateReport, error) {
+	var report model.DuplicateReport
+	if err := readJSONFile(filepath.Join(st.dir, id+reportSuffix), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// remove deletes a job and its report.
+func (st *store) remove(id string) error {
+	var errs []error
+	for _, suffix := range []string{jobSuffix, reportSuffix} {
+		if err := os.Remove(filepath.Join(st.dir, id+suffix)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readJSONFile decodes the JSON file at path into v.
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSONFile encodes v to the file at path, replacing it atomically.
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
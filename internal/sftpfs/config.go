@@ -0,0 +1,152 @@
+package sftpfs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Config configures SSH connections.
+type Config struct {
+	// User is the user name for URLs without one.
+	User string
+
+	// Agent holds keys to authenticate with. If nil, the agent listening on AgentSocket is used.
+	Agent agent.Agent
+
+	// AgentSocket is the socket of an SSH agent, like SSH_AUTH_SOCK.
+	AgentSocket string
+
+	// IdentityFiles are private keys to authenticate with. Missing and passphrase-protected keys are skipped;
+	// load the latter into the agent instead.
+	IdentityFiles []string
+
+	// HostKeyCallback verifies host keys. If nil, host keys are verified against KnownHostsFiles.
+	HostKeyCallback ssh.HostKeyCallback
+
+	// KnownHostsFiles are OpenSSH known_hosts files listing the keys of trusted hosts.
+	KnownHostsFiles []string
+
+	// Timeout limits the time to establish a connection, 30 seconds by default.
+	Timeout time.Duration
+}
+
+// ConfigFromEnv returns the configuration of the current user: the SSH agent named by SSH_AUTH_SOCK,
+// and the default keys and known_hosts file of ~/.ssh.
+func ConfigFromEnv() Config {
+	cfg := Config{AgentSocket: os.Getenv("SSH_AUTH_SOCK")}
+
+	if u, err := user.Current(); err == nil {
+		cfg.User = u.Username
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		sshDir := filepath.Join(home, ".ssh")
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			cfg.IdentityFiles = append(cfg.IdentityFiles, filepath.Join(sshDir, name))
+		}
+		cfg.KnownHostsFiles = []string{filepath.Join(sshDir, "known_hosts")}
+	}
+
+	return cfg
+}
+
+// clientConfig returns the SSH client configuration for user.
+// The returned function releases the connection to the agent, which is only needed during authentication.
+func (cfg Config) clientConfig(user string) (*ssh.ClientConfig, func(), error) {
+	hostKeyCallback := cfg.HostKeyCallback
+	if hostKeyCallback == nil {
+		var files []string
+		for _, file := range cfg.KnownHostsFiles {
+			if _, err := os.Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+		if len(files) == 0 {
+			return nil, nil, errors.New("no known_hosts file to verify host keys with; connect with ssh once to add the host")
+		}
+
+		var err error
+		if hostKeyCallback, err = knownhosts.New(files...); err != nil {
+			return nil, nil, fmt.Errorf("error reading known hosts: %w", err)
+		}
+	}
+
+	signers, err := loadKeys(cfg.IdentityFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closeAgent := func() {}
+	keyring := cfg.Agent
+	if keyring == nil && cfg.AgentSocket != "" {
+		if conn, err := net.Dial("unix", cfg.AgentSocket); err == nil {
+			keyring = agent.NewClient(conn)
+			closeAgent = func() {
+				_ = conn.Close()
+			}
+		}
+	}
+
+	if keyring == nil && len(signers) == 0 {
+		return nil, nil, errors.New("no SSH agent or private key to authenticate with")
+	}
+
+	// Clients try each authentication method once, so agent and file keys are offered together.
+	auth := ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		if keyring == nil {
+			return signers, nil
+		}
+		agentSigners, err := keyring.Signers()
+		if err != nil {
+			return signers, nil
+		}
+		return append(agentSigners, signers...), nil
+	})
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, closeAgent, nil
+}
+
+// loadKeys reads unencrypted private keys, skipping missing and passphrase-protected ones.
+func loadKeys(files []string) ([]ssh.Signer, error) {
+	var signers []ssh.Signer
+	for _, file := range files {
+		//nolint:gosec
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading private key: %w", err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(data)
+		var passphraseErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseErr) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing private key %s: %w", file, err)
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
@@ -0,0 +1,143 @@
+package sftpfs
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an in-process SSH server serving an in-memory filesystem over SFTP.
+// It accepts public key authentication with a single client key.
+type testServer struct {
+	addr      string
+	hostKey   ssh.Signer
+	clientKey ssh.Signer
+
+	// keyFile holds the unencrypted client key in OpenSSH format.
+	keyFile string
+}
+
+// newTestServer starts a server on a loopback port, stopped when the test ends.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	hostKey := newSigner(t)
+	clientKey, keyPEM := newSignerPEM(t)
+
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write private key: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.PublicKey().Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	handlers := sftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config, handlers)
+		}
+	}()
+
+	return &testServer{addr: listener.Addr().String(), hostKey: hostKey, clientKey: clientKey, keyFile: keyFile}
+}
+
+// serveConn serves the SFTP subsystem on the sessions of an SSH connection.
+func serveConn(conn net.Conn, config *ssh.ServerConfig, handlers sftp.Handlers) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+				if ok {
+					server := sftp.NewRequestServer(channel, handlers)
+					_ = server.Serve()
+					_ = server.Close()
+				}
+			}
+		}()
+	}
+}
+
+// config returns a client configuration trusting the server's host key.
+func (s *testServer) config() Config {
+	return Config{
+		User:            "tester",
+		IdentityFiles:   []string{s.keyFile},
+		HostKeyCallback: ssh.FixedHostKey(s.hostKey.PublicKey()),
+	}
+}
+
+// url returns the URL of a path on the server.
+func (s *testServer) url(p string) *url.URL {
+	return &url.URL{Scheme: Scheme, User: url.User("tester"), Host: s.addr, Path: p}
+}
+
+// newSigner generates an Ed25519 key.
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	signer, _ := newSignerPEM(t)
+	return signer
+}
+
+// newSignerPEM generates an Ed25519 key, also returned as an unencrypted OpenSSH private key.
+func newSignerPEM(t *testing.T) (ssh.Signer, []byte) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return signer, pem.EncodeToMemory(block)
+}
@@ -0,0 +1,267 @@
+// Package sftpfs scans remote hosts over SFTP.
+//
+// It provides a [vfs.FS] over the files of an SSH server, so that remote directories can be walked and hashed
+// without installing doppel on the host. Quick hashes only read the first and last portions of each file
+// through ranged reads. Files are named by URLs such as "sftp://user@host/path",
+// which keeps them apart from local files and from other hosts in reports.
+package sftpfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Scheme is the URL scheme of files on SFTP servers.
+const Scheme = "sftp"
+
+// FS is a read-only filesystem over the files of an SFTP server.
+// Names are URLs like "sftp://user@host/dir/file" holding absolute remote paths.
+type FS struct {
+	prefix string
+	client *sftp.Client
+	conn   *ssh.Client
+	stop   func() bool
+}
+
+// Dial connects to the host of a URL and starts an SFTP session.
+// The connection is closed when ctx is done, or with [FS.Close].
+func Dial(ctx context.Context, cfg Config, u *url.URL) (*FS, error) {
+	user := u.User.Username()
+	if user == "" {
+		user = cfg.User
+	}
+	if user == "" {
+		return nil, fmt.Errorf("missing user name in '%s'", u.Redacted())
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+
+	clientConfig, closeAgent, err := cfg.clientConfig(user)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAgent()
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", host, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, host, clientConfig)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("error connecting to %s: %w", host, err)
+	}
+	conn := ssh.NewClient(sshConn, chans, reqs)
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("error starting SFTP session on %s: %w", host, err)
+	}
+
+	f := &FS{
+		prefix: Scheme + "://" + user + "@" + u.Host,
+		client: client,
+		conn:   conn,
+	}
+	f.stop = context.AfterFunc(ctx, func() {
+		_ = f.close()
+	})
+
+	return f, nil
+}
+
+// Opener returns a [vfs.Opener] for "sftp://" URLs that connects with cfg.
+func Opener(cfg Config) vfs.Opener {
+	return func(ctx context.Context, u *url.URL) (vfs.FS, error) {
+		return Dial(ctx, cfg, u)
+	}
+}
+
+// Close ends the SFTP session and closes the connection.
+func (f *FS) Close() error {
+	f.stop()
+	return f.close()
+}
+
+func (f *FS) close() error {
+	return errors.Join(f.client.Close(), f.conn.Close())
+}
+
+// NameOf returns the name of the file a URL refers to.
+func (f *FS) NameOf(u *url.URL) string {
+	return f.name(u.Path)
+}
+
+// name returns the name of a remote path.
+func (f *FS) name(remotePath string) string {
+	return f.prefix + path.Clean("/"+remotePath)
+}
+
+// remotePath returns the remote path of a name.
+func (f *FS) remotePath(op, name string) (string, error) {
+	rest, ok := strings.CutPrefix(name, f.prefix)
+	if !ok || rest != "" && rest[0] != '/' {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Clean("/" + rest), nil
+}
+
+// Join joins name elements, keeping the "sftp://user@host" prefix of the first.
+func (f *FS) Join(elem ...string) string {
+	if len(elem) == 0 {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(elem[0], f.prefix); ok {
+		return f.name(path.Join(append([]string{rest}, elem[1:]...)...))
+	}
+	return path.Join(elem...)
+}
+
+// Open opens a remote file. Reads stream the file, and ReadAt issues ranged reads.
+func (f *FS) Open(name string) (vfs.File, error) {
+	p, err := f.remotePath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := f.client.Open(p)
+	if err != nil {
+		return nil, f.pathError("open", name, err)
+	}
+	return file, nil
+}
+
+// Stat returns information about a remote file, following symlinks.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.remotePath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.client.Stat(p)
+	if err != nil {
+		return nil, f.pathError("stat", name, err)
+	}
+	return info, nil
+}
+
+// Lstat returns information about a remote file without following symlinks.
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := f.remotePath("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.client.Lstat(p)
+	if err != nil {
+		return nil, f.pathError("lstat", name, err)
+	}
+	return info, nil
+}
+
+// ReadDir reads a remote directory, returning its entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := f.remotePath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := f.client.ReadDir(p)
+	if err != nil {
+		return nil, f.pathError("readdir", name, err)
+	}
+
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// EvalSymlinks resolves symlinks and returns the name of an existing file.
+// Links are resolved one path element at a time, since not all servers resolve them in realpath requests.
+func (f *FS) EvalSymlinks(name string) (string, error) {
+	p, err := f.remotePath("evalsymlinks", name)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := f.resolve(p)
+	if err != nil {
+		return "", f.pathError("evalsymlinks", name, err)
+	}
+
+	return f.name(resolved), nil
+}
+
+// resolve resolves the symlinks in an absolute remote path.
+func (f *FS) resolve(p string) (string, error) {
+	const maxLinks = 255
+
+	resolved := "/"
+	pending := strings.Split(p, "/")
+	for links := 0; len(pending) > 0; {
+		elem := pending[0]
+		pending = pending[1:]
+
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, elem)
+		info, err := f.client.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > maxLinks {
+			return "", errors.New("too many levels of symbolic links")
+		}
+		target, err := f.client.ReadLink(next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	return resolved, nil
+}
+
+// pathError names the file of an SFTP error by its URL.
+// The SFTP client already maps missing files and denied access to [fs.ErrNotExist] and [fs.ErrPermission].
+func (f *FS) pathError(op, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
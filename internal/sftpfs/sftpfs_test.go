@@ -0,0 +1,233 @@
+package sftpfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// writeFiles creates remote files through a connection to the server.
+func writeFiles(t *testing.T, fsys *FS, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		if err := fsys.client.MkdirAll(filepath.ToSlash(filepath.Dir(name))); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		f, err := fsys.client.Create(name)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+}
+
+// TestFS verifies walking, stat data, symlink resolution, and reads of remote files.
+func TestFS(t *testing.T) {
+	srv := newTestServer(t)
+	fsys, err := Dial(context.Background(), srv.config(), srv.url("/"))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func(fsys *FS) {
+		if err := fsys.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}(fsys)
+
+	content := []byte("hello, remote host")
+	writeFiles(t, fsys, map[string][]byte{"/data/b.txt": content, "/data/a/c.txt": content})
+	if err := fsys.client.Symlink("/data/b.txt", "/data/link"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+
+	root := fsys.NameOf(srv.url("/data/"))
+	if want := "sftp://tester@" + srv.addr + "/data"; root != want {
+		t.Errorf("NameOf() = %q, want %q", root, want)
+	}
+
+	var walked []string
+	err = vfs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, strings.TrimPrefix(name, root))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+	if want := []string{"", "/a", "/a/c.txt", "/b.txt", "/link"}; !slices.Equal(walked, want) {
+		t.Errorf("WalkDir() visited %v, want %v", walked, want)
+	}
+
+	info, err := fsys.Lstat(fsys.Join(root, "link"))
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat() of a symlink = %v, %v, want a symlink", info, err)
+	}
+	if target, err := fsys.EvalSymlinks(fsys.Join(root, "link")); err != nil || target != root+"/b.txt" {
+		t.Errorf("EvalSymlinks() = %q, %v, want %q", target, err, root+"/b.txt")
+	}
+	if _, err := fsys.Stat(fsys.Join(root, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a missing file error = %v, want not exist", err)
+	}
+
+	f, err := fsys.Open(fsys.Join(root, "b.txt"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func(f vfs.File) {
+		_ = f.Close()
+	}(f)
+
+	data, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("ReadAll() = %q, %v, want %q", data, err, content)
+	}
+	buf := make([]byte, 6)
+	if n, err := f.ReadAt(buf, 7); err != nil || string(buf[:n]) != "remote" {
+		t.Errorf("ReadAt() = %q, %v, want %q", buf[:n], err, "remote")
+	}
+}
+
+// TestDial verifies agent authentication and the rejection of unknown host keys.
+func TestDial(t *testing.T) {
+	srv := newTestServer(t)
+
+	keyring := agent.NewKeyring()
+	_, keyPEM := newSignerPEM(t)
+	rawKey, err := ssh.ParseRawPrivateKey(keyPEM)
+	if err != nil {
+		t.Fatalf("ParseRawPrivateKey() error = %v", err)
+	}
+	if err := keyring.Add(agent.AddedKey{PrivateKey: rawKey}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// The agent holds an unknown key; the identity file is still tried after it.
+	cfg := srv.config()
+	cfg.Agent = keyring
+	fsys, err := Dial(context.Background(), cfg, srv.url("/"))
+	if err != nil {
+		t.Fatalf("Dial() with agent and key file error = %v", err)
+	}
+	_ = fsys.Close()
+
+	// Authentication through the agent alone.
+	clientKey, err := ssh.ParseRawPrivateKey(mustRead(t, srv.keyFile))
+	if err != nil {
+		t.Fatalf("ParseRawPrivateKey() error = %v", err)
+	}
+	keyring = agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: clientKey}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	cfg = srv.config()
+	cfg.Agent, cfg.IdentityFiles = keyring, nil
+	fsys, err = Dial(context.Background(), cfg, srv.url("/"))
+	if err != nil {
+		t.Fatalf("Dial() with agent error = %v", err)
+	}
+	_ = fsys.Close()
+
+	// A host key missing from known_hosts is rejected.
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := "127.0.0.1 " + string(ssh.MarshalAuthorizedKey(newSigner(t).PublicKey()))
+	if err := os.WriteFile(knownHosts, []byte(line), 0o600); err != nil {
+		t.Fatalf("Failed to write known_hosts: %v", err)
+	}
+	cfg = srv.config()
+	cfg.HostKeyCallback, cfg.KnownHostsFiles = nil, []string{knownHosts}
+	if fsys, err := Dial(context.Background(), cfg, srv.url("/")); err == nil {
+		_ = fsys.Close()
+		t.Error("Dial() with an unknown host key should return an error")
+	}
+
+	cfg = srv.config()
+	cfg.IdentityFiles = []string{filepath.Join(t.TempDir(), "missing")}
+	if fsys, err := Dial(context.Background(), cfg, srv.url("/")); err == nil {
+		_ = fsys.Close()
+		t.Error("Dial() without keys should return an error")
+	}
+}
+
+// TestFindDuplicates verifies that duplicates are found across local and remote roots in one report.
+func TestFindDuplicates(t *testing.T) {
+	content := bytes.Repeat([]byte("shared between hosts "), 2000)
+
+	srv := newTestServer(t)
+	backends := vfs.NewRegistry()
+	backends.Register(Scheme, Opener(srv.config()))
+	defer func(backends *vfs.Registry) {
+		_ = backends.Close()
+	}(backends)
+
+	fsys, name, err := backends.Open(context.Background(), srv.url("/srv/share").String())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	writeFiles(t, fsys.(*FS), map[string][]byte{
+		"/srv/share/a.bin":     content,
+		"/srv/share/sub/b.bin": content,
+		"/srv/share/c.bin":     bytes.ToUpper(content),
+	})
+
+	localDir := t.TempDir()
+	localFile := filepath.Join(localDir, "copy.bin")
+	if err := os.WriteFile(localFile, content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	roots := []scanner.Root{{Path: localDir}, {Path: name, FS: fsys}}
+	s := &model.Stats{}
+	sizeGroups, err := scanner.GroupRootsBySize(context.Background(), roots, &filter.Config{}, s)
+	if err != nil {
+		t.Fatalf("GroupRootsBySize() error = %v", err)
+	}
+	if s.TotalFiles != 4 {
+		t.Errorf("Stats.TotalFiles = %d, want 4", s.TotalFiles)
+	}
+
+	report, err := finder.FindDuplicatesByHash(context.Background(), sizeGroups, 2, s)
+	if err != nil {
+		t.Fatalf("FindDuplicatesByHash() error = %v", err)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("FindDuplicatesByHash() returned %d duplicate groups, want 1", len(report.Groups))
+	}
+
+	files := slices.Sorted(slices.Values(report.Groups[0].Files))
+	want := slices.Sorted(slices.Values([]string{localFile, name + "/a.bin", name + "/sub/b.bin"}))
+	if !slices.Equal(files, want) {
+		t.Errorf("Duplicate group files = %v, want %v", files, want)
+	}
+}
+
+func mustRead(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return data
+}
@@ -0,0 +1,163 @@
+package vfs
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory filesystem with slash-separated absolute names, such as "/data/file.txt".
+// It is safe for concurrent use.
+type MemFS struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+}
+
+// memNode is a file or directory in a [MemFS]. It doubles as its own [fs.FileInfo].
+type memNode struct {
+	name    string
+	data    []byte
+	dir     bool
+	modTime time.Time
+}
+
+// NewMemFS returns an empty in-memory filesystem containing only the root directory.
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{"/": {name: "/", dir: true}}}
+}
+
+// WriteFile creates or replaces the named file, creating any missing parent directories.
+func (m *MemFS) WriteFile(name string, data []byte) error {
+	name = memClean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if n, ok := m.nodes[name]; ok && n.dir {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		n, ok := m.nodes[dir]
+		if ok && !n.dir {
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: fs.ErrExist}
+		}
+		if !ok {
+			m.nodes[dir] = &memNode{name: path.Base(dir), dir: true, modTime: time.Now()}
+		}
+		if dir == "/" {
+			break
+		}
+	}
+
+	m.nodes[name] = &memNode{name: path.Base(name), data: bytes.Clone(data), modTime: time.Now()}
+	return nil
+}
+
+// Open opens the named file for reading.
+func (m *MemFS) Open(name string) (File, error) {
+	n, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{Reader: bytes.NewReader(n.data), node: n}, nil
+}
+
+// Stat returns information about the named file.
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	return m.lookup("stat", name)
+}
+
+// Lstat returns information about the named file. MemFS has no symlinks, so it is the same as [MemFS.Stat].
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.lookup("lstat", name)
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	dir := memClean(name)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []fs.DirEntry
+	for p, child := range m.nodes {
+		if p != "/" && path.Dir(p) == dir {
+			entries = append(entries, fs.FileInfoToDirEntry(child))
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// EvalSymlinks returns the cleaned name of an existing file.
+func (m *MemFS) EvalSymlinks(name string) (string, error) {
+	if _, err := m.lookup("lstat", name); err != nil {
+		return "", err
+	}
+	return memClean(name), nil
+}
+
+// Join joins name elements with slashes.
+func (m *MemFS) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// lookup returns the node with the given name.
+func (m *MemFS) lookup(op, name string) (*memNode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n, ok := m.nodes[memClean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return n, nil
+}
+
+// memClean returns the canonical form of a name.
+func memClean(name string) string {
+	return path.Clean("/" + name)
+}
+
+func (n *memNode) Name() string       { return n.name }
+func (n *memNode) Size() int64        { return int64(len(n.data)) }
+func (n *memNode) ModTime() time.Time { return n.modTime }
+func (n *memNode) IsDir() bool        { return n.dir }
+func (n *memNode) Sys() any           { return nil }
+
+func (n *memNode) Mode() fs.FileMode {
+	if n.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+// memFile is an open [MemFS] file.
+type memFile struct {
+	*bytes.Reader
+	node *memNode
+}
+
+// Stat returns information about the file.
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.node, nil
+}
+
+// Close does nothing, since the contents stay in memory.
+func (f *memFile) Close() error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// OS is the local operating system filesystem.
+type OS struct{}
+
+// Open opens the named file for reading.
+func (OS) Open(name string) (File, error) {
+	//nolint:gosec
+	return os.Open(name)
+}
+
+// Stat returns information about the named file, following symlinks.
+func (OS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Lstat returns information about the named file without following symlinks.
+func (OS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (OS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// EvalSymlinks returns the name after resolving any symlinks.
+func (OS) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(name)
+}
+
+// Join joins name elements with the OS path separator.
+func (OS) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
@@ -0,0 +1,122 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Checksummer is implemented by the [io/fs.FileInfo.Sys] data of backends that store a content checksum
+// with each file, such as the MD5 ETag of S3 objects.
+type Checksummer interface {
+	// Checksum returns the checksum prefixed by its algorithm, such as "md5:<hex>",
+	// or an empty string if the file has no usable checksum.
+	Checksum() string
+}
+
+// Checksum returns the stored checksum of a file, if its backend provides one.
+func Checksum(info interface{ Sys() any }) string {
+	if c, ok := info.Sys().(Checksummer); ok {
+		return c.Checksum()
+	}
+	return ""
+}
+
+// Opener opens the filesystem holding a URL. Filesystems are shared by URLs with the same scheme, user, and host.
+type Opener func(ctx context.Context, u *url.URL) (FS, error)
+
+// URLNamer is implemented by filesystems whose names are not the path of their URLs.
+type URLNamer interface {
+	// NameOf returns the name of the file a URL refers to.
+	NameOf(u *url.URL) string
+}
+
+// Registry maps URL schemes to the backends serving them.
+type Registry struct {
+	mu      sync.Mutex
+	openers map[string]Opener
+	opened  map[string]FS
+}
+
+// NewRegistry creates an empty backend registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		openers: make(map[string]Opener),
+		opened:  make(map[string]FS),
+	}
+}
+
+// Register adds a backend for a URL scheme.
+func (r *Registry) Register(scheme string, opener Opener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.openers[strings.ToLower(scheme)] = opener
+}
+
+// Open returns the filesystem holding rawURL and the name of the URL's file on it.
+// URLs of the same scheme, user, and host share a filesystem.
+func (r *Registry) Open(ctx context.Context, rawURL string) (FS, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL '%s': %w", rawURL, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	opener, ok := r.openers[u.Scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported URL scheme '%s'", u.Scheme)
+	}
+
+	key := u.Scheme + "://" + u.User.String() + "@" + u.Host
+	fsys, ok := r.opened[key]
+	if !ok {
+		if fsys, err = opener(ctx, u); err != nil {
+			return nil, "", err
+		}
+		r.opened[key] = fsys
+	}
+
+	if namer, ok := fsys.(URLNamer); ok {
+		return fsys, namer.NameOf(u), nil
+	}
+	return fsys, u.Path, nil
+}
+
+// Close closes the opened filesystems that hold resources, such as network connections.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for key, fsys := range r.opened {
+		if c, ok := fsys.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+		delete(r.opened, key)
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsURL reports whether path is a URL with a scheme, like "s3://bucket/key", rather than a local path.
+func IsURL(path string) bool {
+	scheme, rest, ok := strings.Cut(path, "://")
+	if !ok || len(scheme) < 2 || rest == "" {
+		return false
+	}
+	for i, c := range scheme {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+// Package vfs defines the filesystem interface the scanner walks and the hasher reads,
+// so that files can come from backends other than the local OS filesystem.
+//
+// The interface mirrors [io/fs], with two differences:
+//   - Names are the backend's own paths (e.g. absolute OS paths), not slash-separated relative paths.
+//   - Opened files support random access through [io.ReaderAt], which partial hashing relies on.
+//
+// This package provides the [OS] and in-memory ([MemFS]) implementations,
+// and a [Registry] mapping URL schemes such as "s3" to the backends serving them.
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// FS is a filesystem that can be walked and read.
+type FS interface {
+	// Open opens the named file for reading.
+	Open(name string) (File, error)
+
+	// Stat returns information about the named file, following symlinks.
+	Stat(name string) (fs.FileInfo, error)
+
+	// Lstat returns information about the named file without following symlinks.
+	Lstat(name string) (fs.FileInfo, error)
+
+	// ReadDir reads the named directory, returning its entries sorted by name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+
+	// EvalSymlinks returns the name after resolving any symlinks.
+	// Backends without symlinks return the cleaned name of an existing file.
+	EvalSymlinks(name string) (string, error)
+
+	// Join joins name elements with the backend's separator.
+	Join(elem ...string) string
+}
+
+// File is an open file with random access.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Closer
+
+	// Stat returns information about the file.
+	Stat() (fs.FileInfo, error)
+}
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or directory in the tree,
+// including root. It behaves like [io/fs.WalkDir] but joins names with [FS.Join].
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkDir recursively descends name, calling fn.
+func walkDir(fsys FS, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			// Successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		// Second call, to report the ReadDir error.
+		err = fn(name, d, err)
+		if err != nil {
+			if errors.Is(err, fs.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := walkDir(fsys, fsys.Join(name, entry.Name()), entry, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package vfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestWalkDir verifies that [WalkDir] visits entries in lexical order and honors [fs.SkipDir].
+func TestWalkDir(t *testing.T) {
+	mem := NewMemFS()
+	for _, name := range []string{"/root/b.txt", "/root/a/1.txt", "/root/skip/2.txt", "/root/c/d/3.txt"} {
+		if err := mem.WriteFile(name, []byte(name)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	var visited []string
+	err := WalkDir(mem, "/root", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "skip" {
+			return fs.SkipDir
+		}
+		visited = append(visited, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+
+	want := []string{"/root", "/root/a", "/root/a/1.txt", "/root/b.txt", "/root/c", "/root/c/d", "/root/c/d/3.txt"}
+	if !slices.Equal(visited, want) {
+		t.Errorf("WalkDir() visited %v, want %v", visited, want)
+	}
+
+	// A missing root is reported to the callback.
+	var rootErr error
+	_ = WalkDir(mem, "/missing", func(_ string, _ fs.DirEntry, err error) error {
+		rootErr = err
+		return nil
+	})
+	if !os.IsNotExist(rootErr) {
+		t.Errorf("WalkDir() reported %v for a missing root, want not exist", rootErr)
+	}
+}
+
+// TestMemFS verifies reading, random access, and stat data of [MemFS] files.
+func TestMemFS(t *testing.T) {
+	mem := NewMemFS()
+	if err := mem.WriteFile("/dir/file.txt", []byte("hello, world")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := mem.WriteFile("/dir", nil); err == nil {
+		t.Error("WriteFile() over a directory should return an error")
+	}
+	if err := mem.WriteFile("/dir/file.txt/child", nil); err == nil {
+		t.Error("WriteFile() below a file should return an error")
+	}
+
+	info, err := mem.Stat("/dir/../dir/file.txt")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Name() != "file.txt" || info.Size() != 12 || !info.Mode().IsRegular() {
+		t.Errorf("Stat() = (%s, %d, %v), want (file.txt, 12, regular)", info.Name(), info.Size(), info.Mode())
+	}
+
+	f, err := mem.Open("/dir/file.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func(f File) {
+		_ = f.Close()
+	}(f)
+
+	buf := make([]byte, 5)
+	if _, err := f.ReadAt(buf, 7); err != nil || string(buf) != "world" {
+		t.Errorf("ReadAt() = %q, %v, want \"world\", nil", buf, err)
+	}
+	if content, err := io.ReadAll(f); err != nil || string(content) != "hello, world" {
+		t.Errorf("ReadAll() = %q, %v, want \"hello, world\", nil", content, err)
+	}
+
+	if _, err := mem.Open("/dir/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("Open() of a missing file error = %v, want not exist", err)
+	}
+	if _, err := mem.ReadDir("/dir/file.txt"); err == nil {
+		t.Error("ReadDir() of a file should return an error")
+	}
+}
+
+// TestIsURL verifies that URLs are told apart from local paths, including Windows drive paths.
+func TestIsURL(t *testing.T) {
+	tests := map[string]bool{
+		"s3://bucket/key":       true,
+		"sftp://user@host/path": true,
+		"s3://":                 false,
+		"/home/user":            false,
+		"relative/dir":          false,
+		`C:\Users`:              false,
+		"c://weird":             false,
+		"1s://bucket":           false,
+	}
+	for path, want := range tests {
+		if got := IsURL(path); got != want {
+			t.Errorf("IsURL(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// TestRegistry verifies that URLs are opened by the backend of their scheme,
+// sharing a filesystem per host and naming files by URL path.
+func TestRegistry(t *testing.T) {
+	opened := 0
+	r := NewRegistry()
+	r.Register("mem", func(_ context.Context, _ *url.URL) (FS, error) {
+		opened++
+		return NewMemFS(), nil
+	})
+
+	fs1, name, err := r.Open(context.Background(), "mem://host/a/b")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if name != "/a/b" {
+		t.Errorf("Open() name = %q, want %q", name, "/a/b")
+	}
+	fs2, _, err := r.Open(context.Background(), "MEM://host/c")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if fs1 != fs2 || opened != 1 {
+		t.Errorf("Open() opened %d filesystems for one host, want 1", opened)
+	}
+	if _, _, err := r.Open(context.Background(), "mem://other/c"); err != nil || opened != 2 {
+		t.Errorf("Open() of another host = %v, opened %d filesystems, want 2", err, opened)
+	}
+
+	if _, _, err := r.Open(context.Background(), "ftp://host/file"); err == nil {
+		t.Error("Open() of an unregistered scheme should return an error")
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
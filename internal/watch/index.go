@@ -0,0 +1,374 @@
+package watch
+
+import (
+	"context"
+	"encoding/hex"
+	"hash"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+// Kind is the kind of change an [Event] reports.
+type Kind string
+
+const (
+	// KindGroup reports a duplicate group found by the initial scan.
+	KindGroup Kind = "group"
+
+	// KindDuplicate reports a file that became a copy of other files.
+	KindDuplicate Kind = "duplicate"
+
+	// KindRemoved reports a file that left a group which still has duplicates.
+	KindRemoved Kind = "removed"
+
+	// KindResolved reports a group that no longer has duplicates, after one of its files left it.
+	KindResolved Kind = "resolved"
+
+	// KindMoved reports a duplicate file that was renamed within the watched directories.
+	KindMoved Kind = "moved"
+)
+
+// Event reports a change to the duplicate groups.
+type Event struct {
+	// Time is when the change was seen.
+	Time time.Time `json:"time"`
+
+	// Kind is the kind of change.
+	Kind Kind `json:"event"`
+
+	// Path is the file that changed. For group events, it is the first file of the group.
+	Path string `json:"path"`
+
+	// From is the previous path of a moved file.
+	From string `json:"from,omitempty"`
+
+	// Size is the size of the file.
+	Size int64 `json:"size"`
+
+	// Hash is the hex-encoded full hash of the file.
+	Hash string `json:"hash"`
+
+	// Files are the other files of the group, after the change.
+	Files []string `json:"files"`
+}
+
+// File is a file found while scanning the watched directories.
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// file is a file known to the index.
+type file struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// Index holds the size and hash groups of the watched files, and updates them one file at a time.
+// Files are only hashed once another file has the same size.
+// An Index is not safe for concurrent use.
+type Index struct {
+	files  map[string]*file
+	bySize map[int64]map[string]struct{}
+	byHash map[string]map[string]struct{}
+
+	hasher hash.Hash
+	buf    []byte
+	stats  *model.Stats
+	now    func() time.Time
+}
+
+// NewIndex returns an empty index. Hashing errors are counted in stats.
+func NewIndex(stats *model.Stats) *Index {
+	return &Index{
+		files:  make(map[string]*file),
+		bySize: make(map[int64]map[string]struct{}),
+		byHash: make(map[string]map[string]struct{}),
+		hasher: scanner.NewHasher(),
+		buf:    make([]byte, 64*1024),
+		stats:  stats,
+		now:    time.Now,
+	}
+}
+
+// Has reports whether a file is in the index.
+func (ix *Index) Has(path string) bool {
+	_, ok := ix.files[path]
+	return ok
+}
+
+// Len returns the number of files in the index.
+func (ix *Index) Len() int {
+	return len(ix.files)
+}
+
+// Load adds the files found by a full scan, hashing the files with the same sizes with numWorkers workers.
+// It returns a [KindGroup] event for each duplicate group.
+func (ix *Index) Load(ctx context.Context, files []File, numWorkers int) []Event {
+	for _, f := range files {
+		ix.insert(f.Path, &file{size: f.Size, modTime: f.ModTime})
+	}
+
+	var candidates []scanner.FileInfo
+	for size, paths := range ix.bySize {
+		if len(paths) > 1 {
+			for path := range paths {
+				candidates = append(candidates, scanner.FileInfo{Path: path, Size: size})
+			}
+		}
+	}
+
+	hashed := make(map[string]bool, len(candidates))
+	for _, digest := range finder.ChecksumFiles(ctx, candidates, numWorkers, ix.stats, scanner.NewHasher) {
+		ix.setHash(digest.File.Path, string(digest.Sum))
+		hashed[digest.File.Path] = true
+	}
+	// Files that could not be read are left out, as during an update.
+	for _, f := range candidates {
+		if !hashed[f.Path] {
+			ix.delete(f.Path)
+		}
+	}
+
+	var events []Event
+	for _, paths := range ix.byHash {
+		if len(paths) > 1 {
+			sorted := slices.Sorted(maps.Keys(paths))
+			events = append(events, ix.event(KindGroup, sorted[0], sorted[1:]))
+		}
+	}
+	slices.SortFunc(events, func(a, b Event) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return events
+}
+
+// Update records the current size and modification time of a file, adding it if it is new.
+// Unchanged files are left alone; changed files leave their group and join the group of their new content.
+func (ix *Index) Update(ctx context.Context, f File) []Event {
+	path, size := f.Path, f.Size
+	old, known := ix.files[path]
+	if known && old.size == size && old.modTime.Equal(f.ModTime) {
+		return nil
+	}
+
+	events := ix.Remove(path)
+	ix.insert(path, &file{size: size, modTime: f.ModTime})
+
+	peers := ix.bySize[size]
+	if len(peers) < 2 {
+		return events
+	}
+
+	// Files are hashed once their size group has two files: the new file is hashed,
+	// along with the first file of the group if it was alone until now.
+	for peer := range peers {
+		if ix.files[peer].hash == "" {
+			h, err := ix.hash(ctx, peer, size)
+			if err != nil {
+				ix.delete(peer)
+				continue
+			}
+			ix.setHash(peer, h)
+		}
+	}
+
+	current, ok := ix.files[path]
+	if !ok {
+		return events
+	}
+	if known && old.hash == current.hash {
+		// Rewritten with the same content: the groups are unchanged.
+		return nil
+	}
+	if others := ix.others(current.hash, path); len(others) > 0 {
+		events = append(events, ix.event(KindDuplicate, path, others))
+	}
+	return events
+}
+
+// Remove removes a file from the index.
+func (ix *Index) Remove(path string) []Event {
+	f, ok := ix.files[path]
+	if !ok {
+		return nil
+	}
+	ix.delete(path)
+	if f.hash == "" {
+		return nil
+	}
+
+	others := ix.others(f.hash, path)
+	switch len(others) {
+	case 0:
+		return nil
+	case 1:
+		return []Event{ix.eventOf(KindResolved, path, f, others)}
+	default:
+		return []Event{ix.eventOf(KindRemoved, path, f, others)}
+	}
+}
+
+// RemoveTree removes every file under dir from the index.
+func (ix *Index) RemoveTree(dir string) []Event {
+	var events []Event
+	for _, path := range ix.under(dir) {
+		events = append(events, ix.Remove(path)...)
+	}
+	return events
+}
+
+// Rename moves a file to a new path, keeping its hash.
+func (ix *Index) Rename(oldPath, newPath string) []Event {
+	f, ok := ix.files[oldPath]
+	if !ok {
+		return nil
+	}
+	events := ix.Remove(newPath)
+	ix.delete(oldPath)
+	ix.insert(newPath, f)
+
+	if others := ix.others(f.hash, newPath); f.hash != "" && len(others) > 0 {
+		e := ix.eventOf(KindMoved, newPath, f, others)
+		e.From = oldPath
+		events = append(events, e)
+	}
+	return events
+}
+
+// RenameTree moves every file under oldDir to newDir.
+func (ix *Index) RenameTree(oldDir, newDir string) []Event {
+	var events []Event
+	for _, path := range ix.under(oldDir) {
+		events = append(events, ix.Rename(path, newDir+strings.TrimPrefix(path, oldDir))...)
+	}
+	return events
+}
+
+// Sync makes the files under dir match those found by rescanning it: files that are gone are removed,
+// and the others are updated.
+func (ix *Index) Sync(ctx context.Context, dir string, files []File) []Event {
+	found := make(map[string]bool, len(files))
+	for _, f := range files {
+		found[f.Path] = true
+	}
+
+	var events []Event
+	for _, path := range ix.under(dir) {
+		if !found[path] {
+			events = append(events, ix.Remove(path)...)
+		}
+	}
+	for _, f := range files {
+		events = append(events, ix.Update(ctx, f)...)
+	}
+	return events
+}
+
+// under returns the paths of the files under dir, sorted.
+func (ix *Index) under(dir string) []string {
+	var paths []string
+	for path := range ix.files {
+		if within(path, dir) {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// insert adds a file to its size group, and to its hash group if it is hashed.
+func (ix *Index) insert(path string, f *file) {
+	ix.files[path] = f
+	if ix.bySize[f.size] == nil {
+		ix.bySize[f.size] = make(map[string]struct{})
+	}
+	ix.bySize[f.size][path] = struct{}{}
+	if f.hash != "" {
+		ix.addHash(path, f.hash)
+	}
+}
+
+// delete removes a file from the index and its groups.
+func (ix *Index) delete(path string) {
+	f, ok := ix.files[path]
+	if !ok {
+		return
+	}
+	delete(ix.files, path)
+	removeFrom(ix.bySize, f.size, path)
+	if f.hash != "" {
+		removeFrom(ix.byHash, f.hash, path)
+	}
+}
+
+// setHash records the hash of a file.
+func (ix *Index) setHash(path, h string) {
+	ix.files[path].hash = h
+	ix.addHash(path, h)
+}
+
+// addHash adds a file to a hash group.
+func (ix *Index) addHash(path, h string) {
+	if ix.byHash[h] == nil {
+		ix.byHash[h] = make(map[string]struct{})
+	}
+	ix.byHash[h][path] = struct{}{}
+}
+
+// removeFrom removes path from the group of key, dropping the group once it is empty.
+func removeFrom[K comparable](groups map[K]map[string]struct{}, key K, path string) {
+	delete(groups[key], path)
+	if len(groups[key]) == 0 {
+		delete(groups, key)
+	}
+}
+
+// others returns the files with hash h other than path, sorted.
+func (ix *Index) others(h, path string) []string {
+	var others []string
+	for p := range ix.byHash[h] {
+		if p != path {
+			others = append(others, p)
+		}
+	}
+	slices.Sort(others)
+	return others
+}
+
+// hash computes the full hash of a file, logging and counting failures.
+func (ix *Index) hash(ctx context.Context, path string, size int64) (string, error) {
+	h, err := scanner.HashFileInfo(scanner.FileInfo{Path: path, Size: size}, ix.hasher, ix.buf)
+	if err != nil {
+		logger.ErrorAttrs(ctx, "failed to hash a file", slog.String("path", path), slog.String("err", err.Error()))
+		ix.stats.IncrementErrorCount()
+	}
+	return h, err
+}
+
+// event returns an event about the file at path.
+func (ix *Index) event(kind Kind, path string, others []string) Event {
+	return ix.eventOf(kind, path, ix.files[path], others)
+}
+
+// eventOf returns an event about f, at path.
+func (ix *Index) eventOf(kind Kind, path string, f *file, others []string) Event {
+	return Event{
+		Time:  ix.now(),
+		Kind:  kind,
+		Path:  path,
+		Size:  f.size,
+		Hash:  hex.EncodeToString([]byte(f.hash)),
+		Files: others,
+	}
+}
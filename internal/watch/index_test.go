@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// writeFile writes a file and returns it as seen by the watcher.
+func writeFile(t *testing.T, path, content string) File {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	return File{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+}
+
+// kinds returns the kinds and paths of events.
+func kinds(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, string(e.Kind)+" "+filepath.Base(e.Path))
+	}
+	return out
+}
+
+// TestIndex verifies that duplicate groups are tracked as files are added, changed, renamed, and removed.
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := &model.Stats{}
+	ix := NewIndex(s)
+
+	a := writeFile(t, filepath.Join(dir, "a.txt"), "same content")
+	b := writeFile(t, filepath.Join(dir, "b.txt"), "same content")
+	c := writeFile(t, filepath.Join(dir, "c.txt"), "other stuff!")
+	d := writeFile(t, filepath.Join(dir, "d.txt"), "unique size")
+
+	events := ix.Load(ctx, []File{a, b, c, d}, 2)
+	if got, want := kinds(events), []string{"group a.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("Load() events = %v, want %v", got, want)
+	}
+	if !slices.Equal(events[0].Files, []string{b.Path}) || events[0].Size != a.Size || len(events[0].Hash) != 64 {
+		t.Errorf("Load() event = %+v, want a group of a.txt and b.txt", events[0])
+	}
+	if ix.files[d.Path].hash != "" {
+		t.Error("Load() should not hash files of unique sizes")
+	}
+
+	// Updating an unchanged file does nothing.
+	if events := ix.Update(ctx, a); len(events) != 0 {
+		t.Errorf("Update() of an unchanged file = %v, want no events", kinds(events))
+	}
+
+	// A new copy joins the group.
+	e := writeFile(t, filepath.Join(dir, "sub", "e.txt"), "same content")
+	events = ix.Update(ctx, e)
+	if got, want := kinds(events), []string{"duplicate e.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("Update() events = %v, want %v", got, want)
+	}
+	if !slices.Equal(events[0].Files, []string{a.Path, b.Path}) {
+		t.Errorf("Update() event files = %v, want a.txt and b.txt", events[0].Files)
+	}
+
+	// A file of a unique size becomes a duplicate once a copy appears.
+	f := writeFile(t, filepath.Join(dir, "f.txt"), "unique size")
+	if got, want := kinds(ix.Update(ctx, f)), []string{"duplicate f.txt"}; !slices.Equal(got, want) {
+		t.Errorf("Update() events = %v, want %v", got, want)
+	}
+
+	// A renamed duplicate is not hashed again.
+	g := e
+	g.Path = filepath.Join(dir, "sub", "g.txt")
+	events = ix.Rename(e.Path, g.Path)
+	if got, want := kinds(events), []string{"moved g.txt"}; !slices.Equal(got, want) || events[0].From != e.Path {
+		t.Errorf("Rename() events = %v (from %s), want %v", got, events[0].From, want)
+	}
+
+	// Rewriting a file with other content moves it to another group.
+	time.Sleep(10 * time.Millisecond)
+	b = writeFile(t, b.Path, "other stuff!")
+	if got, want := kinds(ix.Update(ctx, b)), []string{"removed b.txt", "duplicate b.txt"}; !slices.Equal(got, want) {
+		t.Errorf("Update() events = %v, want %v", got, want)
+	}
+
+	// Removing the second to last copy resolves the group.
+	if got, want := kinds(ix.RemoveTree(filepath.Join(dir, "sub"))), []string{"resolved g.txt"}; !slices.Equal(got, want) {
+		t.Errorf("RemoveTree() events = %v, want %v", got, want)
+	}
+	if events := ix.Remove(a.Path); len(events) != 0 {
+		t.Errorf("Remove() of a file without duplicates = %v, want no events", kinds(events))
+	}
+
+	if ix.Len() != 4 || ix.Has(a.Path) || s.ErrorCount != 0 {
+		t.Errorf("Index has %d files (a.txt: %v, %d errors), want 4 files without a.txt and no errors", ix.Len(), ix.Has(a.Path), s.ErrorCount)
+	}
+}
+
+// TestIndex_Sync verifies that rescanning a directory removes the files that are gone and adds the new ones.
+func TestIndex_Sync(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ix := NewIndex(&model.Stats{})
+
+	a := writeFile(t, filepath.Join(dir, "a.txt"), "content")
+	b := writeFile(t, filepath.Join(dir, "sub", "b.txt"), "content")
+	ix.Load(ctx, []File{a, b}, 1)
+
+	if err := os.Remove(b.Path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	c := writeFile(t, filepath.Join(dir, "sub", "c.txt"), "content")
+
+	events := ix.Sync(ctx, filepath.Join(dir, "sub"), []File{c})
+	if got, want := kinds(events), []string{"resolved b.txt", "duplicate c.txt"}; !slices.Equal(got, want) {
+		t.Errorf("Sync() events = %v, want %v", got, want)
+	}
+	if ix.Has(b.Path) || !ix.Has(a.Path) || !ix.Has(c.Path) {
+		t.Error("Sync() should only replace the files under the rescanned directory")
+	}
+}
+
+// TestIndex_Unreadable verifies that files that cannot be hashed are left out and counted as errors.
+func TestIndex_Unreadable(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := &model.Stats{}
+	ix := NewIndex(s)
+
+	a := writeFile(t, filepath.Join(dir, "a.txt"), "content")
+	ix.Update(ctx, a)
+	if err := os.Remove(a.Path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	b := writeFile(t, filepath.Join(dir, "b.txt"), "content")
+	if events := ix.Update(ctx, b); len(events) != 0 {
+		t.Errorf("Update() events = %v, want none", kinds(events))
+	}
+	if ix.Has(a.Path) || !ix.Has(b.Path) || s.ErrorCount != 1 {
+		t.Errorf("Index has a.txt: %v, b.txt: %v, %d errors, want only b.txt and 1 error", ix.Has(a.Path), ix.Has(b.Path), s.ErrorCount)
+	}
+}
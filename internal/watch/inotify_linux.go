@@ -0,0 +1,298 @@
+package watch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/dr8co/doppel/internal/logger"
+)
+
+// watchMask selects the inotify events the watcher reacts to.
+// New files are picked up when they are closed after writing or moved into a watched directory,
+// rather than when they are created, so that files still being written are not hashed.
+const watchMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_DELETE | unix.IN_ONLYDIR | unix.IN_DONT_FOLLOW | unix.IN_EXCL_UNLINK
+
+// rootWatch is the inotify instance watching the directories under a root.
+// Each root has its own instance, so that a queue overflow only requires rescanning that root.
+type rootWatch struct {
+	root string
+	fd   int
+	file *os.File
+
+	// dirs maps watch descriptors to the directories they watch, and wds maps them back.
+	dirs map[int]string
+	wds  map[string]int
+}
+
+// rawEvent is an inotify event.
+type rawEvent struct {
+	wd     int
+	mask   uint32
+	cookie uint32
+	name   string
+}
+
+// batch is the events returned by a single read of an inotify instance.
+type batch struct {
+	rw     *rootWatch
+	events []rawEvent
+	err    error
+}
+
+// newRootWatch creates the inotify instance of a root.
+func newRootWatch(root string) (*rootWatch, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing inotify: %w", err)
+	}
+	// The descriptor is non-blocking, so reads go through the runtime poller and are interrupted by Close.
+	return &rootWatch{
+		root: root,
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		dirs: make(map[int]string),
+		wds:  make(map[string]int),
+	}, nil
+}
+
+// add watches a directory.
+func (rw *rootWatch) add(dir string) error {
+	wd, err := unix.InotifyAddWatch(rw.fd, dir, watchMask)
+	if err != nil {
+		if errors.Is(err, unix.ENOSPC) {
+			return fmt.Errorf("%w (raise fs.inotify.max_user_watches)", err)
+		}
+		return err
+	}
+	if old, ok := rw.dirs[wd]; ok {
+		delete(rw.wds, old)
+	}
+	rw.dirs[wd], rw.wds[dir] = dir, wd
+	return nil
+}
+
+// forgetTree stops watching dir and the directories under it.
+func (rw *rootWatch) forgetTree(dir string) {
+	for path, wd := range rw.wds {
+		if within(path, dir) {
+			// Directories moved out of the root are still watched by the kernel until removed.
+			_, _ = unix.InotifyRmWatch(rw.fd, uint32(wd)) //nolint:gosec
+			delete(rw.wds, path)
+			delete(rw.dirs, wd)
+		}
+	}
+}
+
+// renameTree updates the paths of the directories under a renamed directory.
+// Watches follow the directories they watch, so they are kept.
+func (rw *rootWatch) renameTree(oldDir, newDir string) {
+	for path, wd := range rw.wds {
+		if within(path, oldDir) {
+			newPath := newDir + strings.TrimPrefix(path, oldDir)
+			delete(rw.wds, path)
+			rw.dirs[wd], rw.wds[newPath] = newPath, wd
+		}
+	}
+}
+
+// read reads events until ctx is canceled or the instance is closed, sending them to batches.
+func (rw *rootWatch) read(ctx context.Context, batches chan<- batch) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := rw.file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+				return
+			}
+			select {
+			case batches <- batch{rw: rw, err: fmt.Errorf("error reading inotify events: %w", err)}:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		select {
+		case batches <- batch{rw: rw, events: parseEvents(buf[:n])}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// parseEvents decodes the inotify events in buf.
+func parseEvents(buf []byte) []rawEvent {
+	var events []rawEvent
+	for len(buf) >= unix.SizeofInotifyEvent {
+		nameLen := int(binary.NativeEndian.Uint32(buf[12:16]))
+		if len(buf) < unix.SizeofInotifyEvent+nameLen {
+			break
+		}
+		events = append(events, rawEvent{
+			wd:     int(int32(binary.NativeEndian.Uint32(buf[0:4]))), //nolint:gosec
+			mask:   binary.NativeEndian.Uint32(buf[4:8]),
+			cookie: binary.NativeEndian.Uint32(buf[8:12]),
+			name:   strings.TrimRight(string(buf[unix.SizeofInotifyEvent:unix.SizeofInotifyEvent+nameLen]), "\x00"),
+		})
+		buf = buf[unix.SizeofInotifyEvent+nameLen:]
+	}
+	return events
+}
+
+// Run scans the watched directories, emits a [KindGroup] event for each duplicate group,
+// and then emits events as files change, until ctx is canceled.
+func (w *Watcher) Run(ctx context.Context, emit func(Event)) error {
+	watches := make([]*rootWatch, 0, len(w.roots))
+	defer func() {
+		for _, rw := range watches {
+			_ = rw.file.Close()
+		}
+	}()
+
+	var files []File
+	for _, root := range w.roots {
+		rw, err := newRootWatch(root)
+		if err != nil {
+			return err
+		}
+		watches = append(watches, rw)
+		files = append(files, w.scanTree(ctx, root, rw.add)...)
+	}
+	w.stats.TotalFiles = uint64(len(files))
+
+	for _, e := range w.index.Load(ctx, files, w.opts.workers) {
+		emit(e)
+	}
+	if w.opts.ready != nil {
+		w.opts.ready(w.index.Len())
+	}
+
+	batches := make(chan batch)
+	for _, rw := range watches {
+		go rw.read(ctx, batches)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case b := <-batches:
+			if b.err != nil {
+				return b.err
+			}
+			for _, e := range w.handle(ctx, b.rw, b.events) {
+				emit(e)
+			}
+		}
+	}
+}
+
+// handle applies a batch of events to the index.
+func (w *Watcher) handle(ctx context.Context, rw *rootWatch, events []rawEvent) []Event {
+	var out []Event
+	for i := 0; i < len(events); i++ {
+		ev := events[i]
+		if ev.mask&unix.IN_Q_OVERFLOW != 0 {
+			logger.WarnAttrs(ctx, "inotify queue overflow, rescanning", slog.String("path", rw.root))
+			out = append(out, w.rescan(ctx, rw, rw.root)...)
+			continue
+		}
+
+		dir, ok := rw.dirs[ev.wd]
+		if !ok {
+			continue
+		}
+		if ev.mask&unix.IN_IGNORED != 0 {
+			delete(rw.dirs, ev.wd)
+			delete(rw.wds, dir)
+			continue
+		}
+		if ev.name == "" {
+			continue
+		}
+
+		path := filepath.Join(dir, ev.name)
+		isDir := ev.mask&unix.IN_ISDIR != 0
+		switch {
+		case ev.mask&unix.IN_MOVED_FROM != 0:
+			// A rename within the root is reported as a pair of events with the same cookie.
+			if i+1 < len(events) {
+				next := events[i+1]
+				if nextDir, ok := rw.dirs[next.wd]; ok && next.mask&unix.IN_MOVED_TO != 0 && next.cookie == ev.cookie {
+					i++
+					out = append(out, w.rename(ctx, rw, path, filepath.Join(nextDir, next.name), isDir)...)
+					continue
+				}
+			}
+			out = append(out, w.remove(rw, path, isDir)...)
+		case ev.mask&unix.IN_DELETE != 0:
+			out = append(out, w.remove(rw, path, isDir)...)
+		case isDir && ev.mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			if !w.filter.ShouldExcludeDir(path) {
+				out = append(out, w.rescan(ctx, rw, path)...)
+			}
+		case !isDir && ev.mask&(unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO) != 0:
+			out = append(out, w.update(ctx, path)...)
+		}
+	}
+	return out
+}
+
+// rescan scans dir again, watching any new directories, and brings the index up to date.
+func (w *Watcher) rescan(ctx context.Context, rw *rootWatch, dir string) []Event {
+	return w.index.Sync(ctx, dir, w.scanTree(ctx, dir, rw.add))
+}
+
+// update brings the index up to date with a file that was written or moved into place.
+func (w *Watcher) update(ctx context.Context, path string) []Event {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return w.index.Remove(path)
+	}
+	f, ok := w.fileOf(path, info)
+	if !ok {
+		return w.index.Remove(path)
+	}
+	return w.index.Update(ctx, f)
+}
+
+// remove removes a deleted or moved-out file or directory from the index.
+func (w *Watcher) remove(rw *rootWatch, path string, isDir bool) []Event {
+	if isDir {
+		rw.forgetTree(path)
+		return w.index.RemoveTree(path)
+	}
+	return w.index.Remove(path)
+}
+
+// rename moves a renamed file or directory in the index, without hashing it again.
+func (w *Watcher) rename(ctx context.Context, rw *rootWatch, oldPath, newPath string, isDir bool) []Event {
+	if isDir {
+		if w.filter.ShouldExcludeDir(newPath) {
+			return w.remove(rw, oldPath, true)
+		}
+		rw.renameTree(oldPath, newPath)
+		return w.index.RenameTree(oldPath, newPath)
+	}
+
+	if !w.index.Has(oldPath) {
+		return w.update(ctx, newPath)
+	}
+	info, err := os.Lstat(newPath)
+	if err != nil {
+		return w.index.Remove(oldPath)
+	}
+	if _, ok := w.fileOf(newPath, info); !ok {
+		return w.index.Remove(oldPath)
+	}
+	events := w.index.Rename(oldPath, newPath)
+	return append(events, w.update(ctx, newPath)...)
+}
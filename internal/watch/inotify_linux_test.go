@@ -0,0 +1,206 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/model"
+)
+
+// TestWatcher verifies that changes to watched directories are reported as they happen.
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "same content")
+	writeFile(t, filepath.Join(dir, "b.txt"), "same content")
+	writeFile(t, filepath.Join(dir, "skip", "c.txt"), "same content")
+
+	filterConfig, err := filter.BuildConfig("skip", "", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("BuildConfig() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 16)
+	ready := make(chan int, 1)
+	w := New([]string{dir}, filterConfig, &model.Stats{}, WithWorkers(2), WithReady(func(n int) { ready <- n }))
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(e Event) { events <- e })
+	}()
+
+	next := func() string {
+		t.Helper()
+		select {
+		case e := <-events:
+			rel, _ := filepath.Rel(dir, e.Path)
+			return string(e.Kind) + " " + rel
+		case err := <-done:
+			t.Fatalf("Run() returned early: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+		}
+		return ""
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		for _, w := range want {
+			if got := next(); got != w {
+				t.Fatalf("event = %q, want %q", got, w)
+			}
+		}
+	}
+
+	expect("group a.txt")
+	if n := <-ready; n != 2 {
+		t.Errorf("ready with %d files, want 2", n)
+	}
+
+	// Files in new directories are found, even if they were written before the directory was watched.
+	writeFile(t, filepath.Join(dir, "new", "deep", "d.txt"), "same content")
+	expect("duplicate new/deep/d.txt")
+
+	if err := os.Rename(filepath.Join(dir, "new"), filepath.Join(dir, "renamed")); err != nil {
+		t.Fatalf("Failed to rename directory: %v", err)
+	}
+	expect("moved renamed/deep/d.txt")
+
+	// Files in the renamed directory are still watched, under their new paths.
+	if err := os.Remove(filepath.Join(dir, "renamed", "deep", "d.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	expect("removed renamed/deep/d.txt")
+
+	writeFile(t, filepath.Join(dir, "b.txt"), "different content")
+	expect("resolved b.txt")
+
+	// Excluded directories are not watched.
+	writeFile(t, filepath.Join(dir, "skip", "e.txt"), "different content")
+	writeFile(t, filepath.Join(dir, "f.txt"), "different content")
+	expect("duplicate f.txt")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+// TestWatcher_Overflow verifies that a queue overflow rescans the root.
+func TestWatcher_Overflow(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "same content")
+	b := writeFile(t, filepath.Join(dir, "sub", "b.txt"), "same content")
+
+	w := New([]string{dir}, &filter.Config{}, &model.Stats{})
+	rw, err := newRootWatch(dir)
+	if err != nil {
+		t.Fatalf("newRootWatch() error = %v", err)
+	}
+	defer func() {
+		_ = rw.file.Close()
+	}()
+	w.index.Load(ctx, w.scanTree(ctx, dir, rw.add), 1)
+
+	// Changes whose events were lost.
+	if err := os.Remove(b.Path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "sub", "deeper", "c.txt"), "same content")
+
+	events := w.handle(ctx, rw, []rawEvent{{wd: -1, mask: unix.IN_Q_OVERFLOW}})
+	if got, want := kinds(events), []string{"resolved b.txt", "duplicate c.txt"}; !slices.Equal(got, want) {
+		t.Errorf("handle() events = %v, want %v", got, want)
+	}
+	if _, ok := rw.wds[filepath.Join(dir, "sub", "deeper")]; !ok {
+		t.Error("handle() should watch the directories found by the rescan")
+	}
+}
+
+// TestWatcher_Ownership verifies that the files excluded by the ownership filters are neither indexed
+// by the initial scan nor added as they change.
+func TestWatcher_Ownership(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "same content")
+	writeFile(t, filepath.Join(dir, "b.txt"), "same content")
+
+	tests := []struct {
+		name  string
+		owner int
+		want  int
+	}{
+		{"current user", os.Getuid(), 2},
+		{"another user", os.Getuid() + 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filterConfig := &filter.Config{}
+			if err := filterConfig.ApplyOwnership(strconv.Itoa(tt.owner), "", "", "", false); err != nil {
+				t.Fatalf("ApplyOwnership() error = %v", err)
+			}
+			w := New([]string{dir}, filterConfig, &model.Stats{})
+			rw, err := newRootWatch(dir)
+			if err != nil {
+				t.Fatalf("newRootWatch() error = %v", err)
+			}
+			defer func() {
+				_ = rw.file.Close()
+			}()
+
+			if files := w.scanTree(ctx, dir, rw.add); len(files) != tt.want {
+				t.Errorf("scanTree() found %d files, want %d", len(files), tt.want)
+			}
+			c := writeFile(t, filepath.Join(dir, tt.name+".txt"), "new content")
+			w.update(ctx, c.Path)
+			if got := w.index.Has(c.Path); got != (tt.want > 0) {
+				t.Errorf("update() indexed the new file = %v, want %v", got, tt.want > 0)
+			}
+		})
+	}
+}
+
+// TestParseEvents verifies the decoding of inotify events.
+func TestParseEvents(t *testing.T) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		t.Fatalf("InotifyInit1() error = %v", err)
+	}
+	defer func() {
+		_ = unix.Close(fd)
+	}()
+
+	dir := t.TempDir()
+	wd, err := unix.InotifyAddWatch(fd, dir, unix.IN_CLOSE_WRITE)
+	if err != nil {
+		t.Fatalf("InotifyAddWatch() error = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "a-somewhat-long-name.txt"), "x")
+	writeFile(t, filepath.Join(dir, "b"), "y")
+
+	buf := make([]byte, 4096)
+	n, err := unix.Read(fd, buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	events := parseEvents(buf[:n])
+	var names []string
+	for _, e := range events {
+		if e.wd != wd || e.mask&unix.IN_CLOSE_WRITE == 0 {
+			t.Errorf("event = %+v, want a close-write event of watch %d", e, wd)
+		}
+		names = append(names, e.name)
+	}
+	if want := []string{"a-somewhat-long-name.txt", "b"}; !slices.Equal(names, want) {
+		t.Errorf("parseEvents() names = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"runtime"
+)
+
+// Run is not supported on this platform.
+func (w *Watcher) Run(context.Context, func(Event)) error {
+	return errors.New("watching directories is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,132 @@
+// Package watch keeps track of duplicate files in directories as they change.
+//
+// A [Watcher] scans its directories once, then follows the changes reported by the operating system
+// and updates the size and hash groups of an [Index] one file at a time, emitting an [Event]
+// whenever a file becomes a duplicate of other files or a group of duplicates is resolved.
+//
+// Watching relies on inotify, and is only available on Linux.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/logger"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/vfs"
+)
+
+// Option configures a [Watcher].
+type Option func(*options)
+
+// options holds the settings of a watcher.
+type options struct {
+	workers int
+	ready   func(files int)
+}
+
+// WithWorkers sets the number of workers hashing files during the initial scan.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithReady sets a function called with the number of watched files once the initial scan is done,
+// before any change is processed.
+func WithReady(ready func(files int)) Option {
+	return func(o *options) {
+		o.ready = ready
+	}
+}
+
+// Watcher watches directories for duplicate files.
+type Watcher struct {
+	roots  []string
+	filter *filter.Config
+	stats  *model.Stats
+	index  *Index
+	opts   options
+}
+
+// New returns a watcher of the given directories, which must be absolute paths that do not overlap.
+// Files excluded by filterConfig are ignored, and errors are counted in stats.
+func New(roots []string, filterConfig *filter.Config, stats *model.Stats, opts ...Option) *Watcher {
+	o := options{workers: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Watcher{
+		roots:  roots,
+		filter: filterConfig,
+		stats:  stats,
+		index:  NewIndex(stats),
+		opts:   o,
+	}
+}
+
+// scanTree walks the directory tree at root, calling watchDir for each directory that is not excluded
+// and returning the regular files that are not excluded.
+// Symlinks are not followed.
+func (w *Watcher) scanTree(ctx context.Context, root string, watchDir func(dir string) error) []File {
+	var files []File
+	_ = vfs.WalkDir(vfs.OS{}, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			w.logError(ctx, "error accessing file", path, err)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fs.SkipAll
+		}
+
+		switch {
+		case d.IsDir():
+			if path != root && w.filter.ShouldExcludeDir(path) {
+				w.stats.IncrementSkippedDirs()
+				return fs.SkipDir
+			}
+			if err := watchDir(path); err != nil {
+				w.logError(ctx, "error watching directory", path, err)
+			}
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				w.logError(ctx, "error getting file info", path, err)
+				return nil
+			}
+			if f, ok := w.fileOf(path, info); ok {
+				files = append(files, f)
+			}
+		}
+		return nil
+	})
+	return files
+}
+
+// fileOf returns the file at path, unless it is not a regular file or it is excluded by the filters,
+// including the ownership filters, as in a scan.
+func (w *Watcher) fileOf(path string, info fs.FileInfo) (File, bool) {
+	if !info.Mode().IsRegular() || w.filter.ShouldExcludeFile(path, info.Size()) {
+		return File{}, false
+	}
+	if skip, _ := w.filter.ShouldExcludeByOwnership(path, info); skip {
+		return File{}, false
+	}
+	return File{Path: path, Size: info.Size(), ModTime: info.ModTime()}, true
+}
+
+// logError logs and counts an error about a path.
+func (w *Watcher) logError(ctx context.Context, msg, path string, err error) {
+	logger.ErrorAttrs(ctx, msg, slog.String("path", path), slog.String("err", err.Error()))
+	w.stats.IncrementErrorCount()
+}
+
+// within reports whether path is dir or a path under it.
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -47,16 +47,22 @@ func main() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 
+	// The first signal cancels the context, letting the command finish what it is doing and report
+	// what it found so far. The second one exits at once.
 	go func() {
 		sig := <-c
-		logger.InfoAttrs(ctx, "Received signal, shutting down", slog.String("signal", sig.String()))
-		exit(1)
+		logger.InfoAttrs(ctx, "Received signal, finishing up (send it again to exit now)",
+			slog.String("signal", sig.String()))
+		cancel()
+		sig = <-c
+		logger.InfoAttrs(ctx, "Received signal again, exiting", slog.String("signal", sig.String()))
+		exit(cmd.ExitError)
 	}()
 
 	appConfig, err := config.Load()
 	if err != nil {
 		logger.Error("failed to load the config", "error", err)
-		exit(1)
+		exit(cmd.ExitError)
 	}
 
 	app := &cli.Command{
@@ -95,6 +101,10 @@ and supports extensive filtering options to exclude unwanted files and directori
 		Commands: []*cli.Command{
 			cmd.FindCommand(&appConfig.Find),
 			cmd.PresetCommand(&appConfig.Preset),
+			cmd.ManifestCommand(&appConfig.Manifest),
+			cmd.HashCommand(&appConfig.Hash),
+			cmd.WatchCommand(&appConfig.Watch),
+			cmd.ServeCommand(&appConfig.Serve, &appConfig.Find),
 		},
 		DefaultCommand:        "find",
 		Suggest:               true,
@@ -124,10 +134,14 @@ and supports extensive filtering options to exclude unwanted files and directori
 		},
 	}
 
+	// The exit status tells scripts whether duplicates were found: see [cmd.ExitCode].
 	if err := app.Run(ctx, os.Args); err != nil {
-		fmt.Println()
-		logger.Error("application error", "error", err)
-		exit(1)
+		code := cmd.ExitCode(err)
+		if code == cmd.ExitError {
+			_, _ = fmt.Fprintln(os.Stderr)
+			logger.Error("application error", "error", err)
+		}
+		exit(code)
 	}
 }
 
@@ -0,0 +1,252 @@
+// Package doppel finds duplicate files. It is the library behind the doppel command,
+// for Go programs that embed the scan instead of running the binary.
+//
+// A [Finder] is configured once with options, then runs any number of scans.
+// A scan walks directory trees and groups the files by size, then compares the candidates
+// with a quick hash of their first and last bytes before hashing their whole content.
+// Scans write nothing to standard output: their progress is reported to observers,
+// and their results are returned as a [Report].
+//
+//	f, err := doppel.New(
+//		doppel.WithWorkers(8),
+//		doppel.WithExcludeDirs(".git", "node_modules"),
+//		doppel.WithMinSize(1<<20),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	report, err := f.Find(ctx, "/srv/photos", "/mnt/backup")
+package doppel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/dr8co/doppel/internal/checksum"
+	"github.com/dr8co/doppel/internal/filter"
+	"github.com/dr8co/doppel/internal/finder"
+	"github.com/dr8co/doppel/internal/iosched"
+	"github.com/dr8co/doppel/internal/model"
+	"github.com/dr8co/doppel/internal/scanner"
+)
+
+type (
+	// Report is the result of a scan: the groups of duplicate files found, and statistics.
+	Report = model.DuplicateReport
+
+	// Group is a group of files with the same content.
+	Group = model.DuplicateGroup
+
+	// Stats holds the statistics of a scan.
+	Stats = model.Stats
+
+	// FileError is an error that left a file out of a stage of a scan, as listed in a [Report].
+	FileError = model.FileError
+
+	// ProgressObserver is notified of the files and bytes processed by each stage of a scan.
+	ProgressObserver = model.ProgressObserver
+
+	// HashObserver is notified of the time taken to hash each file, and of the bytes read from it.
+	HashObserver = model.HashObserver
+
+	// EventObserver is notified of the stages of a scan, the entries skipped, the errors met,
+	// and the groups of duplicates found.
+	EventObserver = model.EventObserver
+)
+
+// Stages of a scan, as reported to observers.
+const (
+	StageWalk      = model.StageWalk
+	StageQuickHash = model.StageQuickHash
+	StageSample    = model.StageSample
+	StageCompare   = model.StageCompare
+	StageFullHash  = model.StageFullHash
+)
+
+// Kinds of entries skipped during a scan, as reported to an [EventObserver].
+const (
+	SkipFile    = model.SkipFile
+	SkipDir     = model.SkipDir
+	SkipMount   = model.SkipMount
+	SkipSymlink = model.SkipSymlink
+)
+
+// Kinds of errors listed in a [Report].
+const (
+	ErrorPermission = model.ErrorPermission
+	ErrorNotFound   = model.ErrorNotFound
+	ErrorIO         = model.ErrorIO
+	ErrorOther      = model.ErrorOther
+)
+
+// Finder finds duplicate files.
+type Finder struct {
+	workers    int
+	references []string
+	filter     *filter.Config
+	scanOpts   []scanner.Option
+	findOpts   []finder.Option
+	maxMemory  int64
+	progress   ProgressObserver
+	observer   HashObserver
+	events     EventObserver
+}
+
+// New returns a finder configured with opts.
+// It fails if an option is invalid, such as a malformed regular expression or an unknown hash algorithm.
+func New(opts ...Option) (*Finder, error) {
+	o := options{workers: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.workers < 1 {
+		return nil, fmt.Errorf("too few workers: %d", o.workers)
+	}
+
+	filterConfig, err := filter.NewConfig(o.excludeDirs, o.excludeFiles, o.excludeDirRegex, o.excludeFileRegex,
+		o.minSize, o.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := filterConfig.ApplyOwnership(o.owner, o.group, "", "", o.writableOnly); err != nil {
+		return nil, err
+	}
+
+	f := &Finder{
+		workers:    o.workers,
+		references: o.references,
+		filter:     filterConfig,
+		scanOpts: []scanner.Option{
+			scanner.WithFollowSymlinks(o.followSymlinks),
+			scanner.WithReportSymlinks(o.reportSymlinks),
+			scanner.WithOneFileSystem(o.oneFileSystem),
+			scanner.WithExcludeFSTypes(o.excludeFSTypes),
+			scanner.WithScanArchives(o.scanArchives),
+		},
+		findOpts: []finder.Option{finder.WithReferenceMode(len(o.references) > 0)},
+		progress: o.progress,
+		observer: o.observer,
+		events:   o.events,
+	}
+
+	if o.hashAlgorithm != "" && o.hashAlgorithm != "blake3" {
+		alg, err := checksum.Lookup(o.hashAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		f.findOpts = append(f.findOpts, finder.WithHasher(alg.New))
+	}
+	if o.ioPerDevice != "" {
+		limits, err := iosched.ParseLimits(o.ioPerDevice)
+		if err != nil {
+			return nil, err
+		}
+		f.findOpts = append(f.findOpts, finder.WithIOPerDevice(limits))
+	}
+	if o.maxReadRate < 0 || o.maxOpenFiles < 0 {
+		return nil, fmt.Errorf("invalid read limits: %d bytes per second, %d open files", o.maxReadRate, o.maxOpenFiles)
+	}
+	if throttle := iosched.NewThrottle(o.maxReadRate, o.maxOpenFiles); throttle != nil {
+		f.findOpts = append(f.findOpts, finder.WithThrottle(throttle))
+	}
+
+	samples, err := scanner.ParseSamples(o.samples)
+	if err != nil {
+		return nil, err
+	}
+	if samples.Enabled() {
+		f.findOpts = append(f.findOpts, finder.WithSamples(samples))
+	}
+	if o.compareSize > 0 {
+		f.findOpts = append(f.findOpts, finder.WithChunkedCompare(o.compareSize))
+	}
+	if o.maxMemory < 0 {
+		return nil, fmt.Errorf("invalid memory ceiling: %d bytes", o.maxMemory)
+	}
+	if o.maxMemory > 0 {
+		f.maxMemory = o.maxMemory
+		f.scanOpts = append(f.scanOpts, scanner.WithSpill(o.spillDir, f.batchMemory()))
+	}
+
+	return f, nil
+}
+
+// Find scans paths, local files or directories, and returns the groups of duplicate files found.
+//
+// Errors reading individual files do not stop the scan: they are counted in the statistics of the report,
+// listed in its errors, and reported to the event observer.
+// Canceling ctx stops the scan, and Find then returns the error of ctx.
+func (f *Finder) Find(ctx context.Context, paths ...string) (*Report, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no paths to scan")
+	}
+
+	roots, err := scanner.GetRoots(ctx, paths, "", f.references, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &model.Stats{StartTime: time.Now(), Observer: f.observer, Progress: f.progress, Events: f.events,
+		Errors: &model.ErrorLog{}}
+
+	index, err := scanner.IndexRootsBySize(ctx, roots, f.filter, s, f.scanOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning files: %w", err)
+	}
+	defer func() {
+		_ = index.Close()
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report, err := f.findInBatches(ctx, index, s)
+	s.Duration = time.Since(s.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("error finding duplicates: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// findInBatches hashes the files of index in batches of sizes, all at once unless memory is bounded.
+func (f *Finder) findInBatches(ctx context.Context, index *scanner.SizeIndex, s *Stats) (*Report, error) {
+	batchMemory := int64(math.MaxInt64)
+	if f.maxMemory > 0 {
+		batchMemory = f.batchMemory()
+	}
+
+	report := &Report{Stats: s}
+	for batch := range index.Batches(batchMemory, 2) {
+		part, err := finder.FindDuplicatesByHash(ctx, batch, f.workers, s,
+			append(slices.Clip(f.findOpts), finder.WithFirstGroupID(len(report.Groups)+1))...)
+		if err != nil {
+			return nil, err
+		}
+		report.Groups = append(report.Groups, part.Groups...)
+		report.TotalWastedSpace += part.TotalWastedSpace
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if err := index.Err(); err != nil {
+		return nil, err
+	}
+	report.ScanDate = time.Now()
+	report.Complete = ctx.Err() == nil
+	report.Errors = s.Errors.List()
+	return report, nil
+}
+
+// batchMemory returns the memory taken by the files spilled at a time, and by each batch of sizes hashed.
+func (f *Finder) batchMemory() int64 {
+	return max(f.maxMemory/4, 1)
+}
@@ -0,0 +1,302 @@
+package doppel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeFiles creates files under dir from a map of relative paths to contents.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// groupNames returns the sorted base names of the files in each group, sorted by their first name.
+func groupNames(report *Report) [][]string {
+	groups := make([][]string, 0, len(report.Groups))
+	for _, g := range report.Groups {
+		names := make([]string, 0, len(g.Files))
+		for _, f := range g.Files {
+			names = append(names, filepath.Base(f))
+		}
+		slices.Sort(names)
+		groups = append(groups, names)
+	}
+	slices.SortFunc(groups, func(a, b []string) int { return slices.Compare(a, b) })
+	return groups
+}
+
+// stageRecorder records the stages it is told about.
+type stageRecorder struct {
+	mu    sync.Mutex
+	ended []string
+}
+
+func (r *stageRecorder) StartStage(string, int64, int64) {}
+
+func (r *stageRecorder) Advance(string, int64, int64) {}
+
+func (r *stageRecorder) EndStage(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ended = append(r.ended, stage)
+}
+
+// hashCounter counts the files hashed in each stage.
+type hashCounter struct {
+	mu     sync.Mutex
+	hashed map[string]int
+}
+
+func (c *hashCounter) ObserveHash(stage string, _ time.Duration, _ int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hashed[stage]++
+}
+
+// groupCounter counts the groups found.
+type groupCounter struct {
+	mu     sync.Mutex
+	groups int
+}
+
+func (c *groupCounter) StageStarted(string, int64, int64) {}
+
+func (c *groupCounter) StageFinished(string) {}
+
+func (c *groupCounter) FileSkipped(string, string, string) {}
+
+func (c *groupCounter) Error(string, string, error) {}
+
+func (c *groupCounter) GroupFound(*Group) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.groups++
+}
+
+// TestFinder_Find verifies that duplicates are found under the options given.
+func TestFinder_Find(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":            "duplicate content",
+		"b.txt":            "duplicate content",
+		"c.log":            "duplicate content",
+		"unique.txt":       "unique content!!!",
+		"skip/d.txt":       "duplicate content",
+		"small/e.txt":      "tiny",
+		"small/f.txt":      "tiny",
+		"other/large.bin":  "a somewhat larger file",
+		"other/large2.bin": "a somewhat larger file",
+	})
+
+	tests := []struct {
+		name string
+		opts []Option
+		want [][]string
+	}{
+		{
+			name: "no options",
+			want: [][]string{
+				{"a.txt", "b.txt", "c.log", "d.txt"},
+				{"e.txt", "f.txt"},
+				{"large.bin", "large2.bin"},
+			},
+		},
+		{
+			name: "exclusions",
+			opts: []Option{WithExcludeDirs("skip"), WithExcludeFiles("*.log"), WithExcludeDirRegex("other$")},
+			want: [][]string{{"a.txt", "b.txt"}, {"e.txt", "f.txt"}},
+		},
+		{
+			name: "size limits",
+			opts: []Option{WithMinSize(5), WithMaxSize(20)},
+			want: [][]string{{"a.txt", "b.txt", "c.log", "d.txt"}},
+		},
+		{
+			name: "hash algorithm",
+			opts: []Option{WithHashAlgorithm("sha256"), WithWorkers(1), WithExcludeFileRegex(`\.bin$`)},
+			want: [][]string{{"a.txt", "b.txt", "c.log", "d.txt"}, {"e.txt", "f.txt"}},
+		},
+		{
+			name: "optional stages",
+			opts: []Option{WithSamples("middle,random=2"), WithChunkedCompare(10)},
+			want: [][]string{
+				{"a.txt", "b.txt", "c.log", "d.txt"},
+				{"e.txt", "f.txt"},
+				{"large.bin", "large2.bin"},
+			},
+		},
+		{
+			name: "low memory",
+			opts: []Option{WithMaxMemory(4), WithSpillDir(t.TempDir())},
+			want: [][]string{
+				{"a.txt", "b.txt", "c.log", "d.txt"},
+				{"e.txt", "f.txt"},
+				{"large.bin", "large2.bin"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.opts...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			report, err := f.Find(t.Context(), dir)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if got := groupNames(report); !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Find() groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFinder_References verifies that only the groups with reference files are reported.
+func TestFinder_References(t *testing.T) {
+	scan, refs := t.TempDir(), t.TempDir()
+	writeFiles(t, scan, map[string]string{"copy.txt": "kept", "a.txt": "pair", "b.txt": "pair"})
+	writeFiles(t, refs, map[string]string{"original.txt": "kept"})
+
+	f, err := New(WithReferences(refs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := f.Find(t.Context(), scan)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(report.Groups) != 1 || len(report.Groups[0].References) != 1 {
+		t.Fatalf("Find() groups = %+v, want one group with a reference", report.Groups)
+	}
+	if report.TotalWastedSpace != 4 {
+		t.Errorf("TotalWastedSpace = %d, want 4", report.TotalWastedSpace)
+	}
+}
+
+// TestFinder_Observers verifies that progress and hashing are reported to the observers.
+func TestFinder_Observers(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "same", "b": "same", "c": "diff"})
+
+	progress := &stageRecorder{}
+	hashes := &hashCounter{hashed: map[string]int{}}
+	groups := &groupCounter{}
+	f, err := New(WithProgress(progress), WithHashObserver(hashes), WithEvents(groups))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Find(t.Context(), dir); err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	wantStages := []string{StageWalk, StageQuickHash, StageFullHash}
+	if !slices.Equal(progress.ended, wantStages) {
+		t.Errorf("ended stages = %v, want %v", progress.ended, wantStages)
+	}
+	// The quick hash of c differs, so it is not hashed in full.
+	if hashes.hashed[StageQuickHash] != 3 || hashes.hashed[StageFullHash] != 2 {
+		t.Errorf("files hashed = %v, want 3 quick hashes and 2 full hashes", hashes.hashed)
+	}
+	if groups.groups != 1 {
+		t.Errorf("groups found = %d, want 1", groups.groups)
+	}
+}
+
+// TestFinder_Canceled verifies that a canceled scan returns the error of its context.
+func TestFinder_Canceled(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "same", "b": "same"})
+
+	f, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	report, err := f.Find(ctx, dir)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Find() error = %v, want %v", err, context.Canceled)
+	}
+	if report != nil {
+		t.Errorf("Find() report = %+v, want nil", report)
+	}
+}
+
+// TestFinder_NoStdout verifies that scans write nothing to standard output.
+func TestFinder_NoStdout(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "same", "b": "same", "c/d": "same"})
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f, err := New(WithExcludeDirs("e"))
+	if err == nil {
+		_, err = f.Find(t.Context(), dir)
+	}
+	os.Stdout = stdout
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(out) > 0 {
+		t.Errorf("Find() wrote %q to standard output", out)
+	}
+}
+
+// TestNew_Invalid verifies that invalid options are rejected.
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"workers", WithWorkers(0)},
+		{"regex", WithExcludeFileRegex("(")},
+		{"hash algorithm", WithHashAlgorithm("crc32")},
+		{"I/O limits", WithIOPerDevice("hdd=0")},
+		{"open files", WithMaxOpenFiles(-1)},
+		{"samples", WithSamples("random=0")},
+		{"memory", WithMaxMemory(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opt); err == nil {
+				t.Errorf("New() error = nil, want an error")
+			}
+		})
+	}
+
+	f, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Find(t.Context()); err == nil {
+		t.Errorf("Find() without paths error = nil, want an error")
+	}
+}
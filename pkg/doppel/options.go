@@ -0,0 +1,240 @@
+package doppel
+
+// Option configures a [Finder].
+type Option func(*options)
+
+// options holds the settings of a [Finder].
+type options struct {
+	workers int
+
+	excludeDirs      []string
+	excludeFiles     []string
+	excludeDirRegex  []string
+	excludeFileRegex []string
+	minSize          int64
+	maxSize          int64
+	owner            string
+	group            string
+	writableOnly     bool
+
+	followSymlinks bool
+	reportSymlinks bool
+	oneFileSystem  bool
+	excludeFSTypes []string
+	scanArchives   bool
+
+	references    []string
+	hashAlgorithm string
+	ioPerDevice   string
+	maxReadRate   int64
+	maxOpenFiles  int
+	samples       string
+	compareSize   int64
+	maxMemory     int64
+	spillDir      string
+
+	progress ProgressObserver
+	observer HashObserver
+	events   EventObserver
+}
+
+// WithWorkers sets the number of files hashed at the same time. The default is the number of CPUs.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithExcludeDirs skips the directories whose names match any of the glob patterns.
+func WithExcludeDirs(patterns ...string) Option {
+	return func(o *options) {
+		o.excludeDirs = append(o.excludeDirs, patterns...)
+	}
+}
+
+// WithExcludeFiles skips the files whose names match any of the glob patterns.
+func WithExcludeFiles(patterns ...string) Option {
+	return func(o *options) {
+		o.excludeFiles = append(o.excludeFiles, patterns...)
+	}
+}
+
+// WithExcludeDirRegex skips the directories whose paths match any of the regular expressions.
+func WithExcludeDirRegex(patterns ...string) Option {
+	return func(o *options) {
+		o.excludeDirRegex = append(o.excludeDirRegex, patterns...)
+	}
+}
+
+// WithExcludeFileRegex skips the files whose paths match any of the regular expressions.
+func WithExcludeFileRegex(patterns ...string) Option {
+	return func(o *options) {
+		o.excludeFileRegex = append(o.excludeFileRegex, patterns...)
+	}
+}
+
+// WithMinSize skips the files smaller than size bytes.
+func WithMinSize(size int64) Option {
+	return func(o *options) {
+		o.minSize = size
+	}
+}
+
+// WithMaxSize skips the files larger than size bytes.
+func WithMaxSize(size int64) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
+
+// WithOwner only includes the files owned by a user, given by name or ID.
+// Ownership is not supported on Windows, where [New] returns an error if it is set.
+func WithOwner(owner string) Option {
+	return func(o *options) {
+		o.owner = owner
+	}
+}
+
+// WithGroup only includes the files owned by a group, given by name or ID.
+// Ownership is not supported on Windows, where [New] returns an error if it is set.
+func WithGroup(group string) Option {
+	return func(o *options) {
+		o.group = group
+	}
+}
+
+// WithWritableOnly only includes the files the current user can remove.
+// Permissions are not checked on Windows, where [New] returns an error if it is enabled.
+func WithWritableOnly(enabled bool) Option {
+	return func(o *options) {
+		o.writableOnly = enabled
+	}
+}
+
+// WithFollowSymlinks follows the symlinks found while scanning.
+func WithFollowSymlinks(follow bool) Option {
+	return func(o *options) {
+		o.followSymlinks = follow
+	}
+}
+
+// WithReportSymlinks lists the symlinks found while scanning in the groups of their targets.
+func WithReportSymlinks(report bool) Option {
+	return func(o *options) {
+		o.reportSymlinks = report
+	}
+}
+
+// WithOneFileSystem stays on the filesystem of each path scanned, skipping mount points.
+func WithOneFileSystem(enabled bool) Option {
+	return func(o *options) {
+		o.oneFileSystem = enabled
+	}
+}
+
+// WithExcludeFSTypes skips the mount points of the given filesystem types, such as "nfs" or "tmpfs" (Linux only).
+func WithExcludeFSTypes(types ...string) Option {
+	return func(o *options) {
+		o.excludeFSTypes = append(o.excludeFSTypes, types...)
+	}
+}
+
+// WithScanArchives scans the members of zip and tar archives as read-only files.
+func WithScanArchives(enabled bool) Option {
+	return func(o *options) {
+		o.scanArchives = enabled
+	}
+}
+
+// WithReferences scans directories holding canonical copies, which are never suggested for removal.
+// Only the groups with both a reference file and another file are then reported.
+func WithReferences(dirs ...string) Option {
+	return func(o *options) {
+		o.references = append(o.references, dirs...)
+	}
+}
+
+// WithHashAlgorithm sets the algorithm of the full hashes: "blake3" (the default), "sha256", "sha512",
+// "sha384", "sha224", "sha1", or "md5".
+func WithHashAlgorithm(name string) Option {
+	return func(o *options) {
+		o.hashAlgorithm = name
+	}
+}
+
+// WithIOPerDevice reads the files of each device with workers of its own, as many as set for its kind
+// of device by limits such as "hdd=1,ssd=8", instead of sharing the workers across devices.
+// The files on rotational disks are read in the order they are laid out on the disk.
+func WithIOPerDevice(limits string) Option {
+	return func(o *options) {
+		o.ioPerDevice = limits
+	}
+}
+
+// WithMaxReadRate reads files at most bytesPerSecond bytes per second, across all workers.
+func WithMaxReadRate(bytesPerSecond int64) Option {
+	return func(o *options) {
+		o.maxReadRate = bytesPerSecond
+	}
+}
+
+// WithMaxOpenFiles reads at most n files at the same time.
+func WithMaxOpenFiles(n int) Option {
+	return func(o *options) {
+		o.maxOpenFiles = n
+	}
+}
+
+// WithSamples hashes samples of large files with the same first and last bytes at more points before reading
+// them in full: "middle", and "random=N" for N offsets picked at random, the same for all files of a size.
+// Points are separated by commas, as in "middle,random=4".
+func WithSamples(points string) Option {
+	return func(o *options) {
+		o.samples = points
+	}
+}
+
+// WithChunkedCompare compares the files of at least minSize bytes block by block instead of hashing them in full,
+// so that files stop being read as soon as they differ from all others.
+func WithChunkedCompare(minSize int64) Option {
+	return func(o *options) {
+		o.compareSize = minSize
+	}
+}
+
+// WithMaxMemory bounds the memory taken by the files found by a scan: the files are spilled to run files on disk
+// whenever they take a quarter of bytes, then hashed in batches of sizes taking another quarter.
+// Unlike the doppel command, it leaves the memory limit of the Go runtime as is.
+func WithMaxMemory(bytes int64) Option {
+	return func(o *options) {
+		o.maxMemory = bytes
+	}
+}
+
+// WithSpillDir creates the run files of [WithMaxMemory] in dir instead of the default directory for temporary files.
+func WithSpillDir(dir string) Option {
+	return func(o *options) {
+		o.spillDir = dir
+	}
+}
+
+// WithProgress reports the progress of each scan to p, which is called concurrently.
+func WithProgress(p ProgressObserver) Option {
+	return func(o *options) {
+		o.progress = p
+	}
+}
+
+// WithHashObserver reports every file hashed to observer, which is called concurrently.
+func WithHashObserver(observer HashObserver) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// WithEvents reports the events of each scan to observer, which is called concurrently.
+func WithEvents(observer EventObserver) Option {
+	return func(o *options) {
+		o.events = observer
+	}
+}
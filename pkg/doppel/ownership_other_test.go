@@ -0,0 +1,28 @@
+//go:build !unix
+
+package doppel
+
+import "testing"
+
+// TestNew_Ownership verifies that the ownership options are rejected where ownership is not supported.
+func TestNew_Ownership(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"owner", WithOwner("1000")},
+		{"group", WithGroup("1000")},
+		{"writable only", WithWritableOnly(true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opt); err == nil {
+				t.Errorf("New() error = nil, want an error")
+			}
+		})
+	}
+
+	if _, err := New(WithOwner(""), WithWritableOnly(false)); err != nil {
+		t.Errorf("New() without ownership filters error = %v", err)
+	}
+}
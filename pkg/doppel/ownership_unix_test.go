@@ -0,0 +1,40 @@
+//go:build unix
+
+package doppel
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestNew_Ownership verifies that the ownership options filter the files scanned.
+func TestNew_Ownership(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "same", "b": "same"})
+
+	f, err := New(WithOwner(strconv.Itoa(os.Getuid())), WithGroup(strconv.Itoa(os.Getgid())), WithWritableOnly(true))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	report, err := f.Find(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(report.Groups) != 1 {
+		t.Errorf("Find() returned %d groups, want 1", len(report.Groups))
+	}
+
+	f, err = New(WithOwner(strconv.Itoa(os.Getuid() + 1)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	report, err = f.Find(t.Context(), filepath.Join(dir, "a"), filepath.Join(dir, "b"))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(report.Groups) != 0 {
+		t.Errorf("Find() returned %d groups of files owned by another user, want none", len(report.Groups))
+	}
+}